@@ -62,6 +62,16 @@ class TallyService:
         election.status = ElectionStatus.TALLYING
         await self.db.commit()
 
+        # Move the ledger's election to tallying as well
+        try:
+            await self.fabric_client.invoke_chaincode(
+                chaincode_name=settings.FABRIC_CHAINCODE_NAME,
+                function_name="StartTallying",
+                args=[str(election_id)]
+            )
+        except Exception as e:
+            print(f"Error starting tally on blockchain: {e}")
+
         # Generate tally ID
         tally_id = hashlib.sha256(
             f"{election_id}:{datetime.utcnow().isoformat()}".encode()
@@ -126,7 +136,7 @@ class TallyService:
             result = await self.fabric_client.query_chaincode(
                 chaincode_name=settings.FABRIC_CHAINCODE_NAME,
                 function_name="GetAllVotes",
-                args=[str(election_id)]
+                args=[str(election_id), "false"]
             )
             return result.get("votes", [])
         except Exception as e:
@@ -162,7 +162,7 @@ class VerificationService:
         votes_result = await self.fabric_client.query_chaincode(
             chaincode_name=settings.FABRIC_CHAINCODE_NAME,
             function_name="GetAllVotes",
-            args=[str(election_id)]
+            args=[str(election_id), "false"]
         )
         recorded_votes = votes_result.get("votes", [])
 
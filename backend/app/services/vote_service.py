@@ -284,7 +284,8 @@ class VoteService:
             encrypted_vote=encrypted_vote,
             nullifier=nullifier,
             eligibility_proof_hash=hashlib.sha256(eligibility_proof.encode()).hexdigest(),
-            validity_proof_hash=hashlib.sha256(validity_proof.encode()).hexdigest()
+            validity_proof_hash=hashlib.sha256(validity_proof.encode()).hexdigest(),
+            proof_voter_root=election.voter_merkle_root
         )
 
         # Generate verification code
@@ -409,7 +410,8 @@ class VoteService:
         encrypted_vote: str,
         nullifier: str,
         eligibility_proof_hash: str,
-        validity_proof_hash: str
+        validity_proof_hash: str,
+        proof_voter_root: str
     ) -> dict:
         """Submit the vote to the Hyperledger Fabric blockchain."""
         try:
@@ -421,7 +423,8 @@ class VoteService:
                     encrypted_vote,
                     nullifier,
                     eligibility_proof_hash,
-                    validity_proof_hash
+                    validity_proof_hash,
+                    proof_voter_root
                 ]
             )
             return result
@@ -0,0 +1,149 @@
+/*
+ * Archive - Purging per-vote state of completed elections
+ *
+ * Every vote leaves a vote record, a verification code, sequence and block
+ * index entries, stored proofs and bulletin board entries in the world
+ * state long after the tally is final. ArchiveElection replaces them with a compact ElectionArchive
+ * holding the final tally and the bulletin board Merkle root, then deletes
+ * them with DelState.
+ *
+ * Nothing is lost: DelState only removes keys from the world state, and
+ * every deleted value stays in the block history, where GetHistoryForKey and
+ * a block scan can recover it and the archived Merkle root proves the
+ * recovered bulletin board complete. The election record, tally, tally
+ * versions and bulletin board root stay in the world state.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ArchiveRetention is how long after its tally a completed election keeps its
+// per-vote state before it may be archived
+const ArchiveRetention = 90 * 24 * time.Hour
+
+// ElectionArchive is the compact summary kept in place of an archived
+// election's votes and bulletin board entries
+type ElectionArchive struct {
+	ElectionID   string      `json:"electionId"`
+	Title        string      `json:"title"`
+	Tally        TallyResult `json:"tally"`
+	MerkleRoot   string      `json:"merkleRoot"`
+	MerkleScheme string      `json:"merkleScheme"`
+	BulletinSize int         `json:"bulletinSize"`
+	VoteCount    int         `json:"voteCount"`
+	// PurgedKeys is the number of world state keys deleted
+	PurgedKeys int       `json:"purgedKeys"`
+	TxID       string    `json:"txId"`
+	ArchivedAt time.Time `json:"archivedAt"`
+}
+
+func archiveKey(electionID string) string {
+	return fmt.Sprintf("archive:%s", electionID)
+}
+
+// purgeVotes deletes every vote of an election with its private ciphertext,
+// private proofs and sequence index entry
+func purgeVotes(ctx contractapi.TransactionContextInterface, election *Election) (int, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{election.ID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query votes: %v", err)
+	}
+	defer iterator.Close()
+
+	purged := 0
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read vote: %v", err)
+		}
+		var vote Vote
+		if err := json.Unmarshal(kv.Value, &vote); err != nil {
+			return 0, err
+		}
+
+		if vote.PrivateCollection != "" {
+			if err := ctx.GetStub().DelPrivateData(vote.PrivateCollection, kv.Key); err != nil {
+				return 0, fmt.Errorf("failed to delete private vote: %v", err)
+			}
+		}
+		if election.ProofStorage == ProofStoragePrivate {
+			proofKey, err := voteProofKey(ctx, election.ID, vote.NullifierCommitment)
+			if err != nil {
+				return 0, err
+			}
+			if err := ctx.GetStub().DelPrivateData(VoteProofCollection, proofKey); err != nil {
+				return 0, fmt.Errorf("failed to delete private vote proof: %v", err)
+			}
+		}
+		if vote.BulletinSequence > 0 {
+			if err := ctx.GetStub().DelState(voteSequenceKey(election.ID, vote.BulletinSequence)); err != nil {
+				return 0, fmt.Errorf("failed to delete vote sequence index: %v", err)
+			}
+			purged++
+		}
+		if err := ctx.GetStub().DelState(kv.Key); err != nil {
+			return 0, fmt.Errorf("failed to delete vote: %v", err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// purgeCompositeKeys deletes every key under objectType~electionID
+func purgeCompositeKeys(ctx contractapi.TransactionContextInterface, objectType, electionID string) (int, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{electionID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s keys: %v", objectType, err)
+	}
+	defer iterator.Close()
+
+	return purgeIterated(ctx, iterator)
+}
+
+// purgeParticipation deletes the periodic-reset participation records,
+// which are simple keys under participation:electionID:
+func purgeParticipation(ctx contractapi.TransactionContextInterface, electionID string) (int, error) {
+	prefix := fmt.Sprintf("participation:%s:", electionID)
+	iterator, err := ctx.GetStub().GetStateByRange(prefix, prefix[:len(prefix)-1]+";")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query participation: %v", err)
+	}
+	defer iterator.Close()
+
+	return purgeIterated(ctx, iterator)
+}
+
+// purgeVoteBlockIndex deletes the block number index of an election
+func purgeVoteBlockIndex(ctx contractapi.TransactionContextInterface, electionID string) (int, error) {
+	prefix := fmt.Sprintf("voteblock:%s:", electionID)
+	iterator, err := ctx.GetStub().GetStateByRange(prefix, prefix[:len(prefix)-1]+";")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query vote block index: %v", err)
+	}
+	defer iterator.Close()
+
+	return purgeIterated(ctx, iterator)
+}
+
+func purgeIterated(ctx contractapi.TransactionContextInterface, iterator shim.StateQueryIteratorInterface) (int, error) {
+	purged := 0
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read key: %v", err)
+		}
+		if err := ctx.GetStub().DelState(kv.Key); err != nil {
+			return 0, fmt.Errorf("failed to delete %q: %v", kv.Key, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
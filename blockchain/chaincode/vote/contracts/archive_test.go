@@ -0,0 +1,106 @@
+/*
+ * Archive Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCompletedElection(t *testing.T) (*VoteContract, *MockTransactionContext, *MockStub) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "alice", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	stub.Transient = map[string][]byte{"encryptedVote": []byte(testVote(2))}
+	_, err = contract.CastVotePrivate(ctx, "election-001", "bob", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 2}`, "hash", "proof"))
+	return contract, ctx, stub
+}
+
+func TestArchiveElection(t *testing.T) {
+	contract, ctx, stub := setupCompletedElection(t)
+
+	root, _ := contract.GetBulletinBoardRoot(ctx, "election-001")
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: time.Now().Add(ArchiveRetention + time.Hour).Unix()}
+
+	archive, err := contract.ArchiveElection(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, root["merkleRoot"], archive.MerkleRoot)
+	assert.Equal(t, root["size"], archive.BulletinSize)
+	assert.Equal(t, 2, archive.VoteCount)
+	assert.Equal(t, map[string]int{"1": 2}, archive.Tally.VoteCounts[DefaultQuestionID])
+
+	stored, err := contract.GetElectionArchive(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, archive, stored)
+
+	// Votes, their indexes and bulletin board entries are gone
+	for key := range stub.State {
+		for _, prefix := range []string{"\x00vote\x00", "\x00vcode\x00", "\x00bb\x00", "voteseq:"} {
+			assert.False(t, strings.HasPrefix(key, prefix), "key %q was not purged", key)
+		}
+	}
+	for _, collection := range stub.PrivateState {
+		assert.Empty(t, collection)
+	}
+
+	// The election, tally and bulletin board root remain
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "archived", election.Status)
+	results, err := contract.GetElectionResults(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, results.TotalVotes)
+	after, _ := contract.GetBulletinBoardRoot(ctx, "election-001")
+	assert.Equal(t, root["merkleRoot"], after["merkleRoot"])
+
+	_, err = contract.ArchiveElection(ctx, "election-001")
+	assert.Error(t, err)
+}
+
+func TestArchiveElectionRejections(t *testing.T) {
+	contract, ctx, stub := setupCompletedElection(t)
+
+	// Within the retention period
+	_, err := contract.ArchiveElection(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be archived before")
+
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: time.Now().Add(ArchiveRetention + time.Hour).Unix()}
+
+	ctx.Identity = &MockClientIdentity{MSPID: "Org2MSP"}
+	_, err = contract.ArchiveElection(ctx, "election-001")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	ctx.Identity = nil
+
+	// Elections that never completed
+	pending := createMockElection()
+	pending.ID = "election-002"
+	pending.Status = "pending"
+	electionJSON, _ := json.Marshal(pending)
+	stub.State["election:election-002"] = electionJSON
+	_, err = contract.ArchiveElection(ctx, "election-002")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only completed")
+
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,193 @@
+/*
+ * Canonical JSON - Stable serialization for hashing
+ *
+ * Hashes published on the bulletin board are computed over JSON, so a
+ * verifier in another language must be able to reproduce the exact bytes.
+ * The canonical form is:
+ *   - object keys sorted by Unicode code point (UTF-8 byte order)
+ *   - no whitespace between tokens
+ *   - strings escaped only where JSON requires it: '"', '\\', and control
+ *     characters (\b \f \n \r \t, otherwise \u00xx in lowercase hex);
+ *     everything else, including non-ASCII, is written as UTF-8
+ *   - integers in plain decimal without a sign on zero; other numbers in
+ *     the shortest form that round-trips a float64, using an exponent only
+ *     below 1e-6 or from 1e21 (as JavaScript's Number.prototype.toString)
+ *
+ * e.g. {"b": [1.50, true], "a": "é\n"} is written as {"a":"é\n","b":[1.5,true]}
+ */
+
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// canonicalJSON marshals v and returns its canonical form
+func canonicalJSON(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return canonicalizeJSON(data)
+}
+
+// canonicalizeJSON rewrites a JSON document in canonical form
+func canonicalizeJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid JSON: trailing data")
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// hashJSON returns the hex digest of a JSON document's canonical form under
+// the given hash algorithm (SHA-256 when empty)
+func hashJSON(algorithm string, data []byte) (string, error) {
+	canonical, err := canonicalizeJSON(data)
+	if err != nil {
+		return "", err
+	}
+	return hashStringWith(algorithm, string(canonical)), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		number, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(number)
+	case string:
+		writeCanonicalString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, key)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported JSON value %T", value)
+	}
+	return nil
+}
+
+// canonicalNumber formats a JSON number. Integers keep every digit so large
+// values are not rounded through float64.
+func canonicalNumber(n json.Number) (string, error) {
+	s := n.String()
+	if isJSONInteger(s) {
+		if s == "-0" {
+			return "0", nil
+		}
+		return s, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsInf(f, 0) {
+		return "", fmt.Errorf("invalid number %s", s)
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	if abs := math.Abs(f); abs < 1e-6 || abs >= 1e21 {
+		// Go writes e-07 where JavaScript writes e-7
+		formatted := strconv.FormatFloat(f, 'e', -1, 64)
+		i := strings.IndexByte(formatted, 'e')
+		mantissa, sign, digits := formatted[:i], formatted[i+1:i+2], strings.TrimLeft(formatted[i+2:], "0")
+		return mantissa + "e" + sign + digits, nil
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
+
+func isJSONInteger(s string) bool {
+	if len(s) > 0 && s[0] == '-' {
+		s = s[1:]
+	}
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == '"':
+			buf.WriteString(`\"`)
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\b':
+			buf.WriteString(`\b`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r < 0x20:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[r>>4])
+			buf.WriteByte(hex[r&0xf])
+		default:
+			buf.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	buf.WriteByte('"')
+}
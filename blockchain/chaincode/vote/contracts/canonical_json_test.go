@@ -0,0 +1,92 @@
+/*
+ * Canonical JSON Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalizeJSON(t *testing.T) {
+	for _, tc := range []struct {
+		input, want string
+	}{
+		// The example documented in canonical_json.go
+		{`{"b": [1.50, true], "a": "é\n"}`, `{"a":"é\n","b":[1.5,true]}`},
+		{`{"z": {"y": null, "x": 1}, "a": []}`, `{"a":[],"z":{"x":1,"y":null}}`},
+		{`"é <&>\"\\\/"`, `"é` + " " + `<&>\"\\/"`},
+		{`"\u0001\t\b\f\r"`, `"\u0001\t\b\f\r"`},
+		{`[-0, 123456789012345678901234567890, 1E2, 0.000001, 1e-7, 1e21, 2.5E+30, -0.0]`,
+			`[0,123456789012345678901234567890,100,0.000001,1e-7,1e+21,2.5e+30,0]`},
+	} {
+		got, err := canonicalizeJSON([]byte(tc.input))
+		assert.NoError(t, err, tc.input)
+		assert.Equal(t, tc.want, string(got), tc.input)
+	}
+
+	for _, input := range []string{``, `{"a":`, `{} {}`, `1e400`} {
+		_, err := canonicalizeJSON([]byte(input))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestCanonicalJSONIsStable(t *testing.T) {
+	result := TallyResult{
+		ElectionID: "election-001",
+		VoteCounts: map[string]map[string]int{
+			"q2": {"b": 2, "a": 1},
+			"q1": {"c": 3},
+		},
+	}
+	first, err := canonicalJSON(result)
+	assert.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		again, err := canonicalJSON(result)
+		assert.NoError(t, err)
+		assert.Equal(t, first, again)
+	}
+
+	// Whitespace and key order of the input do not change the hash
+	hash, err := hashJSON("", []byte(`{"b":1,"a":[1,2]}`))
+	assert.NoError(t, err)
+	reordered, err := hashJSON("", []byte("{\n  \"a\": [1, 2],\n  \"b\": 1\n}"))
+	assert.NoError(t, err)
+	assert.Equal(t, hash, reordered)
+	assert.Equal(t, hashString(`{"a":[1,2],"b":1}`), hash)
+}
+
+func TestBulletinBoardHashesCanonicalElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	board, err := contract.GetBulletinBoard(ctx, "election-001")
+	assert.NoError(t, err)
+	entries := board["entries"].([]BulletinBoardEntry)
+	closed := entries[len(entries)-2]
+	assert.Equal(t, "election_closed", closed.Type)
+
+	// A verifier re-serializing the stored election reproduces the hash
+	var election map[string]interface{}
+	assert.NoError(t, json.Unmarshal(stub.State[electionKey("election-001")], &election))
+	canonical, err := canonicalJSON(election)
+	assert.NoError(t, err)
+	assert.Equal(t, hashString(string(canonical)), closed.Hash)
+}
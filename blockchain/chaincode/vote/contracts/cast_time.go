@@ -0,0 +1,129 @@
+/*
+ * Cast Time - Kiosk-signed cast times for ballots submitted late
+ *
+ * An offline voting kiosk stores ballots and submits them when it is back
+ * online, so the transaction timestamp says when the ballot reached the
+ * ledger, not when it was cast. Elections created with KioskPublicKeys
+ * accept a ClientCastTime with each ballot: the time the voter voted,
+ * signed by one of those Ed25519 keys. CastVote takes it in the transient
+ * field "clientCastTime"; CastVoteBatch takes one per ballot.
+ *
+ * The signed message is electionId, encryptedVoteHash and the RFC3339Nano
+ * UTC cast time, separated by newlines, so a signature cannot be moved to
+ * another ballot. A cast time is accepted when it is within the election's
+ * CastTimeSkewSeconds of the transaction timestamp and inside the voting
+ * window (StartTime to EndTime). It is stored as Vote.CastTime; the vote's
+ * Timestamp stays the ledger time, and the election must still be open
+ * when the ballot is submitted.
+ */
+
+package contracts
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Bounds on how far a client cast time may be from the transaction
+// timestamp, in seconds
+const (
+	DefaultCastTimeSkewSeconds = 24 * 3600
+	MaxCastTimeSkewSeconds     = 7 * 24 * 3600
+)
+
+// ClientCastTime is the kiosk-signed time a ballot was cast
+type ClientCastTime struct {
+	CastTime time.Time `json:"castTime"`
+	// KioskKey is the hex Ed25519 public key that signed the cast time
+	KioskKey  string `json:"kioskKey"`
+	Signature string `json:"signature"`
+}
+
+func castTimeSigningBytes(electionID, encryptedVoteHash string, castTime time.Time) []byte {
+	return []byte(electionID + "\n" + encryptedVoteHash + "\n" + castTime.UTC().Format(time.RFC3339Nano))
+}
+
+// validateKioskKeys checks the kiosk keys and cast time skew of a new election
+func validateKioskKeys(keys []string, skewSeconds int) error {
+	if len(keys) == 0 {
+		if skewSeconds != 0 {
+			return fmt.Errorf("castTimeSkewSeconds requires kioskPublicKeys")
+		}
+		return nil
+	}
+	for _, key := range keys {
+		publicKey, err := hex.DecodeString(key)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("invalid kiosk public key %q", key)
+		}
+	}
+	if skewSeconds < 0 || skewSeconds > MaxCastTimeSkewSeconds {
+		return fmt.Errorf("castTimeSkewSeconds must be between 0 and %d", MaxCastTimeSkewSeconds)
+	}
+	return nil
+}
+
+// submittedCastTime returns the client cast time passed with a ballot, or
+// nil when there is none
+func submittedCastTime(ctx contractapi.TransactionContextInterface) (*ClientCastTime, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient data: %v", err)
+	}
+	raw, ok := transient["clientCastTime"]
+	if !ok {
+		return nil, nil
+	}
+	var castTime ClientCastTime
+	if err := json.Unmarshal(raw, &castTime); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCastTime, err)
+	}
+	return &castTime, nil
+}
+
+// checkCastTime verifies a client cast time for the ballot hashed to
+// encryptedVoteHash and submitted at now. It returns the time to record,
+// or nil when the ballot carries none.
+func checkCastTime(election *Election, encryptedVoteHash string, castTime *ClientCastTime, now time.Time) (*time.Time, error) {
+	if castTime == nil {
+		return nil, nil
+	}
+	if len(election.KioskPublicKeys) == 0 {
+		return nil, fmt.Errorf("%w: election %s does not accept client cast times", ErrInvalidCastTime, election.ID)
+	}
+
+	registered := false
+	for _, key := range election.KioskPublicKeys {
+		if key == castTime.KioskKey {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		return nil, fmt.Errorf("%w: unknown kiosk key", ErrInvalidCastTime)
+	}
+	publicKey, _ := hex.DecodeString(castTime.KioskKey)
+	signature, err := hex.DecodeString(castTime.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w: invalid signature encoding", ErrInvalidCastTime)
+	}
+	if !ed25519.Verify(publicKey, castTimeSigningBytes(election.ID, encryptedVoteHash, castTime.CastTime), signature) {
+		return nil, fmt.Errorf("%w: signature does not match", ErrInvalidCastTime)
+	}
+
+	cast := castTime.CastTime.UTC()
+	skew := time.Duration(election.CastTimeSkewSeconds) * time.Second
+	if cast.Before(now.Add(-skew)) || cast.After(now.Add(skew)) {
+		return nil, fmt.Errorf("%w: %s is more than %s from the transaction time", ErrInvalidCastTime,
+			cast.Format(time.RFC3339), skew)
+	}
+	if cast.Before(election.StartTime) || cast.After(election.EndTime) {
+		return nil, fmt.Errorf("%w: %s is outside the voting window", ErrInvalidCastTime, cast.Format(time.RFC3339))
+	}
+	return &cast, nil
+}
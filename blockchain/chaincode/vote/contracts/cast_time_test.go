@@ -0,0 +1,169 @@
+/*
+ * Cast Time Tests
+ */
+
+package contracts
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/assert"
+)
+
+// testKioskKey is a fixed kiosk signing key
+var testKioskKey = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+
+func testKioskPublicKey() string {
+	return hex.EncodeToString(testKioskKey.Public().(ed25519.PublicKey))
+}
+
+// testCastTime signs a cast time of a ballot in election-001
+func testCastTime(key ed25519.PrivateKey, encryptedVote string, castTime time.Time) *ClientCastTime {
+	message := castTimeSigningBytes("election-001", hashString(encryptedVote), castTime)
+	return &ClientCastTime{
+		CastTime:  castTime,
+		KioskKey:  hex.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: hex.EncodeToString(ed25519.Sign(key, message)),
+	}
+}
+
+func TestValidateKioskKeys(t *testing.T) {
+	assert.NoError(t, validateKioskKeys(nil, 0))
+	assert.NoError(t, validateKioskKeys([]string{testKioskPublicKey()}, 0))
+	assert.NoError(t, validateKioskKeys([]string{testKioskPublicKey()}, 600))
+
+	assert.Error(t, validateKioskKeys(nil, 600), "a skew without kiosks")
+	assert.Error(t, validateKioskKeys([]string{"zz"}, 0))
+	assert.Error(t, validateKioskKeys([]string{testKioskPublicKey()[:32]}, 0))
+	assert.Error(t, validateKioskKeys([]string{testKioskPublicKey()}, -1))
+	assert.Error(t, validateKioskKeys([]string{testKioskPublicKey()}, MaxCastTimeSkewSeconds+1))
+}
+
+func TestCastVoteWithClientCastTime(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	now := time.Now().Truncate(time.Second).UTC()
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: now.Unix()}
+	config, _ := json.Marshal(ElectionConfig{Options: []string{"yes"}, KioskPublicKeys: []string{testKioskPublicKey()}, CastTimeSkewSeconds: 3 * 3600})
+	election, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		now.Add(-2*time.Hour).Format(time.RFC3339), now.Add(24*time.Hour).Format(time.RFC3339), string(config))
+	assert.NoError(t, err)
+	assert.Equal(t, 3*3600, election.CastTimeSkewSeconds)
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	cast := func(vote, nullifier string, castTime *ClientCastTime) error {
+		if castTime != nil {
+			castTimeJSON, _ := json.Marshal(castTime)
+			stub.Transient["clientCastTime"] = castTimeJSON
+			defer delete(stub.Transient, "clientCastTime")
+		}
+		_, err := contract.CastVote(ctx, "election-001", vote, nullifier,
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		return err
+	}
+
+	// A ballot cast offline an hour ago keeps its cast time
+	castAt := now.Add(-time.Hour)
+	assert.NoError(t, cast(testVote(10), "nullifier1", testCastTime(testKioskKey, testVote(10), castAt)))
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier1")
+	assert.NoError(t, err)
+	assert.Equal(t, castAt, *vote.CastTime)
+	assert.Equal(t, now, vote.Timestamp)
+
+	// Before the voting window opened
+	err = cast(testVote(11), "nullifier2", testCastTime(testKioskKey, testVote(11), now.Add(-150*time.Minute)))
+	assert.True(t, errors.Is(err, ErrInvalidCastTime))
+	assert.Contains(t, err.Error(), "outside the voting window")
+
+	// Too far from the transaction time, either way
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: now.Add(5 * time.Hour).Unix()}
+	err = cast(testVote(12), "nullifier3", testCastTime(testKioskKey, testVote(12), castAt))
+	assert.True(t, errors.Is(err, ErrInvalidCastTime))
+	assert.Contains(t, err.Error(), "from the transaction time")
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: now.Unix()}
+	err = cast(testVote(12), "nullifier3", testCastTime(testKioskKey, testVote(12), now.Add(4*time.Hour)))
+	assert.True(t, errors.Is(err, ErrInvalidCastTime))
+
+	// The signature is bound to the kiosk and the ballot
+	otherKiosk := ed25519.NewKeyFromSeed([]byte("another kiosk seed of 32 bytes.."))
+	err = cast(testVote(13), "nullifier4", testCastTime(otherKiosk, testVote(13), castAt))
+	assert.True(t, errors.Is(err, ErrInvalidCastTime))
+	err = cast(testVote(13), "nullifier4", testCastTime(testKioskKey, testVote(14), castAt))
+	assert.True(t, errors.Is(err, ErrInvalidCastTime))
+
+	// A ballot without a cast time is recorded at the ledger time only
+	assert.NoError(t, cast(testVote(15), "nullifier5", nil))
+	vote, _ = contract.GetVote(ctx, "election-001", "nullifier5")
+	assert.Nil(t, vote.CastTime)
+}
+
+func TestCastVoteBatchWithClientCastTimes(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	now := time.Now().Truncate(time.Second).UTC()
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: now.Unix()}
+	config, _ := json.Marshal(ElectionConfig{Options: []string{"yes"}, KioskPublicKeys: []string{testKioskPublicKey()}})
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		now.Add(-2*time.Hour).Format(time.RFC3339), now.Add(24*time.Hour).Format(time.RFC3339), string(config))
+	assert.NoError(t, err)
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	ballot := func(r int64, nullifier string, castTime time.Time) EncryptedBallotInput {
+		return EncryptedBallotInput{
+			EncryptedVote:        testVote(r),
+			Nullifier:            nullifier,
+			EligibilityProofHash: testEligibilityHash,
+			ValidityProofHash:    testValidityHash,
+			ProofVoterRoot:       testVoterRoot,
+			ClientCastTime:       testCastTime(testKioskKey, testVote(r), castTime),
+		}
+	}
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		ballot(10, "nullifier1", now.Add(-90*time.Minute)),
+		ballot(11, "nullifier2", now.Add(-3*time.Hour)),
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Receipts, 1)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, 1, result.Errors[0].Index)
+	assert.Contains(t, result.Errors[0].Error, "outside the voting window")
+
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier1")
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-90*time.Minute), *vote.CastTime)
+}
+
+func TestClientCastTimeRequiresKiosks(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	castTimeJSON, _ := json.Marshal(testCastTime(testKioskKey, testVote(10), time.Now().Add(-time.Minute)))
+	stub.Transient["clientCastTime"] = castTimeJSON
+	_, err := contract.CastVote(ctx, "election-001", testVote(10), "nullifier1",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrInvalidCastTime))
+	assert.Contains(t, err.Error(), "does not accept client cast times")
+}
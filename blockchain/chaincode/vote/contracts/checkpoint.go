@@ -0,0 +1,63 @@
+/*
+ * Bulletin Checkpoint - Signed bulletin board roots for light clients
+ *
+ * A light client cannot download the bulletin board, so it trusts a
+ * checkpoint instead: the board's size and Merkle root at a transaction
+ * time, signed with the election's receipt signing key (see receipt.go).
+ * Inclusion proofs from GetVoteInclusionProof are then checked against the
+ * checkpoint root. Elections without a receipt signing key return unsigned
+ * checkpoints.
+ *
+ * The signed message is electionId, size, merkleScheme, merkleRoot and the
+ * RFC3339Nano UTC timestamp, separated by newlines.
+ */
+
+package contracts
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// BulletinCheckpoint is a bulletin board root a light client can trust
+type BulletinCheckpoint struct {
+	ElectionID   string    `json:"electionId"`
+	Size         int       `json:"size"`
+	MerkleRoot   string    `json:"merkleRoot"`
+	MerkleScheme string    `json:"merkleScheme"`
+	Timestamp    time.Time `json:"timestamp"`
+	// Signature is the election's Ed25519 signature over the checkpoint
+	// (hex), empty when the election does not sign receipts
+	Signature string `json:"signature,omitempty"`
+}
+
+func checkpointSigningBytes(checkpoint *BulletinCheckpoint) []byte {
+	return []byte(checkpoint.ElectionID + "\n" + strconv.Itoa(checkpoint.Size) + "\n" +
+		checkpoint.MerkleScheme + "\n" + checkpoint.MerkleRoot + "\n" +
+		checkpoint.Timestamp.UTC().Format(time.RFC3339Nano))
+}
+
+// signCheckpoint returns the hex encoded signature of a checkpoint
+func signCheckpoint(key ed25519.PrivateKey, checkpoint *BulletinCheckpoint) string {
+	return hex.EncodeToString(ed25519.Sign(key, checkpointSigningBytes(checkpoint)))
+}
+
+// verifyCheckpoint checks a checkpoint's signature against a hex encoded
+// public key, as a light client would with the election's ReceiptPublicKey
+func verifyCheckpoint(publicKeyHex string, checkpoint *BulletinCheckpoint) error {
+	publicKey, err := hex.DecodeString(publicKeyHex)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid checkpoint public key")
+	}
+	signature, err := hex.DecodeString(checkpoint.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid checkpoint signature encoding")
+	}
+	if !ed25519.Verify(publicKey, checkpointSigningBytes(checkpoint), signature) {
+		return fmt.Errorf("checkpoint signature does not match")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+/*
+ * Bulletin Checkpoint Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLatestBulletinCheckpoint(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	_, err := contract.GetLatestBulletinCheckpoint(ctx, "election-001")
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	stub.Transient["receiptSigningKey"] = []byte(strings.Repeat("k", 32))
+	config, _ := json.Marshal(ElectionConfig{Options: []string{"1"}})
+	election, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	delete(stub.Transient, "receiptSigningKey")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second).UTC()
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: now.Unix()}
+	first, err := contract.GetLatestBulletinCheckpoint(ctx, "election-001")
+	assert.NoError(t, err)
+	root, _ := contract.GetBulletinBoardRoot(ctx, "election-001")
+	assert.Equal(t, 1, first.Size)
+	assert.Equal(t, root["merkleRoot"], first.MerkleRoot)
+	assert.Equal(t, root["merkleScheme"], first.MerkleScheme)
+	assert.Equal(t, now, first.Timestamp)
+	assert.NoError(t, verifyCheckpoint(election.ReceiptPublicKey, first))
+
+	// A new entry moves the checkpoint on
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(10), "nullifier1",
+		testEligibilityHash, testValidityHash, "root")
+	assert.NoError(t, err)
+	latest, err := contract.GetLatestBulletinCheckpoint(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, latest.Size)
+	assert.NotEqual(t, first.MerkleRoot, latest.MerkleRoot)
+	assert.NotEqual(t, first.Signature, latest.Signature)
+	assert.NoError(t, verifyCheckpoint(election.ReceiptPublicKey, latest))
+
+	// A light client checks inclusion against the checkpoint root
+	proof, err := contract.GetVoteInclusionProof(ctx, "election-001", receipt.EncryptedVoteHash)
+	assert.NoError(t, err)
+	assert.Equal(t, latest.MerkleRoot, proof.MerkleRoot)
+	assert.True(t, proof.Verify())
+
+	// The signature covers every field
+	tampered := *latest
+	tampered.Size = 1
+	assert.Error(t, verifyCheckpoint(election.ReceiptPublicKey, &tampered))
+	tampered = *latest
+	tampered.Timestamp = now.Add(time.Hour)
+	assert.Error(t, verifyCheckpoint(election.ReceiptPublicKey, &tampered))
+}
+
+func TestUnsignedBulletinCheckpoint(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+
+	checkpoint, err := contract.GetLatestBulletinCheckpoint(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, checkpoint.Size)
+	assert.NotEmpty(t, checkpoint.MerkleRoot)
+	assert.Empty(t, checkpoint.Signature)
+}
@@ -0,0 +1,204 @@
+/*
+ * Decryption Proof Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testChaumPedersenProof proves decryption of each ciphertext with the test
+// private key and nonce w
+func testChaumPedersenProof(ciphertexts []CiphertextJSON, w int64) []ChaumPedersenProof {
+	key, _ := parseElGamalPublicKey(testPublicKeyJSON())
+	x := big.NewInt(testPrivateKey)
+	proofs := make([]ChaumPedersenProof, len(ciphertexts))
+	for i, raw := range ciphertexts {
+		cts, _ := ciphertextsFromJSON([]CiphertextJSON{raw})
+		c := cts[0]
+		nonce := big.NewInt(w + int64(i))
+		d := new(big.Int).Exp(c.C1, x, key.P)
+		a := new(big.Int).Exp(key.G, nonce, key.P)
+		b := new(big.Int).Exp(c.C1, nonce, key.P)
+		e := chaumPedersenChallenge(key, key.H, c, d, a, b)
+		z := new(big.Int).Mul(e, x)
+		z.Add(z, nonce).Mod(z, key.Q)
+		proofs[i] = ChaumPedersenProof{D: d.String(), A: a.String(), B: b.String(), Z: z.String()}
+	}
+	return proofs
+}
+
+func TestChaumPedersenVerifier(t *testing.T) {
+	key, _ := parseElGamalPublicKey(testPublicKeyJSON())
+	raw := []CiphertextJSON{testEncrypt(3, 11), testEncrypt(0, 12)}
+	ciphertexts, _ := ciphertextsFromJSON(raw)
+	aggregates := map[string][]ElGamalCiphertext{DefaultQuestionID: ciphertexts}
+
+	proofJSON, _ := json.Marshal(map[string][]ChaumPedersenProof{
+		DefaultQuestionID: testChaumPedersenProof(raw, 5),
+	})
+	verifier := ChaumPedersenVerifier{}
+
+	assert.NoError(t, verifier.Verify(key, aggregates, map[string][]int64{DefaultQuestionID: {3, 0}}, string(proofJSON)))
+
+	// A wrong count does not match the proven decryption
+	err := verifier.Verify(key, aggregates, map[string][]int64{DefaultQuestionID: {2, 1}}, string(proofJSON))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not decrypt to 2")
+
+	// A proof for a different decryption share fails the equality checks
+	proofs := testChaumPedersenProof(raw, 5)
+	proofs[0].D = proofs[1].D
+	tampered, _ := json.Marshal(map[string][]ChaumPedersenProof{DefaultQuestionID: proofs})
+	assert.Error(t, verifier.Verify(key, aggregates, map[string][]int64{DefaultQuestionID: {3, 0}}, string(tampered)))
+
+	// Every ciphertext needs a proof
+	short, _ := json.Marshal(map[string][]ChaumPedersenProof{DefaultQuestionID: proofs[:1]})
+	assert.Error(t, verifier.Verify(key, aggregates, map[string][]int64{DefaultQuestionID: {3, 0}}, string(short)))
+
+	assert.Error(t, verifier.Verify(key, aggregates, map[string][]int64{DefaultQuestionID: {3, 0}}, "proof"))
+}
+
+func setupDecryptionProofElection(t *testing.T) (*VoteContract, *MockTransactionContext, *EncryptedAggregate) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.PublicKey = testPublicKeyJSON()
+	election.DecryptionProofScheme = DecryptionProofChaumPedersen
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	for i, choice := range []int{0, 2, 0} {
+		_, err := contract.CastVote(ctx, "election-001", testBallot(choice, 3, int64(10*i+3)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+	_, err := contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+	return contract, ctx, aggregate
+}
+
+func TestStoreTallyResultVerifiesDecryptionProof(t *testing.T) {
+	contract, ctx, aggregate := setupDecryptionProofElection(t)
+
+	proofJSON, _ := json.Marshal(map[string][]ChaumPedersenProof{
+		DefaultQuestionID: testChaumPedersenProof(aggregate.Ciphertexts, 17),
+	})
+
+	// Counts that disagree with the aggregate are rejected
+	err := contract.StoreTallyResult(ctx, "election-001", `{"0": 1, "2": 2}`, aggregate.AggregateHash, string(proofJSON))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "decryption proof rejected")
+
+	// So is a malformed proof
+	err = contract.StoreTallyResult(ctx, "election-001", `{"0": 2, "2": 1}`, aggregate.AggregateHash, "proof")
+	assert.Error(t, err)
+
+	// Counts beyond the ballot width have no ciphertext to prove
+	err = contract.StoreTallyResult(ctx, "election-001", `{"0": 2, "3": 1}`, aggregate.AggregateHash, string(proofJSON))
+	assert.Error(t, err)
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "closed", election.Status)
+
+	// Options missing from the counts are proven to be zero
+	err = contract.StoreTallyResult(ctx, "election-001", `{"0": 2, "2": 1}`, aggregate.AggregateHash, string(proofJSON))
+	assert.NoError(t, err)
+
+	result, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.TotalVotes)
+	assert.Equal(t, string(proofJSON), result.DecryptionProof)
+}
+
+func TestGetTallyProofBundle(t *testing.T) {
+	contract, ctx, aggregate := setupDecryptionProofElection(t)
+
+	_, err := contract.GetTallyProofBundle(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not completed")
+
+	proofJSON, _ := json.Marshal(map[string][]ChaumPedersenProof{
+		DefaultQuestionID: testChaumPedersenProof(aggregate.Ciphertexts, 17),
+	})
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"0": 2, "2": 1}`, aggregate.AggregateHash, string(proofJSON)))
+
+	bundle, err := contract.GetTallyProofBundle(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, testPublicKeyJSON(), bundle.PublicKey)
+	assert.Equal(t, DecryptionProofChaumPedersen, bundle.DecryptionProofScheme)
+	assert.Equal(t, *aggregate, bundle.Aggregate)
+	assert.Equal(t, bundle.Aggregate.AggregateHash, bundle.Tally.AggregatedHash)
+	assert.Equal(t, string(proofJSON), bundle.DecryptionProof)
+	assert.Empty(t, bundle.PartialDecryptions)
+
+	root, _ := contract.GetBulletinBoardRoot(ctx, "election-001")
+	assert.Equal(t, root["merkleRoot"], bundle.MerkleRoot)
+	assert.Equal(t, root["size"], bundle.BulletinSize)
+
+	// The bundle alone is enough to check the decryption
+	key, _ := parseElGamalPublicKey(bundle.PublicKey)
+	ciphertexts, _ := ciphertextsFromJSON(bundle.Aggregate.Ciphertexts)
+	claimed := map[string][]int64{DefaultQuestionID: {
+		int64(bundle.Tally.VoteCounts[DefaultQuestionID]["0"]),
+		int64(bundle.Tally.VoteCounts[DefaultQuestionID]["1"]),
+		int64(bundle.Tally.VoteCounts[DefaultQuestionID]["2"]),
+	}}
+	assert.NoError(t, ChaumPedersenVerifier{}.Verify(key, map[string][]ElGamalCiphertext{DefaultQuestionID: ciphertexts},
+		claimed, bundle.DecryptionProof))
+
+	// Repeated calls serialize identically
+	again, _ := contract.GetTallyProofBundle(ctx, "election-001")
+	first, _ := json.Marshal(bundle)
+	second, _ := json.Marshal(again)
+	assert.Equal(t, string(first), string(second))
+}
+
+func TestStoreTallyResultRequiresAggregateForDecryptionProof(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := &Election{
+		ID:                    "election-001",
+		Status:                "closed",
+		PublicKey:             testPublicKeyJSON(),
+		DecryptionProofScheme: DecryptionProofChaumPedersen,
+	}
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.StoreTallyResult(ctx, "election-001", `{"0": 1}`, "hash", "{}")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "encrypted aggregate")
+}
+
+func TestCreateElectionRejectsUnknownDecryptionProofScheme(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	ctx.On("GetStub").Return(NewMockStub())
+
+	config, _ := json.Marshal(ElectionConfig{DecryptionProofScheme: "unknown"})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported decryption proof scheme")
+}
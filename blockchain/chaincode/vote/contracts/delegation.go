@@ -0,0 +1,116 @@
+/*
+ * Delegation - Liquid democracy delegation records
+ *
+ * In elections with AllowDelegation a voter may hand their vote to another
+ * voter instead of casting a ballot. A delegation is keyed by the delegator's
+ * nullifier commitment and names the delegate by theirs, so neither
+ * nullifier is stored. A commitment either votes or delegates, never both.
+ *
+ * Delegations are transitive: at aggregation each delegation follows the
+ * chain of delegates until it reaches one who voted, whose ballot then
+ * counts once more. Delegations ending in a cycle or at a voter who never
+ * voted are not counted.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Delegation records a voter handing their vote to a delegate
+type Delegation struct {
+	ElectionID          string    `json:"electionId"`
+	DelegatorCommitment string    `json:"delegatorCommitment"`
+	DelegateCommitment  string    `json:"delegateCommitment"`
+	ProofHash           string    `json:"proofHash"`
+	TxID                string    `json:"txId"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+// delegationObjectType is the composite key namespace of delegations,
+// delegation~electionID~delegatorCommitment
+const delegationObjectType = "delegation"
+
+func delegationKey(ctx contractapi.TransactionContextInterface, electionID, delegatorCommitment string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(delegationObjectType, []string{electionID, delegatorCommitment})
+	if err != nil {
+		return "", fmt.Errorf("failed to create delegation key: %v", err)
+	}
+	return key, nil
+}
+
+// hasDelegated reports whether the commitment has delegated its vote
+func hasDelegated(ctx contractapi.TransactionContextInterface, electionID, commitment string) (bool, error) {
+	key, err := delegationKey(ctx, electionID, commitment)
+	if err != nil {
+		return false, err
+	}
+	delegationJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to check delegation: %v", err)
+	}
+	return delegationJSON != nil, nil
+}
+
+// resolveDelegatedWeights follows every delegation of an election to the
+// voter it ends at and returns the number of delegations each voter carries,
+// keyed by nullifier commitment
+func resolveDelegatedWeights(ctx contractapi.TransactionContextInterface, electionID string) (map[string]int, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(delegationObjectType, []string{electionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delegations: %v", err)
+	}
+	defer iterator.Close()
+
+	delegateOf := make(map[string]string)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read delegation: %v", err)
+		}
+		var delegation Delegation
+		if err := json.Unmarshal(kv.Value, &delegation); err != nil {
+			return nil, err
+		}
+		delegateOf[delegation.DelegatorCommitment] = delegation.DelegateCommitment
+	}
+
+	weights := make(map[string]int)
+	voted := make(map[string]bool)
+	for delegator := range delegateOf {
+		target := delegateOf[delegator]
+		visited := map[string]bool{delegator: true}
+		for {
+			next, delegated := delegateOf[target]
+			if !delegated || visited[target] {
+				break
+			}
+			visited[target] = true
+			target = next
+		}
+		if _, delegated := delegateOf[target]; delegated {
+			continue // cycle
+		}
+
+		if _, known := voted[target]; !known {
+			key, err := voteKeyForCommitment(ctx, electionID, target)
+			if err != nil {
+				return nil, err
+			}
+			voteJSON, err := ctx.GetStub().GetState(key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read vote: %v", err)
+			}
+			voted[target] = voteJSON != nil
+		}
+		if voted[target] {
+			weights[target]++
+		}
+	}
+	return weights, nil
+}
@@ -0,0 +1,145 @@
+/*
+ * Delegation Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDelegationElection() (*VoteContract, *MockTransactionContext, *MockStub) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.PublicKey = testPublicKeyJSON()
+	election.AllowDelegation = true
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	return contract, ctx, stub
+}
+
+func TestDelegateVote(t *testing.T) {
+	contract, ctx, stub := setupDelegationElection()
+
+	delegate := nullifierCommitment("election-001", "alice")
+	assert.NoError(t, contract.DelegateVote(ctx, "election-001", "carol", delegate, "proof"))
+
+	// The delegation is kept apart from votes, under the delegator's commitment
+	key := compositeKey("delegation", "election-001", nullifierCommitment("election-001", "carol"))
+	var delegation Delegation
+	assert.NoError(t, json.Unmarshal(stub.State[key], &delegation))
+	assert.Equal(t, delegate, delegation.DelegateCommitment)
+	assert.Nil(t, stub.State[voteStateKey("election-001", "carol")])
+
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 0, count)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "vote_delegated", entries[len(entries)-1].Type)
+
+	// A voter cannot delegate to themselves or delegate twice
+	err := contract.DelegateVote(ctx, "election-001", "dave", nullifierCommitment("election-001", "dave"), "proof")
+	assert.Error(t, err)
+	err = contract.DelegateVote(ctx, "election-001", "carol", nullifierCommitment("election-001", "bob"), "proof")
+	assert.True(t, errors.Is(err, ErrVoteDelegated))
+}
+
+func TestDelegateVoteConflictsWithDirectVote(t *testing.T) {
+	contract, ctx, _ := setupDelegationElection()
+	delegate := nullifierCommitment("election-001", "alice")
+
+	// Delegate, then try to vote
+	assert.NoError(t, contract.DelegateVote(ctx, "election-001", "carol", delegate, "proof"))
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "carol", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrVoteDelegated))
+
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(2), Nullifier: "carol", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Receipts)
+	assert.Equal(t, ErrVoteDelegated.Error(), result.Errors[0].Error)
+
+	// Vote, then try to delegate
+	_, err = contract.CastVote(ctx, "election-001", testVote(3), "bob", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	err = contract.DelegateVote(ctx, "election-001", "bob", delegate, "proof")
+	assert.True(t, errors.Is(err, ErrDuplicateNullifier))
+}
+
+func TestDelegateVoteRequiresAllowDelegation(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.DelegateVote(ctx, "election-001", "carol", nullifierCommitment("election-001", "alice"), "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not allow delegation")
+}
+
+func TestAggregateEncryptedVotesCountsDelegations(t *testing.T) {
+	contract, ctx, _ := setupDelegationElection()
+	commitment := func(nullifier string) string {
+		return nullifierCommitment("election-001", nullifier)
+	}
+
+	// Alice votes for candidate 0 and Bob for candidate 1
+	_, err := contract.CastVote(ctx, "election-001", testBallot(0, 2, 3), "alice", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	_, err = contract.CastVote(ctx, "election-001", testBallot(1, 2, 5), "bob", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	// Carol delegates to Alice and Dave to Carol, so both reach Alice
+	assert.NoError(t, contract.DelegateVote(ctx, "election-001", "carol", commitment("alice"), "proof"))
+	assert.NoError(t, contract.DelegateVote(ctx, "election-001", "dave", commitment("carol"), "proof"))
+	// Erin's delegate never votes and Frank and Grace delegate to each other
+	assert.NoError(t, contract.DelegateVote(ctx, "election-001", "erin", commitment("heidi"), "proof"))
+	assert.NoError(t, contract.DelegateVote(ctx, "election-001", "frank", commitment("grace"), "proof"))
+	assert.NoError(t, contract.DelegateVote(ctx, "election-001", "grace", commitment("frank"), "proof"))
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, aggregate.VoteCount)
+	assert.Equal(t, 2, aggregate.DelegatedVotes)
+	assert.Equal(t, 4, aggregate.TotalWeight)
+	assert.Equal(t, 3, testDecrypt(aggregate.Ciphertexts[0]))
+	assert.Equal(t, 1, testDecrypt(aggregate.Ciphertexts[1]))
+
+	// The tally is bounded by the delegated weight, not the two ballots
+	err = contract.StoreTallyResult(ctx, "election-001", `{"0": 3, "1": 2}`, aggregate.AggregateHash, "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at most 4")
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"0": 3, "1": 1}`, aggregate.AggregateHash, "proof"))
+}
+
+func TestStoreTallyResultRequiresAggregateWithDelegation(t *testing.T) {
+	contract, ctx, _ := setupDelegationElection()
+
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "alice", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	err = contract.StoreTallyResult(ctx, "election-001", `{"0": 1}`, "hash", "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must be aggregated")
+}
@@ -0,0 +1,257 @@
+/*
+ * ElGamal - Exponential ElGamal ciphertexts used by the CGS protocol
+ *
+ * Public keys are JSON objects of decimal strings {"p","q","g","h"} and a
+ * ballot is a JSON array of {"c1","c2"} ciphertexts, one per candidate (a
+ * single {"c1","c2"} object is accepted as a one-candidate ballot).
+ * Multiplying ciphertexts component-wise adds the encrypted plaintexts.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ElGamalPublicKey holds the group parameters and public key h = g^x mod p
+type ElGamalPublicKey struct {
+	P *big.Int
+	Q *big.Int
+	G *big.Int
+	H *big.Int
+}
+
+// ElGamalCiphertext is an encryption (g^r, h^r * g^m)
+type ElGamalCiphertext struct {
+	C1 *big.Int
+	C2 *big.Int
+}
+
+// PublicKeyJSON is the wire form of an ElGamalPublicKey
+type PublicKeyJSON struct {
+	P string `json:"p"`
+	Q string `json:"q,omitempty"`
+	G string `json:"g"`
+	H string `json:"h"`
+}
+
+// CiphertextJSON is the wire form of an ElGamalCiphertext
+type CiphertextJSON struct {
+	C1 string `json:"c1"`
+	C2 string `json:"c2"`
+}
+
+func parseBigInt(name, value string) (*big.Int, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	n, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an integer", name)
+	}
+	return n, nil
+}
+
+// parseElGamalPublicKey parses the election public key. When q is omitted
+// p is taken to be a safe prime and q = (p-1)/2.
+func parseElGamalPublicKey(publicKey string) (*ElGamalPublicKey, error) {
+	var raw PublicKeyJSON
+	if err := json.Unmarshal([]byte(publicKey), &raw); err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+
+	var err error
+	key := &ElGamalPublicKey{}
+	if key.P, err = parseBigInt("p", raw.P); err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	if key.G, err = parseBigInt("g", raw.G); err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	if key.H, err = parseBigInt("h", raw.H); err != nil {
+		return nil, fmt.Errorf("invalid public key: %v", err)
+	}
+	if raw.Q != "" {
+		if key.Q, err = parseBigInt("q", raw.Q); err != nil {
+			return nil, fmt.Errorf("invalid public key: %v", err)
+		}
+	} else {
+		key.Q = new(big.Int).Rsh(new(big.Int).Sub(key.P, big.NewInt(1)), 1)
+	}
+
+	if key.P.Cmp(big.NewInt(3)) < 0 {
+		return nil, fmt.Errorf("invalid public key: modulus too small")
+	}
+
+	return key, nil
+}
+
+// validate checks the key describes a usable group: p and q prime with q
+// dividing p-1, g generating the order-q subgroup and h a non-trivial
+// element of it. Primality is probabilistic but deterministic for a given
+// key, so every peer reaches the same verdict.
+func (k *ElGamalPublicKey) validate() error {
+	one := big.NewInt(1)
+	if !k.P.ProbablyPrime(20) {
+		return fmt.Errorf("invalid public key: p is not prime")
+	}
+	if k.Q.Cmp(one) <= 0 || !k.Q.ProbablyPrime(20) {
+		return fmt.Errorf("invalid public key: q is not prime")
+	}
+	if new(big.Int).Mod(new(big.Int).Sub(k.P, one), k.Q).Sign() != 0 {
+		return fmt.Errorf("invalid public key: q does not divide p-1")
+	}
+	// With q prime, any element other than 1 of the subgroup generates it
+	if k.G.Cmp(one) == 0 || !k.inGroup(k.G) {
+		return fmt.Errorf("invalid public key: g does not generate the order-q subgroup")
+	}
+	if k.H.Cmp(one) == 0 || !k.inGroup(k.H) {
+		return fmt.Errorf("invalid public key: h is not a non-trivial element of the group")
+	}
+	return nil
+}
+
+// canonicalElGamalPublicKey validates a public key and returns it in the
+// form elections store: every parameter, q included, as a decimal string
+func canonicalElGamalPublicKey(publicKey string) (string, error) {
+	key, err := parseElGamalPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	if err := key.validate(); err != nil {
+		return "", err
+	}
+	keyJSON, err := json.Marshal(PublicKeyJSON{
+		P: key.P.String(),
+		Q: key.Q.String(),
+		G: key.G.String(),
+		H: key.H.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(keyJSON), nil
+}
+
+// inGroup reports whether x is an element of the order-q subgroup of Z_p*
+func (k *ElGamalPublicKey) inGroup(x *big.Int) bool {
+	if x.Sign() <= 0 || x.Cmp(k.P) >= 0 {
+		return false
+	}
+	return new(big.Int).Exp(x, k.Q, k.P).Cmp(big.NewInt(1)) == 0
+}
+
+// validateCiphertext checks that an encrypted vote is a well-formed ballot
+// whose components all lie in the group of the election public key. A
+// ballot encrypted under another election's key is caught when that key
+// has other group parameters; under the same group it cannot be told apart
+// without a proof, such as a range proof, made against this key.
+func validateCiphertext(encryptedVote string, publicKey string) error {
+	key, err := parseElGamalPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+	ballot, err := parseBallot(encryptedVote)
+	if err != nil {
+		return err
+	}
+
+	for i, c := range ballot {
+		if !key.inGroup(c.C1) {
+			return fmt.Errorf("%w: c1 of ciphertext %d (encrypted under another key?)", ErrForeignCiphertext, i)
+		}
+		if !key.inGroup(c.C2) {
+			return fmt.Errorf("%w: c2 of ciphertext %d (encrypted under another key?)", ErrForeignCiphertext, i)
+		}
+	}
+	return nil
+}
+
+// parseBallot parses a ballot into its ciphertext vector
+func parseBallot(encryptedVote string) ([]ElGamalCiphertext, error) {
+	var raw []CiphertextJSON
+	trimmed := strings.TrimSpace(encryptedVote)
+	if strings.HasPrefix(trimmed, "{") {
+		var single CiphertextJSON
+		if err := json.Unmarshal([]byte(trimmed), &single); err != nil {
+			return nil, fmt.Errorf("invalid ciphertext: %v", err)
+		}
+		raw = []CiphertextJSON{single}
+	} else if err := json.Unmarshal([]byte(trimmed), &raw); err != nil {
+		return nil, fmt.Errorf("invalid ciphertext: %v", err)
+	}
+
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("invalid ciphertext: empty ballot")
+	}
+
+	return ciphertextsFromJSON(raw)
+}
+
+// aggregateCiphertexts multiplies ballots component-wise modulo p, so the
+// result encrypts the per-candidate sum of the plaintexts
+func aggregateCiphertexts(key *ElGamalPublicKey, ballots [][]ElGamalCiphertext) ([]ElGamalCiphertext, error) {
+	if len(ballots) == 0 {
+		return nil, nil
+	}
+
+	width := len(ballots[0])
+	aggregate := make([]ElGamalCiphertext, width)
+	for i := range aggregate {
+		// Encryption of zero with zero randomness
+		aggregate[i] = ElGamalCiphertext{C1: big.NewInt(1), C2: big.NewInt(1)}
+	}
+
+	for n, ballot := range ballots {
+		if len(ballot) != width {
+			return nil, fmt.Errorf("ballot %d has %d ciphertexts, expected %d", n, len(ballot), width)
+		}
+		for i, c := range ballot {
+			aggregate[i].C1 = new(big.Int).Mod(new(big.Int).Mul(aggregate[i].C1, c.C1), key.P)
+			aggregate[i].C2 = new(big.Int).Mod(new(big.Int).Mul(aggregate[i].C2, c.C2), key.P)
+		}
+	}
+
+	return aggregate, nil
+}
+
+func ciphertextsToJSON(ciphertexts []ElGamalCiphertext) []CiphertextJSON {
+	out := make([]CiphertextJSON, len(ciphertexts))
+	for i, c := range ciphertexts {
+		out[i] = CiphertextJSON{C1: c.C1.String(), C2: c.C2.String()}
+	}
+	return out
+}
+
+func ciphertextsFromJSON(raw []CiphertextJSON) ([]ElGamalCiphertext, error) {
+	ciphertexts := make([]ElGamalCiphertext, len(raw))
+	for i, c := range raw {
+		c1, err := parseBigInt("c1", c.C1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ciphertext %d: %v", i, err)
+		}
+		c2, err := parseBigInt("c2", c.C2)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ciphertext %d: %v", i, err)
+		}
+		ciphertexts[i] = ElGamalCiphertext{C1: c1, C2: c2}
+	}
+	return ciphertexts, nil
+}
+
+// scaleCiphertexts raises every ciphertext to weight, so the result encrypts
+// weight times the original plaintexts
+func scaleCiphertexts(key *ElGamalPublicKey, ballot []ElGamalCiphertext, weight int) []ElGamalCiphertext {
+	w := big.NewInt(int64(weight))
+	scaled := make([]ElGamalCiphertext, len(ballot))
+	for i, c := range ballot {
+		scaled[i] = ElGamalCiphertext{
+			C1: new(big.Int).Exp(c.C1, w, key.P),
+			C2: new(big.Int).Exp(c.C2, w, key.P),
+		}
+	}
+	return scaled
+}
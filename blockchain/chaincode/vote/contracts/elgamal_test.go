@@ -0,0 +1,319 @@
+/*
+ * ElGamal Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Small safe-prime group for tests: p = 2q+1, g generates the order-q subgroup
+const (
+	testP          = 2039
+	testQ          = 1019
+	testG          = 4
+	testPrivateKey = 7
+)
+
+func testPublicKeyJSON() string {
+	h := new(big.Int).Exp(big.NewInt(testG), big.NewInt(testPrivateKey), big.NewInt(testP))
+	return fmt.Sprintf(`{"p":"%d","q":"%d","g":"%d","h":"%s"}`, testP, testQ, testG, h.String())
+}
+
+// testEncrypt encrypts g^m under the test key with randomness r
+func testEncrypt(m, r int64) CiphertextJSON {
+	p, g := big.NewInt(testP), big.NewInt(testG)
+	h := new(big.Int).Exp(g, big.NewInt(testPrivateKey), p)
+	c1 := new(big.Int).Exp(g, big.NewInt(r), p)
+	c2 := new(big.Int).Mul(new(big.Int).Exp(h, big.NewInt(r), p), new(big.Int).Exp(g, big.NewInt(m), p))
+	c2.Mod(c2, p)
+	return CiphertextJSON{C1: c1.String(), C2: c2.String()}
+}
+
+// A second test group, p = 2q+1 with g = 4, for contests under their own key
+const (
+	testLocalP          = 2063
+	testLocalQ          = 1031
+	testLocalPrivateKey = 11
+)
+
+func testLocalPublicKeyJSON() string {
+	h := new(big.Int).Exp(big.NewInt(testG), big.NewInt(testLocalPrivateKey), big.NewInt(testLocalP))
+	return fmt.Sprintf(`{"p":"%d","q":"%d","g":"%d","h":"%s"}`, testLocalP, testLocalQ, testG, h.String())
+}
+
+// testLocalBallot encrypts a one-hot vote for choice among n options under
+// the second test key
+func testLocalBallot(choice, n int, r int64) []CiphertextJSON {
+	p, g := big.NewInt(testLocalP), big.NewInt(testG)
+	h := new(big.Int).Exp(g, big.NewInt(testLocalPrivateKey), p)
+	ballot := make([]CiphertextJSON, n)
+	for i := range ballot {
+		m := int64(0)
+		if i == choice {
+			m = 1
+		}
+		k := big.NewInt(r + int64(i))
+		c1 := new(big.Int).Exp(g, k, p)
+		c2 := new(big.Int).Mul(new(big.Int).Exp(h, k, p), new(big.Int).Exp(g, big.NewInt(m), p))
+		ballot[i] = CiphertextJSON{C1: c1.String(), C2: c2.Mod(c2, p).String()}
+	}
+	return ballot
+}
+
+// testLocalDecrypt recovers m from g^m under the second test key
+func testLocalDecrypt(c CiphertextJSON) int {
+	p, g := big.NewInt(testLocalP), big.NewInt(testG)
+	c1, _ := new(big.Int).SetString(c.C1, 10)
+	c2, _ := new(big.Int).SetString(c.C2, 10)
+	s := new(big.Int).Exp(c1, big.NewInt(testLocalPrivateKey), p)
+	gm := new(big.Int).Mul(c2, new(big.Int).ModInverse(s, p))
+	gm.Mod(gm, p)
+	for m := 0; m < testLocalQ; m++ {
+		if new(big.Int).Exp(g, big.NewInt(int64(m)), p).Cmp(gm) == 0 {
+			return m
+		}
+	}
+	return -1
+}
+
+// testVote returns a valid one-candidate ballot; distinct r give distinct hashes
+func testVote(r int64) string {
+	voteJSON, _ := json.Marshal(testEncrypt(1, r))
+	return string(voteJSON)
+}
+
+// testBallot encrypts a one-hot vote for choice among n candidates
+func testBallot(choice, n int, r int64) string {
+	ballot := make([]CiphertextJSON, n)
+	for i := range ballot {
+		m := int64(0)
+		if i == choice {
+			m = 1
+		}
+		ballot[i] = testEncrypt(m, r+int64(i))
+	}
+	ballotJSON, _ := json.Marshal(ballot)
+	return string(ballotJSON)
+}
+
+// testDecrypt recovers m from g^m by brute force
+func testDecrypt(c CiphertextJSON) int {
+	p, g := big.NewInt(testP), big.NewInt(testG)
+	c1, _ := new(big.Int).SetString(c.C1, 10)
+	c2, _ := new(big.Int).SetString(c.C2, 10)
+	s := new(big.Int).Exp(c1, big.NewInt(testPrivateKey), p)
+	gm := new(big.Int).Mul(c2, new(big.Int).ModInverse(s, p))
+	gm.Mod(gm, p)
+	for m := 0; m < testQ; m++ {
+		if new(big.Int).Exp(g, big.NewInt(int64(m)), p).Cmp(gm) == 0 {
+			return m
+		}
+	}
+	return -1
+}
+
+func TestParseElGamalPublicKey(t *testing.T) {
+	key, err := parseElGamalPublicKey(testPublicKeyJSON())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(testQ), key.Q.Int64())
+
+	// q defaults to (p-1)/2
+	key, err = parseElGamalPublicKey(`{"p":"2039","g":"4","h":"16"}`)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(testQ), key.Q.Int64())
+
+	_, err = parseElGamalPublicKey(`{"p":"abc","g":"4","h":"16"}`)
+	assert.Error(t, err)
+	_, err = parseElGamalPublicKey("publickey")
+	assert.Error(t, err)
+}
+
+func TestParseBallot(t *testing.T) {
+	ballot, err := parseBallot(testBallot(1, 3, 5))
+	assert.NoError(t, err)
+	assert.Len(t, ballot, 3)
+
+	single, _ := json.Marshal(testEncrypt(1, 5))
+	ballot, err = parseBallot(string(single))
+	assert.NoError(t, err)
+	assert.Len(t, ballot, 1)
+
+	_, err = parseBallot("[]")
+	assert.Error(t, err)
+	_, err = parseBallot(`[{"c1":"12"}]`)
+	assert.Error(t, err)
+}
+
+func TestAggregateCiphertexts(t *testing.T) {
+	key, _ := parseElGamalPublicKey(testPublicKeyJSON())
+
+	a := ElGamalCiphertext{C1: big.NewInt(10), C2: big.NewInt(20)}
+	b := ElGamalCiphertext{C1: big.NewInt(300), C2: big.NewInt(400)}
+	aggregate, err := aggregateCiphertexts(key, [][]ElGamalCiphertext{{a}, {b}})
+	assert.NoError(t, err)
+
+	// Aggregate is the product of the inputs modulo p
+	assert.Equal(t, int64(10*300%testP), aggregate[0].C1.Int64())
+	assert.Equal(t, int64(20*400%testP), aggregate[0].C2.Int64())
+
+	_, err = aggregateCiphertexts(key, [][]ElGamalCiphertext{{a}, {a, b}})
+	assert.Error(t, err)
+}
+
+func TestAggregateEncryptedVotes(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.PublicKey = testPublicKeyJSON()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	// Candidate 0 gets two votes, candidate 2 gets one
+	for i, choice := range []int{0, 2, 0} {
+		_, err := contract.CastVote(ctx, "election-001", testBallot(choice, 3, int64(10*i+3)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	// Aggregation waits until voting is over
+	_, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.Error(t, err)
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, aggregate.VoteCount)
+	assert.Equal(t, []int{2, 0, 1}, []int{
+		testDecrypt(aggregate.Ciphertexts[0]),
+		testDecrypt(aggregate.Ciphertexts[1]),
+		testDecrypt(aggregate.Ciphertexts[2]),
+	})
+
+	stored, err := contract.GetEncryptedAggregate(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, aggregate.AggregateHash, stored.AggregateHash)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "votes_aggregated", entries[len(entries)-1].Type)
+	assert.Equal(t, aggregate.AggregateHash, entries[len(entries)-1].Hash)
+}
+
+func TestAggregateEncryptedVotesNoVotes(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Status = "closed"
+	election.PublicKey = testPublicKeyJSON()
+	election.EndTime = time.Now().Add(-1 * time.Hour)
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	_, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "no votes")
+}
+
+func TestValidateCiphertext(t *testing.T) {
+	publicKey := testPublicKeyJSON()
+
+	assert.NoError(t, validateCiphertext(testVote(3), publicKey))
+	assert.NoError(t, validateCiphertext(testBallot(1, 3, 5), publicKey))
+
+	valid := testEncrypt(1, 3)
+	for name, encryptedVote := range map[string]string{
+		"empty object":  "{}",
+		"not json":      "ciphertext",
+		"missing c2":    fmt.Sprintf(`{"c1":"%s"}`, valid.C1),
+		"not integer":   fmt.Sprintf(`{"c1":"%s","c2":"0x1f"}`, valid.C1),
+		"zero":          fmt.Sprintf(`{"c1":"0","c2":"%s"}`, valid.C2),
+		"negative":      fmt.Sprintf(`{"c1":"-4","c2":"%s"}`, valid.C2),
+		"not reduced":   fmt.Sprintf(`{"c1":"%d","c2":"%s"}`, testP+4, valid.C2),
+		"outside group": fmt.Sprintf(`{"c1":"%s","c2":"%d"}`, valid.C1, testP-1),
+		"empty ballot":  "[]",
+	} {
+		assert.Error(t, validateCiphertext(encryptedVote, publicKey), name)
+	}
+
+	// Without a usable public key nothing can be validated
+	assert.Error(t, validateCiphertext(testVote(3), "key"))
+}
+
+func TestCastVoteRejectsMalformedCiphertext(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	_, err := contract.CastVote(ctx, "election-001", "{}", "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid ciphertext")
+
+	used, _ := contract.IsNullifierUsed(ctx, "election-001", "nullifier123")
+	assert.False(t, used)
+
+	// A malformed ballot in a batch is reported without dropping the rest
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: fmt.Sprintf(`{"c1":"%d","c2":"1"}`, testP-1), Nullifier: "nullifier1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(3), Nullifier: "nullifier2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Receipts, 1)
+	assert.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Error, "not in the group")
+}
+
+func TestCastVoteRejectsForeignKeyCiphertext(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// Encrypted under this election's key
+	_, err := contract.CastVote(ctx, "election-001", testBallot(1, 3, 5), "nullifier1",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	// Encrypted under another election's key, in another group
+	foreign, _ := json.Marshal(testLocalBallot(1, 3, 5))
+	_, err = contract.CastVote(ctx, "election-001", string(foreign), "nullifier2",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrForeignCiphertext))
+	assert.Contains(t, err.Error(), "another key")
+	used, _ := contract.IsNullifierUsed(ctx, "election-001", "nullifier2")
+	assert.False(t, used)
+
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: string(foreign), Nullifier: "nullifier3", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Receipts)
+	assert.Contains(t, result.Errors[0].Error, ErrForeignCiphertext.Error())
+}
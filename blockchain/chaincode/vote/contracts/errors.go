@@ -0,0 +1,46 @@
+/*
+ * Errors - Sentinel errors returned by the vote contract
+ *
+ * Contract methods wrap these with fmt.Errorf("%w", ...) and add context such
+ * as the election ID, so callers can match them with errors.Is instead of
+ * comparing error text.
+ */
+
+package contracts
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotFound is wrapped by every not-found sentinel, so callers can tell a
+// missing key from a ledger read failure, which is never wrapped in it
+var ErrNotFound = errors.New("not found")
+
+var (
+	ErrElectionNotFound      = fmt.Errorf("election %w", ErrNotFound)
+	ErrVoteNotFound          = fmt.Errorf("vote %w", ErrNotFound)
+	ErrTallyNotFound         = fmt.Errorf("tally %w", ErrNotFound)
+	ErrBulletinBoardNotFound = fmt.Errorf("bulletin board %w", ErrNotFound)
+	ErrVoteProofNotFound     = fmt.Errorf("vote proof %w", ErrNotFound)
+)
+
+var (
+	ErrElectionNotPending  = errors.New("election is not in pending status")
+	ErrElectionIncomplete  = errors.New("election is not fully configured")
+	ErrElectionNotActive   = errors.New("election is not active")
+	ErrElectionPaused      = errors.New("election is paused")
+	ErrElectionCancelled   = errors.New("election has been cancelled")
+	ErrElectionNotStarted  = errors.New("election has not started yet")
+	ErrElectionEnded       = errors.New("election has ended")
+	ErrDuplicateNullifier  = errors.New("vote already submitted (duplicate nullifier)")
+	ErrVoteDelegated       = errors.New("vote already delegated")
+	ErrVoteInvalidated     = errors.New("vote has been invalidated")
+	ErrDuplicateCiphertext = errors.New("ciphertext already cast under another nullifier")
+	ErrForeignCiphertext   = errors.New("ciphertext is not in the group of the election key")
+	ErrBallotOutOfRange    = errors.New("ballot range proof does not verify")
+	ErrBallotFrozen        = errors.New("ballot structure is frozen")
+	ErrInvalidCastTime     = errors.New("client cast time rejected")
+	ErrPermissionDenied    = errors.New("permission denied")
+	ErrInvalidTransition   = errors.New("invalid status transition")
+)
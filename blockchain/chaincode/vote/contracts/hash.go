@@ -0,0 +1,76 @@
+/*
+ * Hash Algorithms - Per-election choice of digest
+ *
+ * Elections hash with SHA-256 unless created with another HashAlgorithm,
+ * e.g. Keccak-256 so Ethereum verifiers reproduce the same hashes. The
+ * algorithm covers encrypted vote and proof hashes, verification codes,
+ * bulletin board payload and chain hashes, and the Merkle trees over the
+ * board and the vote set at close. Nullifier commitments, which key the
+ * world state, and decryption proof challenges always use SHA-256.
+ *
+ * A Merkle scheme hashed with anything but SHA-256 names the algorithm
+ * after a slash, e.g. "v2/keccak256", so a verifier holding only a root or
+ * inclusion proof knows how to recompute it.
+ */
+
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	HashAlgorithmSHA256    = "sha256"
+	HashAlgorithmKeccak256 = "keccak256" // Ethereum's pre-standard SHA-3
+	HashAlgorithmSHA3256   = "sha3-256"  // FIPS 202
+)
+
+// hashSize is the digest size in bytes, the same for every algorithm
+const hashSize = sha256.Size
+
+// normalizeHashAlgorithm maps the empty algorithm of elections created
+// before it was configurable to SHA-256
+func normalizeHashAlgorithm(algorithm string) string {
+	if algorithm == "" {
+		return HashAlgorithmSHA256
+	}
+	return algorithm
+}
+
+func validateHashAlgorithm(algorithm string) error {
+	switch normalizeHashAlgorithm(algorithm) {
+	case HashAlgorithmSHA256, HashAlgorithmKeccak256, HashAlgorithmSHA3256:
+		return nil
+	}
+	return fmt.Errorf("unsupported hash algorithm: %s", algorithm)
+}
+
+// hashBytes returns the digest of data under algorithm
+func hashBytes(algorithm string, data []byte) [hashSize]byte {
+	switch normalizeHashAlgorithm(algorithm) {
+	case HashAlgorithmKeccak256:
+		var digest [hashSize]byte
+		h := sha3.NewLegacyKeccak256()
+		h.Write(data)
+		h.Sum(digest[:0])
+		return digest
+	case HashAlgorithmSHA3256:
+		return sha3.Sum256(data)
+	}
+	return sha256.Sum256(data)
+}
+
+// hashStringWith is hashString under algorithm
+func hashStringWith(algorithm, s string) string {
+	hash := hashBytes(algorithm, []byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+// hashForElection hashes s with the election's algorithm
+func hashForElection(election *Election, s string) string {
+	return hashStringWith(election.HashAlgorithm, s)
+}
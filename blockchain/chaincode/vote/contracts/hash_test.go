@@ -0,0 +1,160 @@
+/*
+ * Hash Algorithm Tests
+ */
+
+package contracts
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashStringWith(t *testing.T) {
+	assert.Equal(t, hashString("abc"), hashStringWith("", "abc"))
+	assert.Equal(t, hashString("abc"), hashStringWith(HashAlgorithmSHA256, "abc"))
+	assert.Equal(t, "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+		hashStringWith(HashAlgorithmKeccak256, "abc"))
+	assert.Equal(t, "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+		hashStringWith(HashAlgorithmSHA3256, "abc"))
+
+	assert.NoError(t, validateHashAlgorithm(""))
+	assert.Error(t, validateHashAlgorithm("md5"))
+}
+
+func TestHashBytesKnownAnswers(t *testing.T) {
+	// Around the 136-byte rate of both sponges, where the padding spills
+	// into a block of its own, and over several blocks
+	for _, tc := range []struct {
+		length       int
+		keccak, sha3 string
+	}{
+		{0, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+			"a7ffc6f8bf1ed76651c14756a061d662f580ff4de43b49fa82d80a4b80f8434a"},
+		{135, "34367dc248bbd832f4e3e69dfaac2f92638bd0bbd18f2912ba4ef454919cf446",
+			"8094bb53c44cfb1e67b7c30447f9a1c33696d2463ecc1d9c92538913392843c9"},
+		{136, "a6c4d403279fe3e0af03729caada8374b5ca54d8065329a3ebcaeb4b60aa386e",
+			"3fc5559f14db8e453a0a3091edbd2bc25e11528d81c66fa570a4efdcc2695ee1"},
+		{137, "d869f639c7046b4929fc92a4d988a8b22c55fbadb802c0c66ebcd484f1915f39",
+			"f8d6846cedd2ccfadf15c5879ef95af724d799eed7391fb1c91f95344e738614"},
+		{272, "cf7fcd4f705ee749930d19ca84561a9bf62516bd90a471545fa2f49fdc7e63c8",
+			"a490357b9b3fb39d0a89a117734e5b020b1f33c7bf3fa3575c396425432003d3"},
+		{300, "5b7e0e47a96f32a88b4f14ca177982790807c40e1a105742ba0fc1babe1ef826",
+			"8a5720b2ca0cae7b89ad399c5daab22c29f5c72bcf30ab81e807d9bda95b4580"},
+	} {
+		input := strings.Repeat("a", tc.length)
+		assert.Equal(t, tc.keccak, hashStringWith(HashAlgorithmKeccak256, input), tc.length)
+		assert.Equal(t, tc.sha3, hashStringWith(HashAlgorithmSHA3256, input), tc.length)
+	}
+}
+
+func TestMerkleRootHashAlgorithms(t *testing.T) {
+	keccakScheme := merkleSchemeWithHash(MerkleSchemeV2, HashAlgorithmKeccak256)
+	assert.Equal(t, "v2/keccak256", keccakScheme)
+	assert.Equal(t, MerkleSchemeV2, merkleSchemeWithHash(MerkleSchemeV2, ""))
+
+	// The same entries give a different root under each algorithm
+	entries := makeEntries(2)
+	sha256Root := computeMerkleRoot(MerkleSchemeV2, entries)
+	keccakRoot := computeMerkleRoot(keccakScheme, entries)
+	assert.NotEqual(t, sha256Root, keccakRoot)
+
+	// The keccak root follows the v2 layout with Keccak-256 in place of SHA-256
+	keccak := func(data []byte) []byte {
+		digest := hashBytes(HashAlgorithmKeccak256, data)
+		return digest[:]
+	}
+	leaf0 := keccak([]byte("\x00hash0tx0"))
+	leaf1 := keccak([]byte("\x00hash1tx1"))
+	root := keccak(append(append([]byte{0x01}, leaf0...), leaf1...))
+	assert.Equal(t, hex.EncodeToString(root), keccakRoot)
+
+	// v1 swaps its hash too
+	assert.Equal(t, hashStringWith(HashAlgorithmKeccak256, hashStringWith(HashAlgorithmKeccak256, "hash0tx0")+
+		hashStringWith(HashAlgorithmKeccak256, "hash1tx1")),
+		computeMerkleRoot(merkleSchemeWithHash(MerkleSchemeV1, HashAlgorithmKeccak256), entries))
+
+	// Frontiers and inclusion proofs agree with the full tree
+	entries = makeEntries(9)
+	frontier, err := newMerkleFrontier(keccakScheme, entries)
+	assert.NoError(t, err)
+	assert.Equal(t, computeMerkleRoot(keccakScheme, entries), frontier.Root)
+
+	levels := buildMerkleLevels(keccakScheme, merkleLeaves(keccakScheme, entries))
+	proof := &MerkleInclusionProof{
+		LeafHash:   levels[0][8],
+		Path:       merklePath(keccakScheme, levels, 8),
+		MerkleRoot: frontier.Root,
+		Scheme:     keccakScheme,
+	}
+	assert.True(t, proof.Verify())
+	proof.Scheme = MerkleSchemeV2
+	assert.False(t, proof.Verify())
+
+	assert.Equal(t, hashStringWith(HashAlgorithmKeccak256, ""), computeVoteSetRoot(keccakScheme, nil))
+}
+
+func TestKeccakElection(t *testing.T) {
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	roots := map[string]string{}
+	for _, algorithm := range []string{HashAlgorithmSHA256, HashAlgorithmKeccak256} {
+		contract := new(VoteContract)
+		ctx := new(MockTransactionContext)
+		stub := NewMockStub()
+
+		ctx.On("GetStub").Return(stub)
+
+		config, _ := json.Marshal(ElectionConfig{Options: []string{"yes"}, HashAlgorithm: algorithm})
+		_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, string(config))
+		assert.NoError(t, err)
+		_, err = contract.ActivateElection(ctx, "election-001")
+		assert.NoError(t, err)
+		election, _ := contract.GetElection(ctx, "election-001")
+		assert.Equal(t, algorithm, election.HashAlgorithm)
+
+		receipt, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier1",
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err, algorithm)
+		assert.Equal(t, hashStringWith(algorithm, testVote(1)), receipt.EncryptedVoteHash)
+		assert.Equal(t, hashStringWith(algorithm, stub.GetTxID()+receipt.EncryptedVoteHash)[:DefaultVerificationCodeLength],
+			receipt.VerificationCode)
+
+		scheme := merkleSchemeWithHash(MerkleSchemeV2, algorithm)
+		board, err := contract.GetBulletinBoard(ctx, "election-001")
+		assert.NoError(t, err)
+		assert.Equal(t, scheme, board["merkleScheme"])
+		entries := board["entries"].([]BulletinBoardEntry)
+		assert.Equal(t, computeMerkleRoot(scheme, entries), board["merkleRoot"])
+		assert.Equal(t, bulletinEntryHash(scheme, entries[0]), entries[1].PrevEntryHash)
+		roots[algorithm] = board["merkleRoot"].(string)
+
+		chain, err := contract.VerifyBulletinChain(ctx, "election-001")
+		assert.NoError(t, err)
+		assert.Equal(t, true, chain["valid"], algorithm)
+
+		proof, err := contract.GetVoteInclusionProof(ctx, "election-001", receipt.EncryptedVoteHash)
+		assert.NoError(t, err)
+		assert.Equal(t, scheme, proof.Scheme)
+		assert.True(t, proof.Verify())
+
+		// The vote set checkpoint uses the election's algorithm too
+		_, err = contract.CloseElection(ctx, "election-001")
+		assert.NoError(t, err)
+		election, _ = contract.GetElection(ctx, "election-001")
+		assert.Equal(t, computeVoteSetRoot(scheme, []string{receipt.EncryptedVoteHash}), election.ClosedVoteRoot)
+	}
+	assert.NotEqual(t, roots[HashAlgorithmSHA256], roots[HashAlgorithmKeccak256])
+
+	ctx := new(MockTransactionContext)
+	ctx.On("GetStub").Return(NewMockStub())
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, `{"hashAlgorithm": "md5"}`)
+	assert.Error(t, err)
+}
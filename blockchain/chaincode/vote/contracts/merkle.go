@@ -0,0 +1,325 @@
+/*
+ * Merkle Tree - Bulletin board commitments and inclusion proofs
+ *
+ * Two hashing schemes exist. Each election records the one its bulletin
+ * board root is computed with, so roots published earlier stay reproducible.
+ *
+ * v1 (legacy): leaves are hashString(entry.Hash + entry.TxID); each level
+ * pairs adjacent nodes as hashString(left + right) over the hex strings, and
+ * an unpaired last node is promoted to the next level unchanged.
+ *
+ * v2: domain-separated SHA-256 over raw bytes. A leaf is
+ * SHA-256(0x00 || entry.Hash || entry.TxID) and an internal node is
+ * SHA-256(0x01 || left || right) over the 32-byte child digests. An unpaired
+ * last node is paired with itself. All hashes are hex encoded.
+ *
+ * The vote checkpoint taken at CloseElection uses the election's scheme
+ * with the sorted encrypted vote hashes themselves as leaf data.
+ *
+ * Elections with another hash algorithm use it in place of SHA-256 in
+ * either scheme; their scheme is recorded as e.g. "v2/keccak256".
+ */
+
+package contracts
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	MerkleSchemeV1 = "v1" // hex concatenation, odd node promoted
+	MerkleSchemeV2 = "v2" // 0x00/0x01 domain separation, odd node duplicated
+)
+
+// DefaultMerkleScheme is used for elections created from now on
+const DefaultMerkleScheme = MerkleSchemeV2
+
+const (
+	merkleLeafPrefix = 0x00
+	merkleNodePrefix = 0x01
+)
+
+// MerkleProofStep is one sibling on the path from a leaf to the root.
+// Position says which side the sibling sits on when hashing the pair.
+type MerkleProofStep struct {
+	Hash     string `json:"hash"`
+	Position string `json:"position"`
+}
+
+// MerkleInclusionProof proves a vote's bulletin board entry is under MerkleRoot
+type MerkleInclusionProof struct {
+	ElectionID        string            `json:"electionId"`
+	EncryptedVoteHash string            `json:"encryptedVoteHash"`
+	Sequence          int               `json:"sequence"`
+	LeafIndex         int               `json:"leafIndex"`
+	LeafHash          string            `json:"leafHash"`
+	Path              []MerkleProofStep `json:"path"`
+	MerkleRoot        string            `json:"merkleRoot"`
+	Scheme            string            `json:"scheme"`
+}
+
+// Verify recomputes the root from the leaf and path. Under v1 promoted nodes
+// have no sibling at that level, so the path simply skips them.
+func (p *MerkleInclusionProof) Verify() bool {
+	scheme := normalizeMerkleScheme(p.Scheme)
+	node := p.LeafHash
+	for _, step := range p.Path {
+		var err error
+		if step.Position == "left" {
+			node, err = hashMerkleNode(scheme, step.Hash, node)
+		} else {
+			node, err = hashMerkleNode(scheme, node, step.Hash)
+		}
+		if err != nil {
+			return false
+		}
+	}
+	return node == p.MerkleRoot
+}
+
+// normalizeMerkleScheme maps the empty scheme of elections created before
+// schemes were recorded to v1
+func normalizeMerkleScheme(scheme string) string {
+	if scheme == "" {
+		return MerkleSchemeV1
+	}
+	return scheme
+}
+
+// merkleSchemeWithHash appends a hash algorithm other than SHA-256 to a scheme
+func merkleSchemeWithHash(scheme, algorithm string) string {
+	algorithm = normalizeHashAlgorithm(algorithm)
+	if algorithm == HashAlgorithmSHA256 {
+		return scheme
+	}
+	return scheme + "/" + algorithm
+}
+
+// splitMerkleScheme returns the tree hashing and the hash algorithm of a scheme
+func splitMerkleScheme(scheme string) (string, string) {
+	tree, algorithm, _ := strings.Cut(scheme, "/")
+	return tree, normalizeHashAlgorithm(algorithm)
+}
+
+// electionMerkleScheme is the scheme an election's trees are hashed with
+func electionMerkleScheme(election *Election) string {
+	return merkleSchemeWithHash(normalizeMerkleScheme(election.MerkleScheme), election.HashAlgorithm)
+}
+
+// promotesOddNode reports whether an unpaired node moves up unchanged (v1)
+// rather than being paired with itself
+func promotesOddNode(scheme string) bool {
+	tree, _ := splitMerkleScheme(scheme)
+	return tree == MerkleSchemeV1
+}
+
+func validateMerkleScheme(scheme string) error {
+	switch normalizeMerkleScheme(scheme) {
+	case MerkleSchemeV1, MerkleSchemeV2:
+		return nil
+	}
+	return fmt.Errorf("unsupported merkle scheme: %s", scheme)
+}
+
+func hashMerkleLeaf(scheme string, entry BulletinBoardEntry) string {
+	return hashMerkleLeafData(scheme, entry.Hash+entry.TxID)
+}
+
+func hashMerkleLeafData(scheme string, leaf string) string {
+	tree, algorithm := splitMerkleScheme(scheme)
+	if tree == MerkleSchemeV1 {
+		return hashStringWith(algorithm, leaf)
+	}
+	data := append([]byte{merkleLeafPrefix}, leaf...)
+	hash := hashBytes(algorithm, data)
+	return hex.EncodeToString(hash[:])
+}
+
+func hashMerkleNode(scheme string, left, right string) (string, error) {
+	tree, algorithm := splitMerkleScheme(scheme)
+	if tree == MerkleSchemeV1 {
+		return hashStringWith(algorithm, left+right), nil
+	}
+	leftBytes, err := hex.DecodeString(left)
+	if err != nil || len(leftBytes) != hashSize {
+		return "", fmt.Errorf("invalid merkle node hash %q", left)
+	}
+	rightBytes, err := hex.DecodeString(right)
+	if err != nil || len(rightBytes) != hashSize {
+		return "", fmt.Errorf("invalid merkle node hash %q", right)
+	}
+	data := append([]byte{merkleNodePrefix}, leftBytes...)
+	data = append(data, rightBytes...)
+	hash := hashBytes(algorithm, data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func merkleLeaves(scheme string, entries []BulletinBoardEntry) []string {
+	leaves := make([]string, len(entries))
+	for i, entry := range entries {
+		leaves[i] = hashMerkleLeaf(scheme, entry)
+	}
+	return leaves
+}
+
+// buildMerkleLevels returns every level of the tree, leaves first and root last
+func buildMerkleLevels(scheme string, leaves []string) [][]string {
+	if len(leaves) == 0 {
+		return nil
+	}
+
+	levels := [][]string{leaves}
+	hashes := leaves
+	for len(hashes) > 1 {
+		var newHashes []string
+		for i := 0; i < len(hashes); i += 2 {
+			if i+1 < len(hashes) {
+				node, _ := hashMerkleNode(scheme, hashes[i], hashes[i+1])
+				newHashes = append(newHashes, node)
+			} else if promotesOddNode(scheme) {
+				newHashes = append(newHashes, hashes[i])
+			} else {
+				node, _ := hashMerkleNode(scheme, hashes[i], hashes[i])
+				newHashes = append(newHashes, node)
+			}
+		}
+		levels = append(levels, newHashes)
+		hashes = newHashes
+	}
+
+	return levels
+}
+
+// merklePath collects the siblings of the node at index on each level
+func merklePath(scheme string, levels [][]string, index int) []MerkleProofStep {
+	path := []MerkleProofStep{}
+	for _, level := range levels[:len(levels)-1] {
+		if index%2 == 1 {
+			path = append(path, MerkleProofStep{Hash: level[index-1], Position: "left"})
+		} else if index+1 < len(level) {
+			path = append(path, MerkleProofStep{Hash: level[index+1], Position: "right"})
+		} else if !promotesOddNode(scheme) {
+			// Unpaired node is hashed with itself
+			path = append(path, MerkleProofStep{Hash: level[index], Position: "right"})
+		}
+		index /= 2
+	}
+	return path
+}
+
+// computeVoteSetRoot returns the root of a tree whose leaves are the vote
+// hashes in sorted order, so the root depends only on the set of votes. The
+// root of an empty set is the hash of the empty string.
+func computeVoteSetRoot(scheme string, voteHashes []string) string {
+	if len(voteHashes) == 0 {
+		_, algorithm := splitMerkleScheme(scheme)
+		return hashStringWith(algorithm, "")
+	}
+	sorted := append([]string(nil), voteHashes...)
+	sort.Strings(sorted)
+	leaves := make([]string, len(sorted))
+	for i, voteHash := range sorted {
+		leaves[i] = hashMerkleLeafData(scheme, voteHash)
+	}
+	levels := buildMerkleLevels(scheme, leaves)
+	return levels[len(levels)-1][0]
+}
+
+func computeMerkleRoot(scheme string, entries []BulletinBoardEntry) string {
+	levels := buildMerkleLevels(scheme, merkleLeaves(scheme, entries))
+	if levels == nil {
+		return ""
+	}
+	return levels[len(levels)-1][0]
+}
+
+// MerkleFrontier maintains a bulletin board root incrementally. Frontier[i]
+// holds the node of level i still waiting for its right sibling, so appending
+// an entry hashes at most one node per level instead of the whole tree.
+type MerkleFrontier struct {
+	Scheme   string   `json:"scheme"`
+	Size     int      `json:"size"`
+	Frontier []string `json:"frontier"`
+	Root     string   `json:"root"`
+}
+
+// newMerkleFrontier builds the frontier of an existing list of entries
+func newMerkleFrontier(scheme string, entries []BulletinBoardEntry) (*MerkleFrontier, error) {
+	f := &MerkleFrontier{Scheme: normalizeMerkleScheme(scheme), Frontier: []string{}}
+	for _, entry := range entries {
+		if err := f.Append(entry); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// Append adds an entry as the next leaf and updates Root
+func (f *MerkleFrontier) Append(entry BulletinBoardEntry) error {
+	node := hashMerkleLeaf(f.Scheme, entry)
+	level := 0
+	for ; level < len(f.Frontier) && f.Frontier[level] != ""; level++ {
+		var err error
+		node, err = hashMerkleNode(f.Scheme, f.Frontier[level], node)
+		if err != nil {
+			return err
+		}
+		f.Frontier[level] = ""
+	}
+	if level == len(f.Frontier) {
+		f.Frontier = append(f.Frontier, node)
+	} else {
+		f.Frontier[level] = node
+	}
+	f.Size++
+
+	root, err := f.computeRoot()
+	if err != nil {
+		return err
+	}
+	f.Root = root
+	return nil
+}
+
+// computeRoot folds the frontier into the root buildMerkleLevels produces.
+// carry is the rightmost node of a level whose subtree is still incomplete;
+// when it has no left neighbour it is treated like any unpaired node.
+func (f *MerkleFrontier) computeRoot() (string, error) {
+	if f.Size == 0 {
+		return "", nil
+	}
+
+	carry := ""
+	level := 0
+	for width := f.Size; width > 1; width = (width + 1) / 2 {
+		left := ""
+		if level < len(f.Frontier) {
+			left = f.Frontier[level]
+		}
+
+		var err error
+		switch {
+		case left != "" && carry != "":
+			carry, err = hashMerkleNode(f.Scheme, left, carry)
+		case left != "" || carry != "":
+			unpaired := left + carry
+			if promotesOddNode(f.Scheme) {
+				carry = unpaired
+			} else {
+				carry, err = hashMerkleNode(f.Scheme, unpaired, unpaired)
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+		level++
+	}
+
+	if carry != "" {
+		return carry, nil
+	}
+	return f.Frontier[level], nil
+}
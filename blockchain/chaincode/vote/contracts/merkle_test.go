@@ -0,0 +1,319 @@
+/*
+ * Merkle Tree Tests
+ */
+
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeEntries(n int) []BulletinBoardEntry {
+	entries := make([]BulletinBoardEntry, n)
+	for i := range entries {
+		entries[i] = BulletinBoardEntry{
+			Sequence: i + 1,
+			Type:     "vote_cast",
+			Hash:     fmt.Sprintf("hash%d", i),
+			TxID:     fmt.Sprintf("tx%d", i),
+		}
+	}
+	return entries
+}
+
+func TestMerklePathMatchesRoot(t *testing.T) {
+	// Odd sizes exercise node promotion at different levels
+	for n := 1; n <= 9; n++ {
+		entries := makeEntries(n)
+		root := computeMerkleRoot(MerkleSchemeV1, entries)
+		levels := buildMerkleLevels(MerkleSchemeV1, merkleLeaves(MerkleSchemeV1, entries))
+
+		for i := 0; i < n; i++ {
+			proof := &MerkleInclusionProof{
+				LeafHash:   levels[0][i],
+				Path:       merklePath(MerkleSchemeV1, levels, i),
+				MerkleRoot: root,
+			}
+			assert.True(t, proof.Verify(), "n=%d index=%d", n, i)
+		}
+	}
+}
+
+func TestMerklePathRejectsTampering(t *testing.T) {
+	entries := makeEntries(5)
+	levels := buildMerkleLevels(MerkleSchemeV1, merkleLeaves(MerkleSchemeV1, entries))
+
+	proof := &MerkleInclusionProof{
+		LeafHash:   levels[0][2],
+		Path:       merklePath(MerkleSchemeV1, levels, 2),
+		MerkleRoot: computeMerkleRoot(MerkleSchemeV1, entries),
+	}
+	assert.True(t, proof.Verify())
+
+	proof.LeafHash = levels[0][3]
+	assert.False(t, proof.Verify())
+
+	proof.LeafHash = levels[0][2]
+	proof.Path[0].Position = "left"
+	assert.False(t, proof.Verify())
+}
+
+func TestMerklePromotedNodeHasShorterPath(t *testing.T) {
+	levels := buildMerkleLevels(MerkleSchemeV1, merkleLeaves(MerkleSchemeV1, makeEntries(5)))
+
+	// The fifth leaf is promoted twice before it meets a sibling
+	path := merklePath(MerkleSchemeV1, levels, 4)
+	assert.Len(t, path, 1)
+	assert.Equal(t, "left", path[0].Position)
+	assert.Len(t, merklePath(MerkleSchemeV1, levels, 0), 3)
+}
+
+// v2Hash hashes a domain prefix followed by raw bytes, as documented for v2
+func v2Hash(prefix byte, parts ...[]byte) string {
+	data := []byte{prefix}
+	for _, part := range parts {
+		data = append(data, part...)
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+func v2Node(left, right string) string {
+	l, _ := hex.DecodeString(left)
+	r, _ := hex.DecodeString(right)
+	return v2Hash(0x01, l, r)
+}
+
+func TestMerkleRootV2EvenAndOdd(t *testing.T) {
+	entries := makeEntries(4)
+	leaves := make([]string, len(entries))
+	for i, entry := range entries {
+		leaves[i] = v2Hash(0x00, []byte(entry.Hash+entry.TxID))
+	}
+
+	// Four leaves: a balanced tree
+	even := v2Node(v2Node(leaves[0], leaves[1]), v2Node(leaves[2], leaves[3]))
+	assert.Equal(t, even, computeMerkleRoot(MerkleSchemeV2, entries))
+
+	// Three leaves: the last one is paired with itself
+	odd := v2Node(v2Node(leaves[0], leaves[1]), v2Node(leaves[2], leaves[2]))
+	assert.Equal(t, odd, computeMerkleRoot(MerkleSchemeV2, entries[:3]))
+
+	// Legacy roots are still reproducible and differ from v2
+	legacyOdd := hashString(hashString(hashString("hash0tx0")+hashString("hash1tx1")) + hashString("hash2tx2"))
+	assert.Equal(t, legacyOdd, computeMerkleRoot(MerkleSchemeV1, entries[:3]))
+	assert.NotEqual(t, legacyOdd, odd)
+
+	// A single leaf is its own root; leaves never equal plain hashes of their data
+	assert.Equal(t, leaves[0], computeMerkleRoot(MerkleSchemeV2, entries[:1]))
+	assert.NotEqual(t, hashString("hash0tx0"), leaves[0])
+}
+
+func TestComputeVoteSetRoot(t *testing.T) {
+	leaf := func(hash string) string { return v2Hash(0x00, []byte(hash)) }
+
+	// Leaves are the sorted vote hashes, whatever order they are given in
+	root := v2Node(v2Node(leaf("a"), leaf("b")), v2Node(leaf("c"), leaf("c")))
+	assert.Equal(t, root, computeVoteSetRoot(MerkleSchemeV2, []string{"c", "a", "b"}))
+	assert.Equal(t, root, computeVoteSetRoot(MerkleSchemeV2, []string{"b", "c", "a"}))
+
+	// Adding or dropping a vote changes the root
+	assert.NotEqual(t, root, computeVoteSetRoot(MerkleSchemeV2, []string{"a", "b"}))
+	assert.NotEqual(t, root, computeVoteSetRoot(MerkleSchemeV2, []string{"a", "b", "c", "d"}))
+
+	// The empty set has a root of its own
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		computeVoteSetRoot(MerkleSchemeV2, nil))
+}
+
+func TestMerklePathMatchesRootV2(t *testing.T) {
+	for n := 1; n <= 9; n++ {
+		entries := makeEntries(n)
+		root := computeMerkleRoot(MerkleSchemeV2, entries)
+		levels := buildMerkleLevels(MerkleSchemeV2, merkleLeaves(MerkleSchemeV2, entries))
+
+		for i := 0; i < n; i++ {
+			proof := &MerkleInclusionProof{
+				LeafHash:   levels[0][i],
+				Path:       merklePath(MerkleSchemeV2, levels, i),
+				MerkleRoot: root,
+				Scheme:     MerkleSchemeV2,
+			}
+			assert.True(t, proof.Verify(), "n=%d index=%d", n, i)
+			// Every level contributes a sibling under v2
+			assert.Len(t, proof.Path, len(levels)-1)
+		}
+	}
+}
+
+func TestMerkleProofV2RejectsMalformedPath(t *testing.T) {
+	entries := makeEntries(4)
+	levels := buildMerkleLevels(MerkleSchemeV2, merkleLeaves(MerkleSchemeV2, entries))
+	proof := &MerkleInclusionProof{
+		LeafHash:   levels[0][1],
+		Path:       merklePath(MerkleSchemeV2, levels, 1),
+		MerkleRoot: computeMerkleRoot(MerkleSchemeV2, entries),
+		Scheme:     MerkleSchemeV2,
+	}
+	assert.True(t, proof.Verify())
+
+	proof.Path[0].Hash = "not-hex"
+	assert.False(t, proof.Verify())
+
+	// A v2 proof does not verify under the legacy scheme
+	proof.Path = merklePath(MerkleSchemeV2, levels, 1)
+	proof.Scheme = MerkleSchemeV1
+	assert.False(t, proof.Verify())
+}
+
+func TestElectionMerkleScheme(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+
+	// New elections use v2
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, MerkleSchemeV2, election.MerkleScheme)
+
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	board, err := contract.GetBulletinBoard(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, MerkleSchemeV2, board["merkleScheme"])
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, computeMerkleRoot(MerkleSchemeV2, entries), board["merkleRoot"])
+
+	proof, err := contract.GetVoteInclusionProof(ctx, "election-001", receipt.EncryptedVoteHash)
+	assert.NoError(t, err)
+	assert.Equal(t, MerkleSchemeV2, proof.Scheme)
+	assert.True(t, proof.Verify())
+
+	// Elections stored before schemes existed keep their v1 roots
+	legacy := createMockElection()
+	legacy.ID = "election-002"
+	legacyJSON, _ := json.Marshal(legacy)
+	stub.State["election:election-002"] = legacyJSON
+	putBulletinBoard(stub, "election-002", entries)
+
+	board, err = contract.GetBulletinBoard(ctx, "election-002")
+	assert.NoError(t, err)
+	assert.Equal(t, MerkleSchemeV1, board["merkleScheme"])
+	assert.Equal(t, computeMerkleRoot(MerkleSchemeV1, entries), board["merkleRoot"])
+
+	// Unknown schemes are rejected at creation
+	_, err = contract.CreateElectionWithConfig(ctx, "election-003", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, `{"merkleScheme": "v9"}`)
+	assert.Error(t, err)
+}
+
+func TestMerkleFrontierMatchesBatchRoot(t *testing.T) {
+	for _, scheme := range []string{MerkleSchemeV1, MerkleSchemeV2} {
+		entries := makeEntries(40)
+		frontier, err := newMerkleFrontier(scheme, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "", frontier.Root)
+
+		for n := 1; n <= len(entries); n++ {
+			assert.NoError(t, frontier.Append(entries[n-1]))
+			assert.Equal(t, n, frontier.Size)
+			assert.Equal(t, computeMerkleRoot(scheme, entries[:n]), frontier.Root, "%s after %d entries", scheme, n)
+		}
+
+		// The frontier holds one node per level at most
+		assert.LessOrEqual(t, len(frontier.Frontier), 6)
+	}
+}
+
+func TestBulletinBoardRootIsIncremental(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	for i := 0; i < 7; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+1)), fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+
+		board, _ := contract.GetBulletinBoard(ctx, "election-001")
+		entries := board["entries"].([]BulletinBoardEntry)
+		root, err := contract.GetBulletinBoardRoot(ctx, "election-001")
+		assert.NoError(t, err)
+		assert.Equal(t, len(entries), root["size"])
+		assert.Equal(t, computeMerkleRoot(MerkleSchemeV2, entries), root["merkleRoot"])
+		assert.Equal(t, root["merkleRoot"], board["merkleRoot"])
+	}
+
+	recomputed, err := contract.RecomputeBulletinBoardRoot(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.True(t, recomputed["matches"].(bool))
+
+	// A tampered stored root is caught by the full recomputation
+	var tree MerkleFrontier
+	_ = json.Unmarshal(stub.State["bulletinboardtree:election-001"], &tree)
+	tree.Root = hashString("forged")
+	stub.State["bulletinboardtree:election-001"], _ = json.Marshal(tree)
+
+	recomputed, err = contract.RecomputeBulletinBoardRoot(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.False(t, recomputed["matches"].(bool))
+	assert.Equal(t, tree.Root, recomputed["storedRoot"])
+}
+
+func TestBulletinBoardRootFoldsExistingEntries(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// A board written before the root state was stored
+	entries := makeEntries(5)
+	putBulletinBoard(stub, "election-001", entries)
+
+	root, err := contract.GetBulletinBoardRoot(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, computeMerkleRoot(MerkleSchemeV1, entries), root["merkleRoot"])
+
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.NotNil(t, stub.State["bulletinboardtree:election-001"])
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	all := board["entries"].([]BulletinBoardEntry)
+	assert.Len(t, all, 6)
+	assert.Equal(t, computeMerkleRoot(MerkleSchemeV1, all), board["merkleRoot"])
+
+	proof, err := contract.GetVoteInclusionProof(ctx, "election-001", receipt.EncryptedVoteHash)
+	assert.NoError(t, err)
+	assert.Equal(t, board["merkleRoot"], proof.MerkleRoot)
+}
@@ -0,0 +1,136 @@
+/*
+ * Metrics - Per-election counters for monitoring
+ *
+ * Vote metrics are striped like the vote counter: each vote updates the
+ * metrics key beside the counter shard its nullifier hashes to, so metrics
+ * add no MVCC conflicts the counter does not already have. Status metrics
+ * change only with admin transitions and live in one key per election.
+ *
+ * Duplicate attempts can only be counted when the election records them
+ * (RecordDuplicateAttempts); a rejected vote's transaction commits nothing.
+ * Elections created before metrics existed report zeros until they change.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// voteMetrics is one shard of an election's vote metrics
+type voteMetrics struct {
+	VotesCast         int       `json:"votesCast"`
+	DuplicateAttempts int       `json:"duplicateAttempts"`
+	LastVoteTime      time.Time `json:"lastVoteTime"`
+}
+
+// statusMetrics counts an election's status transitions
+type statusMetrics struct {
+	Transitions        int       `json:"transitions"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// voteMetricsKey is the metrics key beside a vote counter key
+func voteMetricsKey(counterKey string) string {
+	return "metrics:" + counterKey
+}
+
+func statusMetricsKey(electionID string) string {
+	return fmt.Sprintf("metrics:status:%s", electionID)
+}
+
+// addVoteMetrics adds each delta to its metrics key, keeping the latest
+// vote time. Keys are updated in sorted order so the write set does not
+// depend on map iteration.
+func addVoteMetrics(ctx contractapi.TransactionContextInterface, deltas map[string]voteMetrics) error {
+	keys := make([]string, 0, len(deltas))
+	for key := range deltas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var metrics voteMetrics
+		if err := readMetrics(ctx, key, &metrics); err != nil {
+			return err
+		}
+		delta := deltas[key]
+		metrics.VotesCast += delta.VotesCast
+		metrics.DuplicateAttempts += delta.DuplicateAttempts
+		if delta.LastVoteTime.After(metrics.LastVoteTime) {
+			metrics.LastVoteTime = delta.LastVoteTime
+		}
+		if err := writeMetrics(ctx, key, metrics); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordStatusTransition counts a status change of an election
+func recordStatusTransition(ctx contractapi.TransactionContextInterface, electionID string) error {
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	var metrics statusMetrics
+	if err := readMetrics(ctx, statusMetricsKey(electionID), &metrics); err != nil {
+		return err
+	}
+	metrics.Transitions++
+	metrics.LastTransitionTime = now
+	return writeMetrics(ctx, statusMetricsKey(electionID), metrics)
+}
+
+// readMetrics reads a metrics key into metrics, leaving it zero when unset
+func readMetrics(ctx contractapi.TransactionContextInterface, key string, metrics interface{}) error {
+	metricsJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to read metrics: %v", err)
+	}
+	if metricsJSON == nil {
+		return nil
+	}
+	if err := json.Unmarshal(metricsJSON, metrics); err != nil {
+		return fmt.Errorf("invalid metrics: %v", err)
+	}
+	return nil
+}
+
+func writeMetrics(ctx contractapi.TransactionContextInterface, key string, metrics interface{}) error {
+	metricsJSON, err := json.Marshal(metrics)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, metricsJSON); err != nil {
+		return fmt.Errorf("failed to update metrics: %v", err)
+	}
+	return nil
+}
+
+// sumVoteMetrics combines the metrics beside the unsharded counter and every shard
+func sumVoteMetrics(ctx contractapi.TransactionContextInterface, election *Election) (voteMetrics, error) {
+	keys := []string{voteMetricsKey(voteCountKey(election.ID))}
+	for shard := 0; shard < election.VoteCountShards; shard++ {
+		keys = append(keys, voteMetricsKey(voteCountShardKey(election.ID, shard)))
+	}
+
+	var total voteMetrics
+	for _, key := range keys {
+		var metrics voteMetrics
+		if err := readMetrics(ctx, key, &metrics); err != nil {
+			return voteMetrics{}, err
+		}
+		total.VotesCast += metrics.VotesCast
+		total.DuplicateAttempts += metrics.DuplicateAttempts
+		if metrics.LastVoteTime.After(total.LastVoteTime) {
+			total.LastVoteTime = metrics.LastVoteTime
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,96 @@
+/*
+ * Metrics Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetElectionMetrics(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	_, err := contract.GetElectionMetrics(ctx, "election-001")
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+
+	base := time.Now().Truncate(time.Second).UTC()
+	at := func(minutes int) time.Time {
+		when := base.Add(time.Duration(minutes) * time.Minute)
+		stub.TxTimestamp = &timestamp.Timestamp{Seconds: when.Unix()}
+		return when
+	}
+
+	at(0)
+	config, _ := json.Marshal(ElectionConfig{Options: []string{"yes"}, RecordDuplicateAttempts: true})
+	_, err = contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		base.Add(-time.Hour).Format(time.RFC3339), base.Add(24*time.Hour).Format(time.RFC3339), string(config))
+	assert.NoError(t, err)
+
+	metrics, err := contract.GetElectionMetrics(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, &ElectionMetrics{ElectionID: "election-001", Status: "pending"}, metrics)
+
+	activated := at(1)
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// Votes land on different shards and the latest time wins
+	var lastVote time.Time
+	for i := 0; i < 3; i++ {
+		lastVote = at(2 + i)
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)), fmt.Sprintf("nullifier%d", i),
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	// A recorded duplicate attempt is counted but is not a vote
+	at(10)
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(99), "nullifier0",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.False(t, receipt.Success)
+
+	metrics, err = contract.GetElectionMetrics(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, "active", metrics.Status)
+	assert.Equal(t, 3, metrics.VoteCount)
+	assert.Equal(t, 3, metrics.VotesCast)
+	assert.Equal(t, 1, metrics.DuplicateAttempts)
+	assert.Equal(t, lastVote, *metrics.LastVoteTime)
+	assert.Equal(t, 1, metrics.StatusTransitions)
+	assert.Equal(t, activated, *metrics.LastTransitionTime)
+
+	// Batched ballots count too
+	lastVote = at(11)
+	batch := []EncryptedBallotInput{
+		{EncryptedVote: testVote(21), Nullifier: "nullifier21", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(22), Nullifier: "nullifier22", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	}
+	_, err = contract.CastVoteBatch(ctx, "election-001", batch)
+	assert.NoError(t, err)
+
+	closed := at(12)
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	metrics, err = contract.GetElectionMetrics(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, "closed", metrics.Status)
+	assert.Equal(t, 5, metrics.VoteCount)
+	assert.Equal(t, 5, metrics.VotesCast)
+	assert.Equal(t, lastVote, *metrics.LastVoteTime)
+	assert.Equal(t, 2, metrics.StatusTransitions)
+	assert.Equal(t, closed, *metrics.LastTransitionTime)
+}
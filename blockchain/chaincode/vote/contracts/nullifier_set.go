@@ -0,0 +1,264 @@
+/*
+ * Nullifier Set - Used nullifier commitments for cross-ledger checks
+ *
+ * When one electorate votes on several independent channels, a coordinator
+ * detects double voting by intersecting the nullifier commitments used on
+ * each. Commitments are bound to the election ID, so the sets only
+ * intersect across channels that run the election under the same ID.
+ *
+ * Small elections return the exact sorted set. Above NullifierSetExactLimit
+ * a Bloom filter is returned instead; a commitment c is in the filter when
+ * for every i in [0, k) bit (h1 + i*h2) mod m is set, where h1 and h2 are
+ * the first and second big-endian uint64 of SHA-256(c), the sum wraps at
+ * 2^64, and bit j is bits[j/8] & (1 << (j%8)). A coordinator checks each commitment of one
+ * channel's exact set against the other channel's filter; hits are double
+ * votes or, at the filter's falsePositiveRate, false alarms.
+ *
+ * A voter who did not vote proves it with ProveNonMembership. The sorted
+ * commitments are the leaves of a v2 Merkle tree under the election's hash
+ * algorithm, each leaf hashing the commitment as leaf data. An unused
+ * commitment falls between two adjacent leaves, before the first or after
+ * the last, and the proof carries those neighbours with their paths. Every
+ * level of a v2 tree has a sibling, so a path also fixes its leaf's index;
+ * the last leaf is the one whose left-child steps all pair it with itself.
+ * The root of an empty set is the hash of the empty string.
+ */
+
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// NullifierSetExactLimit is the largest set GetNullifierSet returns
+// exactly; bigger sets are returned as a Bloom filter
+const NullifierSetExactLimit = 4096
+
+// NullifierBloomFalsePositiveRate is the false positive rate the Bloom
+// filter is sized for
+const NullifierBloomFalsePositiveRate = 0.001
+
+// NullifierSet is the set of nullifier commitments used in an election,
+// either exactly (Commitments) or as a Bloom filter (Bloom)
+type NullifierSet struct {
+	ElectionID  string       `json:"electionId"`
+	Count       int          `json:"count"`
+	Commitments []string     `json:"commitments,omitempty"`
+	Bloom       *BloomFilter `json:"bloom,omitempty"`
+}
+
+// BloomFilter is a Bloom filter of m bits probed with k hashes. Bits is
+// base64 encoded in JSON.
+type BloomFilter struct {
+	M                 uint64  `json:"m"`
+	K                 int     `json:"k"`
+	FalsePositiveRate float64 `json:"falsePositiveRate"`
+	Bits              []byte  `json:"bits"`
+}
+
+// usedNullifierCommitments returns the sorted commitments that cast a vote
+// or delegated one. Invalidated votes still used their nullifier.
+func usedNullifierCommitments(ctx contractapi.TransactionContextInterface, electionID string) ([]string, error) {
+	used := make(map[string]bool)
+	for _, objectType := range []string{voteObjectType, delegationObjectType} {
+		iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectType, []string{electionID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s index: %v", objectType, err)
+		}
+		for iterator.HasNext() {
+			kv, err := iterator.Next()
+			if err != nil {
+				iterator.Close()
+				return nil, fmt.Errorf("failed to read %s index: %v", objectType, err)
+			}
+			_, attributes, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+			if err != nil || len(attributes) != 2 {
+				iterator.Close()
+				return nil, fmt.Errorf("invalid %s key %q", objectType, kv.Key)
+			}
+			used[attributes[1]] = true
+		}
+		iterator.Close()
+	}
+
+	commitments := make([]string, 0, len(used))
+	for commitment := range used {
+		commitments = append(commitments, commitment)
+	}
+	sort.Strings(commitments)
+	return commitments, nil
+}
+
+// newBloomFilter sizes a filter for n items at the given false positive rate
+func newBloomFilter(n int, falsePositiveRate float64) *BloomFilter {
+	m := uint64(math.Ceil(-float64(n) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	m = (m + 7) / 8 * 8
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &BloomFilter{
+		M:                 m,
+		K:                 k,
+		FalsePositiveRate: falsePositiveRate,
+		Bits:              make([]byte, m/8),
+	}
+}
+
+// bloomProbes returns the bit positions of item
+func (f *BloomFilter) bloomProbes(item string) []uint64 {
+	digest := sha256.Sum256([]byte(item))
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16])
+	probes := make([]uint64, f.K)
+	for i := range probes {
+		probes[i] = (h1 + uint64(i)*h2) % f.M
+	}
+	return probes
+}
+
+// add sets the bits of item
+func (f *BloomFilter) add(item string) {
+	for _, bit := range f.bloomProbes(item) {
+		f.Bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// mayContain reports whether item may have been added; false is certain
+func (f *BloomFilter) mayContain(item string) bool {
+	for _, bit := range f.bloomProbes(item) {
+		if f.Bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// NullifierSetLeaf is a commitment in the sorted nullifier set with its
+// Merkle path
+type NullifierSetLeaf struct {
+	Commitment string            `json:"commitment"`
+	Index      int               `json:"index"`
+	Path       []MerkleProofStep `json:"path"`
+}
+
+// NonMembershipProof shows a nullifier commitment is absent from an
+// election's used set. Lower and Upper are the adjacent leaves around it;
+// one is nil at either end of the set and both are nil for an empty set.
+type NonMembershipProof struct {
+	ElectionID          string            `json:"electionId"`
+	NullifierCommitment string            `json:"nullifierCommitment"`
+	Root                string            `json:"root"`
+	Scheme              string            `json:"scheme"`
+	Lower               *NullifierSetLeaf `json:"lower,omitempty"`
+	Upper               *NullifierSetLeaf `json:"upper,omitempty"`
+}
+
+// nullifierSetScheme is the tree hashing of an election's nullifier set
+func nullifierSetScheme(election *Election) string {
+	return merkleSchemeWithHash(MerkleSchemeV2, election.HashAlgorithm)
+}
+
+// proveNonMembership builds the proof that commitment is not among the
+// sorted commitments, or fails when it is
+func proveNonMembership(scheme string, commitments []string, commitment string) (*NonMembershipProof, error) {
+	position := sort.SearchStrings(commitments, commitment)
+	if position < len(commitments) && commitments[position] == commitment {
+		return nil, fmt.Errorf("nullifier commitment %s is in the used set", commitment)
+	}
+
+	proof := &NonMembershipProof{NullifierCommitment: commitment, Scheme: scheme}
+	if len(commitments) == 0 {
+		_, algorithm := splitMerkleScheme(scheme)
+		proof.Root = hashStringWith(algorithm, "")
+		return proof, nil
+	}
+
+	leaves := make([]string, len(commitments))
+	for i, c := range commitments {
+		leaves[i] = hashMerkleLeafData(scheme, c)
+	}
+	levels := buildMerkleLevels(scheme, leaves)
+	proof.Root = levels[len(levels)-1][0]
+	leaf := func(index int) *NullifierSetLeaf {
+		return &NullifierSetLeaf{
+			Commitment: commitments[index],
+			Index:      index,
+			Path:       merklePath(scheme, levels, index),
+		}
+	}
+	if position > 0 {
+		proof.Lower = leaf(position - 1)
+	}
+	if position < len(commitments) {
+		proof.Upper = leaf(position)
+	}
+	return proof, nil
+}
+
+// Verify checks the neighbours are adjacent leaves under Root that bracket
+// the commitment
+func (p *NonMembershipProof) Verify() bool {
+	if p.Lower == nil && p.Upper == nil {
+		_, algorithm := splitMerkleScheme(p.Scheme)
+		return p.Root == hashStringWith(algorithm, "")
+	}
+	if p.Lower != nil && (!p.verifyLeaf(p.Lower) || p.Lower.Commitment >= p.NullifierCommitment) {
+		return false
+	}
+	if p.Upper != nil && (!p.verifyLeaf(p.Upper) || p.Upper.Commitment <= p.NullifierCommitment) {
+		return false
+	}
+
+	switch {
+	case p.Lower == nil:
+		return p.Upper.Index == 0
+	case p.Upper == nil:
+		return p.isLastLeaf(p.Lower)
+	default:
+		return p.Upper.Index == p.Lower.Index+1
+	}
+}
+
+// verifyLeaf recomputes the root from a leaf and checks its path leads
+// from the claimed index
+func (p *NonMembershipProof) verifyLeaf(leaf *NullifierSetLeaf) bool {
+	node := hashMerkleLeafData(p.Scheme, leaf.Commitment)
+	index := 0
+	for level, step := range leaf.Path {
+		var err error
+		if step.Position == "left" {
+			index |= 1 << level
+			node, err = hashMerkleNode(p.Scheme, step.Hash, node)
+		} else {
+			node, err = hashMerkleNode(p.Scheme, node, step.Hash)
+		}
+		if err != nil {
+			return false
+		}
+	}
+	return node == p.Root && index == leaf.Index
+}
+
+// isLastLeaf reports whether a leaf has no right neighbour on any level,
+// which in a v2 tree means each left-child step pairs it with itself
+func (p *NonMembershipProof) isLastLeaf(leaf *NullifierSetLeaf) bool {
+	node := hashMerkleLeafData(p.Scheme, leaf.Commitment)
+	for _, step := range leaf.Path {
+		if step.Position == "left" {
+			node, _ = hashMerkleNode(p.Scheme, step.Hash, node)
+			continue
+		}
+		if step.Hash != node {
+			return false
+		}
+		node, _ = hashMerkleNode(p.Scheme, node, node)
+	}
+	return true
+}
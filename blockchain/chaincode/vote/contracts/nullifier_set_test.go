@@ -0,0 +1,198 @@
+/*
+ * Nullifier Set Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNullifierSetExact(t *testing.T) {
+	contract, ctx, stub := setupDelegationElection()
+
+	for _, nullifier := range []string{"alice", "bob"} {
+		_, err := contract.CastVote(ctx, "election-001", testVote(10), nullifier,
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, contract.DelegateVote(ctx, "election-001", "carol", nullifierCommitment("election-001", "alice"), "proof"))
+	// An invalidated vote still used its nullifier
+	assert.NoError(t, contract.InvalidateVote(ctx, "election-001", nullifierCommitment("election-001", "bob"), "fraud"))
+
+	set, err := contract.GetNullifierSet(ctx, "election-001")
+	assert.NoError(t, err)
+
+	want := []string{
+		nullifierCommitment("election-001", "alice"),
+		nullifierCommitment("election-001", "bob"),
+		nullifierCommitment("election-001", "carol"),
+	}
+	sort.Strings(want)
+	assert.Equal(t, "election-001", set.ElectionID)
+	assert.Equal(t, 3, set.Count)
+	assert.Equal(t, want, set.Commitments)
+	assert.Nil(t, set.Bloom)
+
+	// Other elections are not included
+	other := createMockElection()
+	other.ID = "election-002"
+	otherJSON, _ := json.Marshal(other)
+	stub.State["election:election-002"] = otherJSON
+	set, err = contract.GetNullifierSet(ctx, "election-002")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, set.Count)
+	assert.Empty(t, set.Commitments)
+
+	_, err = contract.GetNullifierSet(ctx, "election-404")
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+}
+
+func TestGetNullifierSetBloom(t *testing.T) {
+	contract, ctx, stub := setupDelegationElection()
+
+	n := NullifierSetExactLimit + 1
+	commitments := make([]string, n)
+	for i := range commitments {
+		commitments[i] = nullifierCommitment("election-001", fmt.Sprintf("voter%d", i))
+		stub.State[compositeKey("vote", "election-001", commitments[i])] = []byte("{}")
+	}
+
+	set, err := contract.GetNullifierSet(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, n, set.Count)
+	assert.Nil(t, set.Commitments)
+	if !assert.NotNil(t, set.Bloom) {
+		return
+	}
+
+	// Sized for the false positive rate, far smaller than the exact set
+	bloom := set.Bloom
+	assert.Equal(t, 10, bloom.K)
+	assert.Equal(t, int(bloom.M/8), len(bloom.Bits))
+	assert.Less(t, len(bloom.Bits)*4, n*64)
+
+	// The digest survives the JSON round trip a coordinator performs
+	setJSON, _ := json.Marshal(set)
+	var decoded NullifierSet
+	assert.NoError(t, json.Unmarshal(setJSON, &decoded))
+	assert.Equal(t, *bloom, *decoded.Bloom)
+
+	for _, commitment := range commitments {
+		assert.True(t, decoded.Bloom.mayContain(commitment))
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if decoded.Bloom.mayContain(nullifierCommitment("election-001", fmt.Sprintf("other%d", i))) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, falsePositives, 50)
+}
+
+func TestBloomFilterProbes(t *testing.T) {
+	// Bit positions follow the documented double hashing, so verifiers in
+	// other languages can reproduce them
+	bloom := &BloomFilter{M: 64, K: 3, Bits: make([]byte, 8)}
+	bloom.add("abc")
+
+	// SHA-256("abc") = ba7816bf8f01cfea 414140de5dae2223 ...
+	h1, h2 := uint64(0xba7816bf8f01cfea), uint64(0x414140de5dae2223)
+	want := make([]byte, 8)
+	for i := uint64(0); i < 3; i++ {
+		bit := (h1 + i*h2) % 64
+		want[bit/8] |= 1 << (bit % 8)
+	}
+	assert.Equal(t, want, bloom.Bits)
+	assert.True(t, bloom.mayContain("abc"))
+}
+
+func TestProveNonMembership(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// Nobody has voted yet
+	proof, err := contract.ProveNonMembership(ctx, "election-001", "nullifier0")
+	assert.NoError(t, err)
+	assert.Nil(t, proof.Lower)
+	assert.Nil(t, proof.Upper)
+	assert.True(t, proof.Verify())
+
+	for i := 0; i < 5; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)), fmt.Sprintf("nullifier%d", i),
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	// A used nullifier has no proof
+	_, err = contract.ProveNonMembership(ctx, "election-001", "nullifier3")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "in the used set")
+
+	// Unused nullifiers fall between neighbours or beyond either end; the
+	// commitments are hashes, so a few dozen cover every position
+	ends := map[string]bool{}
+	for i := 5; i < 60; i++ {
+		proof, err := contract.ProveNonMembership(ctx, "election-001", fmt.Sprintf("nullifier%d", i))
+		assert.NoError(t, err)
+		assert.True(t, proof.Verify(), "nullifier%d", i)
+		ends[fmt.Sprintf("lower=%t upper=%t", proof.Lower != nil, proof.Upper != nil)] = true
+	}
+	assert.Len(t, ends, 3)
+}
+
+func TestNonMembershipProofRejectsForgery(t *testing.T) {
+	scheme := MerkleSchemeV2
+	var commitments []string
+	for i := 0; i < 5; i++ {
+		commitments = append(commitments, nullifierCommitment("election-001", fmt.Sprintf("nullifier%d", i)))
+	}
+	sort.Strings(commitments)
+
+	_, err := proveNonMembership(scheme, commitments, commitments[2])
+	assert.Error(t, err)
+
+	// Leaving out a used commitment changes the root
+	withoutUsed, err := proveNonMembership(scheme, append(append([]string(nil), commitments[:2]...), commitments[3:]...), commitments[2])
+	assert.NoError(t, err)
+	assert.True(t, withoutUsed.Verify())
+
+	// Bracketing a used commitment with leaves that are not adjacent fails
+	honest, _ := proveNonMembership(scheme, commitments, commitments[1]+"0")
+	beyond, _ := proveNonMembership(scheme, commitments, commitments[3]+"0")
+	forged := *honest
+	forged.NullifierCommitment = commitments[2]
+	forged.Upper = beyond.Lower
+	assert.False(t, forged.Verify())
+
+	// Leaves are tied to their positions and to the root
+	shifted := *honest
+	lower := *honest.Lower
+	lower.Index++
+	shifted.Lower = &lower
+	assert.False(t, shifted.Verify())
+	rerooted := *honest
+	rerooted.Root = withoutUsed.Root
+	assert.False(t, rerooted.Verify())
+
+	// Only the last leaf may stand alone as the lower neighbour
+	afterSecond := *honest
+	afterSecond.NullifierCommitment = commitments[1] + "0"
+	afterSecond.Upper = nil
+	assert.False(t, afterSecond.Verify())
+	last, _ := proveNonMembership(scheme, commitments, commitments[4]+"0")
+	assert.Nil(t, last.Upper)
+	assert.True(t, last.Verify())
+}
@@ -0,0 +1,246 @@
+/*
+ * Proof Verifier - On-chain zero-knowledge proof verification
+ *
+ * Proof systems are pluggable: each election names the proof system its
+ * circuits were compiled for, and the matching ProofVerifier is looked up
+ * from the registry at CastVote time. Groth16 over BN254 (ZoKrates "g16" on
+ * "bn128") is registered by default.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// ProofSystemGroth16 is the identifier of the default Groth16 verifier
+const ProofSystemGroth16 = "groth16"
+
+// ProofVerifier verifies a serialized proof against a serialized verifying key
+type ProofVerifier interface {
+	// Verify returns nil only if the proof is valid for the verifying key
+	Verify(verifyingKey string, proof string) error
+}
+
+// PublicInputReader is implemented by verifiers whose proofs carry their
+// public inputs, so the contract can read values a proof commits to
+type PublicInputReader interface {
+	PublicInputs(proof string) ([]*big.Int, error)
+}
+
+var proofVerifiers = map[string]ProofVerifier{
+	ProofSystemGroth16: Groth16Verifier{},
+}
+
+// RegisterProofVerifier makes a proof system available to elections
+func RegisterProofVerifier(proofSystem string, verifier ProofVerifier) {
+	proofVerifiers[proofSystem] = verifier
+}
+
+func getProofVerifier(proofSystem string) (ProofVerifier, error) {
+	if proofSystem == "" {
+		proofSystem = ProofSystemGroth16
+	}
+	verifier, ok := proofVerifiers[proofSystem]
+	if !ok {
+		return nil, fmt.Errorf("unsupported proof system: %s", proofSystem)
+	}
+	return verifier, nil
+}
+
+// Groth16Verifier verifies ZoKrates-format Groth16 proofs on BN254
+type Groth16Verifier struct{}
+
+// Groth16VerifyingKey is the ZoKrates verification.key JSON layout.
+// G2 coordinates are encoded as [c0, c1] pairs.
+type Groth16VerifyingKey struct {
+	Alpha    []string   `json:"alpha"`
+	Beta     [][]string `json:"beta"`
+	Gamma    [][]string `json:"gamma"`
+	Delta    [][]string `json:"delta"`
+	GammaABC [][]string `json:"gamma_abc"`
+}
+
+// Groth16ProofPoints are the A, B, C points of a Groth16 proof
+type Groth16ProofPoints struct {
+	A []string   `json:"a"`
+	B [][]string `json:"b"`
+	C []string   `json:"c"`
+}
+
+// Groth16Proof is the ZoKrates proof.json layout
+type Groth16Proof struct {
+	Proof  Groth16ProofPoints `json:"proof"`
+	Inputs []string           `json:"inputs"`
+}
+
+// Verify checks e(A, B) = e(alpha, beta) * e(vk_x, gamma) * e(C, delta)
+func (Groth16Verifier) Verify(verifyingKey string, proof string) error {
+	var vk Groth16VerifyingKey
+	if err := json.Unmarshal([]byte(verifyingKey), &vk); err != nil {
+		return fmt.Errorf("invalid verifying key: %v", err)
+	}
+
+	var p Groth16Proof
+	if err := json.Unmarshal([]byte(proof), &p); err != nil {
+		return fmt.Errorf("invalid proof: %v", err)
+	}
+
+	alpha, err := parseG1(vk.Alpha)
+	if err != nil {
+		return fmt.Errorf("invalid verifying key alpha: %v", err)
+	}
+	beta, err := parseG2(vk.Beta)
+	if err != nil {
+		return fmt.Errorf("invalid verifying key beta: %v", err)
+	}
+	gamma, err := parseG2(vk.Gamma)
+	if err != nil {
+		return fmt.Errorf("invalid verifying key gamma: %v", err)
+	}
+	delta, err := parseG2(vk.Delta)
+	if err != nil {
+		return fmt.Errorf("invalid verifying key delta: %v", err)
+	}
+
+	if len(vk.GammaABC) != len(p.Inputs)+1 {
+		return fmt.Errorf("expected %d public inputs, got %d", len(vk.GammaABC)-1, len(p.Inputs))
+	}
+
+	a, err := parseG1(p.Proof.A)
+	if err != nil {
+		return fmt.Errorf("invalid proof point a: %v", err)
+	}
+	b, err := parseG2(p.Proof.B)
+	if err != nil {
+		return fmt.Errorf("invalid proof point b: %v", err)
+	}
+	c, err := parseG1(p.Proof.C)
+	if err != nil {
+		return fmt.Errorf("invalid proof point c: %v", err)
+	}
+
+	// vk_x = gamma_abc[0] + sum(inputs[i] * gamma_abc[i+1])
+	vkX, err := parseG1(vk.GammaABC[0])
+	if err != nil {
+		return fmt.Errorf("invalid verifying key gamma_abc: %v", err)
+	}
+	for i, input := range p.Inputs {
+		scalar, err := parseScalar(input)
+		if err != nil {
+			return fmt.Errorf("invalid public input %d: %v", i, err)
+		}
+		point, err := parseG1(vk.GammaABC[i+1])
+		if err != nil {
+			return fmt.Errorf("invalid verifying key gamma_abc: %v", err)
+		}
+		var term bn254.G1Affine
+		term.ScalarMultiplication(&point, scalar)
+		vkX.Add(&vkX, &term)
+	}
+
+	var negA bn254.G1Affine
+	negA.Neg(&a)
+
+	ok, err := bn254.PairingCheck(
+		[]bn254.G1Affine{negA, alpha, vkX, c},
+		[]bn254.G2Affine{b, beta, gamma, delta},
+	)
+	if err != nil {
+		return fmt.Errorf("pairing check failed: %v", err)
+	}
+	if !ok {
+		return fmt.Errorf("proof verification failed")
+	}
+	return nil
+}
+
+// PublicInputs returns the public inputs listed in a ZoKrates proof
+func (Groth16Verifier) PublicInputs(proof string) ([]*big.Int, error) {
+	var p Groth16Proof
+	if err := json.Unmarshal([]byte(proof), &p); err != nil {
+		return nil, fmt.Errorf("invalid proof: %v", err)
+	}
+	inputs := make([]*big.Int, len(p.Inputs))
+	for i, input := range p.Inputs {
+		scalar, err := parseScalar(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public input %d: %v", i, err)
+		}
+		inputs[i] = scalar
+	}
+	return inputs, nil
+}
+
+func parseFieldElement(s string) (fp.Element, error) {
+	var e fp.Element
+	n, ok := new(big.Int).SetString(strings.TrimSpace(s), 0)
+	if !ok {
+		return e, fmt.Errorf("invalid field element %q", s)
+	}
+	if n.Sign() < 0 || n.Cmp(fp.Modulus()) >= 0 {
+		return e, fmt.Errorf("field element out of range")
+	}
+	e.SetBigInt(n)
+	return e, nil
+}
+
+func parseScalar(s string) (*big.Int, error) {
+	n, ok := new(big.Int).SetString(strings.TrimSpace(s), 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid scalar %q", s)
+	}
+	if n.Sign() < 0 || n.Cmp(fr.Modulus()) >= 0 {
+		return nil, fmt.Errorf("scalar out of range")
+	}
+	return n, nil
+}
+
+func parseG1(coords []string) (bn254.G1Affine, error) {
+	var p bn254.G1Affine
+	if len(coords) != 2 {
+		return p, fmt.Errorf("G1 point needs 2 coordinates")
+	}
+	var err error
+	if p.X, err = parseFieldElement(coords[0]); err != nil {
+		return p, err
+	}
+	if p.Y, err = parseFieldElement(coords[1]); err != nil {
+		return p, err
+	}
+	if !p.IsOnCurve() || !p.IsInSubGroup() {
+		return p, fmt.Errorf("G1 point is not on the curve")
+	}
+	return p, nil
+}
+
+func parseG2(coords [][]string) (bn254.G2Affine, error) {
+	var p bn254.G2Affine
+	if len(coords) != 2 || len(coords[0]) != 2 || len(coords[1]) != 2 {
+		return p, fmt.Errorf("G2 point needs 2x2 coordinates")
+	}
+	var err error
+	if p.X.A0, err = parseFieldElement(coords[0][0]); err != nil {
+		return p, err
+	}
+	if p.X.A1, err = parseFieldElement(coords[0][1]); err != nil {
+		return p, err
+	}
+	if p.Y.A0, err = parseFieldElement(coords[1][0]); err != nil {
+		return p, err
+	}
+	if p.Y.A1, err = parseFieldElement(coords[1][1]); err != nil {
+		return p, err
+	}
+	if !p.IsOnCurve() || !p.IsInSubGroup() {
+		return p, fmt.Errorf("G2 point is not on the curve")
+	}
+	return p, nil
+}
@@ -0,0 +1,463 @@
+/*
+ * Proof Verifier Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fp"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+)
+
+func fpHex(e fp.Element) string {
+	var n big.Int
+	e.BigInt(&n)
+	return fmt.Sprintf("0x%064x", &n)
+}
+
+func g1JSON(p bn254.G1Affine) []string {
+	return []string{fpHex(p.X), fpHex(p.Y)}
+}
+
+func g2JSON(p bn254.G2Affine) [][]string {
+	return [][]string{{fpHex(p.X.A0), fpHex(p.X.A1)}, {fpHex(p.Y.A0), fpHex(p.Y.A1)}}
+}
+
+// newGroth16Fixture builds a verifying key and a proof that satisfies the
+// Groth16 verification equation for the given public inputs. The proof is
+// simulated from known trapdoor scalars, which is enough to exercise the verifier.
+func newGroth16Fixture(inputs []int64) (string, string) {
+	scalars := make([]*big.Int, len(inputs))
+	for i, in := range inputs {
+		scalars[i] = big.NewInt(in)
+	}
+	return newGroth16FixtureFor(scalars...)
+}
+
+// newGroth16FixtureFor is newGroth16Fixture for field element inputs. The
+// verifying key only depends on the number of inputs.
+func newGroth16FixtureFor(inputs ...*big.Int) (string, string) {
+	_, _, g1, g2 := bn254.Generators()
+	r := fr.Modulus()
+
+	alpha, beta, gamma, delta := big.NewInt(11), big.NewInt(13), big.NewInt(17), big.NewInt(19)
+	abc := make([]*big.Int, len(inputs)+1)
+	for i := range abc {
+		abc[i] = big.NewInt(int64(23 + i))
+	}
+
+	// vk_x scalar = abc[0] + sum(inputs[i] * abc[i+1])
+	vkX := new(big.Int).Set(abc[0])
+	for i, in := range inputs {
+		vkX.Add(vkX, new(big.Int).Mul(in, abc[i+1]))
+	}
+
+	// a*b = alpha*beta + vkX*gamma + c*delta
+	a, b := big.NewInt(29), big.NewInt(31)
+	c := new(big.Int).Mul(a, b)
+	c.Sub(c, new(big.Int).Mul(alpha, beta))
+	c.Sub(c, new(big.Int).Mul(vkX, gamma))
+	c.Mul(c, new(big.Int).ModInverse(delta, r))
+	c.Mod(c, r)
+
+	mulG1 := func(s *big.Int) bn254.G1Affine {
+		var p bn254.G1Affine
+		p.ScalarMultiplication(&g1, s)
+		return p
+	}
+	mulG2 := func(s *big.Int) bn254.G2Affine {
+		var p bn254.G2Affine
+		p.ScalarMultiplication(&g2, s)
+		return p
+	}
+
+	vk := Groth16VerifyingKey{
+		Alpha: g1JSON(mulG1(alpha)),
+		Beta:  g2JSON(mulG2(beta)),
+		Gamma: g2JSON(mulG2(gamma)),
+		Delta: g2JSON(mulG2(delta)),
+	}
+	for _, s := range abc {
+		vk.GammaABC = append(vk.GammaABC, g1JSON(mulG1(s)))
+	}
+
+	proof := Groth16Proof{
+		Proof: Groth16ProofPoints{
+			A: g1JSON(mulG1(a)),
+			B: g2JSON(mulG2(b)),
+			C: g1JSON(mulG1(c)),
+		},
+	}
+	for _, in := range inputs {
+		proof.Inputs = append(proof.Inputs, fmt.Sprintf("0x%064x", in))
+	}
+
+	vkJSON, _ := json.Marshal(vk)
+	proofJSON, _ := json.Marshal(proof)
+	return string(vkJSON), string(proofJSON)
+}
+
+func tamperProofInput(proofJSON string) string {
+	var proof Groth16Proof
+	_ = json.Unmarshal([]byte(proofJSON), &proof)
+	proof.Inputs[0] = fmt.Sprintf("0x%064x", 999)
+	tampered, _ := json.Marshal(proof)
+	return string(tampered)
+}
+
+func TestGroth16VerifyValidProof(t *testing.T) {
+	vk, proof := newGroth16Fixture([]int64{5, 7})
+
+	err := Groth16Verifier{}.Verify(vk, proof)
+	assert.NoError(t, err)
+}
+
+func TestGroth16VerifyTamperedProof(t *testing.T) {
+	vk, proof := newGroth16Fixture([]int64{5, 7})
+
+	// Tampered public input
+	err := Groth16Verifier{}.Verify(vk, tamperProofInput(proof))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "verification failed")
+
+	// Tampered proof point: swap A and C
+	var p Groth16Proof
+	_ = json.Unmarshal([]byte(proof), &p)
+	p.Proof.A, p.Proof.C = p.Proof.C, p.Proof.A
+	swapped, _ := json.Marshal(p)
+	err = Groth16Verifier{}.Verify(vk, string(swapped))
+	assert.Error(t, err)
+
+	// Point not on the curve
+	p.Proof.A = []string{"0x1", "0x1"}
+	offCurve, _ := json.Marshal(p)
+	err = Groth16Verifier{}.Verify(vk, string(offCurve))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not on the curve")
+}
+
+func TestGroth16VerifyInputCountMismatch(t *testing.T) {
+	vk, _ := newGroth16Fixture([]int64{5, 7})
+	_, proof := newGroth16Fixture([]int64{5})
+
+	err := Groth16Verifier{}.Verify(vk, proof)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "public inputs")
+}
+
+type rejectingVerifier struct{}
+
+func (rejectingVerifier) Verify(verifyingKey string, proof string) error {
+	return fmt.Errorf("rejected")
+}
+
+func TestRegisterProofVerifier(t *testing.T) {
+	RegisterProofVerifier("reject-all", rejectingVerifier{})
+	defer delete(proofVerifiers, "reject-all")
+
+	verifier, err := getProofVerifier("reject-all")
+	assert.NoError(t, err)
+	assert.Error(t, verifier.Verify("", ""))
+
+	_, err = getProofVerifier("plonk")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported proof system")
+}
+
+// setupProofElection creates an election whose eligibility proofs commit to
+// the nullifier and voter root and whose validity proofs commit to the
+// ballot hash
+func setupProofElection(t *testing.T, ctx *MockTransactionContext, eligibilityVK, validityVK string) {
+	config := ElectionConfig{
+		Options:                 []string{"yes"},
+		ProofSystem:             ProofSystemGroth16,
+		EligibilityVerifyingKey: eligibilityVK,
+		ValidityVerifyingKey:    validityVK,
+	}
+	if eligibilityVK != "" {
+		config.EligibilityPublicInputs = []string{PublicInputNullifier, PublicInputVoterRoot}
+	}
+	if validityVK != "" {
+		config.ValidityPublicInputs = []string{PublicInputBallotHash}
+	}
+	configJSON, _ := json.Marshal(config)
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(configJSON))
+	assert.NoError(t, err)
+	_, err = new(VoteContract).ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+}
+
+// testBallotProofs returns proofs for a ballot in a setupProofElection
+// election, with the verifying keys they check against
+func testBallotProofs(nullifier, encryptedVote string) (string, string, string, string) {
+	eligibilityVK, eligibilityProof := newGroth16FixtureFor(publicInputValue(nullifier), publicInputValue("root"))
+	validityVK, validityProof := newGroth16FixtureFor(ballotHashInput(&Election{}, encryptedVote))
+	return eligibilityVK, eligibilityProof, validityVK, validityProof
+}
+
+func TestCastVoteWithValidProof(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	eligibilityVK, eligibilityProof, validityVK, validityProof := testBallotProofs("nullifier123", testVote(4))
+	setupProofElection(t, ctx, eligibilityVK, validityVK)
+
+	receipt, err := contract.CastVoteWithProof(ctx, "election-001", testVote(4),
+		"nullifier123", eligibilityProof, validityProof, testVoterRoot)
+	assert.NoError(t, err)
+	assert.True(t, receipt.Success)
+
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+	assert.Equal(t, hashString(eligibilityProof), vote.EligibilityProofHash)
+	assert.Equal(t, hashString(validityProof), vote.ValidityProofHash)
+}
+
+func TestCastVoteWithTamperedProof(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	eligibilityVK, eligibilityProof, validityVK, validityProof := testBallotProofs("nullifier123", testVote(4))
+	setupProofElection(t, ctx, eligibilityVK, validityVK)
+
+	_, err := contract.CastVoteWithProof(ctx, "election-001", testVote(4),
+		"nullifier123", tamperProofInput(eligibilityProof), validityProof, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid eligibility proof")
+
+	_, err = contract.CastVoteWithProof(ctx, "election-001", testVote(4),
+		"nullifier123", eligibilityProof, tamperProofInput(validityProof), testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid validity proof")
+
+	_, err = contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.Error(t, err)
+}
+
+func TestCastVoteWithProofBoundToBallot(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	eligibilityVK, eligibilityProof, validityVK, validityProof := testBallotProofs("nullifier123", testVote(4))
+	setupProofElection(t, ctx, eligibilityVK, validityVK)
+
+	// A valid proof replayed under a fresh nullifier
+	_, err := contract.CastVoteWithProof(ctx, "election-001", testVote(4),
+		"nullifier456", eligibilityProof, validityProof, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "nullifier does not match the eligibility proof")
+
+	// A valid validity proof for another ballot
+	_, err = contract.CastVoteWithProof(ctx, "election-001", testVote(5),
+		"nullifier123", eligibilityProof, validityProof, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ballotHash does not match the validity proof")
+
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 0, count)
+
+	_, err = contract.CastVoteWithProof(ctx, "election-001", testVote(4),
+		"nullifier123", eligibilityProof, validityProof, testVoterRoot)
+	assert.NoError(t, err)
+}
+
+func TestCastVoteWithProofReadsVoterRootFromProof(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// The roll is replaced before voting starts
+	eligibilityVK, _, validityVK, validityProof := testBallotProofs("nullifier123", testVote(4))
+	config, _ := json.Marshal(ElectionConfig{
+		Options:                 []string{"yes"},
+		ProofSystem:             ProofSystemGroth16,
+		EligibilityVerifyingKey: eligibilityVK,
+		ValidityVerifyingKey:    validityVK,
+		EligibilityPublicInputs: []string{PublicInputNullifier, PublicInputVoterRoot},
+		ValidityPublicInputs:    []string{PublicInputBallotHash},
+	})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "oldroot", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	assert.NoError(t, contract.UpdateVoterMerkleRoot(ctx, "election-001", "newroot"))
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// A proof against the superseded roll fails whatever root the caller claims
+	_, staleProof := newGroth16FixtureFor(publicInputValue("nullifier123"), publicInputValue("oldroot"))
+	_, err = contract.CastVoteWithProof(ctx, "election-001", testVote(4),
+		"nullifier123", staleProof, validityProof, "newroot")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "voterRoot does not match the eligibility proof")
+
+	// The caller's root is advisory once the proof carries one
+	_, currentProof := newGroth16FixtureFor(publicInputValue("nullifier123"), publicInputValue("newroot"))
+	_, err = contract.CastVoteWithProof(ctx, "election-001", testVote(4),
+		"nullifier123", currentProof, validityProof, "oldroot")
+	assert.NoError(t, err)
+}
+
+func TestProofElectionsDeclarePublicInputs(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	vk, _ := newGroth16Fixture([]int64{1})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	for _, config := range []ElectionConfig{
+		{EligibilityVerifyingKey: vk},
+		{EligibilityVerifyingKey: vk, EligibilityPublicInputs: []string{PublicInputBallotHash}},
+		{EligibilityVerifyingKey: vk, EligibilityPublicInputs: []string{PublicInputNullifier}},
+		{EligibilityVerifyingKey: vk, EligibilityPublicInputs: []string{PublicInputNullifier, PublicInputVoterRoot, PublicInputVoterRoot}},
+		{EligibilityVerifyingKey: vk, EligibilityPublicInputs: []string{PublicInputNullifier, PublicInputVoterRoot, "age"}},
+		{EligibilityVerifyingKey: vk, EligibilityPublicInputs: []string{PublicInputNullifier, PublicInputVoterRoot, PublicInputWeight}},
+		{ValidityVerifyingKey: vk},
+		{EligibilityPublicInputs: []string{PublicInputNullifier}},
+	} {
+		configJSON, _ := json.Marshal(config)
+		_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, string(configJSON))
+		assert.Error(t, err, string(configJSON))
+	}
+}
+
+func TestCastVoteRequiresOnChainProof(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	eligibilityVK, _ := newGroth16Fixture([]int64{1, 2})
+	setupProofElection(t, ctx, eligibilityVK, "")
+
+	// Hash-only submission is refused once a verifying key is registered
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requires on-chain proof verification")
+}
+
+func setupWeightedElection(t *testing.T, ctx *MockTransactionContext, eligibilityVK string) {
+	config, _ := json.Marshal(ElectionConfig{
+		Options:                 []string{"0", "1", "2"},
+		ProofSystem:             ProofSystemGroth16,
+		EligibilityVerifyingKey: eligibilityVK,
+		EligibilityPublicInputs: []string{PublicInputNullifier, PublicInputVoterRoot, PublicInputWeight},
+		Weighted:                true,
+	})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	_, err = new(VoteContract).ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+}
+
+func TestCastVoteWeighted(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// The verifying key only depends on the number of inputs, so proofs for
+	// different weights verify against the same key. The inputs are the
+	// nullifier, the voter root and the weight.
+	eligibilityVK, _ := newGroth16Fixture([]int64{1, 1, 1})
+	weightProof := func(nullifier string, weight int64) string {
+		_, proof := newGroth16FixtureFor(publicInputValue(nullifier), publicInputValue("root"), big.NewInt(weight))
+		return proof
+	}
+	setupWeightedElection(t, ctx, eligibilityVK)
+
+	_, err := contract.CastVote(ctx, "election-001", testBallot(0, 3, 10), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+
+	_, err = contract.CastVoteWeighted(ctx, "election-001", testBallot(0, 3, 10), "nullifier0",
+		weightProof("nullifier0", 3), "", testVoterRoot, 4)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "weight does not match the eligibility proof")
+	_, err = contract.CastVoteWeighted(ctx, "election-001", testBallot(0, 3, 10), "nullifier9",
+		weightProof("nullifier0", 3), "", testVoterRoot, 3)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "nullifier does not match the eligibility proof")
+
+	// Candidate 0 gets weights 3 and 1, candidate 1 gets weight 5
+	for i, ballot := range []struct {
+		choice int
+		weight int64
+	}{{0, 3}, {1, 5}, {0, 1}} {
+		nullifier := fmt.Sprintf("nullifier%d", i+1)
+		_, err := contract.CastVoteWeighted(ctx, "election-001", testBallot(ballot.choice, 3, int64(10*i+3)),
+			nullifier, weightProof(nullifier, ballot.weight), "", testVoterRoot, int(ballot.weight))
+		assert.NoError(t, err)
+	}
+
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier2")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, vote.Weight)
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, aggregate.VoteCount)
+	assert.Equal(t, 9, aggregate.TotalWeight)
+	assert.Equal(t, []int{4, 5, 0}, []int{
+		testDecrypt(aggregate.Ciphertexts[0]),
+		testDecrypt(aggregate.Ciphertexts[1]),
+		testDecrypt(aggregate.Ciphertexts[2]),
+	})
+
+	err = contract.StoreTallyResult(ctx, "election-001", `{"0": 4, "1": 5, "2": 0}`, aggregate.AggregateHash, "proof")
+	assert.NoError(t, err)
+	result, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.True(t, result.Weighted)
+	assert.Equal(t, 9, result.TotalVotes)
+	assert.Equal(t, 3, result.BallotCount)
+}
+
+func TestWeightedElectionRequiresEligibilityKey(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	ctx.On("GetStub").Return(NewMockStub())
+
+	config, _ := json.Marshal(ElectionConfig{Weighted: true})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "eligibility verifying key")
+}
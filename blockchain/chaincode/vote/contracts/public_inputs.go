@@ -0,0 +1,131 @@
+/*
+ * Public Inputs - Binding on-chain verified proofs to the ballot they carry
+ *
+ * A proof only attests to the public inputs listed in it, so a valid proof
+ * says nothing about the nullifier or ballot it is submitted with unless
+ * those are among its inputs. Each election declares the layout of its
+ * eligibility and validity proof inputs, one name per input, and the
+ * contract rebuilds the named inputs from the call arguments and rejects
+ * any mismatch. Inputs named "" are not checked.
+ *
+ *   nullifier   the nullifier the ballot is cast under
+ *   voterRoot   the election's current voter Merkle root
+ *   ballotHash  the encrypted vote hash on the receipt (encryptedVoteHash)
+ *   weight      the weight of the ballot in weighted elections
+ *
+ * A value that is an integer (decimal or 0x hex) below the BN254 scalar
+ * field modulus is its own input; any other string, and every ballot hash,
+ * enters as its digest reduced modulo the field.
+ */
+
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// Public input names of a proof layout
+const (
+	PublicInputNullifier  = "nullifier"
+	PublicInputVoterRoot  = "voterRoot"
+	PublicInputBallotHash = "ballotHash"
+	PublicInputWeight     = "weight"
+)
+
+var publicInputNames = map[string]bool{
+	PublicInputNullifier:  true,
+	PublicInputVoterRoot:  true,
+	PublicInputBallotHash: true,
+	PublicInputWeight:     true,
+}
+
+// validatePublicInputLayout checks a declared layout and that it names
+// every input in required
+func validatePublicInputLayout(proofType string, layout []string, required ...string) error {
+	seen := map[string]bool{}
+	for i, name := range layout {
+		if name == "" {
+			continue
+		}
+		if !publicInputNames[name] {
+			return fmt.Errorf("%s proof input %d: unknown public input %q", proofType, i, name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%s proof input %d: %s is declared twice", proofType, i, name)
+		}
+		seen[name] = true
+	}
+	for _, name := range required {
+		if !seen[name] {
+			return fmt.Errorf("%s proof public inputs must include %s", proofType, name)
+		}
+	}
+	return nil
+}
+
+// publicInputValue maps a call argument onto the scalar field. Integers
+// are decimal, leading zeros included, unless prefixed with 0x.
+func publicInputValue(value string) *big.Int {
+	digits, base := strings.TrimSpace(value), 10
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits, base = digits[2:], 16
+	}
+	if n, ok := new(big.Int).SetString(digits, base); ok && n.Sign() >= 0 && n.Cmp(fr.Modulus()) < 0 {
+		return n
+	}
+	digest := sha256.Sum256([]byte(value))
+	return digestInput(digest[:])
+}
+
+// ballotHashInput is the encrypted vote hash of a ballot as a public input
+func ballotHashInput(election *Election, encryptedVote string) *big.Int {
+	digest, _ := hex.DecodeString(hashForElection(election, encryptedVote))
+	return digestInput(digest)
+}
+
+func digestInput(digest []byte) *big.Int {
+	n := new(big.Int).SetBytes(digest)
+	return n.Mod(n, fr.Modulus())
+}
+
+// bindPublicInputs checks that the inputs of a proof named in layout equal
+// values. Every named input must have a value.
+func bindPublicInputs(election *Election, proofType string, proof string, layout []string, values map[string]*big.Int) error {
+	if len(layout) == 0 {
+		return nil
+	}
+	verifier, err := getProofVerifier(election.ProofSystem)
+	if err != nil {
+		return err
+	}
+	reader, ok := verifier.(PublicInputReader)
+	if !ok {
+		return fmt.Errorf("proof system %s does not expose public inputs", election.ProofSystem)
+	}
+	inputs, err := reader.PublicInputs(proof)
+	if err != nil {
+		return fmt.Errorf("invalid %s proof: %v", proofType, err)
+	}
+	if len(inputs) != len(layout) {
+		return fmt.Errorf("%s proof has %d public inputs, expected %d", proofType, len(inputs), len(layout))
+	}
+	for i, name := range layout {
+		if name == "" {
+			continue
+		}
+		value, ok := values[name]
+		if !ok {
+			return fmt.Errorf("%s proof input %s cannot be checked here", proofType, name)
+		}
+		if inputs[i].Cmp(value) != 0 {
+			return fmt.Errorf("%s does not match the %s proof", name, proofType)
+		}
+	}
+	return nil
+}
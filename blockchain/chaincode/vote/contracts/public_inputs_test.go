@@ -0,0 +1,44 @@
+/*
+ * Public Inputs Tests
+ */
+
+package contracts
+
+import (
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicInputValue(t *testing.T) {
+	// Field elements are taken as they are
+	assert.Equal(t, big.NewInt(255), publicInputValue("0xff"))
+	assert.Equal(t, big.NewInt(42), publicInputValue("42"))
+	assert.Equal(t, big.NewInt(255), publicInputValue("0XFF"))
+
+	// Leading zeros do not make a decimal octal
+	assert.Equal(t, big.NewInt(10), publicInputValue("010"))
+	assert.Equal(t, big.NewInt(89), publicInputValue("0089"))
+
+	// Anything else enters as its digest
+	digest := sha256.Sum256([]byte("nullifier123"))
+	expected := new(big.Int).SetBytes(digest[:])
+	assert.Equal(t, expected.Mod(expected, fr.Modulus()), publicInputValue("nullifier123"))
+	assert.Equal(t, -1, publicInputValue(fr.Modulus().String()).Cmp(fr.Modulus()))
+
+	// The ballot hash follows the election's algorithm
+	assert.NotEqual(t, ballotHashInput(&Election{}, "ct"),
+		ballotHashInput(&Election{HashAlgorithm: HashAlgorithmKeccak256}, "ct"))
+}
+
+func TestValidatePublicInputLayout(t *testing.T) {
+	assert.NoError(t, validatePublicInputLayout("eligibility", []string{"", PublicInputNullifier}, PublicInputNullifier))
+	assert.NoError(t, validatePublicInputLayout("validity", nil))
+
+	assert.Error(t, validatePublicInputLayout("eligibility", nil, PublicInputNullifier))
+	assert.Error(t, validatePublicInputLayout("eligibility", []string{"age"}))
+	assert.Error(t, validatePublicInputLayout("eligibility", []string{PublicInputWeight, PublicInputWeight}))
+}
@@ -0,0 +1,55 @@
+/*
+ * Quorum - Minimum participation for a valid result
+ *
+ * An election may require a quorum. A quorum below 1 is a fraction of the
+ * electorate, whose size is MaxVoters or, without a cap, VoterRollSize; a
+ * quorum of 1 or more is an absolute number of ballots. Turnout is the
+ * number of ballots cast. A tally that falls short is still stored, but is
+ * marked quorumMet: false and is not valid.
+ */
+
+package contracts
+
+import (
+	"fmt"
+	"math"
+)
+
+// validateQuorum checks a quorum against the electorate it is measured on
+func validateQuorum(quorum float64, maxVoters, voterRollSize int) error {
+	if voterRollSize < 0 {
+		return fmt.Errorf("voterRollSize must not be negative")
+	}
+	if quorum < 0 || math.IsNaN(quorum) || math.IsInf(quorum, 0) {
+		return fmt.Errorf("quorum must not be negative")
+	}
+	if quorum >= 1 && quorum != math.Trunc(quorum) {
+		return fmt.Errorf("a quorum of 1 or more is a number of ballots and must be whole")
+	}
+	if quorum > 0 && quorum < 1 && maxVoters == 0 && voterRollSize == 0 {
+		return fmt.Errorf("a fractional quorum needs maxVoters or voterRollSize")
+	}
+	return nil
+}
+
+// electorateSize is the number of voters a fractional quorum is measured on
+func electorateSize(election *Election) int {
+	if election.MaxVoters > 0 {
+		return election.MaxVoters
+	}
+	return election.VoterRollSize
+}
+
+// requiredTurnout is the number of ballots the election's quorum requires
+func requiredTurnout(election *Election) int {
+	if election.Quorum >= 1 {
+		return int(election.Quorum)
+	}
+	return int(math.Ceil(election.Quorum * float64(electorateSize(election))))
+}
+
+// quorumMet reports whether turnout reaches the election's quorum. An
+// election without a quorum always meets it.
+func quorumMet(election *Election, turnout int) bool {
+	return turnout >= requiredTurnout(election)
+}
@@ -0,0 +1,106 @@
+/*
+ * Quorum Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequiredTurnout(t *testing.T) {
+	for _, tc := range []struct {
+		quorum                   float64
+		maxVoters, voterRollSize int
+		want                     int
+	}{
+		{0, 0, 0, 0},
+		{0.5, 1000, 0, 500},
+		{0.5, 0, 1001, 501},
+		// A cap on votes takes precedence over the roll
+		{0.25, 100, 1000, 25},
+		{300, 0, 0, 300},
+	} {
+		election := &Election{Quorum: tc.quorum, MaxVoters: tc.maxVoters, VoterRollSize: tc.voterRollSize}
+		assert.Equal(t, tc.want, requiredTurnout(election), tc)
+		assert.True(t, quorumMet(election, tc.want), tc)
+		if tc.want > 0 {
+			assert.False(t, quorumMet(election, tc.want-1), tc)
+		}
+	}
+}
+
+func TestTallyResultQuorum(t *testing.T) {
+	for _, tc := range []struct {
+		quorum float64
+		met    bool
+	}{
+		{0, true},
+		// 1000 ballots cast of an electorate of 1500
+		{0.6, true},
+		{0.7, false},
+		{1000, true},
+		{1001, false},
+	} {
+		contract := new(VoteContract)
+		ctx := new(MockTransactionContext)
+		stub := NewMockStub()
+
+		ctx.On("GetStub").Return(stub)
+
+		election := createMockElection()
+		election.Quorum = tc.quorum
+		election.VoterRollSize = 1500
+		storeCompletedTally(t, ctx, stub, election, `{"1": 600, "2": 400}`)
+
+		// The tally is stored either way
+		tally, err := contract.GetTallyResult(ctx, "election-001")
+		assert.NoError(t, err, tc.quorum)
+		assert.Equal(t, tc.met, tally.QuorumMet, tc.quorum)
+		assert.Equal(t, tc.met, tally.Valid, tc.quorum)
+
+		change := statusChangeEvent(t, stub)
+		assert.Equal(t, tc.met, change.Tally.QuorumMet, tc.quorum)
+
+		results, err := contract.GetElectionResults(ctx, "election-001")
+		assert.NoError(t, err, tc.quorum)
+		assert.Equal(t, 1000, results.Turnout)
+		assert.Equal(t, tc.met, results.QuorumMet, tc.quorum)
+		assert.Equal(t, tc.met, results.Valid, tc.quorum)
+		assert.Equal(t, []string{"1"}, results.Questions[0].Winners)
+	}
+}
+
+func TestCreateElectionValidatesQuorum(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	ctx.On("GetStub").Return(NewMockStub())
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	create := func(id string, config ElectionConfig) error {
+		configJSON, _ := json.Marshal(config)
+		_, err := new(VoteContract).CreateElectionWithConfig(ctx, id, "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, string(configJSON))
+		return err
+	}
+
+	for _, config := range []ElectionConfig{
+		{Quorum: -0.5, VoterRollSize: 100},
+		{Quorum: 10.5},
+		{Quorum: 0.5},
+		{VoterRollSize: -1},
+	} {
+		assert.Error(t, create("election-bad", config), config)
+	}
+
+	assert.NoError(t, create("election-001", ElectionConfig{Quorum: 0.5, VoterRollSize: 100}))
+	election, _ := new(VoteContract).GetElection(ctx, "election-001")
+	assert.Equal(t, 0.5, election.Quorum)
+	assert.Equal(t, 100, election.VoterRollSize)
+	assert.NoError(t, create("election-002", ElectionConfig{Quorum: 0.5, MaxVoters: 100}))
+	assert.NoError(t, create("election-003", ElectionConfig{Quorum: 50}))
+}
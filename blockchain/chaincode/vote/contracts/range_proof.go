@@ -0,0 +1,304 @@
+/*
+ * Range Proof - Proof that a ballot encodes an allowed selection
+ *
+ * Homomorphic counting is only sound if every ciphertext encrypts a small
+ * count: a ballot encrypting 1000 for one candidate would add 1000 votes.
+ * Elections created with a RangeProofScheme only accept ballots whose
+ * validity proof shows, for every question, that each ciphertext encrypts
+ * 0..MaxPerOption and, when MaxSelections is set, that their product (the
+ * encrypted number of selections) encrypts MinSelections..MaxSelections.
+ *
+ * Range proof schemes are pluggable like decryption proof schemes. The
+ * default disjunctive Chaum-Pedersen scheme (as in Helios) proves a
+ * ciphertext (c1, c2) encrypts one of the values v_0..v_n with one branch
+ * {"a","b","c","z"} per value: g^z = a*c1^c and h^z = b*(c2/g^v)^c, where
+ * the branch challenges c sum to rangeProofChallenge mod q. Only the branch
+ * of the encrypted value is a real proof; the others are simulated.
+ *
+ * The validity proof maps question ID to {"options": [[branch...]...],
+ * "total": [branch...]}; single-question elections use DefaultQuestionID.
+ * CastVoteWithProof passes it as validityProof, every other cast function
+ * reads it from the transient field "validityProof". Either way it must
+ * hash to the vote's validity proof hash.
+ */
+
+package contracts
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// RangeProofDisjunctiveChaumPedersen is the identifier of the default range proof scheme
+const RangeProofDisjunctiveChaumPedersen = "disjunctive-chaum-pedersen"
+
+// MaxRangeProofValues bounds how many values one range may allow, which
+// bounds the size and verification cost of a proof
+const MaxRangeProofValues = 64
+
+// BallotRange is what each ballot of an election may encode
+type BallotRange struct {
+	// MaxPerOption is the largest count one ciphertext may encrypt (default 1)
+	MaxPerOption int `json:"maxPerOption"`
+	// MinSelections and MaxSelections bound the sum of a ballot's counts;
+	// MaxSelections 0 leaves the sum unbounded
+	MinSelections int `json:"minSelections,omitempty"`
+	MaxSelections int `json:"maxSelections,omitempty"`
+}
+
+// BallotRangeVerifier verifies that a ballot encodes values within bounds
+type BallotRangeVerifier interface {
+	// Verify returns nil only if proof shows ballot is within bounds
+	Verify(key *ElGamalPublicKey, ballot []ElGamalCiphertext, bounds BallotRange, proof json.RawMessage) error
+}
+
+var ballotRangeVerifiers = map[string]BallotRangeVerifier{
+	RangeProofDisjunctiveChaumPedersen: DisjunctiveChaumPedersenVerifier{},
+}
+
+// RegisterBallotRangeVerifier makes a range proof scheme available to elections
+func RegisterBallotRangeVerifier(scheme string, verifier BallotRangeVerifier) {
+	ballotRangeVerifiers[scheme] = verifier
+}
+
+func getBallotRangeVerifier(scheme string) (BallotRangeVerifier, error) {
+	verifier, ok := ballotRangeVerifiers[scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported range proof scheme: %s", scheme)
+	}
+	return verifier, nil
+}
+
+// normalizeBallotRange validates the range settings of a new election and
+// fills in defaults. Without a scheme no range may be given.
+func normalizeBallotRange(scheme string, bounds *BallotRange) (*BallotRange, error) {
+	if scheme == "" {
+		if bounds != nil {
+			return nil, fmt.Errorf("ballotRange requires a rangeProofScheme")
+		}
+		return nil, nil
+	}
+	if _, err := getBallotRangeVerifier(scheme); err != nil {
+		return nil, err
+	}
+
+	normalized := BallotRange{MaxPerOption: 1}
+	if bounds != nil {
+		normalized = *bounds
+		if normalized.MaxPerOption == 0 {
+			normalized.MaxPerOption = 1
+		}
+	}
+	if normalized.MaxPerOption < 0 || normalized.MaxPerOption >= MaxRangeProofValues {
+		return nil, fmt.Errorf("maxPerOption must be between 1 and %d", MaxRangeProofValues-1)
+	}
+	if normalized.MinSelections < 0 || normalized.MaxSelections < 0 {
+		return nil, fmt.Errorf("selection bounds must not be negative")
+	}
+	if normalized.MinSelections > 0 && normalized.MaxSelections == 0 {
+		return nil, fmt.Errorf("minSelections requires maxSelections")
+	}
+	if normalized.MaxSelections > 0 {
+		if normalized.MinSelections > normalized.MaxSelections {
+			return nil, fmt.Errorf("minSelections %d exceeds maxSelections %d", normalized.MinSelections, normalized.MaxSelections)
+		}
+		if normalized.MaxSelections-normalized.MinSelections >= MaxRangeProofValues {
+			return nil, fmt.Errorf("selection range allows more than %d values", MaxRangeProofValues)
+		}
+	}
+	return &normalized, nil
+}
+
+// checkBallotRange verifies the submitted validity proof shows every
+// question's ballot is within the election's range
+func checkBallotRange(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	sub voteSubmission,
+	encryptedVote string,
+	questionVotes map[string]string,
+) error {
+	proof := sub.ValidityProof
+	if proof == "" {
+		transient, err := ctx.GetStub().GetTransient()
+		if err != nil {
+			return fmt.Errorf("failed to read transient data: %v", err)
+		}
+		proof = string(transient["validityProof"])
+	}
+	if proof == "" {
+		return fmt.Errorf("election requires a ballot range proof; the validity proof must be passed in the transient map")
+	}
+	if len(proof) > MaxStoredProofSize {
+		return fmt.Errorf("validity proof of %d bytes exceeds the limit of %d", len(proof), MaxStoredProofSize)
+	}
+	if hashForElection(election, proof) != sub.ValidityProofHash {
+		return fmt.Errorf("validity proof does not match its hash")
+	}
+
+	var proofs map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(proof), &proofs); err != nil {
+		return fmt.Errorf("%w: %v", ErrBallotOutOfRange, err)
+	}
+
+	ballots := questionVotes
+	if len(ballots) == 0 {
+		ballots = map[string]string{DefaultQuestionID: encryptedVote}
+	}
+	verifier, err := getBallotRangeVerifier(election.RangeProofScheme)
+	if err != nil {
+		return err
+	}
+	for questionID, questionVote := range ballots {
+		key, err := parseElGamalPublicKey(questionPublicKey(election, questionID))
+		if err != nil {
+			return err
+		}
+		ballot, err := parseBallot(questionVote)
+		if err != nil {
+			return err
+		}
+		questionProof, ok := proofs[questionID]
+		if !ok {
+			return fmt.Errorf("%w: no proof for question %s", ErrBallotOutOfRange, questionID)
+		}
+		if err := verifier.Verify(key, ballot, *election.BallotRange, questionProof); err != nil {
+			return fmt.Errorf("%w: question %s: %v", ErrBallotOutOfRange, questionID, err)
+		}
+	}
+	return nil
+}
+
+// DisjunctiveChaumPedersenVerifier verifies disjunctive Chaum-Pedersen
+// proofs that ciphertexts encrypt values in a range
+type DisjunctiveChaumPedersenVerifier struct{}
+
+// RangeProofBranch is the proof for one value of a disjunctive proof
+type RangeProofBranch struct {
+	A string `json:"a"`
+	B string `json:"b"`
+	C string `json:"c"`
+	Z string `json:"z"`
+}
+
+// BallotRangeProof proves each ciphertext of a ballot and their sum in range
+type BallotRangeProof struct {
+	Options [][]RangeProofBranch `json:"options"`
+	Total   []RangeProofBranch   `json:"total,omitempty"`
+}
+
+// Verify checks one disjunctive proof per ciphertext over 0..MaxPerOption
+// and, when MaxSelections is set, one over the product of the ciphertexts
+func (DisjunctiveChaumPedersenVerifier) Verify(
+	key *ElGamalPublicKey,
+	ballot []ElGamalCiphertext,
+	bounds BallotRange,
+	proof json.RawMessage,
+) error {
+	var p BallotRangeProof
+	if err := json.Unmarshal(proof, &p); err != nil {
+		return fmt.Errorf("invalid range proof: %v", err)
+	}
+	if len(p.Options) != len(ballot) {
+		return fmt.Errorf("expected %d option proofs, got %d", len(ballot), len(p.Options))
+	}
+
+	for i, c := range ballot {
+		if err := verifyDisjunctiveProof(key, c, 0, bounds.MaxPerOption, p.Options[i]); err != nil {
+			return fmt.Errorf("ciphertext %d: %v", i, err)
+		}
+	}
+
+	if bounds.MaxSelections > 0 {
+		total := ElGamalCiphertext{C1: big.NewInt(1), C2: big.NewInt(1)}
+		for _, c := range ballot {
+			total.C1 = new(big.Int).Mod(new(big.Int).Mul(total.C1, c.C1), key.P)
+			total.C2 = new(big.Int).Mod(new(big.Int).Mul(total.C2, c.C2), key.P)
+		}
+		if err := verifyDisjunctiveProof(key, total, bounds.MinSelections, bounds.MaxSelections, p.Total); err != nil {
+			return fmt.Errorf("selection count: %v", err)
+		}
+	}
+	return nil
+}
+
+// verifyDisjunctiveProof checks that c encrypts one of low..high, with one
+// branch per value in order
+func verifyDisjunctiveProof(key *ElGamalPublicKey, c ElGamalCiphertext, low, high int, branches []RangeProofBranch) error {
+	if len(branches) != high-low+1 {
+		return fmt.Errorf("expected %d proof branches, got %d", high-low+1, len(branches))
+	}
+
+	p := key.P
+	gInverse := new(big.Int).ModInverse(key.G, p)
+	commitments := make([]*big.Int, 0, 2*len(branches))
+	challengeSum := new(big.Int)
+	for j, branch := range branches {
+		a, err := parseBigInt("a", branch.A)
+		if err != nil {
+			return err
+		}
+		b, err := parseBigInt("b", branch.B)
+		if err != nil {
+			return err
+		}
+		e, err := parseBigInt("c", branch.C)
+		if err != nil {
+			return err
+		}
+		z, err := parseBigInt("z", branch.Z)
+		if err != nil {
+			return err
+		}
+		if !key.inGroup(a) || !key.inGroup(b) {
+			return fmt.Errorf("proof element is not in the group")
+		}
+		if e.Sign() < 0 || e.Cmp(key.Q) >= 0 || z.Sign() < 0 || z.Cmp(key.Q) >= 0 {
+			return fmt.Errorf("proof scalar out of range")
+		}
+
+		// g^z = a * c1^c
+		left := new(big.Int).Exp(key.G, z, p)
+		right := new(big.Int).Mul(a, new(big.Int).Exp(c.C1, e, p))
+		if left.Cmp(right.Mod(right, p)) != 0 {
+			return fmt.Errorf("range proof does not verify")
+		}
+
+		// h^z = b * (c2 / g^v)^c
+		d := new(big.Int).Mul(c.C2, new(big.Int).Exp(gInverse, big.NewInt(int64(low+j)), p))
+		d.Mod(d, p)
+		left = new(big.Int).Exp(key.H, z, p)
+		right = new(big.Int).Mul(b, new(big.Int).Exp(d, e, p))
+		if left.Cmp(right.Mod(right, p)) != 0 {
+			return fmt.Errorf("range proof does not verify")
+		}
+
+		commitments = append(commitments, a, b)
+		challengeSum.Add(challengeSum, e)
+	}
+
+	if challengeSum.Mod(challengeSum, key.Q).Cmp(rangeProofChallenge(key, c, low, commitments)) != 0 {
+		return fmt.Errorf("range proof challenges do not match")
+	}
+	return nil
+}
+
+// rangeProofChallenge is the Fiat-Shamir challenge
+// SHA-256("range"|p|q|g|h|c1|c2|low|a_0|b_0|...) mod q over decimal strings
+func rangeProofChallenge(key *ElGamalPublicKey, c ElGamalCiphertext, low int, commitments []*big.Int) *big.Int {
+	parts := []string{"range"}
+	for _, x := range []*big.Int{key.P, key.Q, key.G, key.H, c.C1, c.C2, big.NewInt(int64(low))} {
+		parts = append(parts, x.String())
+	}
+	for _, x := range commitments {
+		parts = append(parts, x.String())
+	}
+	hash := sha256.Sum256([]byte(strings.Join(parts, "|")))
+	e := new(big.Int).SetBytes(hash[:])
+	return e.Mod(e, key.Q)
+}
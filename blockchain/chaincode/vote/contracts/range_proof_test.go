@@ -0,0 +1,212 @@
+/*
+ * Range Proof Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testDisjunctiveProof proves c = testEncrypt(m, r) encrypts one of
+// low..high, claiming the value claimed. With claimed != m the real branch
+// is built for the wrong value, as a voter forging a ballot would have to.
+func testDisjunctiveProof(c CiphertextJSON, r int64, claimed, low, high int) []RangeProofBranch {
+	key, _ := parseElGamalPublicKey(testPublicKeyJSON())
+	cts, _ := ciphertextsFromJSON([]CiphertextJSON{c})
+	ct := cts[0]
+	p, q := key.P, key.Q
+	gInverse := new(big.Int).ModInverse(key.G, p)
+
+	n := high - low + 1
+	as, bs, cs, zs := make([]*big.Int, n), make([]*big.Int, n), make([]*big.Int, n), make([]*big.Int, n)
+	w := big.NewInt(5)
+	commitments := []*big.Int{}
+	simulated := new(big.Int)
+	for j := 0; j < n; j++ {
+		if low+j == claimed {
+			as[j] = new(big.Int).Exp(key.G, w, p)
+			bs[j] = new(big.Int).Exp(key.H, w, p)
+		} else {
+			// Simulate the branch from a chosen challenge and response
+			cs[j], zs[j] = big.NewInt(int64(3+j)), big.NewInt(int64(17+j))
+			d := new(big.Int).Mul(ct.C2, new(big.Int).Exp(gInverse, big.NewInt(int64(low+j)), p))
+			d.Mod(d, p)
+			as[j] = new(big.Int).Mul(new(big.Int).Exp(key.G, zs[j], p),
+				new(big.Int).ModInverse(new(big.Int).Exp(ct.C1, cs[j], p), p))
+			as[j].Mod(as[j], p)
+			bs[j] = new(big.Int).Mul(new(big.Int).Exp(key.H, zs[j], p),
+				new(big.Int).ModInverse(new(big.Int).Exp(d, cs[j], p), p))
+			bs[j].Mod(bs[j], p)
+			simulated.Add(simulated, cs[j])
+		}
+		commitments = append(commitments, as[j], bs[j])
+	}
+
+	branches := make([]RangeProofBranch, n)
+	e := rangeProofChallenge(key, ct, low, commitments)
+	for j := 0; j < n; j++ {
+		if low+j == claimed {
+			cs[j] = new(big.Int).Sub(e, simulated)
+			cs[j].Mod(cs[j], q)
+			zs[j] = new(big.Int).Mul(cs[j], big.NewInt(r))
+			zs[j].Add(zs[j], w).Mod(zs[j], q)
+		}
+		branches[j] = RangeProofBranch{A: as[j].String(), B: bs[j].String(), C: cs[j].String(), Z: zs[j].String()}
+	}
+	return branches
+}
+
+// testRangeBallot encrypts counts with randomness r+i and proves each in
+// 0..maxPerOption and their sum in minSelections..maxSelections
+func testRangeBallot(counts []int, r int64, bounds BallotRange) (string, string) {
+	ballot := make([]CiphertextJSON, len(counts))
+	proof := BallotRangeProof{}
+	sum, sumR := 0, int64(0)
+	for i, m := range counts {
+		ballot[i] = testEncrypt(int64(m), r+int64(i))
+		proof.Options = append(proof.Options, testDisjunctiveProof(ballot[i], r+int64(i), m, 0, bounds.MaxPerOption))
+		sum += m
+		sumR += r + int64(i)
+	}
+	if bounds.MaxSelections > 0 {
+		proof.Total = testDisjunctiveProof(testEncrypt(int64(sum), sumR), sumR, sum, bounds.MinSelections, bounds.MaxSelections)
+	}
+	ballotJSON, _ := json.Marshal(ballot)
+	proofJSON, _ := json.Marshal(map[string]BallotRangeProof{DefaultQuestionID: proof})
+	return string(ballotJSON), string(proofJSON)
+}
+
+func TestDisjunctiveChaumPedersenVerifier(t *testing.T) {
+	key, _ := parseElGamalPublicKey(testPublicKeyJSON())
+	bounds := BallotRange{MaxPerOption: 1, MinSelections: 1, MaxSelections: 1}
+	verify := func(ballotJSON string, proof BallotRangeProof) error {
+		ballot, _ := parseBallot(ballotJSON)
+		proofJSON, _ := json.Marshal(proof)
+		return DisjunctiveChaumPedersenVerifier{}.Verify(key, ballot, bounds, proofJSON)
+	}
+	proofOf := func(proofJSON string) BallotRangeProof {
+		var proofs map[string]BallotRangeProof
+		_ = json.Unmarshal([]byte(proofJSON), &proofs)
+		return proofs[DefaultQuestionID]
+	}
+
+	ballot, proofJSON := testRangeBallot([]int{0, 1, 0}, 20, bounds)
+	assert.NoError(t, verify(ballot, proofOf(proofJSON)))
+
+	// A proof for one ballot does not verify another
+	other, _ := testRangeBallot([]int{1, 0, 0}, 30, bounds)
+	assert.Error(t, verify(other, proofOf(proofJSON)))
+
+	// Every option needs a proof, and the total one is required
+	proof := proofOf(proofJSON)
+	assert.Error(t, verify(ballot, BallotRangeProof{Options: proof.Options[:2], Total: proof.Total}))
+	assert.Error(t, verify(ballot, BallotRangeProof{Options: proof.Options}))
+
+	// A changed challenge breaks the challenge sum
+	proof.Options[0][0].C = "1"
+	assert.Error(t, verify(ballot, proof))
+}
+
+func TestNormalizeBallotRange(t *testing.T) {
+	bounds, err := normalizeBallotRange(RangeProofDisjunctiveChaumPedersen, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &BallotRange{MaxPerOption: 1}, bounds)
+
+	bounds, err = normalizeBallotRange("", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, bounds)
+
+	for _, tc := range []struct {
+		scheme string
+		bounds *BallotRange
+	}{
+		{"bulletproofs", nil},
+		{"", &BallotRange{MaxPerOption: 1}},
+		{RangeProofDisjunctiveChaumPedersen, &BallotRange{MaxPerOption: MaxRangeProofValues}},
+		{RangeProofDisjunctiveChaumPedersen, &BallotRange{MinSelections: 1}},
+		{RangeProofDisjunctiveChaumPedersen, &BallotRange{MinSelections: 3, MaxSelections: 2}},
+	} {
+		_, err := normalizeBallotRange(tc.scheme, tc.bounds)
+		assert.Error(t, err, tc.scheme)
+	}
+}
+
+func TestCastVoteRangeProof(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	bounds := BallotRange{MaxPerOption: 1, MinSelections: 1, MaxSelections: 1}
+	config, _ := json.Marshal(ElectionConfig{
+		Options:          []string{"alice", "bob", "carol"},
+		RangeProofScheme: RangeProofDisjunctiveChaumPedersen,
+		BallotRange:      &bounds,
+	})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	cast := func(ballot, proof, nullifier string) error {
+		stub.Transient["validityProof"] = []byte(proof)
+		_, err := contract.CastVote(ctx, "election-001", ballot, nullifier, testEligibilityHash, hashString(proof), testVoterRoot)
+		return err
+	}
+
+	// An in-range ballot is accepted
+	ballot, proof := testRangeBallot([]int{0, 1, 0}, 20, bounds)
+	assert.NoError(t, cast(ballot, proof, "nullifier1"))
+
+	// A forged ballot encrypting 1000 for one candidate cannot be proven
+	forged := []CiphertextJSON{testEncrypt(0, 40), testEncrypt(1000, 41), testEncrypt(0, 42)}
+	forgedJSON, _ := json.Marshal(forged)
+	forgedProof, _ := json.Marshal(map[string]BallotRangeProof{DefaultQuestionID: {
+		Options: [][]RangeProofBranch{
+			testDisjunctiveProof(forged[0], 40, 0, 0, 1),
+			testDisjunctiveProof(forged[1], 41, 1, 0, 1),
+			testDisjunctiveProof(forged[2], 42, 0, 0, 1),
+		},
+		Total: testDisjunctiveProof(testEncrypt(1000, 123), 123, 1, 1, 1),
+	}})
+	err = cast(string(forgedJSON), string(forgedProof), "nullifier2")
+	assert.True(t, errors.Is(err, ErrBallotOutOfRange))
+
+	// So is a ballot selecting two candidates when one is allowed
+	double, doubleProof := testRangeBallot([]int{1, 1, 0}, 50, BallotRange{MaxPerOption: 1, MinSelections: 1, MaxSelections: 2})
+	err = cast(double, doubleProof, "nullifier3")
+	assert.True(t, errors.Is(err, ErrBallotOutOfRange))
+
+	// The proof must be supplied and match the committed hash
+	delete(stub.Transient, "validityProof")
+	_, err = contract.CastVote(ctx, "election-001", ballot, "nullifier4", testEligibilityHash, hashString(proof), testVoterRoot)
+	assert.Error(t, err)
+	stub.Transient["validityProof"] = []byte(proof)
+	_, err = contract.CastVote(ctx, "election-001", ballot, "nullifier4", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match its hash")
+
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 1, count)
+
+	// A range proof is the validity proof, so no validity key may be set too
+	vk, _ := newGroth16Fixture([]int64{1})
+	config, _ = json.Marshal(ElectionConfig{
+		RangeProofScheme:     RangeProofDisjunctiveChaumPedersen,
+		ValidityVerifyingKey: vk,
+	})
+	_, err = contract.CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.Error(t, err)
+}
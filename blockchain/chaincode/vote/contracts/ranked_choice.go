@@ -0,0 +1,190 @@
+/*
+ * Ranked Choice - Instant-runoff ballots and tally
+ *
+ * A ranked ballot is an ordered preference list. It is cast as a ciphertext
+ * vector with one entry per rank, each encrypting the 1-based position of
+ * the preferred candidate in the election's Options, or 0 for no further
+ * preference. Such ballots cannot be added homomorphically, so the tally
+ * authority decrypts them individually and passes the decrypted preference
+ * lists to TallyRankedChoice in the transient field "rankedBallots", e.g.
+ * [["alice","bob"],["carol"]], keeping them out of the proposal.
+ *
+ * Each round counts every ballot for its highest-ranked candidate still in
+ * the race. A candidate with more than half of the ballots not yet
+ * exhausted wins; otherwise the candidate with the fewest votes is
+ * eliminated. Ties for elimination go to the candidate with fewer votes in
+ * the latest earlier round where they differ, then to the one listed last.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Ballot types an election may declare
+const (
+	BallotTypePlurality = "plurality"
+	BallotTypeRanked    = "ranked"
+)
+
+// RankedRound is one instant-runoff counting round
+type RankedRound struct {
+	Round int `json:"round"`
+	// Counts holds the votes of every candidate still in the race
+	Counts map[string]int `json:"counts"`
+	// Exhausted counts ballots ranking no candidate still in the race
+	Exhausted  int    `json:"exhausted"`
+	Eliminated string `json:"eliminated,omitempty"`
+}
+
+// instantRunoff is the outcome of TallyRankedChoice stored with the tally
+type instantRunoff struct {
+	Rounds []RankedRound
+	Winner string
+}
+
+// validateBallotType checks the settings a ranked election depends on
+func validateBallotType(config ElectionConfig, mode VotingMode) error {
+	switch config.BallotType {
+	case "", BallotTypePlurality:
+		return nil
+	case BallotTypeRanked:
+	default:
+		return fmt.Errorf("unknown ballot type %q", config.BallotType)
+	}
+
+	if len(config.Questions) > 0 {
+		return fmt.Errorf("ranked ballots do not support multiple questions")
+	}
+	if len(config.Options) < 2 {
+		return fmt.Errorf("ranked ballots need at least two options")
+	}
+	if mode != VotingModeSingle {
+		return fmt.Errorf("ranked ballots require the %s voting mode", VotingModeSingle)
+	}
+	// Each ballot is decrypted on its own, so nothing that scales or
+	// aggregates ciphertexts applies
+	if config.Weighted || config.AllowDelegation {
+		return fmt.Errorf("ranked ballots cannot be weighted or delegated")
+	}
+	if len(config.Trustees) > 0 || config.DecryptionProofScheme != "" || config.RangeProofScheme != "" {
+		return fmt.Errorf("ranked ballots do not support threshold decryption, decryption proofs or range proofs")
+	}
+	return nil
+}
+
+// checkRankedBallot rejects a ranked ballot with more ranks than candidates
+func checkRankedBallot(election *Election, encryptedVote string) error {
+	if election.BallotType != BallotTypeRanked {
+		return nil
+	}
+	ballot, err := parseBallot(encryptedVote)
+	if err != nil {
+		return err
+	}
+	if len(ballot) > len(election.Options) {
+		return fmt.Errorf("ranked ballot has %d preferences but the election has %d candidates",
+			len(ballot), len(election.Options))
+	}
+	return nil
+}
+
+// parseRankedBallots parses decrypted preference lists, each naming
+// distinct candidates of options
+func parseRankedBallots(ballotsJSON []byte, options []string) ([][]string, error) {
+	var ballots [][]string
+	if err := json.Unmarshal(ballotsJSON, &ballots); err != nil {
+		return nil, fmt.Errorf("invalid ranked ballots: %v", err)
+	}
+	for i, ballot := range ballots {
+		seen := make(map[string]bool, len(ballot))
+		for _, candidate := range ballot {
+			if !containsString(options, candidate) {
+				return nil, fmt.Errorf("ranked ballot %d: unknown candidate %s", i, candidate)
+			}
+			if seen[candidate] {
+				return nil, fmt.Errorf("ranked ballot %d: candidate %s ranked twice", i, candidate)
+			}
+			seen[candidate] = true
+		}
+	}
+	return ballots, nil
+}
+
+// runInstantRunoff counts rounds until a candidate holds a majority of the
+// ballots still in play. The winner is empty if every ballot is exhausted.
+func runInstantRunoff(options []string, ballots [][]string) instantRunoff {
+	active := make(map[string]bool, len(options))
+	for _, option := range options {
+		active[option] = true
+	}
+
+	var result instantRunoff
+	for round := 1; ; round++ {
+		current := RankedRound{Round: round, Counts: make(map[string]int, len(active))}
+		for option := range active {
+			current.Counts[option] = 0
+		}
+		for _, ballot := range ballots {
+			counted := false
+			for _, candidate := range ballot {
+				if active[candidate] {
+					current.Counts[candidate]++
+					counted = true
+					break
+				}
+			}
+			if !counted {
+				current.Exhausted++
+			}
+		}
+
+		continuing := len(ballots) - current.Exhausted
+		if continuing == 0 {
+			result.Rounds = append(result.Rounds, current)
+			return result
+		}
+
+		// Options are scanned in ballot order so ties resolve the same way
+		// on every peer
+		var leader, loser string
+		for _, option := range options {
+			if !active[option] {
+				continue
+			}
+			if leader == "" || current.Counts[option] > current.Counts[leader] {
+				leader = option
+			}
+			if loser == "" || eliminatesBefore(option, loser, current.Counts, result.Rounds) {
+				loser = option
+			}
+		}
+		if 2*current.Counts[leader] > continuing || len(active) == 1 {
+			result.Rounds = append(result.Rounds, current)
+			result.Winner = leader
+			return result
+		}
+
+		current.Eliminated = loser
+		delete(active, loser)
+		result.Rounds = append(result.Rounds, current)
+	}
+}
+
+// eliminatesBefore reports whether candidate, listed after other, is
+// eliminated before it: fewer votes now, or on a tie fewer votes in the
+// latest earlier round where they differ, or on a full tie being listed last
+func eliminatesBefore(candidate, other string, counts map[string]int, previous []RankedRound) bool {
+	if counts[candidate] != counts[other] {
+		return counts[candidate] < counts[other]
+	}
+	for i := len(previous) - 1; i >= 0; i-- {
+		a, b := previous[i].Counts[candidate], previous[i].Counts[other]
+		if a != b {
+			return a < b
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+/*
+ * Ranked Choice Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var rankedOptions = []string{"alice", "bob", "carol"}
+
+// rankedDataset has alice ahead on first choices, but carol's voters
+// prefer bob, who wins once carol is eliminated
+func rankedDataset() [][]string {
+	var ballots [][]string
+	for i := 0; i < 4; i++ {
+		ballots = append(ballots, []string{"alice", "bob"})
+	}
+	for i := 0; i < 3; i++ {
+		ballots = append(ballots, []string{"bob", "alice"})
+	}
+	for i := 0; i < 2; i++ {
+		ballots = append(ballots, []string{"carol", "bob"})
+	}
+	return ballots
+}
+
+// testRankedBallot encrypts each preference's 1-based option position
+func testRankedBallot(preferences []string, r int64) string {
+	ballot := make([]CiphertextJSON, len(preferences))
+	for i, preference := range preferences {
+		position := 0
+		for j, option := range rankedOptions {
+			if option == preference {
+				position = j + 1
+			}
+		}
+		ballot[i] = testEncrypt(int64(position), r+int64(i))
+	}
+	ballotJSON, _ := json.Marshal(ballot)
+	return string(ballotJSON)
+}
+
+func TestRunInstantRunoff(t *testing.T) {
+	runoff := runInstantRunoff(rankedOptions, rankedDataset())
+	assert.Equal(t, "bob", runoff.Winner)
+	assert.Equal(t, []RankedRound{
+		{Round: 1, Counts: map[string]int{"alice": 4, "bob": 3, "carol": 2}, Eliminated: "carol"},
+		{Round: 2, Counts: map[string]int{"alice": 4, "bob": 5}},
+	}, runoff.Rounds)
+
+	// Ballots ranking only eliminated candidates are exhausted
+	ballots := [][]string{{"alice"}, {"alice"}, {"bob"}, {"bob"}, {"carol"}, {}}
+	runoff = runInstantRunoff(rankedOptions, ballots)
+	assert.Equal(t, 1, runoff.Rounds[0].Exhausted)
+	assert.Equal(t, "carol", runoff.Rounds[0].Eliminated)
+	// alice and bob tie on every round, so bob, listed last, goes next
+	assert.Equal(t, "bob", runoff.Rounds[1].Eliminated)
+	assert.Equal(t, 2, runoff.Rounds[1].Exhausted)
+	assert.Equal(t, "alice", runoff.Winner)
+
+	// No winner when nobody ranked anyone
+	runoff = runInstantRunoff(rankedOptions, [][]string{{}, {}})
+	assert.Empty(t, runoff.Winner)
+	assert.Len(t, runoff.Rounds, 1)
+}
+
+func TestEliminatesBefore(t *testing.T) {
+	counts := map[string]int{"alice": 2, "bob": 2}
+	previous := []RankedRound{
+		{Counts: map[string]int{"alice": 1, "bob": 3}},
+		{Counts: map[string]int{"alice": 2, "bob": 2}},
+	}
+	// The latest round where they differ decides
+	assert.True(t, eliminatesBefore("alice", "bob", counts, previous))
+	assert.False(t, eliminatesBefore("bob", "alice", counts, previous))
+	assert.True(t, eliminatesBefore("bob", "alice", map[string]int{"alice": 3, "bob": 2}, nil))
+}
+
+func TestParseRankedBallots(t *testing.T) {
+	ballots, err := parseRankedBallots([]byte(`[["alice","bob"],[]]`), rankedOptions)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"alice", "bob"}, {}}, ballots)
+
+	_, err = parseRankedBallots([]byte(`[["dave"]]`), rankedOptions)
+	assert.Error(t, err)
+	_, err = parseRankedBallots([]byte(`[["alice","alice"]]`), rankedOptions)
+	assert.Error(t, err)
+	_, err = parseRankedBallots([]byte(`{"alice":1}`), rankedOptions)
+	assert.Error(t, err)
+}
+
+func TestTallyRankedChoice(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	config, _ := json.Marshal(ElectionConfig{BallotType: BallotTypeRanked, Options: rankedOptions})
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	dataset := rankedDataset()
+	for i, preferences := range dataset {
+		_, err := contract.CastVote(ctx, "election-001", testRankedBallot(preferences, int64(10*i+1)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	// A ballot cannot rank more candidates than there are
+	tooLong := testRankedBallot([]string{"alice", "bob", "carol", "alice"}, 500)
+	_, err = contract.CastVote(ctx, "election-001", tooLong, "nullifier-long", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "preferences")
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// Homomorphic tallying does not apply
+	_, err = contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.Error(t, err)
+	err = contract.StoreTallyResult(ctx, "election-001", `{"alice": 4, "bob": 3, "carol": 2}`, "hash", "")
+	assert.Error(t, err)
+
+	// Every counted ballot must be decrypted
+	ballotsJSON, _ := json.Marshal(dataset[1:])
+	stub.Transient["rankedBallots"] = ballotsJSON
+	err = contract.TallyRankedChoice(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "got 8 decrypted ballots for 9 votes")
+
+	ballotsJSON, _ = json.Marshal(dataset)
+	stub.Transient["rankedBallots"] = ballotsJSON
+	assert.NoError(t, contract.TallyRankedChoice(ctx, "election-001"))
+
+	tally, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", tally.Winner)
+	assert.Len(t, tally.Rounds, 2)
+	assert.Equal(t, "carol", tally.Rounds[0].Eliminated)
+	assert.Equal(t, map[string]int{"alice": 4, "bob": 5}, tally.Rounds[1].Counts)
+	assert.Equal(t, map[string]int{"alice": 4, "bob": 3, "carol": 2}, tally.VoteCounts[DefaultQuestionID])
+	assert.Equal(t, 9, tally.TotalVotes)
+
+	verification, err := contract.VerifyTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.True(t, verification.Verified)
+
+	// Results name the runoff winner, not the first-choice leader
+	results, err := contract.GetElectionResults(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, results.Questions[0].Winners)
+}
+
+func TestCreateRankedElectionValidation(t *testing.T) {
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	for _, config := range []ElectionConfig{
+		{BallotType: "approval", Options: rankedOptions},
+		{BallotType: BallotTypeRanked, Options: []string{"alice"}},
+		{BallotType: BallotTypeRanked, Options: rankedOptions, VotingMode: VotingModeMultiLimited},
+		{BallotType: BallotTypeRanked, Options: rankedOptions, AllowDelegation: true},
+		{BallotType: BallotTypeRanked, Options: rankedOptions, DecryptionProofScheme: DecryptionProofChaumPedersen},
+	} {
+		ctx := new(MockTransactionContext)
+		ctx.On("GetStub").Return(NewMockStub())
+		configJSON, _ := json.Marshal(config)
+		_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, string(configJSON))
+		assert.Error(t, err, string(configJSON))
+	}
+}
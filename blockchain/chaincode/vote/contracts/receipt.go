@@ -0,0 +1,83 @@
+/*
+ * Receipt Signing - Ed25519 signatures over vote receipts
+ *
+ * An election may be created with a receipt signing key: a 32-byte Ed25519
+ * seed passed in the transient field "receiptSigningKey". The seed is kept in
+ * the ReceiptKeyCollection private data collection and the public key is
+ * recorded on the election. Ed25519 signatures are deterministic, so every
+ * endorser produces the same receipt.
+ *
+ * The signed message is txId, encryptedVoteHash and the RFC3339Nano UTC
+ * timestamp of the receipt, separated by newlines.
+ */
+
+package contracts
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ReceiptKeyCollection is the private data collection holding receipt
+// signing keys (see collections_config.json)
+const ReceiptKeyCollection = "receiptSigningKeys"
+
+// receiptSigningKeyTransient is the transient field carrying the signing seed
+const receiptSigningKeyTransient = "receiptSigningKey"
+
+func receiptSigningBytes(receipt *VoteReceipt) []byte {
+	return []byte(receipt.TxID + "\n" + receipt.EncryptedVoteHash + "\n" +
+		receipt.Timestamp.UTC().Format(time.RFC3339Nano))
+}
+
+func parseReceiptSigningSeed(seed []byte) (ed25519.PrivateKey, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("receipt signing key must be a %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// signReceipt returns the hex encoded signature of a receipt
+func signReceipt(key ed25519.PrivateKey, receipt *VoteReceipt) string {
+	return hex.EncodeToString(ed25519.Sign(key, receiptSigningBytes(receipt)))
+}
+
+// verifyReceipt checks a hex encoded signature against a hex encoded public key
+func verifyReceipt(publicKeyHex string, receipt *VoteReceipt, signatureHex string) error {
+	publicKey, err := hex.DecodeString(publicKeyHex)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid receipt public key")
+	}
+	signature, err := hex.DecodeString(signatureHex)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid receipt signature encoding")
+	}
+	if !ed25519.Verify(publicKey, receiptSigningBytes(receipt), signature) {
+		return fmt.Errorf("receipt signature does not match")
+	}
+	return nil
+}
+
+func receiptKeyKey(electionID string) string {
+	return fmt.Sprintf("receiptkey:%s", electionID)
+}
+
+// loadReceiptSigningKey reads the election's receipt signing key, or returns
+// nil when the election does not sign receipts
+func loadReceiptSigningKey(ctx contractapi.TransactionContextInterface, election *Election) (ed25519.PrivateKey, error) {
+	if election.ReceiptPublicKey == "" {
+		return nil, nil
+	}
+	seed, err := ctx.GetStub().GetPrivateData(ReceiptKeyCollection, receiptKeyKey(election.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read receipt signing key: %v", err)
+	}
+	if seed == nil {
+		return nil, fmt.Errorf("receipt signing key of election %s is not available on this peer", election.ID)
+	}
+	return parseReceiptSigningSeed(seed)
+}
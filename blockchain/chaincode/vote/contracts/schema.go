@@ -0,0 +1,146 @@
+/*
+ * Schema - Versioned election, vote and tally records
+ *
+ * Records of different vintages coexist on the ledger, so elections, votes
+ * and tally results carry a schemaVersion. It is stamped with the current
+ * version whenever a record is marshalled, so every write stores it.
+ * Records without one are version 1, written before versioning, and are
+ * upgraded to the current shape when unmarshalled, filling in the defaults
+ * the rest of the contract relies on. Nothing is rewritten on read; an
+ * upgraded record is stored in the current shape the next time it changes.
+ *
+ * A record from a newer schema than this chaincode knows is refused rather
+ * than read with fields silently dropped.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Current schema versions of the persisted records
+const (
+	ElectionSchemaVersion = 2
+	VoteSchemaVersion     = 2
+	TallySchemaVersion    = 2
+)
+
+// checkSchemaVersion rejects records newer than current and returns the
+// version of the record, 1 when it has none
+func checkSchemaVersion(record string, version, current int) (int, error) {
+	if version == 0 {
+		return 1, nil
+	}
+	if version < 0 || version > current {
+		return 0, fmt.Errorf("%s schema version %d is not supported (current %d)", record, version, current)
+	}
+	return version, nil
+}
+
+// hasField reports whether a JSON object sets key, telling a field written
+// as its zero value from one written before the field existed
+func hasField(data []byte, key string) bool {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false
+	}
+	_, ok := fields[key]
+	return ok
+}
+
+// MarshalJSON stamps the current schema version
+func (e Election) MarshalJSON() ([]byte, error) {
+	type record Election
+	e.SchemaVersion = ElectionSchemaVersion
+	return json.Marshal(record(e))
+}
+
+// UnmarshalJSON reads an election of any supported version
+func (e *Election) UnmarshalJSON(data []byte) error {
+	type record Election
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	version, err := checkSchemaVersion("election", r.SchemaVersion, ElectionSchemaVersion)
+	if err != nil {
+		return err
+	}
+	if version < 2 {
+		// Version 1 elections predate voting modes and configurable
+		// verification codes
+		if r.VotingMode == "" {
+			r.VotingMode = VotingModeSingle
+		}
+		if r.VerificationCodeLength == 0 {
+			r.VerificationCodeLength = DefaultVerificationCodeLength
+		}
+	}
+	r.SchemaVersion = ElectionSchemaVersion
+	*e = Election(r)
+	return nil
+}
+
+// MarshalJSON stamps the current schema version
+func (v Vote) MarshalJSON() ([]byte, error) {
+	type record Vote
+	v.SchemaVersion = VoteSchemaVersion
+	return json.Marshal(record(v))
+}
+
+// UnmarshalJSON reads a vote of any supported version. Every field added
+// since version 1 is optional, so version 1 votes need no defaults.
+func (v *Vote) UnmarshalJSON(data []byte) error {
+	type record Vote
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	if _, err := checkSchemaVersion("vote", r.SchemaVersion, VoteSchemaVersion); err != nil {
+		return err
+	}
+	r.SchemaVersion = VoteSchemaVersion
+	*v = Vote(r)
+	return nil
+}
+
+// MarshalJSON stamps the current schema version
+func (t TallyResult) MarshalJSON() ([]byte, error) {
+	type record TallyResult
+	t.SchemaVersion = TallySchemaVersion
+	return json.Marshal(record(t))
+}
+
+// UnmarshalJSON reads a tally result of any supported version
+func (t *TallyResult) UnmarshalJSON(data []byte) error {
+	type record TallyResult
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	version, err := checkSchemaVersion("tally result", r.SchemaVersion, TallySchemaVersion)
+	if err != nil {
+		return err
+	}
+	if version < 2 {
+		// The first tally of an election is version 1
+		if r.Version == 0 {
+			r.Version = 1
+		}
+		// Before weights, abstentions and spoiled ballots every counted
+		// vote was one ballot
+		if !hasField(data, "ballotCount") {
+			r.BallotCount = r.TotalVotes
+		}
+		// Elections had no quorum before results recorded it
+		if !hasField(data, "quorumMet") {
+			r.QuorumMet = true
+			r.Valid = true
+		}
+	}
+	r.SchemaVersion = TallySchemaVersion
+	*t = TallyResult(r)
+	return nil
+}
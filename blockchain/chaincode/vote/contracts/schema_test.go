@@ -0,0 +1,93 @@
+/*
+ * Schema Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeVersion1Election(t *testing.T) {
+	// An election stored before voting modes and schema versions existed
+	v1 := `{"id": "election-001", "title": "Test", "status": "active", "voterMerkleRoot": "root",
+		"publicKey": "pk", "startTime": "2024-01-01T00:00:00Z", "endTime": "2024-01-02T00:00:00Z",
+		"createdAt": "2023-12-31T00:00:00Z"}`
+	var election Election
+	assert.NoError(t, json.Unmarshal([]byte(v1), &election))
+	assert.Equal(t, "election-001", election.ID)
+	assert.Equal(t, VotingModeSingle, election.VotingMode)
+	assert.Equal(t, DefaultVerificationCodeLength, election.VerificationCodeLength)
+	assert.Equal(t, ElectionSchemaVersion, election.SchemaVersion)
+	// Defaults that mean legacy behaviour are left alone
+	assert.Empty(t, election.MerkleScheme)
+	assert.False(t, election.NamespacedEvents)
+
+	// A current record is read as written
+	election.VerificationCodeLength = 24
+	electionJSON, _ := json.Marshal(election)
+	var reread Election
+	assert.NoError(t, json.Unmarshal(electionJSON, &reread))
+	assert.Equal(t, 24, reread.VerificationCodeLength)
+}
+
+func TestUpgradeVersion1Tally(t *testing.T) {
+	v1 := `{"electionId": "election-001", "voteCounts": {"default": {"1": 3, "2": 2}}, "totalVotes": 5,
+		"aggregatedHash": "hash", "decryptionProof": "proof", "tallyTimestamp": "2024-01-02T00:00:00Z",
+		"txId": "tx1"}`
+	var tally TallyResult
+	assert.NoError(t, json.Unmarshal([]byte(v1), &tally))
+	assert.Equal(t, 1, tally.Version)
+	assert.Equal(t, 5, tally.BallotCount)
+	assert.True(t, tally.QuorumMet)
+	assert.True(t, tally.Valid)
+	assert.Equal(t, TallySchemaVersion, tally.SchemaVersion)
+
+	// A result that recorded a missed quorum keeps it
+	missed := `{"electionId": "election-001", "voteCounts": {}, "totalVotes": 1, "version": 2,
+		"ballotCount": 1, "quorumMet": false, "valid": false}`
+	assert.NoError(t, json.Unmarshal([]byte(missed), &tally))
+	assert.Equal(t, 2, tally.Version)
+	assert.False(t, tally.QuorumMet)
+	assert.False(t, tally.Valid)
+}
+
+func TestSchemaVersionStampedOnWrite(t *testing.T) {
+	var vote Vote
+	assert.NoError(t, json.Unmarshal([]byte(`{"electionId": "election-001", "encryptedVote": "ct"}`), &vote))
+	assert.Equal(t, VoteSchemaVersion, vote.SchemaVersion)
+
+	for _, record := range []interface{}{Vote{}, &Election{}, TallyResult{}} {
+		recordJSON, err := json.Marshal(record)
+		assert.NoError(t, err)
+		var fields map[string]interface{}
+		assert.NoError(t, json.Unmarshal(recordJSON, &fields))
+		assert.Equal(t, float64(2), fields["schemaVersion"])
+	}
+
+	// Records from a newer chaincode are refused, not truncated
+	assert.Error(t, json.Unmarshal([]byte(`{"id": "election-001", "schemaVersion": 3}`), &Election{}))
+	assert.Error(t, json.Unmarshal([]byte(`{"electionId": "election-001", "schemaVersion": 3}`), &Vote{}))
+	assert.Error(t, json.Unmarshal([]byte(`{"electionId": "election-001", "schemaVersion": 3}`), &TallyResult{}))
+}
+
+func TestStoredRecordsCarrySchemaVersion(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	storeCompletedTally(t, ctx, stub, createMockElection(), `{"1": 1}`)
+	for _, key := range []string{electionKey("election-001"), tallyKey("election-001")} {
+		var fields map[string]interface{}
+		assert.NoError(t, json.Unmarshal(stub.State[key], &fields))
+		assert.Equal(t, float64(2), fields["schemaVersion"], key)
+	}
+	tally, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, TallySchemaVersion, tally.SchemaVersion)
+}
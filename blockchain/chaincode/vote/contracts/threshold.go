@@ -0,0 +1,226 @@
+/*
+ * Threshold Decryption - k-of-n trustee decryption of the encrypted tally
+ *
+ * The election secret x is Shamir-shared among n trustees by distributed key
+ * generation: trustee i holds x_i = f(i) for a degree k-1 polynomial with
+ * f(0) = x, and publishes h_i = g^x_i. Each trustee submits the partial
+ * decryption d_i = c1^x_i of every aggregate ciphertext with a Chaum-Pedersen
+ * proof against h_i. Any k partials combine by Lagrange interpolation in the
+ * exponent into d = c1^x, and c2 / d = g^m is solved for the count m.
+ *
+ * Partial decryptions are JSON maps of question ID to one decimal share per
+ * ciphertext; their proofs map question ID to one {"a","b","z"} object per
+ * ciphertext.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Trustee is one holder of a share of the election secret
+type Trustee struct {
+	ID string `json:"id"`
+	// Index is the trustee's Shamir evaluation point, starting at 1
+	Index int `json:"index"`
+	// PublicKey is the decimal verification key h_i = g^x_i
+	PublicKey string `json:"publicKey"`
+	// MSPID is the organization allowed to submit the trustee's partial decryption
+	MSPID string `json:"mspId"`
+}
+
+// PartialDecryption is a trustee's verified share of the tally decryption
+type PartialDecryption struct {
+	ElectionID    string              `json:"electionId"`
+	TrusteeID     string              `json:"trusteeId"`
+	Shares        map[string][]string `json:"shares"`
+	Proof         string              `json:"proof"`
+	AggregateHash string              `json:"aggregateHash"`
+	TxID          string              `json:"txId"`
+	SubmittedAt   time.Time           `json:"submittedAt"`
+}
+
+// DecryptionShareProof proves d_i = c1^x_i for a trustee's h_i = g^x_i
+type DecryptionShareProof struct {
+	A string `json:"a"`
+	B string `json:"b"`
+	Z string `json:"z"`
+}
+
+// partialDecryptionObjectType is the composite key namespace of partial
+// decryptions, partial~electionID~trusteeID
+const partialDecryptionObjectType = "partial"
+
+func findTrustee(trustees []Trustee, trusteeID string) *Trustee {
+	for i := range trustees {
+		if trustees[i].ID == trusteeID {
+			return &trustees[i]
+		}
+	}
+	return nil
+}
+
+// validateTrustees checks the trustee configuration of a threshold election
+// and that the first threshold verification keys interpolate to the
+// election public key
+func validateTrustees(publicKey string, trustees []Trustee, threshold int) error {
+	if len(trustees) == 0 {
+		return fmt.Errorf("threshold elections require trustees")
+	}
+	if threshold < 1 || threshold > len(trustees) {
+		return fmt.Errorf("threshold must be between 1 and %d", len(trustees))
+	}
+
+	key, err := parseElGamalPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[string]bool)
+	indices := make(map[int]bool)
+	for _, trustee := range trustees {
+		if trustee.ID == "" || ids[trustee.ID] {
+			return fmt.Errorf("trustee IDs must be unique and non-empty")
+		}
+		if trustee.Index < 1 || big.NewInt(int64(trustee.Index)).Cmp(key.Q) >= 0 || indices[trustee.Index] {
+			return fmt.Errorf("trustee %s: index must be unique and between 1 and q-1", trustee.ID)
+		}
+		if trustee.MSPID == "" {
+			return fmt.Errorf("trustee %s: mspId is required", trustee.ID)
+		}
+		h, err := parseBigInt("publicKey", trustee.PublicKey)
+		if err != nil {
+			return fmt.Errorf("trustee %s: %v", trustee.ID, err)
+		}
+		if !key.inGroup(h) {
+			return fmt.Errorf("trustee %s: public key is not in the group", trustee.ID)
+		}
+		ids[trustee.ID] = true
+		indices[trustee.Index] = true
+	}
+
+	quorum := sortedTrustees(trustees)[:threshold]
+	points := make([]*big.Int, len(quorum))
+	for i, trustee := range quorum {
+		points[i], _ = parseBigInt("publicKey", trustee.PublicKey)
+	}
+	if combineShares(key, quorum, points).Cmp(key.H) != 0 {
+		return fmt.Errorf("trustee public keys do not combine to the election public key")
+	}
+	return nil
+}
+
+// sortedTrustees returns trustees ordered by index
+func sortedTrustees(trustees []Trustee) []Trustee {
+	sorted := make([]Trustee, len(trustees))
+	copy(sorted, trustees)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Index < sorted[j].Index
+	})
+	return sorted
+}
+
+// lagrangeCoefficient is the coefficient of trustee j at 0 over the given
+// trustees: the product of k / (k - j) mod q for every other index k
+func lagrangeCoefficient(key *ElGamalPublicKey, trustees []Trustee, j int) *big.Int {
+	num, den := big.NewInt(1), big.NewInt(1)
+	for _, trustee := range trustees {
+		k := trustee.Index
+		if k == j {
+			continue
+		}
+		num.Mul(num, big.NewInt(int64(k))).Mod(num, key.Q)
+		den.Mul(den, big.NewInt(int64(k-j))).Mod(den, key.Q)
+	}
+	den.ModInverse(den, key.Q)
+	return num.Mul(num, den).Mod(num, key.Q)
+}
+
+// combineShares interpolates group elements shares[i] = base^f(index_i) of
+// trustees[i] into base^f(0)
+func combineShares(key *ElGamalPublicKey, trustees []Trustee, shares []*big.Int) *big.Int {
+	combined := big.NewInt(1)
+	for i, trustee := range trustees {
+		lambda := lagrangeCoefficient(key, trustees, trustee.Index)
+		combined.Mul(combined, new(big.Int).Exp(shares[i], lambda, key.P)).Mod(combined, key.P)
+	}
+	return combined
+}
+
+// verifyPartialDecryption checks a trustee's shares and proofs against the
+// aggregate and returns the parsed shares
+func verifyPartialDecryption(
+	key *ElGamalPublicKey,
+	trustee *Trustee,
+	aggregates map[string][]ElGamalCiphertext,
+	partialDecryption string,
+	proof string,
+) (map[string][]*big.Int, error) {
+	var rawShares map[string][]string
+	if err := json.Unmarshal([]byte(partialDecryption), &rawShares); err != nil {
+		return nil, fmt.Errorf("invalid partial decryption: %v", err)
+	}
+	var proofs map[string][]DecryptionShareProof
+	if err := json.Unmarshal([]byte(proof), &proofs); err != nil {
+		return nil, fmt.Errorf("invalid partial decryption proof: %v", err)
+	}
+	if len(rawShares) != len(aggregates) {
+		return nil, fmt.Errorf("expected shares for %d questions, got %d", len(aggregates), len(rawShares))
+	}
+
+	h, err := parseBigInt("publicKey", trustee.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make(map[string][]*big.Int, len(aggregates))
+	for questionID, ciphertexts := range aggregates {
+		questionShares := rawShares[questionID]
+		questionProofs := proofs[questionID]
+		if len(questionShares) != len(ciphertexts) || len(questionProofs) != len(ciphertexts) {
+			return nil, fmt.Errorf("question %s: expected %d shares and proofs", questionID, len(ciphertexts))
+		}
+
+		shares[questionID] = make([]*big.Int, len(ciphertexts))
+		for i, c := range ciphertexts {
+			d, err := parseBigInt("share", questionShares[i])
+			if err != nil {
+				return nil, fmt.Errorf("question %s, ciphertext %d: %v", questionID, i, err)
+			}
+			a, err := parseBigInt("a", questionProofs[i].A)
+			if err != nil {
+				return nil, fmt.Errorf("question %s, ciphertext %d: %v", questionID, i, err)
+			}
+			b, err := parseBigInt("b", questionProofs[i].B)
+			if err != nil {
+				return nil, fmt.Errorf("question %s, ciphertext %d: %v", questionID, i, err)
+			}
+			z, err := parseBigInt("z", questionProofs[i].Z)
+			if err != nil {
+				return nil, fmt.Errorf("question %s, ciphertext %d: %v", questionID, i, err)
+			}
+			if err := verifyDecryptionShare(key, h, c, d, a, b, z); err != nil {
+				return nil, fmt.Errorf("question %s, ciphertext %d: %v", questionID, i, err)
+			}
+			shares[questionID][i] = d
+		}
+	}
+	return shares, nil
+}
+
+// discreteLog finds m in [0, max] with g^m = gm
+func discreteLog(key *ElGamalPublicKey, gm *big.Int, max int) (int, error) {
+	x := big.NewInt(1)
+	for m := 0; m <= max; m++ {
+		if x.Cmp(gm) == 0 {
+			return m, nil
+		}
+		x.Mul(x, key.G).Mod(x, key.P)
+	}
+	return 0, fmt.Errorf("decrypted value exceeds %d", max)
+}
@@ -0,0 +1,212 @@
+/*
+ * Threshold Decryption Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTrusteeSecret is f(i) for f(t) = 7 + 3t + 5t^2 mod q, a 3-of-n sharing
+// of the test private key
+func testTrusteeSecret(i int) int64 {
+	t := int64(i)
+	return (testPrivateKey + 3*t + 5*t*t) % testQ
+}
+
+// testTrustees returns n trustees holding shares of the test private key
+func testTrustees(n int) []Trustee {
+	trustees := make([]Trustee, n)
+	for i := range trustees {
+		index := i + 1
+		h := new(big.Int).Exp(big.NewInt(testG), big.NewInt(testTrusteeSecret(index)), big.NewInt(testP))
+		trustees[i] = Trustee{
+			ID:        fmt.Sprintf("trustee%d", index),
+			Index:     index,
+			PublicKey: h.String(),
+			MSPID:     fmt.Sprintf("Trustee%dMSP", index),
+		}
+	}
+	return trustees
+}
+
+// testPartialDecryption decrypts every aggregate ciphertext with secret and
+// proves each share
+func testPartialDecryption(aggregates map[string][]CiphertextJSON, secret int64) (string, string) {
+	key, _ := parseElGamalPublicKey(testPublicKeyJSON())
+	x := big.NewInt(secret)
+	h := new(big.Int).Exp(key.G, x, key.P)
+
+	shares := make(map[string][]string)
+	proofs := make(map[string][]DecryptionShareProof)
+	for questionID, raw := range aggregates {
+		ciphertexts, _ := ciphertextsFromJSON(raw)
+		for i, c := range ciphertexts {
+			w := big.NewInt(int64(31 + i))
+			d := new(big.Int).Exp(c.C1, x, key.P)
+			a := new(big.Int).Exp(key.G, w, key.P)
+			b := new(big.Int).Exp(c.C1, w, key.P)
+			e := chaumPedersenChallenge(key, h, c, d, a, b)
+			z := new(big.Int).Mul(e, x)
+			z.Add(z, w).Mod(z, key.Q)
+			shares[questionID] = append(shares[questionID], d.String())
+			proofs[questionID] = append(proofs[questionID], DecryptionShareProof{A: a.String(), B: b.String(), Z: z.String()})
+		}
+	}
+	sharesJSON, _ := json.Marshal(shares)
+	proofsJSON, _ := json.Marshal(proofs)
+	return string(sharesJSON), string(proofsJSON)
+}
+
+func setupThresholdElection(t *testing.T) (*VoteContract, *MockTransactionContext, map[string][]CiphertextJSON) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.PublicKey = testPublicKeyJSON()
+	election.Trustees = testTrustees(5)
+	election.Threshold = 3
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	for i, choice := range []int{0, 2, 0, 1} {
+		_, err := contract.CastVote(ctx, "election-001", testBallot(choice, 3, int64(10*i+3)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+	_, err := contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+	return contract, ctx, map[string][]CiphertextJSON{DefaultQuestionID: aggregate.Ciphertexts}
+}
+
+// submitAs submits a partial decryption from the trustee's organization
+func submitAs(contract *VoteContract, ctx *MockTransactionContext, index int, shares, proof string) error {
+	ctx.Identity = &MockClientIdentity{MSPID: fmt.Sprintf("Trustee%dMSP", index)}
+	defer func() { ctx.Identity = nil }()
+	return contract.SubmitPartialDecryption(ctx, "election-001", fmt.Sprintf("trustee%d", index), shares, proof)
+}
+
+func TestThresholdDecryption(t *testing.T) {
+	contract, ctx, aggregates := setupThresholdElection(t)
+
+	// A single authority cannot store the tally of a threshold election
+	err := contract.StoreTallyResult(ctx, "election-001", `{"0": 2, "1": 1, "2": 1}`, "hash", "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "threshold decryption")
+
+	shares, proof := testPartialDecryption(aggregates, testTrusteeSecret(1))
+	assert.NoError(t, submitAs(contract, ctx, 1, shares, proof))
+
+	// Trustee 2 never submits. Trustee 3 decrypts with the wrong share.
+	shares, proof = testPartialDecryption(aggregates, testTrusteeSecret(3)+1)
+	err = submitAs(contract, ctx, 3, shares, proof)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "partial decryption rejected")
+
+	shares, proof = testPartialDecryption(aggregates, testTrusteeSecret(4))
+	assert.NoError(t, submitAs(contract, ctx, 4, shares, proof))
+
+	// Two valid partials are below the threshold
+	err = contract.CombinePartialDecryptions(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 3")
+
+	shares, proof = testPartialDecryption(aggregates, testTrusteeSecret(5))
+	assert.NoError(t, submitAs(contract, ctx, 5, shares, proof))
+
+	assert.NoError(t, contract.CombinePartialDecryptions(ctx, "election-001"))
+
+	result, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"0": 2, "1": 1, "2": 1}, result.VoteCounts[DefaultQuestionID])
+	assert.Equal(t, 4, result.TotalVotes)
+	assert.Equal(t, []string{"trustee1", "trustee4", "trustee5"}, result.Trustees)
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "completed", election.Status)
+
+	// The combined tally verifies against the counted votes
+	verification, err := contract.VerifyTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.True(t, verification.Verified)
+	assert.Equal(t, 4, verification.VoteCount)
+
+	// Verifiers get the combined partials in place of a decryption proof
+	bundle, err := contract.GetTallyProofBundle(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, bundle.Threshold)
+	assert.Len(t, bundle.Trustees, 5)
+	if assert.Len(t, bundle.PartialDecryptions, 3) {
+		assert.Equal(t, "trustee4", bundle.PartialDecryptions[1].TrusteeID)
+		assert.Equal(t, bundle.Aggregate.AggregateHash, bundle.PartialDecryptions[1].AggregateHash)
+	}
+}
+
+func TestSubmitPartialDecryptionRequiresTrusteeMSP(t *testing.T) {
+	contract, ctx, aggregates := setupThresholdElection(t)
+
+	shares, proof := testPartialDecryption(aggregates, testTrusteeSecret(1))
+	ctx.Identity = &MockClientIdentity{MSPID: "Trustee2MSP"}
+	err := contract.SubmitPartialDecryption(ctx, "election-001", "trustee1", shares, proof)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	ctx.Identity = nil
+	err = contract.SubmitPartialDecryption(ctx, "election-001", "trustee9", shares, proof)
+	assert.Error(t, err)
+}
+
+func TestValidateTrustees(t *testing.T) {
+	publicKey := testPublicKeyJSON()
+
+	assert.NoError(t, validateTrustees(publicKey, testTrustees(5), 3))
+
+	// A 3-of-n sharing does not interpolate from two keys
+	assert.Error(t, validateTrustees(publicKey, testTrustees(5), 2))
+	assert.Error(t, validateTrustees(publicKey, testTrustees(5), 6))
+	assert.Error(t, validateTrustees(publicKey, nil, 1))
+
+	duplicate := testTrustees(3)
+	duplicate[2].Index = 1
+	assert.Error(t, validateTrustees(publicKey, duplicate, 3))
+
+	noMSP := testTrustees(3)
+	noMSP[0].MSPID = ""
+	assert.Error(t, validateTrustees(publicKey, noMSP, 3))
+}
+
+func TestCreateThresholdElection(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	config, _ := json.Marshal(ElectionConfig{Trustees: testTrustees(5), Threshold: 3})
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+
+	election, err := new(VoteContract).GetElection(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Len(t, election.Trustees, 5)
+	assert.Equal(t, 3, election.Threshold)
+
+	config, _ = json.Marshal(ElectionConfig{Trustees: testTrustees(5)})
+	_, err = new(VoteContract).CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.Error(t, err)
+}
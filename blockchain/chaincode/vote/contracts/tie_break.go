@@ -0,0 +1,85 @@
+/*
+ * Tie Break - Reproducible ordering of tied winners
+ *
+ * When options tie for the most votes, the tally records a tie-break order
+ * among them so the outcome does not depend on who breaks the tie. Each
+ * tied option is ranked by the hash, under the election's algorithm, of
+ * "seed|questionID|option", lowest first, where the seed is the Merkle root
+ * of the vote set the tally covers (the closed-vote root). Nobody can steer
+ * the order without changing the votes, and anyone holding the root can
+ * recompute it.
+ */
+
+package contracts
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// tiedLeaders returns the options sharing the highest non-zero count, in
+// name order, or nil when one option leads alone
+func tiedLeaders(counts map[string]int) []string {
+	maxVotes := 0
+	var leaders []string
+	for option, votes := range counts {
+		if isReservedOption(option) || votes == 0 {
+			continue
+		}
+		if votes > maxVotes {
+			maxVotes = votes
+			leaders = []string{option}
+		} else if votes == maxVotes {
+			leaders = append(leaders, option)
+		}
+	}
+	if len(leaders) < 2 {
+		return nil
+	}
+	sort.Strings(leaders)
+	return leaders
+}
+
+// breakTie orders tied options by their seeded hash
+func breakTie(election *Election, seed, questionID string, tied []string) []string {
+	keys := make(map[string]string, len(tied))
+	for _, option := range tied {
+		keys[option] = hashForElection(election, seed+"|"+questionID+"|"+option)
+	}
+	order := append([]string(nil), tied...)
+	sort.Slice(order, func(i, j int) bool {
+		return keys[order[i]] < keys[order[j]]
+	})
+	return order
+}
+
+// tieBreakOrders returns the tie-break order of every question whose lead
+// is tied, keyed by question ID, or nil when there is no tie. The seed is
+// the closed-vote root, recomputed for elections closed without one.
+func (v *VoteContract) tieBreakOrders(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	voteCounts map[string]map[string]int,
+) (map[string][]string, error) {
+	var orders map[string][]string
+	seed := election.ClosedVoteRoot
+	for questionID, counts := range voteCounts {
+		tied := tiedLeaders(counts)
+		if tied == nil {
+			continue
+		}
+		if seed == "" {
+			root, err := v.computeClosedVoteRoot(ctx, election)
+			if err != nil {
+				return nil, err
+			}
+			seed = root
+		}
+		if orders == nil {
+			orders = make(map[string][]string)
+		}
+		orders[questionID] = breakTie(election, seed, questionID, tied)
+	}
+	return orders, nil
+}
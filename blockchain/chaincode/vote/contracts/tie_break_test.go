@@ -0,0 +1,84 @@
+/*
+ * Tie Break Tests
+ */
+
+package contracts
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTiedLeaders(t *testing.T) {
+	assert.Equal(t, []string{"alice", "carol"}, tiedLeaders(map[string]int{"carol": 2, "bob": 1, "alice": 2}))
+	assert.Nil(t, tiedLeaders(map[string]int{"alice": 2, "bob": 1}))
+	// Nobody leads an empty race, and reserved options never win
+	assert.Nil(t, tiedLeaders(map[string]int{"alice": 0, "bob": 0}))
+	assert.Nil(t, tiedLeaders(map[string]int{"alice": 2, OptionAbstain: 2}))
+}
+
+func TestBreakTieIsReproducible(t *testing.T) {
+	election := createMockElection()
+	seed := computeVoteSetRoot(MerkleSchemeV2, []string{hashString("vote1"), hashString("vote2")})
+	tied := []string{"alice", "bob", "carol", "dave"}
+
+	order := breakTie(election, seed, DefaultQuestionID, tied)
+	assert.ElementsMatch(t, tied, order)
+
+	// The order follows the seeded hashes, whatever order the tie is found in
+	expected := append([]string(nil), tied...)
+	sort.Slice(expected, func(i, j int) bool {
+		return hashString(seed+"|"+DefaultQuestionID+"|"+expected[i]) < hashString(seed+"|"+DefaultQuestionID+"|"+expected[j])
+	})
+	assert.Equal(t, expected, order)
+	for run := 0; run < 10; run++ {
+		shuffled := []string{tied[run%4], tied[(run+1)%4], tied[(run+2)%4], tied[(run+3)%4]}
+		assert.Equal(t, order, breakTie(election, seed, DefaultQuestionID, shuffled))
+	}
+	assert.Equal(t, []string{"alice", "bob", "carol", "dave"}, tied, "input is not reordered")
+}
+
+func TestStoreTallyResultRecordsTieBreak(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Questions = []Question{
+		{ID: "mayor", Options: []string{"alice", "bob", "carol"}},
+		{ID: "budget", Options: []string{"yes", "no"}},
+	}
+	election.ClosedVoteRoot = computeVoteSetRoot(electionMerkleScheme(election), nil)
+	storeCompletedTally(t, ctx, stub, election, `{"mayor": {"alice": 2, "bob": 1, "carol": 2}, "budget": {"yes": 3, "no": 1}}`)
+
+	tally, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	expected := breakTie(election, election.ClosedVoteRoot, "mayor", []string{"alice", "carol"})
+	assert.Equal(t, map[string][]string{"mayor": expected}, tally.TieBreakOrder)
+
+	results, err := contract.GetElectionResults(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "carol"}, results.Questions[0].Winners)
+	assert.Equal(t, expected, results.Questions[0].TieBreakOrder)
+	assert.Empty(t, results.Questions[1].TieBreakOrder)
+
+	// A recount of the same tie records the same order
+	assert.NoError(t, contract.ReopenTally(ctx, "election-001", "recount"))
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001",
+		`{"mayor": {"carol": 2, "alice": 2, "bob": 1}, "budget": {"yes": 3, "no": 1}}`, "hash", "proof"))
+	recount, _ := contract.GetTallyResult(ctx, "election-001")
+	assert.Equal(t, 2, recount.Version)
+	assert.Equal(t, tally.TieBreakOrder, recount.TieBreakOrder)
+
+	// A clear result records no tie break
+	reset := NewMockStub()
+	ctx = new(MockTransactionContext)
+	ctx.On("GetStub").Return(reset)
+	storeCompletedTally(t, ctx, reset, createMockElection(), `{"1": 50, "2": 30}`)
+	tally, _ = contract.GetTallyResult(ctx, "election-001")
+	assert.Nil(t, tally.TieBreakOrder)
+}
@@ -3,28 +3,89 @@
  *
  * This chaincode implements the core voting functionality:
  * - CastVote: Record encrypted votes with ZKP verification
+ * - CastVoteWithProof: Record encrypted votes with on-chain ZKP verification
+ * - CastVoteMultiQuestion: Record one encrypted ballot per question
+ * - CastVotePrivate: Record a vote whose ciphertext is kept in a private data collection
+ * - CastVoteBatch: Record a buffer of offline-collected ballots in one transaction
+ * - CastVoteWeighted: Record a vote carrying the weight its eligibility proof commits to
+ * - DelegateVote: Hand a vote to another voter (liquid democracy)
+ * - InvalidateVote: Exclude a ballot proven fraudulent from the tally, keeping its record
+ * - ValidateVote: Dry-run CastVote's checks without writing anything
  * - GetVote: Retrieve vote records
+ * - GetVoteHistory: Every version of a vote replaced under AllowRevote
  * - GetAllVotes: Get all votes for an election
+ * - GetAllVotesPaginated: Get one page of votes for an election
+ * - GetVotesSince: Votes after a bulletin board sequence, for incremental indexing
+ * - GetVotesByBlockRange: Votes confirmed in a range of blocks, for audits
+ * - GetVoteProof: Full proofs of a vote, for elections that store them
+ * - GetVoteReceipt: Rebuild the receipt of a stored vote for a voter who lost it
  * - VerifyVote: Verify vote existence and integrity
+ * - VerifyVotesBatch: VerifyVote for many receipts in one read-only call
+ * - GetNullifierSet: Used nullifier commitments, for double-vote checks across channels
+ * - ProveNonMembership: Prove a nullifier was never used in an election
+ * - ComputeVerificationCode: Rebuild a receipt's verification code
+ * - VerifyReceiptSignature: Check a receipt was signed by the election's key
+ * - GetVoteByVerificationCode: Find the vote a receipt's verification code belongs to
+ * - AggregateEncryptedVotes: Homomorphically combine all ballots for decryption
+ * - GetEncryptedWriteIns: Encrypted write-ins of the counted votes, for decryption
+ * - StartTallying: Move a closed election to tallying
  * - StoreTallyResult: Record tally results
+ * - TallyRankedChoice: Run instant-runoff rounds over decrypted ranked ballots
+ * - SubmitPartialDecryption: Record a trustee's verified share of a threshold decryption
+ * - CombinePartialDecryptions: Combine a quorum of trustee shares into the tally
  * - GetTallyResult: Retrieve tally results
+ * - GetAllTalliesPaginated: Get one page of tally results across elections
+ * - ArchiveElection: Purge the per-vote state of a long-completed election
+ * - ReopenTally: Return a completed election to tallying for a recount
+ * - SetTallyEndorsementPolicy: Let only the tally authority endorse tally writes
+ * - VerifyTallyResult: Check a tally covered exactly the ballots on the ledger
+ * - GetElectionResults: Tally with percentages and winners per question
+ * - GetTallyProofBundle: Everything needed to verify a tally in one structure
+ * - GetBulletinBoardRange: Bulletin board entries and root for a sequence range
+ * - GetBulletinBoardRoot: Incrementally maintained bulletin board root
+ * - GetLatestBulletinCheckpoint: Signed bulletin board size and root for light clients
+ * - VerifyBulletinChain: Check the hash chain linking bulletin board entries
+ * - GetVoteInclusionProof: Merkle path proving a vote is on the bulletin board
+ * - UpdateElectionPublicKey: Replace a pending election's key after a new key ceremony
+ * - UpdateBallotOptions: Replace a question's options before the ballot is frozen
+ * - FreezeBallot: Lock a pending election's questions, options and keys
+ * - GetElectionsByStatus: List elections by their current status
+ * - GetElectionSummary: Status, turnout, timing and board root for a dashboard card
+ * - GetElectionMetrics: Vote, duplicate attempt and transition counters for monitoring
+ * - GetElectionPublicParameters: Everything a voting client needs to encrypt a ballot
+ * - GetCurrentTime: The transaction time voting windows are checked against
  */
 
 package contracts
 
 import (
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	// Embedded so every peer validates timezones against the same database
+	_ "time/tzdata"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
 // VoteContract implements the voting chaincode
 type VoteContract struct {
 	contractapi.Contract
+	// AdminMSPIDs lists organizations whose members may run administrative functions
+	AdminMSPIDs []string
 }
 
 // CandidateSelection represents a single candidate vote
@@ -38,7 +99,7 @@ type Vote struct {
 	ElectionID           string               `json:"electionId"`
 	EncryptedVote        string               `json:"encryptedVote"`
 	EncryptedVoteHash    string               `json:"encryptedVoteHash"`
-	Nullifier            string               `json:"nullifier"`
+	NullifierCommitment  string               `json:"nullifierCommitment"`
 	EligibilityProofHash string               `json:"eligibilityProofHash"`
 	ValidityProofHash    string               `json:"validityProofHash"`
 	Timestamp            time.Time            `json:"timestamp"`
@@ -47,6 +108,53 @@ type Vote struct {
 	// 투표 방식별 추가 필드
 	VotingPeriod         int                  `json:"votingPeriod"`
 	CandidateSelections  []CandidateSelection `json:"candidateSelections,omitempty"`
+	// 문항별 암호화 투표 (questionID -> encryptedVote)
+	QuestionVotes map[string]string `json:"questionVotes,omitempty"`
+	// 암호문이 저장된 private data collection (비어 있으면 world state)
+	PrivateCollection string `json:"privateCollection,omitempty"`
+	// 가중 투표의 투표권 수 (0 = 가중치 없음)
+	Weight int `json:"weight,omitempty"`
+	// 게시판 순번 (GetVotesSince 증분 조회용)
+	BulletinSequence int `json:"bulletinSequence,omitempty"`
+	// 무효 처리 (부정 투표로 판명, 집계 제외)
+	Invalidated        bool   `json:"invalidated,omitempty"`
+	InvalidationReason string `json:"invalidationReason,omitempty"`
+	// 종료 시각 이후 유예 시간 중 접수됨
+	InGracePeriod bool `json:"inGracePeriod,omitempty"`
+	// 암호화된 직접 기입 후보 (선택)
+	EncryptedWriteIn string `json:"encryptedWriteIn,omitempty"`
+	// 키오스크가 서명한 실제 투표 시각 (Timestamp는 원장 기록 시각)
+	CastTime *time.Time `json:"castTime,omitempty"`
+	// 레코드 스키마 버전 (없으면 1, schema.go 참고)
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// PrivateBallotCollection is the private data collection holding ciphertexts
+// of votes cast with CastVotePrivate (see collections_config.json)
+const PrivateBallotCollection = "encryptedBallots"
+
+// PrivateVote is the part of a vote kept in the private data collection
+type PrivateVote struct {
+	ElectionID          string `json:"electionId"`
+	NullifierCommitment string `json:"nullifierCommitment"`
+	EncryptedVote       string `json:"encryptedVote"`
+	EncryptedVoteHash   string `json:"encryptedVoteHash"`
+}
+
+// VoteQueryResult identifies a vote matched by a query without its ciphertext
+type VoteQueryResult struct {
+	EncryptedVoteHash string    `json:"encryptedVoteHash"`
+	TxID              string    `json:"txId"`
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// VoteRecord is a ballot returned by GetAllVotes with what links it back
+// to its verification record
+type VoteRecord struct {
+	EncryptedVote       string `json:"encryptedVote"`
+	EncryptedVoteHash   string `json:"encryptedVoteHash"`
+	NullifierCommitment string `json:"nullifierCommitment"`
+	Invalidated         bool   `json:"invalidated,omitempty"`
 }
 
 // VoteReceipt is returned after a successful vote
@@ -57,6 +165,10 @@ type VoteReceipt struct {
 	TxID              string    `json:"txId"`
 	BlockNumber       uint64    `json:"blockNumber"`
 	Timestamp         time.Time `json:"timestamp"`
+	// Signature is the election's Ed25519 signature over the receipt (hex)
+	Signature string `json:"signature,omitempty"`
+	// Error says why a vote was not recorded when Success is false
+	Error string `json:"error,omitempty"`
 }
 
 // VotingMode defines the voting mode type
@@ -68,6 +180,20 @@ const (
 	VotingModePeriodicReset VotingMode = "periodic_reset" // 주기적 리셋 투표
 )
 
+// DefaultQuestionID identifies the single implicit question of an election
+// created without Questions
+const DefaultQuestionID = "default"
+
+// Question is one race on a multi-question ballot
+type Question struct {
+	ID      string   `json:"id"`
+	Text    string   `json:"text"`
+	Options []string `json:"options"`
+	// PublicKey encrypts this contest when it is decrypted by its own
+	// trustees; empty means the election public key
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
 // Election represents an election configuration
 type Election struct {
 	ID              string    `json:"id"`
@@ -83,8 +209,233 @@ type Election struct {
 	MaxCandidatesPerVoter  int        `json:"maxCandidatesPerVoter"`  // MULTI_LIMITED
 	MaxVotesPerCandidate   int        `json:"maxVotesPerCandidate"`   // MULTI_LIMITED
 	ResetIntervalHours     int        `json:"resetIntervalHours"`     // PERIODIC_RESET
+	// 영지식 증명 검증 설정
+	ProofSystem             string `json:"proofSystem,omitempty"`
+	EligibilityVerifyingKey string `json:"eligibilityVerifyingKey,omitempty"`
+	ValidityVerifyingKey    string `json:"validityVerifyingKey,omitempty"`
+	// 증명 공개 입력 구성 (입력 순서별 이름, 호출 인자와 대조)
+	EligibilityPublicInputs []string `json:"eligibilityPublicInputs,omitempty"`
+	ValidityPublicInputs    []string `json:"validityPublicInputs,omitempty"`
+	// 취소 사유
+	CancellationReason string `json:"cancellationReason,omitempty"`
+	// 다중 문항 설정 (비어 있으면 단일 기본 문항)
+	Questions []Question `json:"questions,omitempty"`
+	// 단일 문항 후보 ID 목록 (비어 있으면 집계 키 제한 없음)
+	Options []string `json:"options,omitempty"`
+	// 투표 용지 형식 (비어 있으면 단일 선택, ranked = 선호 순위)
+	BallotType string `json:"ballotType,omitempty"`
+	// 투표 용지 구조 동결 (활성화 전 FreezeBallot, 문항·후보·키 변경 불가)
+	BallotFrozen bool `json:"ballotFrozen,omitempty"`
+	// 게시판 머클 트리 방식 (비어 있으면 v1)
+	MerkleScheme string `json:"merkleScheme,omitempty"`
+	// 해시 알고리즘 (비어 있으면 SHA-256)
+	HashAlgorithm string `json:"hashAlgorithm,omitempty"`
+	// 최대 투표자 수 (0 = 무제한)
+	MaxVoters int `json:"maxVoters,omitempty"`
+	// 투표 수 카운터 샤드 수 (0 = 단일 키)
+	VoteCountShards int `json:"voteCountShards,omitempty"`
+	// 다른 널리파이어로 같은 암호문 재투표 거부
+	UniqueCiphertexts bool `json:"uniqueCiphertexts,omitempty"`
+	// 투표 증명 원문 보관 위치 (비어 있으면 해시만 저장)
+	ProofStorage string `json:"proofStorage,omitempty"`
+	// 마감 시점 투표 집합 머클 루트 (집계 시 검증)
+	ClosedVoteRoot string `json:"closedVoteRoot,omitempty"`
+	// 종료 후 투표 유예 시간 (초, 0 = 없음)
+	GracePeriodSeconds int `json:"gracePeriodSeconds,omitempty"`
+	// 중복 투표 시도 게시판 기록
+	RecordDuplicateAttempts bool `json:"recordDuplicateAttempts,omitempty"`
+	// 이벤트 이름에 선거 ID 포함 (false = 단일 이름)
+	NamespacedEvents bool `json:"namespacedEvents,omitempty"`
+	// 정족수 (1 미만 = 유권자 대비 비율, 1 이상 = 투표 수, 0 = 없음)
+	Quorum float64 `json:"quorum,omitempty"`
+	// 선거인 명부 인원 (maxVoters 없을 때 정족수 기준)
+	VoterRollSize int `json:"voterRollSize,omitempty"`
+	// 집계 허용치 (장부 외 기권·무효표 수, 0 = 장부 투표 수까지만)
+	TallyAllowance int `json:"tallyAllowance,omitempty"`
+	// 집계 결과 보증 조직 (키 수준 보증 정책)
+	TallyEndorsers []string `json:"tallyEndorsers,omitempty"`
+	// 재집계 이력 (마지막으로 저장된 집계 결과 버전)
+	TallyVersion int `json:"tallyVersion,omitempty"`
+	// 가중 투표 (주주·대의원 선거)
+	Weighted bool `json:"weighted,omitempty"`
+	// 영수증 검증 코드 길이 (16진수 문자 수, 0 = 16)
+	VerificationCodeLength int `json:"verificationCodeLength,omitempty"`
+	// 널리파이어 형식 (비어 있으면 임의 문자열, 길이 0 = 제한 없음)
+	NullifierFormat string `json:"nullifierFormat,omitempty"`
+	NullifierLength int    `json:"nullifierLength,omitempty"`
+	// 재투표 허용 (마지막 투표만 집계, 강요 방지)
+	AllowRevote bool `json:"allowRevote,omitempty"`
+	// 위임 투표 허용 (리퀴드 민주주의)
+	AllowDelegation bool `json:"allowDelegation,omitempty"`
+	// 직접 기입 후보 허용 (후보 목록 뒤 기입 채널)
+	AllowWriteIns bool `json:"allowWriteIns,omitempty"`
+	// 영수증 서명 공개키 (Ed25519, hex)
+	ReceiptPublicKey string `json:"receiptPublicKey,omitempty"`
+	// 오프라인 키오스크 서명 공개키 (Ed25519, hex) 및 투표 시각 허용 오차 (초)
+	KioskPublicKeys     []string `json:"kioskPublicKeys,omitempty"`
+	CastTimeSkewSeconds int      `json:"castTimeSkewSeconds,omitempty"`
+	// 복호화 증명 방식 (비어 있으면 검증하지 않음)
+	DecryptionProofScheme string `json:"decryptionProofScheme,omitempty"`
+	// 투표 범위 증명 방식 및 허용 범위 (비어 있으면 검증하지 않음)
+	RangeProofScheme string       `json:"rangeProofScheme,omitempty"`
+	BallotRange      *BallotRange `json:"ballotRange,omitempty"`
+	// 임계값 복호화 위원 (k-of-n, 비어 있으면 단일 집계 기관)
+	Trustees  []Trustee `json:"trustees,omitempty"`
+	Threshold int       `json:"threshold,omitempty"`
+	// 표시용 시간대 (IANA) 및 로캘 (BCP 47), 시간 검사는 항상 UTC
+	Timezone string `json:"timezone,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+	// 레코드 스키마 버전 (없으면 1, schema.go 참고)
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// StartTimeTolerance is how far in the past a new election may start, so
+// clock skew and slow submission do not reject a window that just opened
+const StartTimeTolerance = 24 * time.Hour
+
+// MaxGracePeriodSeconds bounds how long after EndTime votes may still arrive
+const MaxGracePeriodSeconds = 3600
+
+// MaxElectionIDLength bounds election IDs, which are part of every key an
+// election owns
+const MaxElectionIDLength = 64
+
+// ElectionConfig holds the optional settings accepted by CreateElectionWithConfig
+type ElectionConfig struct {
+	VotingMode            VotingMode `json:"votingMode"`
+	MaxCandidatesPerVoter int        `json:"maxCandidatesPerVoter"`
+	MaxVotesPerCandidate  int        `json:"maxVotesPerCandidate"`
+	ResetIntervalHours    int        `json:"resetIntervalHours"`
+	// Verifying keys enable on-chain proof verification in CastVoteWithProof
+	ProofSystem             string `json:"proofSystem,omitempty"`
+	EligibilityVerifyingKey string `json:"eligibilityVerifyingKey,omitempty"`
+	ValidityVerifyingKey    string `json:"validityVerifyingKey,omitempty"`
+	// EligibilityPublicInputs and ValidityPublicInputs name the public
+	// inputs of each proof in order, so the contract can check them against
+	// the ballot (see public_inputs.go). An eligibility key needs the
+	// nullifier and voter root, a validity key the ballot hash.
+	EligibilityPublicInputs []string `json:"eligibilityPublicInputs,omitempty"`
+	ValidityPublicInputs    []string `json:"validityPublicInputs,omitempty"`
+	// Questions turns the election into a multi-question ballot
+	Questions []Question `json:"questions,omitempty"`
+	// Options are the candidate IDs of a single-question election, in
+	// ballot order; tallies may only count these
+	Options []string `json:"options,omitempty"`
+	// BallotType "ranked" makes each ballot an ordered preference list
+	// over Options, tallied by instant runoff with TallyRankedChoice
+	BallotType string `json:"ballotType,omitempty"`
+	// MerkleScheme selects the bulletin board tree hashing (default v2)
+	MerkleScheme string `json:"merkleScheme,omitempty"`
+	// HashAlgorithm is the digest for vote hashes, verification codes, the
+	// bulletin board and its Merkle trees: "sha256" (default), "keccak256"
+	// for Ethereum verifiers, or "sha3-256"
+	HashAlgorithm string `json:"hashAlgorithm,omitempty"`
+	// MaxVoters caps the number of votes accepted; 0 means unlimited
+	MaxVoters int `json:"maxVoters,omitempty"`
+	// VoteCountShards is how many keys the vote counter is striped over
+	// (default 16, at most 256). Votes still conflict on the bulletin
+	// board keys (see vote_count.go).
+	VoteCountShards int `json:"voteCountShards,omitempty"`
+	// UniqueCiphertexts rejects a ballot whose ciphertext was already cast
+	// under another nullifier, so a copied ballot cannot be counted again.
+	// Leave it off for schemes that may repeat ciphertexts.
+	UniqueCiphertexts bool `json:"uniqueCiphertexts,omitempty"`
+	// ProofStorage keeps the full proofs of every vote for later audits, in
+	// the world state ("state") or the voteProofs collection ("private")
+	ProofStorage string `json:"proofStorage,omitempty"`
+	// GracePeriodSeconds keeps accepting votes this long after EndTime, so
+	// a voter who submitted just before the deadline is not turned away by
+	// network latency. Such votes are flagged InGracePeriod.
+	GracePeriodSeconds int `json:"gracePeriodSeconds,omitempty"`
+	// RecordDuplicateAttempts logs every CastVote rejected for a used
+	// nullifier as a "duplicate_attempt" bulletin board entry holding the
+	// nullifier commitment. A failed transaction commits nothing, so such a
+	// CastVote succeeds with an unsuccessful receipt instead of an error.
+	RecordDuplicateAttempts bool `json:"recordDuplicateAttempts,omitempty"`
+	// LegacyEventNames emits flat event names such as "VoteCast" instead of
+	// "VoteCast:<electionID>", for listeners that predate namespacing
+	LegacyEventNames bool `json:"legacyEventNames,omitempty"`
+	// Quorum is the turnout a result needs to be valid: below 1 a fraction
+	// of the electorate (MaxVoters, else VoterRollSize), otherwise a number
+	// of ballots. Results that fall short are still tallied.
+	Quorum float64 `json:"quorum,omitempty"`
+	// VoterRollSize is the number of voters on the roll behind the voter
+	// Merkle root
+	VoterRollSize int `json:"voterRollSize,omitempty"`
+	// TallyAllowance is how many abstentions and spoiled ballots a tally may
+	// count beyond the ballots on the ledger, e.g. ones cast on paper
+	TallyAllowance int `json:"tallyAllowance,omitempty"`
+	// TallyEndorsers are the MSPs that must endorse the tally result
+	TallyEndorsers []string `json:"tallyEndorsers,omitempty"`
+	// MinDurationMinutes rejects voting windows shorter than this
+	MinDurationMinutes int `json:"minDurationMinutes,omitempty"`
+	// Weighted elections count each ballot with the weight committed to by
+	// its eligibility proof; an eligibility verifying key is required
+	Weighted bool `json:"weighted,omitempty"`
+	// VerificationCodeLength is the receipt code length in hex characters
+	// (default 16, at most 32)
+	VerificationCodeLength int `json:"verificationCodeLength,omitempty"`
+	// NullifierFormat restricts the nullifiers accepted ("hex"); with
+	// NullifierLength every nullifier must have exactly that many characters
+	// (default 64 for hex)
+	NullifierFormat string `json:"nullifierFormat,omitempty"`
+	NullifierLength int    `json:"nullifierLength,omitempty"`
+	// AllowRevote lets a voter replace their vote by casting again with the
+	// same nullifier; only the last vote is counted
+	AllowRevote bool `json:"allowRevote,omitempty"`
+	// AllowDelegation lets a voter delegate their vote with DelegateVote
+	// instead of casting a ballot
+	AllowDelegation bool `json:"allowDelegation,omitempty"`
+	// AllowWriteIns adds a write-in channel after Options and lets ballots
+	// carry an encrypted write-in (see write_in.go)
+	AllowWriteIns bool `json:"allowWriteIns,omitempty"`
+	// KioskPublicKeys are the Ed25519 keys (hex) of offline kiosks whose
+	// signed cast times are recorded with their ballots, when within
+	// CastTimeSkewSeconds (default one day) of the transaction (see
+	// cast_time.go)
+	KioskPublicKeys     []string `json:"kioskPublicKeys,omitempty"`
+	CastTimeSkewSeconds int      `json:"castTimeSkewSeconds,omitempty"`
+	// DecryptionProofScheme enables verification of the decryption proof
+	// passed to StoreTallyResult (e.g. "chaum-pedersen")
+	DecryptionProofScheme string `json:"decryptionProofScheme,omitempty"`
+	// RangeProofScheme makes every ballot carry a validity proof that it is
+	// within BallotRange (e.g. "disjunctive-chaum-pedersen"); BallotRange
+	// defaults to 0 or 1 per option with any number of selections
+	RangeProofScheme string       `json:"rangeProofScheme,omitempty"`
+	BallotRange      *BallotRange `json:"ballotRange,omitempty"`
+	// Trustees jointly decrypt the tally; any Threshold of them suffice.
+	// The election public key must be the one produced by their key generation.
+	Trustees  []Trustee `json:"trustees,omitempty"`
+	Threshold int       `json:"threshold,omitempty"`
+	// Timezone (IANA name, e.g. "Asia/Seoul") and Locale (BCP 47 tag, e.g.
+	// "ko-KR") tell clients how to display the election. Voting windows
+	// are still checked in UTC.
+	Timezone string `json:"timezone,omitempty"`
+	Locale   string `json:"locale,omitempty"`
 }
 
+// Nullifier formats an election may require
+const (
+	NullifierFormatAny = ""
+	NullifierFormatHex = "hex"
+)
+
+// MaxNullifierLength bounds every nullifier, whatever the election's format.
+// DefaultHexNullifierLength is a 32-byte field element in hex.
+const (
+	MaxNullifierLength        = 256
+	DefaultHexNullifierLength = 64
+)
+
+// Receipt verification code lengths in hex characters. Codes that collide
+// within an election are lengthened up to the full digest.
+const (
+	DefaultVerificationCodeLength = 16
+	MinVerificationCodeLength     = 8
+	MaxVerificationCodeLength     = 32
+	verificationCodeLengthStep    = 4
+)
+
 // VoterParticipation tracks votes per voter per period
 type VoterParticipation struct {
 	VoterHash        string         `json:"voterHash"`
@@ -96,15 +447,182 @@ type VoterParticipation struct {
 	LastVoteAt       time.Time      `json:"lastVoteAt"`
 }
 
-// TallyResult represents the tally for an election
+// TallyResult represents the tally for an election.
+// VoteCounts is keyed by question ID, then option.
 type TallyResult struct {
-	ElectionID          string         `json:"electionId"`
-	VoteCounts          map[string]int `json:"voteCounts"`
-	TotalVotes          int            `json:"totalVotes"`
-	AggregatedHash      string         `json:"aggregatedHash"`
-	DecryptionProof     string         `json:"decryptionProof"`
-	TallyTimestamp      time.Time      `json:"tallyTimestamp"`
-	TxID                string         `json:"txId"`
+	ElectionID          string                    `json:"electionId"`
+	VoteCounts          map[string]map[string]int `json:"voteCounts"`
+	TotalVotes          int                       `json:"totalVotes"`
+	AggregatedHash      string                    `json:"aggregatedHash"`
+	DecryptionProof     string                    `json:"decryptionProof"`
+	TallyTimestamp      time.Time                 `json:"tallyTimestamp"`
+	TxID                string                    `json:"txId"`
+	// Version counts the tallies stored for the election, starting at 1
+	Version int `json:"version,omitempty"`
+	// BallotCount is the number of ballots cast. In weighted elections
+	// VoteCounts and TotalVotes are weighted totals instead of ballots.
+	BallotCount int  `json:"ballotCount"`
+	Weighted    bool `json:"weighted,omitempty"`
+	// Abstentions and Spoiled are keyed by question ID. They count towards
+	// TotalVotes but are kept out of VoteCounts.
+	Abstentions map[string]int `json:"abstentions,omitempty"`
+	Spoiled     map[string]int `json:"spoiled,omitempty"`
+	// Trustees lists the trustees whose partial decryptions were combined
+	Trustees []string `json:"trustees,omitempty"`
+	// QuorumMet is whether BallotCount reached the election's quorum; a
+	// result is only Valid when it did
+	QuorumMet bool `json:"quorumMet"`
+	Valid     bool `json:"valid"`
+	// Rounds and Winner are the instant-runoff outcome of a ranked
+	// election; VoteCounts then holds the first-choice counts
+	Rounds []RankedRound `json:"rounds,omitempty"`
+	Winner string        `json:"winner,omitempty"`
+	// TieBreakOrder ranks the options tied for the lead, keyed by question
+	// ID, by a hash seeded with the closed-vote root (see tie_break.go)
+	TieBreakOrder map[string][]string `json:"tieBreakOrder,omitempty"`
+	// WriteIns counts the decrypted write-ins by name
+	WriteIns map[string]int `json:"writeIns,omitempty"`
+	// SchemaVersion is the record's schema version (see schema.go)
+	SchemaVersion int `json:"schemaVersion"`
+}
+
+// Reserved option keys for deliberate abstentions and spoiled ballots in
+// tally vote counts. They are never candidates and never win.
+const (
+	OptionAbstain = "__abstain__"
+	OptionSpoiled = "__spoiled__"
+)
+
+func isReservedOption(option string) bool {
+	return option == OptionAbstain || option == OptionSpoiled || option == OptionWriteIn
+}
+
+// EncryptedAggregate is the homomorphic product of every ballot in an election.
+// The tally authority decrypts this instead of individual ballots.
+type EncryptedAggregate struct {
+	ElectionID  string           `json:"electionId"`
+	Ciphertexts []CiphertextJSON `json:"ciphertexts"`
+	// Questions holds one aggregate per question of a multi-question election
+	Questions     map[string][]CiphertextJSON `json:"questions,omitempty"`
+	VoteCount     int                         `json:"voteCount"`
+	AggregateHash string                      `json:"aggregateHash"`
+	TxID          string                      `json:"txId"`
+	// TotalWeight is the sum of ballot weights in a weighted election, or of
+	// ballots and the delegations they carry when delegation is allowed
+	TotalWeight int `json:"totalWeight,omitempty"`
+	// DelegatedVotes is the number of delegations counted through a delegate's ballot
+	DelegatedVotes int `json:"delegatedVotes,omitempty"`
+}
+
+// ElectionStatusChangedEvent is the name of the event every status transition emits
+const ElectionStatusChangedEvent = "ElectionStatusChanged"
+
+// eventName is the name an election emits an event under: "name:<electionID>",
+// so listeners can register for one election, or the flat name for
+// elections created with LegacyEventNames or before namespacing
+func eventName(election *Election, name string) string {
+	if !election.NamespacedEvents {
+		return name
+	}
+	return name + ":" + election.ID
+}
+
+// ElectionStatusChange is the payload of ElectionStatusChangedEvent
+type ElectionStatusChange struct {
+	ElectionID string    `json:"electionId"`
+	OldStatus  string    `json:"oldStatus"`
+	NewStatus  string    `json:"newStatus"`
+	TxID       string    `json:"txId"`
+	Timestamp  time.Time `json:"timestamp"`
+	// Warning flags a transition that succeeded but needs attention
+	Warning string `json:"warning,omitempty"`
+	// Tally summarizes the tally that completed the election
+	Tally *TallyCompletion `json:"tally,omitempty"`
+}
+
+// TallyCompletion is the tally summary of a transition to completed. Seq is
+// the sequence of the tally_completed bulletin board entry.
+type TallyCompletion struct {
+	Seq         int  `json:"seq"`
+	TotalVotes  int  `json:"totalVotes"`
+	BallotCount int  `json:"ballotCount"`
+	QuorumMet   bool `json:"quorumMet"`
+	Version     int  `json:"version"`
+}
+
+// OptionResult is the outcome for one option of a question
+type OptionResult struct {
+	Option     string  `json:"option"`
+	Votes      int     `json:"votes"`
+	Percentage float64 `json:"percentage"`
+}
+
+// QuestionResult is the outcome of one question. Winners holds every option
+// tied for the most votes. TotalVotes is the turnout including abstentions
+// and spoiled ballots; percentages are shares of ValidVotes.
+type QuestionResult struct {
+	QuestionID  string         `json:"questionId"`
+	Options     []OptionResult `json:"options"`
+	Winners     []string       `json:"winners"`
+	TotalVotes  int            `json:"totalVotes"`
+	ValidVotes  int            `json:"validVotes"`
+	Abstentions int            `json:"abstentions"`
+	Spoiled     int            `json:"spoiled"`
+	// TieBreakOrder ranks the Winners when they tied
+	TieBreakOrder []string `json:"tieBreakOrder,omitempty"`
+}
+
+// ElectionResults is the tally with derived percentages and winners
+type ElectionResults struct {
+	ElectionID string           `json:"electionId"`
+	Questions  []QuestionResult `json:"questions"`
+	TotalVotes int              `json:"totalVotes"`
+	// Turnout is the number of ballots cast, measured against
+	// RequiredTurnout (0 without a quorum)
+	Turnout         int  `json:"turnout"`
+	RequiredTurnout int  `json:"requiredTurnout,omitempty"`
+	QuorumMet       bool `json:"quorumMet"`
+	Valid           bool `json:"valid"`
+	// WriteIns counts the decrypted write-ins by name
+	WriteIns map[string]int `json:"writeIns,omitempty"`
+}
+
+// TallyVerification is the outcome of VerifyTallyResult
+type TallyVerification struct {
+	ElectionID     string `json:"electionId"`
+	Verified       bool   `json:"verified"`
+	StoredHash     string `json:"storedHash"`
+	RecomputedHash string `json:"recomputedHash"`
+	VoteCount      int    `json:"voteCount"`
+}
+
+// TallyProofBundle is everything an external verifier needs to check an
+// election's tally end to end, returned by GetTallyProofBundle
+type TallyProofBundle struct {
+	ElectionID string `json:"electionId"`
+	PublicKey  string `json:"publicKey"`
+	// QuestionKeys holds the keys of questions encrypted under their own key
+	QuestionKeys          map[string]string  `json:"questionKeys,omitempty"`
+	DecryptionProofScheme string             `json:"decryptionProofScheme,omitempty"`
+	Tally                 TallyResult        `json:"tally"`
+	Aggregate             EncryptedAggregate `json:"aggregate"`
+	DecryptionProof       string             `json:"decryptionProof"`
+	MerkleRoot            string             `json:"merkleRoot"`
+	MerkleScheme          string             `json:"merkleScheme"`
+	BulletinSize          int                `json:"bulletinSize"`
+	// Threshold elections carry the trustees and the partial decryptions
+	// combined into the tally instead of a single decryption proof
+	Trustees           []Trustee           `json:"trustees,omitempty"`
+	Threshold          int                 `json:"threshold,omitempty"`
+	PartialDecryptions []PartialDecryption `json:"partialDecryptions,omitempty"`
+}
+
+// ElectionHistoryEntry is one version of an election record from the key history
+type ElectionHistoryEntry struct {
+	TxID      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+	Status    string    `json:"status"`
+	IsDelete  bool      `json:"isDelete"`
 }
 
 // BulletinBoardEntry represents a public bulletin board entry
@@ -114,6 +632,9 @@ type BulletinBoardEntry struct {
 	Hash        string    `json:"hash"`
 	TxID        string    `json:"txId"`
 	Timestamp   time.Time `json:"timestamp"`
+	// PrevEntryHash is bulletinEntryHash of the previous entry (empty for
+	// the first entry and for entries written before the chain existed)
+	PrevEntryHash string `json:"prevEntryHash,omitempty"`
 }
 
 // InitLedger initializes the chaincode
@@ -122,7 +643,7 @@ func (v *VoteContract) InitLedger(ctx contractapi.TransactionContextInterface) e
 	return nil
 }
 
-// CreateElection creates a new election on the blockchain
+// CreateElection creates a new election on the blockchain and returns it
 func (v *VoteContract) CreateElection(
 	ctx contractapi.TransactionContextInterface,
 	electionID string,
@@ -131,7 +652,7 @@ func (v *VoteContract) CreateElection(
 	publicKey string,
 	startTimeStr string,
 	endTimeStr string,
-) error {
+) (*Election, error) {
 	return v.CreateElectionWithMode(ctx, electionID, title, voterMerkleRoot, publicKey,
 		startTimeStr, endTimeStr, string(VotingModeSingle), 1, 1, 24)
 }
@@ -149,43 +670,318 @@ func (v *VoteContract) CreateElectionWithMode(
 	maxCandidatesPerVoter int,
 	maxVotesPerCandidate int,
 	resetIntervalHours int,
-) error {
+) (*Election, error) {
+	return v.createElection(ctx, electionID, title, voterMerkleRoot, publicKey, startTimeStr, endTimeStr,
+		ElectionConfig{
+			VotingMode:            VotingMode(votingMode),
+			MaxCandidatesPerVoter: maxCandidatesPerVoter,
+			MaxVotesPerCandidate:  maxVotesPerCandidate,
+			ResetIntervalHours:    resetIntervalHours,
+		})
+}
+
+// CreateElectionWithConfig creates a new election with a JSON-encoded ElectionConfig
+func (v *VoteContract) CreateElectionWithConfig(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	title string,
+	voterMerkleRoot string,
+	publicKey string,
+	startTimeStr string,
+	endTimeStr string,
+	configJSON string,
+) (*Election, error) {
+	var config ElectionConfig
+	if configJSON != "" {
+		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+			return nil, fmt.Errorf("invalid election config: %v", err)
+		}
+	}
+
+	return v.createElection(ctx, electionID, title, voterMerkleRoot, publicKey, startTimeStr, endTimeStr, config)
+}
+
+func (v *VoteContract) createElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	title string,
+	voterMerkleRoot string,
+	publicKey string,
+	startTimeStr string,
+	endTimeStr string,
+	config ElectionConfig,
+) (*Election, error) {
+	if err := v.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// An election missing any of these could not be queried or voted in
+	if err := checkElectionID(electionID); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if strings.TrimSpace(voterMerkleRoot) == "" {
+		return nil, fmt.Errorf("voter Merkle root is required")
+	}
+	if strings.TrimSpace(publicKey) == "" {
+		return nil, fmt.Errorf("public key is required")
+	}
+
 	// Check if election already exists
 	existing, err := ctx.GetStub().GetState(electionKey(electionID))
 	if err != nil {
-		return fmt.Errorf("failed to read election: %v", err)
+		return nil, fmt.Errorf("failed to read election: %v", err)
 	}
 	if existing != nil {
-		return fmt.Errorf("election %s already exists", electionID)
+		return nil, fmt.Errorf("election %s already exists", electionID)
 	}
 
 	// Parse times
 	startTime, err := time.Parse(time.RFC3339, startTimeStr)
 	if err != nil {
-		return fmt.Errorf("invalid start time: %v", err)
+		return nil, fmt.Errorf("invalid start time: %v", err)
 	}
 	endTime, err := time.Parse(time.RFC3339, endTimeStr)
 	if err != nil {
-		return fmt.Errorf("invalid end time: %v", err)
+		return nil, fmt.Errorf("invalid end time: %v", err)
+	}
+	if err := v.validateVotingWindow(ctx, startTime, endTime, config.MinDurationMinutes); err != nil {
+		return nil, err
+	}
+
+	// A malformed key would only surface at aggregation, so it is checked
+	// and stored in canonical form here
+	if publicKey, err = canonicalElGamalPublicKey(publicKey); err != nil {
+		return nil, err
 	}
 
 	// Validate voting mode
-	mode := VotingMode(votingMode)
+	mode := config.VotingMode
 	if mode != VotingModeSingle && mode != VotingModeMultiLimited && mode != VotingModePeriodicReset {
 		mode = VotingModeSingle
 	}
 
 	// Set defaults
+	maxCandidatesPerVoter := config.MaxCandidatesPerVoter
 	if maxCandidatesPerVoter < 1 {
 		maxCandidatesPerVoter = 1
 	}
+	maxVotesPerCandidate := config.MaxVotesPerCandidate
 	if maxVotesPerCandidate < 1 {
 		maxVotesPerCandidate = 1
 	}
+	resetIntervalHours := config.ResetIntervalHours
 	if resetIntervalHours < 1 {
 		resetIntervalHours = 24
 	}
 
+	if err := validateQuestions(config.Questions); err != nil {
+		return nil, err
+	}
+	if len(config.Options) > 0 {
+		if len(config.Questions) > 0 {
+			return nil, fmt.Errorf("options apply to single-question elections; set them per question instead")
+		}
+		if err := validateOptions(DefaultQuestionID, config.Options); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.MaxVoters < 0 {
+		return nil, fmt.Errorf("maxVoters must not be negative")
+	}
+	if config.TallyAllowance < 0 {
+		return nil, fmt.Errorf("tallyAllowance must not be negative")
+	}
+	if err := validateProofStorage(config.ProofStorage); err != nil {
+		return nil, err
+	}
+	if config.GracePeriodSeconds < 0 || config.GracePeriodSeconds > MaxGracePeriodSeconds {
+		return nil, fmt.Errorf("gracePeriodSeconds must be between 0 and %d", MaxGracePeriodSeconds)
+	}
+	if err := validateQuorum(config.Quorum, config.MaxVoters, config.VoterRollSize); err != nil {
+		return nil, err
+	}
+
+	voteCountShards := config.VoteCountShards
+	if voteCountShards == 0 {
+		voteCountShards = DefaultVoteCountShards
+	}
+	if voteCountShards < 1 || voteCountShards > MaxVoteCountShards {
+		return nil, fmt.Errorf("voteCountShards must be between 1 and %d", MaxVoteCountShards)
+	}
+
+	nullifierLength := config.NullifierLength
+	switch config.NullifierFormat {
+	case NullifierFormatAny:
+	case NullifierFormatHex:
+		if nullifierLength == 0 {
+			nullifierLength = DefaultHexNullifierLength
+		}
+	default:
+		return nil, fmt.Errorf("unsupported nullifier format: %s", config.NullifierFormat)
+	}
+	if nullifierLength < 0 || nullifierLength > MaxNullifierLength {
+		return nil, fmt.Errorf("nullifierLength must be between 0 and %d", MaxNullifierLength)
+	}
+
+	codeLength := config.VerificationCodeLength
+	if codeLength == 0 {
+		codeLength = DefaultVerificationCodeLength
+	}
+	if codeLength < MinVerificationCodeLength || codeLength > MaxVerificationCodeLength {
+		return nil, fmt.Errorf("verificationCodeLength must be between %d and %d", MinVerificationCodeLength, MaxVerificationCodeLength)
+	}
+
+	if len(config.TallyEndorsers) > 0 {
+		if err := validateMSPIDs(config.TallyEndorsers); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateDisplayMetadata(config.Timezone, config.Locale); err != nil {
+		return nil, err
+	}
+
+	merkleScheme := config.MerkleScheme
+	if merkleScheme == "" {
+		merkleScheme = DefaultMerkleScheme
+	}
+	if err := validateMerkleScheme(merkleScheme); err != nil {
+		return nil, err
+	}
+	if err := validateHashAlgorithm(config.HashAlgorithm); err != nil {
+		return nil, err
+	}
+
+	createdAt, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// An optional receipt signing key arrives in the transient map so it
+	// never appears in the block
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient data: %v", err)
+	}
+	receiptPublicKey := ""
+	if seed, ok := transient[receiptSigningKeyTransient]; ok {
+		signingKey, err := parseReceiptSigningSeed(seed)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.GetStub().PutPrivateData(ReceiptKeyCollection, receiptKeyKey(electionID), seed); err != nil {
+			return nil, fmt.Errorf("failed to store receipt signing key: %v", err)
+		}
+		receiptPublicKey = hex.EncodeToString(signingKey.Public().(ed25519.PublicKey))
+	}
+
+	// Validate proof system when on-chain verification is requested
+	if config.EligibilityVerifyingKey != "" || config.ValidityVerifyingKey != "" {
+		if _, err := getProofVerifier(config.ProofSystem); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.DecryptionProofScheme != "" {
+		if _, err := getDecryptionProofVerifier(config.DecryptionProofScheme); err != nil {
+			return nil, err
+		}
+	}
+
+	// A range proof is the ballot's validity proof, so it cannot also be
+	// checked against a validity verifying key
+	ballotRange, err := normalizeBallotRange(config.RangeProofScheme, config.BallotRange)
+	if err != nil {
+		return nil, err
+	}
+	if ballotRange != nil && config.ValidityVerifyingKey != "" {
+		return nil, fmt.Errorf("range proofs replace the validity verifying key; set only one")
+	}
+
+	if len(config.Trustees) > 0 || config.Threshold != 0 {
+		if config.DecryptionProofScheme != "" {
+			return nil, fmt.Errorf("threshold elections verify each partial decryption; decryptionProofScheme does not apply")
+		}
+		if err := validateTrustees(publicKey, config.Trustees, config.Threshold); err != nil {
+			return nil, err
+		}
+		// The trustees share the election key only
+		for _, question := range config.Questions {
+			if question.PublicKey != "" {
+				return nil, fmt.Errorf("question %s: per-question public keys are not supported with threshold decryption", question.ID)
+			}
+		}
+	}
+
+	if err := validateBallotType(config, mode); err != nil {
+		return nil, err
+	}
+	if err := validateWriteIns(config); err != nil {
+		return nil, err
+	}
+	if err := validateKioskKeys(config.KioskPublicKeys, config.CastTimeSkewSeconds); err != nil {
+		return nil, err
+	}
+	castTimeSkew := config.CastTimeSkewSeconds
+	if len(config.KioskPublicKeys) > 0 && castTimeSkew == 0 {
+		castTimeSkew = DefaultCastTimeSkewSeconds
+	}
+
+	// Delegations are tracked by nullifier and carry no proven weight
+	if config.AllowDelegation {
+		if mode != VotingModeSingle {
+			return nil, fmt.Errorf("delegation requires the %s voting mode", VotingModeSingle)
+		}
+		if config.Weighted {
+			return nil, fmt.Errorf("delegation is not supported in weighted elections")
+		}
+	}
+
+	// Weights are read from the eligibility proof, so it must be verified on-chain
+	if config.Weighted {
+		if config.EligibilityVerifyingKey == "" {
+			return nil, fmt.Errorf("weighted elections require an eligibility verifying key")
+		}
+	}
+
+	// Verified proofs are bound to the ballot they come with through their
+	// public inputs, or one proof could be replayed under many nullifiers
+	if config.EligibilityVerifyingKey != "" || config.ValidityVerifyingKey != "" {
+		verifier, _ := getProofVerifier(config.ProofSystem)
+		if _, ok := verifier.(PublicInputReader); !ok {
+			return nil, fmt.Errorf("proof system %s does not expose public inputs", config.ProofSystem)
+		}
+	}
+	if config.EligibilityVerifyingKey != "" {
+		required := []string{PublicInputNullifier, PublicInputVoterRoot}
+		if config.Weighted {
+			required = append(required, PublicInputWeight)
+		}
+		if err := validatePublicInputLayout("eligibility", config.EligibilityPublicInputs, required...); err != nil {
+			return nil, err
+		}
+		if !config.Weighted && containsString(config.EligibilityPublicInputs, PublicInputWeight) {
+			return nil, fmt.Errorf("only weighted elections have a weight public input")
+		}
+		// A delegation has no ballot to hash
+		if config.AllowDelegation && containsString(config.EligibilityPublicInputs, PublicInputBallotHash) {
+			return nil, fmt.Errorf("delegation eligibility proofs cannot commit to a ballot hash")
+		}
+	} else if len(config.EligibilityPublicInputs) > 0 {
+		return nil, fmt.Errorf("eligibility public inputs require an eligibility verifying key")
+	}
+	if config.ValidityVerifyingKey != "" {
+		if err := validatePublicInputLayout("validity", config.ValidityPublicInputs, PublicInputBallotHash); err != nil {
+			return nil, err
+		}
+	} else if len(config.ValidityPublicInputs) > 0 {
+		return nil, fmt.Errorf("validity public inputs require a validity verifying key")
+	}
+
 	election := Election{
 		ID:                    electionID,
 		Title:                 title,
@@ -194,57 +990,192 @@ func (v *VoteContract) CreateElectionWithMode(
 		PublicKey:             publicKey,
 		StartTime:             startTime,
 		EndTime:               endTime,
-		CreatedAt:             time.Now(),
+		CreatedAt:             createdAt,
 		VotingMode:            mode,
 		MaxCandidatesPerVoter: maxCandidatesPerVoter,
 		MaxVotesPerCandidate:  maxVotesPerCandidate,
 		ResetIntervalHours:    resetIntervalHours,
+		ProofSystem:             config.ProofSystem,
+		EligibilityVerifyingKey: config.EligibilityVerifyingKey,
+		ValidityVerifyingKey:    config.ValidityVerifyingKey,
+		EligibilityPublicInputs: config.EligibilityPublicInputs,
+		ValidityPublicInputs:    config.ValidityPublicInputs,
+		Questions:               config.Questions,
+		Options:                 config.Options,
+		BallotType:              config.BallotType,
+		MerkleScheme:            merkleScheme,
+		HashAlgorithm:           normalizeHashAlgorithm(config.HashAlgorithm),
+		MaxVoters:               config.MaxVoters,
+		VoteCountShards:         voteCountShards,
+		UniqueCiphertexts:       config.UniqueCiphertexts,
+		ProofStorage:            config.ProofStorage,
+		GracePeriodSeconds:      config.GracePeriodSeconds,
+		RecordDuplicateAttempts: config.RecordDuplicateAttempts,
+		NamespacedEvents:        !config.LegacyEventNames,
+		Quorum:                  config.Quorum,
+		VoterRollSize:           config.VoterRollSize,
+		TallyAllowance:          config.TallyAllowance,
+		TallyEndorsers:          config.TallyEndorsers,
+		Weighted:                config.Weighted,
+		VerificationCodeLength:  codeLength,
+		NullifierFormat:         config.NullifierFormat,
+		NullifierLength:         nullifierLength,
+		AllowRevote:             config.AllowRevote,
+		AllowDelegation:         config.AllowDelegation,
+		AllowWriteIns:           config.AllowWriteIns,
+		ReceiptPublicKey:        receiptPublicKey,
+		KioskPublicKeys:         config.KioskPublicKeys,
+		CastTimeSkewSeconds:     castTimeSkew,
+		DecryptionProofScheme:   config.DecryptionProofScheme,
+		RangeProofScheme:        config.RangeProofScheme,
+		BallotRange:             ballotRange,
+		Trustees:                config.Trustees,
+		Threshold:               config.Threshold,
+		Timezone:                config.Timezone,
+		Locale:                  config.Locale,
+		SchemaVersion:           ElectionSchemaVersion,
 	}
 
 	electionJSON, err := json.Marshal(election)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// The counters, the genesis board entry and the status index are
+	// written before the election itself. Fabric commits none of a
+	// transaction's writes when it returns an error, and writing the
+	// election last also keeps a failed creation from leaving an election
+	// without its board.
+
+	// Start the unsharded base counter and every shard at zero
+	counterKeys := []string{voteCountKey(electionID)}
+	for shard := 0; shard < voteCountShards; shard++ {
+		counterKeys = append(counterKeys, voteCountShardKey(electionID, shard))
+	}
+	for _, key := range counterKeys {
+		if err := ctx.GetStub().PutState(key, []byte("0")); err != nil {
+			return nil, fmt.Errorf("failed to initialize vote count: %v", err)
+		}
+	}
+
+	// Start the bulletin board. The election is not stored yet, so the
+	// board is started with the scheme directly.
+	electionHash, err := hashJSON(election.HashAlgorithm, electionJSON)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.appendBulletinBoard(ctx, electionID, electionMerkleScheme(&election), "election_created", electionHash); err != nil {
+		return nil, fmt.Errorf("failed to start bulletin board: %v", err)
+	}
+
+	// Index the election by status
+	if err := v.emitStatusChanged(ctx, &election, "", election.Status); err != nil {
+		return nil, err
 	}
 
 	// Store election
 	if err := ctx.GetStub().PutState(electionKey(electionID), electionJSON); err != nil {
-		return err
+		return nil, err
+	}
+	return &election, nil
+}
+
+// validateVotingWindow rejects empty or reversed windows, windows shorter
+// than minDurationMinutes, and windows that are already over or started
+// more than StartTimeTolerance before the creating transaction
+func (v *VoteContract) validateVotingWindow(
+	ctx contractapi.TransactionContextInterface,
+	startTime time.Time,
+	endTime time.Time,
+	minDurationMinutes int,
+) error {
+	if !endTime.After(startTime) {
+		return fmt.Errorf("end time must be after start time")
+	}
+	if minDurationMinutes < 0 {
+		return fmt.Errorf("minDurationMinutes must not be negative")
+	}
+	if minDuration := time.Duration(minDurationMinutes) * time.Minute; endTime.Sub(startTime) < minDuration {
+		return fmt.Errorf("voting window must be at least %d minutes", minDurationMinutes)
 	}
 
-	// Add to bulletin board
-	return v.addBulletinBoardEntry(ctx, electionID, "election_created", hashString(string(electionJSON)))
+	// The transaction timestamp keeps the check identical on every endorser
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get timestamp: %v", err)
+	}
+	now := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
+	if !endTime.After(now) {
+		return fmt.Errorf("end time is already in the past")
+	}
+	if startTime.Before(now.Add(-StartTimeTolerance)) {
+		return fmt.Errorf("start time is more than %s in the past", StartTimeTolerance)
+	}
+	return nil
 }
 
-// ActivateElection activates an election for voting
+// ActivateElection activates an election for voting and returns the
+// activated election
 func (v *VoteContract) ActivateElection(
 	ctx contractapi.TransactionContextInterface,
 	electionID string,
-) error {
+) (*Election, error) {
+	if err := v.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
 	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
 	if err != nil {
-		return fmt.Errorf("failed to read election: %v", err)
+		return nil, fmt.Errorf("failed to read election: %v", err)
 	}
 	if electionJSON == nil {
-		return fmt.Errorf("election %s does not exist", electionID)
+		return nil, fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
 	}
 
 	var election Election
 	if err := json.Unmarshal(electionJSON, &election); err != nil {
-		return err
+		return nil, err
 	}
 
 	if election.Status != "pending" {
-		return fmt.Errorf("election is not in pending status")
+		return nil, fmt.Errorf("%w (current status: %s)", ErrElectionNotPending, election.Status)
+	}
+	if err := checkElectionConfigured(&election); err != nil {
+		return nil, err
+	}
+
+	// An election activated after its end would turn every voter away
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if now.After(election.EndTime) {
+		return nil, fmt.Errorf("%w: cannot activate an election that ended at %s", ErrElectionEnded,
+			election.EndTime.Format(time.RFC3339))
+	}
+	warning := ""
+	if now.Before(election.StartTime) {
+		warning = fmt.Sprintf("activated before the start time %s; votes are rejected until then",
+			election.StartTime.Format(time.RFC3339))
 	}
 
-	election.Status = "active"
+	if err := setStatus(&election, "active"); err != nil {
+		return nil, err
+	}
 
 	updatedJSON, err := json.Marshal(election)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := ctx.GetStub().PutState(electionKey(electionID), updatedJSON); err != nil {
+		return nil, err
 	}
 
-	return ctx.GetStub().PutState(electionKey(electionID), updatedJSON)
+	if err := v.emitStatusChangedWithWarning(ctx, &election, "pending", election.Status, warning); err != nil {
+		return nil, err
+	}
+	return &election, nil
 }
 
 // CastVote records an encrypted vote on the blockchain (backward compatible)
@@ -255,9 +1186,41 @@ func (v *VoteContract) CastVote(
 	nullifier string,
 	eligibilityProofHash string,
 	validityProofHash string,
+	proofVoterRoot string,
 ) (*VoteReceipt, error) {
 	return v.CastVoteWithMode(ctx, electionID, encryptedVote, nullifier,
-		eligibilityProofHash, validityProofHash, "", "", 0)
+		eligibilityProofHash, validityProofHash, "", "", 0, proofVoterRoot)
+}
+
+// ValidateVote dry-runs CastVote: it makes every check CastVote makes
+// before writing and returns {"valid": bool, "reasons": []string} without
+// writing state or emitting an event. reasons holds the first failed check.
+func (v *VoteContract) ValidateVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	encryptedVote string,
+	nullifier string,
+	eligibilityProofHash string,
+	validityProofHash string,
+	proofVoterRoot string,
+) (map[string]interface{}, error) {
+	reasons := []string{}
+	_, err := v.checkVoteSubmission(ctx, voteSubmission{
+		ElectionID:           electionID,
+		EncryptedVote:        encryptedVote,
+		Nullifier:            nullifier,
+		EligibilityProofHash: eligibilityProofHash,
+		ValidityProofHash:    validityProofHash,
+		ProofVoterRoot:       proofVoterRoot,
+	})
+	if err != nil {
+		reasons = append(reasons, err.Error())
+	}
+
+	return map[string]interface{}{
+		"valid":   len(reasons) == 0,
+		"reasons": reasons,
+	}, nil
 }
 
 // CastVoteWithMode records an encrypted vote with voting mode support
@@ -271,589 +1234,5630 @@ func (v *VoteContract) CastVoteWithMode(
 	voterHash string,
 	candidateSelectionsJSON string,
 	votingPeriod int,
+	proofVoterRoot string,
 ) (*VoteReceipt, error) {
-	// 1. Verify election exists and is active
-	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read election: %v", err)
+	return v.castVote(ctx, voteSubmission{
+		ElectionID:              electionID,
+		EncryptedVote:           encryptedVote,
+		Nullifier:               nullifier,
+		EligibilityProofHash:    eligibilityProofHash,
+		ValidityProofHash:       validityProofHash,
+		ProofVoterRoot:          proofVoterRoot,
+		VoterHash:               voterHash,
+		CandidateSelectionsJSON: candidateSelectionsJSON,
+	})
+}
+
+// CastVoteMultiQuestion records one ballot holding a separate encrypted vote
+// per question. encryptedVotesJSON maps question ID to encrypted vote.
+func (v *VoteContract) CastVoteMultiQuestion(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	encryptedVotesJSON string,
+	nullifier string,
+	eligibilityProofHash string,
+	validityProofHash string,
+	proofVoterRoot string,
+) (*VoteReceipt, error) {
+	var questionVotes map[string]string
+	if err := json.Unmarshal([]byte(encryptedVotesJSON), &questionVotes); err != nil {
+		return nil, fmt.Errorf("invalid encrypted votes: %v", err)
 	}
-	if electionJSON == nil {
-		return nil, fmt.Errorf("election %s does not exist", electionID)
+	if len(questionVotes) == 0 {
+		return nil, fmt.Errorf("invalid encrypted votes: no questions answered")
 	}
 
-	var election Election
-	if err := json.Unmarshal(electionJSON, &election); err != nil {
+	return v.castVote(ctx, voteSubmission{
+		ElectionID:           electionID,
+		Nullifier:            nullifier,
+		EligibilityProofHash: eligibilityProofHash,
+		ValidityProofHash:    validityProofHash,
+		ProofVoterRoot:       proofVoterRoot,
+		QuestionVotes:        questionVotes,
+	})
+}
+
+// CastVoteWithProof records an encrypted vote after verifying its proofs on-chain.
+// The proofs are stored by hash; the election's verifying keys decide validity,
+// and their public inputs must commit to this nullifier and ballot. With an
+// eligibility verifying key the voter root is read from the proof too, and
+// proofVoterRoot is only advisory.
+func (v *VoteContract) CastVoteWithProof(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	encryptedVote string,
+	nullifier string,
+	eligibilityProof string,
+	validityProof string,
+	proofVoterRoot string,
+) (*VoteReceipt, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
 		return nil, err
 	}
-
-	if election.Status != "active" {
-		return nil, fmt.Errorf("election is not active (current status: %s)", election.Status)
+	if election.EligibilityVerifyingKey == "" && election.ValidityVerifyingKey == "" {
+		return nil, fmt.Errorf("election %s has no verifying keys registered", electionID)
 	}
 
-	// Check time bounds
-	now := time.Now()
-	if now.Before(election.StartTime) {
-		return nil, fmt.Errorf("election has not started yet")
+	if election.EligibilityVerifyingKey != "" {
+		if _, err := v.VerifyProofOnChain(ctx, electionID, "eligibility", eligibilityProof); err != nil {
+			return nil, err
+		}
 	}
-	if now.After(election.EndTime) {
-		return nil, fmt.Errorf("election has ended")
+	if election.ValidityVerifyingKey != "" {
+		if _, err := v.VerifyProofOnChain(ctx, electionID, "validity", validityProof); err != nil {
+			return nil, err
+		}
+	}
+	if err := bindBallotProofs(election, encryptedVote, nullifier, eligibilityProof, validityProof, 0); err != nil {
+		return nil, err
 	}
 
-	// 2. Calculate current voting period for PERIODIC_RESET mode
-	currentPeriod := 0
-	if election.VotingMode == VotingModePeriodicReset && election.ResetIntervalHours > 0 {
-		elapsed := now.Sub(election.StartTime)
-		currentPeriod = int(elapsed.Hours()) / election.ResetIntervalHours
+	return v.castVote(ctx, voteSubmission{
+		ElectionID:           electionID,
+		EncryptedVote:        encryptedVote,
+		Nullifier:            nullifier,
+		EligibilityProofHash: hashForElection(election, eligibilityProof),
+		ValidityProofHash:    hashForElection(election, validityProof),
+		ProofVoterRoot:       proofVoterRoot,
+		ProofsVerified:       true,
+		EligibilityProof:     eligibilityProof,
+		ValidityProof:        validityProof,
+	})
+}
+
+// CastVoteWeighted records a vote in a weighted election. The eligibility
+// proof is verified on-chain and its weight public input must equal weight;
+// its voter root input is checked in place of the advisory proofVoterRoot.
+// In multi-question elections encryptedVote maps question ID to encrypted vote.
+func (v *VoteContract) CastVoteWeighted(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	encryptedVote string,
+	nullifier string,
+	eligibilityProof string,
+	validityProof string,
+	proofVoterRoot string,
+	weight int,
+) (*VoteReceipt, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if !election.Weighted {
+		return nil, fmt.Errorf("election %s is not weighted", electionID)
+	}
+	if weight < 1 {
+		return nil, fmt.Errorf("weight must be positive")
 	}
 
-	// 3. Check voting eligibility based on mode
-	if election.VotingMode == VotingModeSingle {
-		// Traditional: Check nullifier hasn't been used
-		nullifierKey := voteKey(electionID, nullifier)
-		existingVote, err := ctx.GetStub().GetState(nullifierKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check nullifier: %v", err)
+	if _, err := v.VerifyProofOnChain(ctx, electionID, "eligibility", eligibilityProof); err != nil {
+		return nil, err
+	}
+	if election.ValidityVerifyingKey != "" {
+		if _, err := v.VerifyProofOnChain(ctx, electionID, "validity", validityProof); err != nil {
+			return nil, err
 		}
-		if existingVote != nil {
-			return nil, fmt.Errorf("vote already submitted (duplicate nullifier)")
+	}
+	if err := bindBallotProofs(election, encryptedVote, nullifier, eligibilityProof, validityProof, weight); err != nil {
+		return nil, err
+	}
+
+	sub := voteSubmission{
+		ElectionID:           electionID,
+		EncryptedVote:        encryptedVote,
+		Nullifier:            nullifier,
+		EligibilityProofHash: hashForElection(election, eligibilityProof),
+		ValidityProofHash:    hashForElection(election, validityProof),
+		ProofVoterRoot:       proofVoterRoot,
+		ProofsVerified:       true,
+		Weight:               weight,
+		EligibilityProof:     eligibilityProof,
+		ValidityProof:        validityProof,
+	}
+	if len(election.Questions) > 0 {
+		if err := json.Unmarshal([]byte(encryptedVote), &sub.QuestionVotes); err != nil {
+			return nil, fmt.Errorf("invalid encrypted votes: %v", err)
 		}
-	} else if voterHash != "" {
-		// Multi-limited or Periodic reset: Check participation record
-		participationKey := voterParticipationKey(electionID, voterHash, currentPeriod)
-		participationJSON, err := ctx.GetStub().GetState(participationKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to check participation: %v", err)
+		if len(sub.QuestionVotes) == 0 {
+			return nil, fmt.Errorf("invalid encrypted votes: no questions answered")
 		}
+		sub.EncryptedVote = ""
+	}
+	return v.castVote(ctx, sub)
+}
 
-		if participationJSON != nil {
-			var participation VoterParticipation
-			if err := json.Unmarshal(participationJSON, &participation); err != nil {
-				return nil, err
-			}
-
-			if election.VotingMode == VotingModePeriodicReset {
-				// Already voted in this period
-				return nil, fmt.Errorf("already voted in this period (%d)", currentPeriod)
-			} else if election.VotingMode == VotingModeMultiLimited {
-				// Check if max votes reached
-				maxTotal := election.MaxCandidatesPerVoter * election.MaxVotesPerCandidate
-				if participation.TotalVotesCast >= maxTotal {
-					return nil, fmt.Errorf("maximum votes (%d) reached", maxTotal)
-				}
-			}
+// bindBallotProofs checks the public inputs of a ballot's verified proofs
+// against the nullifier, ballot and weight it is cast with and the
+// election's current voter root
+func bindBallotProofs(election *Election, encryptedVote, nullifier, eligibilityProof, validityProof string, weight int) error {
+	values := map[string]*big.Int{
+		PublicInputNullifier:  publicInputValue(nullifier),
+		PublicInputVoterRoot:  publicInputValue(election.VoterMerkleRoot),
+		PublicInputBallotHash: ballotHashInput(election, encryptedVote),
+	}
+	if weight > 0 {
+		values[PublicInputWeight] = big.NewInt(int64(weight))
+	}
+	if election.EligibilityVerifyingKey != "" {
+		if err := bindPublicInputs(election, "eligibility", eligibilityProof, election.EligibilityPublicInputs, values); err != nil {
+			return err
 		}
 	}
-
-	// 4. Parse candidate selections for MULTI_LIMITED mode
-	var candidateSelections []CandidateSelection
-	if candidateSelectionsJSON != "" {
-		if err := json.Unmarshal([]byte(candidateSelectionsJSON), &candidateSelections); err != nil {
-			return nil, fmt.Errorf("invalid candidate selections: %v", err)
+	if election.ValidityVerifyingKey != "" {
+		if err := bindPublicInputs(election, "validity", validityProof, election.ValidityPublicInputs, values); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	// 5. Compute encrypted vote hash
-	encryptedVoteHash := hashString(encryptedVote)
-
-	// 6. Get transaction context
-	txID := ctx.GetStub().GetTxID()
-	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+// CastVotePrivate records a vote whose ciphertext goes to the private data
+// collection. The ciphertext is read from the transient field "encryptedVote"
+// so it never appears in the proposal or block; the public vote record only
+// carries its hash.
+func (v *VoteContract) CastVotePrivate(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	nullifier string,
+	eligibilityProofHash string,
+	validityProofHash string,
+	proofVoterRoot string,
+) (*VoteReceipt, error) {
+	transient, err := ctx.GetStub().GetTransient()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get timestamp: %v", err)
+		return nil, fmt.Errorf("failed to read transient data: %v", err)
+	}
+	encryptedVote, ok := transient["encryptedVote"]
+	if !ok || len(encryptedVote) == 0 {
+		return nil, fmt.Errorf("encryptedVote must be passed in the transient map")
 	}
-	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos))
 
-	// 7. Create vote record
-	vote := Vote{
+	return v.castVote(ctx, voteSubmission{
 		ElectionID:           electionID,
-		EncryptedVote:        encryptedVote,
-		EncryptedVoteHash:    encryptedVoteHash,
+		EncryptedVote:        string(encryptedVote),
 		Nullifier:            nullifier,
 		EligibilityProofHash: eligibilityProofHash,
 		ValidityProofHash:    validityProofHash,
-		Timestamp:            timestamp,
-		TxID:                 txID,
-		BlockNumber:          0,
-		VotingPeriod:         currentPeriod,
-		CandidateSelections:  candidateSelections,
+		ProofVoterRoot:       proofVoterRoot,
+		PrivateCollection:    PrivateBallotCollection,
+	})
+}
+
+// VerifyProofOnChain verifies an eligibility or validity proof against the
+// verifying key registered for the election
+func (v *VoteContract) VerifyProofOnChain(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	proofType string,
+	proof string,
+) (bool, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return false, err
 	}
 
-	voteJSON, err := json.Marshal(vote)
+	var verifyingKey string
+	switch proofType {
+	case "eligibility":
+		verifyingKey = election.EligibilityVerifyingKey
+	case "validity":
+		verifyingKey = election.ValidityVerifyingKey
+	default:
+		return false, fmt.Errorf("unknown proof type: %s", proofType)
+	}
+	if verifyingKey == "" {
+		return false, fmt.Errorf("no %s verifying key registered for election %s", proofType, electionID)
+	}
+
+	verifier, err := getProofVerifier(election.ProofSystem)
 	if err != nil {
-		return nil, err
+		return false, err
+	}
+	if err := verifier.Verify(verifyingKey, proof); err != nil {
+		return false, fmt.Errorf("invalid %s proof: %v", proofType, err)
 	}
 
-	// 8. Store vote
-	nullifierKey := voteKey(electionID, nullifier)
-	if err := ctx.GetStub().PutState(nullifierKey, voteJSON); err != nil {
-		return nil, fmt.Errorf("failed to store vote: %v", err)
+	return true, nil
+}
+
+// DelegateVote records that the holder of delegatorNullifier hands their
+// vote to the voter with nullifier commitment delegateCommitment. A voter who
+// delegated can no longer vote, and one who voted can no longer delegate.
+// proof is the delegator's eligibility proof, verified on-chain when the
+// election has an eligibility verifying key.
+func (v *VoteContract) DelegateVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	delegatorNullifier string,
+	delegateCommitment string,
+	proof string,
+) error {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if !election.AllowDelegation {
+		return fmt.Errorf("election %s does not allow delegation", electionID)
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkVotingOpen(election, now); err != nil {
+		return err
 	}
 
-	// 9. Update voter participation (for MULTI_LIMITED and PERIODIC_RESET)
-	if voterHash != "" && election.VotingMode != VotingModeSingle {
-		if err := v.updateVoterParticipation(ctx, electionID, voterHash, currentPeriod, candidateSelections); err != nil {
-			return nil, fmt.Errorf("failed to update participation: %v", err)
+	if delegatorNullifier == "" || delegateCommitment == "" || proof == "" {
+		return fmt.Errorf("delegator nullifier, delegate commitment and proof are required")
+	}
+	if err := checkNullifierFormat(election, delegatorNullifier); err != nil {
+		return err
+	}
+	if election.EligibilityVerifyingKey != "" {
+		if _, err := v.VerifyProofOnChain(ctx, electionID, "eligibility", proof); err != nil {
+			return err
+		}
+		values := map[string]*big.Int{
+			PublicInputNullifier: publicInputValue(delegatorNullifier),
+			PublicInputVoterRoot: publicInputValue(election.VoterMerkleRoot),
+		}
+		if err := bindPublicInputs(election, "eligibility", proof, election.EligibilityPublicInputs, values); err != nil {
+			return err
 		}
 	}
 
-	// 10. Update vote index for the election
-	if err := v.addVoteToIndex(ctx, electionID, nullifier); err != nil {
-		return nil, fmt.Errorf("failed to update vote index: %v", err)
+	commitment := nullifierCommitment(electionID, delegatorNullifier)
+	if commitment == delegateCommitment {
+		return fmt.Errorf("a voter cannot delegate to themselves")
 	}
 
-	// 11. Add to bulletin board
-	if err := v.addBulletinBoardEntry(ctx, electionID, "vote_cast", encryptedVoteHash); err != nil {
-		return nil, fmt.Errorf("failed to update bulletin board: %v", err)
+	// A nullifier is used either for a vote or for a delegation
+	nullifierKey, err := voteKey(ctx, electionID, delegatorNullifier)
+	if err != nil {
+		return err
+	}
+	existingVote, err := ctx.GetStub().GetState(nullifierKey)
+	if err != nil {
+		return fmt.Errorf("failed to check nullifier: %v", err)
+	}
+	if existingVote != nil {
+		return ErrDuplicateNullifier
+	}
+	delegated, err := hasDelegated(ctx, electionID, commitment)
+	if err != nil {
+		return err
+	}
+	if delegated {
+		return ErrVoteDelegated
 	}
 
-	// 12. Emit event
-	eventPayload := map[string]interface{}{
-		"electionId":        electionID,
-		"encryptedVoteHash": encryptedVoteHash,
-		"txId":              txID,
-		"votingMode":        election.VotingMode,
-		"votingPeriod":      currentPeriod,
+	txID := ctx.GetStub().GetTxID()
+	delegation := Delegation{
+		ElectionID:          electionID,
+		DelegatorCommitment: commitment,
+		DelegateCommitment:  delegateCommitment,
+		ProofHash:           hashForElection(election, proof),
+		TxID:                txID,
+		Timestamp:           now,
 	}
-	eventJSON, _ := json.Marshal(eventPayload)
-	if err := ctx.GetStub().SetEvent("VoteCast", eventJSON); err != nil {
-		return nil, fmt.Errorf("failed to emit event: %v", err)
+	delegationJSON, err := json.Marshal(delegation)
+	if err != nil {
+		return err
+	}
+	key, err := delegationKey(ctx, electionID, commitment)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, delegationJSON); err != nil {
+		return fmt.Errorf("failed to store delegation: %v", err)
 	}
 
-	// 13. Generate verification code
-	verificationCode := generateVerificationCode(txID, encryptedVoteHash)
+	delegationHash, err := hashJSON(election.HashAlgorithm, delegationJSON)
+	if err != nil {
+		return err
+	}
+	if err := v.addBulletinBoardEntry(ctx, electionID, "vote_delegated", delegationHash); err != nil {
+		return fmt.Errorf("failed to update bulletin board: %v", err)
+	}
 
-	// 14. Return receipt
-	return &VoteReceipt{
-		Success:           true,
-		VerificationCode:  verificationCode,
-		EncryptedVoteHash: encryptedVoteHash,
-		TxID:              txID,
-		BlockNumber:       0,
-		Timestamp:         timestamp,
-	}, nil
+	eventJSON, _ := json.Marshal(map[string]interface{}{
+		"electionId":          electionID,
+		"delegatorCommitment": commitment,
+		"delegateCommitment":  delegateCommitment,
+		"txId":                txID,
+	})
+	return ctx.GetStub().SetEvent(eventName(election, "VoteDelegated"), eventJSON)
 }
 
-// updateVoterParticipation updates or creates a voter participation record
-func (v *VoteContract) updateVoterParticipation(
+// voteSubmission carries the inputs of a single ballot through castVote
+type voteSubmission struct {
+	ElectionID              string
+	EncryptedVote           string
+	Nullifier               string
+	EligibilityProofHash    string
+	ValidityProofHash       string
+	VoterHash               string
+	CandidateSelectionsJSON string
+	// ProofVoterRoot is the voter roll root the eligibility proof was built
+	// against, as claimed by the caller
+	ProofVoterRoot string
+	// QuestionVotes is set for multi-question ballots instead of EncryptedVote
+	QuestionVotes map[string]string
+	// PrivateCollection keeps the ciphertext out of the world state when set
+	PrivateCollection string
+	// ProofsVerified is set once the proofs were checked against the election's verifying keys
+	ProofsVerified bool
+	// Weight is the proven weight of a ballot in a weighted election
+	Weight int
+	// EligibilityProof and ValidityProof are the full proofs, when the
+	// caller has them, for elections that store proofs
+	EligibilityProof string
+	ValidityProof    string
+}
+
+// voteCheck is the outcome of the checks a ballot passes before castVote
+// writes anything
+type voteCheck struct {
+	Election            Election
+	EncryptedVote       string
+	QuestionVotes       map[string]string
+	CurrentPeriod       int
+	Commitment          string
+	NullifierKey        string
+	Amended             bool
+	PreviousSequence    int
+	CandidateSelections []CandidateSelection
+	// Resubmission is the stored vote when the same ballot is cast again
+	Resubmission *Vote
+	// WriteIn is the encrypted write-in passed with the ballot, if any
+	WriteIn string
+}
+
+// stateReadError is a ledger read that failed while a ballot was checked.
+// It is not the ballot's fault, so CastVoteBatch fails the batch on it
+// instead of rejecting the ballot.
+type stateReadError struct{ err error }
+
+func (e stateReadError) Error() string { return e.err.Error() }
+func (e stateReadError) Unwrap() error { return e.err }
+
+// checkVoteSubmission runs every check castVote makes before its first
+// write. It only reads state, so ValidateVote can dry-run it.
+func (v *VoteContract) checkVoteSubmission(
 	ctx contractapi.TransactionContextInterface,
-	electionID string,
-	voterHash string,
-	votingPeriod int,
-	selections []CandidateSelection,
-) error {
-	participationKey := voterParticipationKey(electionID, voterHash, votingPeriod)
-	participationJSON, err := ctx.GetStub().GetState(participationKey)
-	if err != nil {
-		return err
+	sub voteSubmission,
+) (*voteCheck, error) {
+	electionID := sub.ElectionID
+	encryptedVote := sub.EncryptedVote
+	nullifier := sub.Nullifier
+	voterHash := sub.VoterHash
+	candidateSelectionsJSON := sub.CandidateSelectionsJSON
+
+	// Every ballot commits to its proofs
+	if err := checkProofHashes(sub.EligibilityProofHash, sub.ValidityProofHash); err != nil {
+		return nil, err
+	}
+	// Malformed nullifiers are rejected before any state is read
+	if err := checkNullifier(nullifier); err != nil {
+		return nil, err
 	}
 
-	var participation VoterParticipation
-	now := time.Now()
+	// 1. Verify election exists and is active
+	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
+	if err != nil {
+		return nil, stateReadError{fmt.Errorf("failed to read election: %v", err)}
+	}
+	if electionJSON == nil {
+		return nil, fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
 
-	if participationJSON != nil {
-		if err := json.Unmarshal(participationJSON, &participation); err != nil {
-			return err
-		}
+	var election Election
+	if err := json.Unmarshal(electionJSON, &election); err != nil {
+		return nil, err
+	}
+	if err := checkNullifierFormat(&election, nullifier); err != nil {
+		return nil, err
+	}
+
+	// Voting window checks use the transaction timestamp so every endorser
+	// reaches the same decision
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVotingOpen(&election, now); err != nil {
+		return nil, err
+	}
+
+	// Records written before voting modes existed carry no mode
+	if election.VotingMode == "" {
+		election.VotingMode = VotingModeSingle
+	}
+
+	// Proofs built against a superseded voter roll cannot be replayed.
+	// A verified eligibility proof commits to its root itself.
+	if !sub.ProofsVerified || election.EligibilityVerifyingKey == "" {
+		if err := checkProofVoterRoot(&election, sub.ProofVoterRoot); err != nil {
+			return nil, err
+		}
+	}
+
+	// Elections with verifying keys only accept proofs checked on-chain
+	if !sub.ProofsVerified && (election.EligibilityVerifyingKey != "" || election.ValidityVerifyingKey != "") {
+		return nil, fmt.Errorf("election %s requires on-chain proof verification (use CastVoteWithProof)", electionID)
+	}
+
+	// Weighted elections only accept ballots with a proven weight
+	if election.Weighted && sub.Weight < 1 {
+		return nil, fmt.Errorf("election %s is weighted (use CastVoteWeighted)", electionID)
+	}
+
+	// Multi-question elections take one encrypted vote per question
+	questionVotes := sub.QuestionVotes
+	if len(questionVotes) > 0 {
+		questions := electionQuestions(&election)
+		for questionID := range questionVotes {
+			if findQuestion(questions, questionID) == nil {
+				return nil, fmt.Errorf("unknown question %s", questionID)
+			}
+		}
+		if len(election.Questions) == 0 {
+			// Single-question election: store as a plain ballot
+			encryptedVote = questionVotes[DefaultQuestionID]
+			questionVotes = nil
+		} else {
+			// Map keys are marshalled in sorted order, so the hash is stable
+			ballotJSON, err := json.Marshal(questionVotes)
+			if err != nil {
+				return nil, err
+			}
+			encryptedVote = string(ballotJSON)
+		}
+	} else if len(election.Questions) > 0 {
+		return nil, fmt.Errorf("election %s has multiple questions (use CastVoteMultiQuestion)", electionID)
+	}
+
+	// Reject ballots that are not ciphertexts in the election's group
+	if len(questionVotes) > 0 {
+		for questionID, questionVote := range questionVotes {
+			if err := validateCiphertext(questionVote, questionPublicKey(&election, questionID)); err != nil {
+				return nil, fmt.Errorf("question %s: %w", questionID, err)
+			}
+			if err := checkBallotWidth(&election, questionID, questionVote); err != nil {
+				return nil, fmt.Errorf("question %s: %v", questionID, err)
+			}
+		}
+	} else if err := validateCiphertext(encryptedVote, election.PublicKey); err != nil {
+		return nil, err
+	} else if err := checkRankedBallot(&election, encryptedVote); err != nil {
+		return nil, err
+	} else if err := checkBallotWidth(&election, DefaultQuestionID, encryptedVote); err != nil {
+		return nil, err
+	}
+
+	// Each ciphertext must provably encrypt an allowed count, or one ballot
+	// could add any number of votes to the homomorphic tally
+	if election.RangeProofScheme != "" {
+		if err := checkBallotRange(ctx, &election, sub, encryptedVote, questionVotes); err != nil {
+			return nil, err
+		}
+	}
+
+	writeIn, err := submittedWriteIn(ctx, &election)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Calculate current voting period for PERIODIC_RESET mode
+	currentPeriod := currentVotingPeriod(&election, now)
+
+	// 3. Check voting eligibility based on mode. Votes are keyed by the
+	// nullifier commitment, so the nullifier itself is never stored.
+	commitment := nullifierCommitment(electionID, nullifier)
+	nullifierKey, err := voteKey(ctx, electionID, nullifier)
+	if err != nil {
+		return nil, err
+	}
+	amended := false
+	previousSequence := 0
+	if election.VotingMode == VotingModeSingle {
+		// Traditional: Check nullifier hasn't been used, unless the election
+		// lets the voter replace their vote
+		existingVote, err := ctx.GetStub().GetState(nullifierKey)
+		if err != nil {
+			return nil, stateReadError{fmt.Errorf("failed to check nullifier: %v", err)}
+		}
+		if existingVote != nil {
+			// A gateway retrying the same ballot gets the original receipt;
+			// only a different ciphertext is a second vote
+			var previous Vote
+			if err := json.Unmarshal(existingVote, &previous); err != nil {
+				return nil, err
+			}
+			// An invalidated vote may not be replaced by an amendment
+			if previous.Invalidated {
+				return nil, ErrVoteInvalidated
+			}
+			if previous.EncryptedVoteHash == hashForElection(&election, encryptedVote) {
+				return &voteCheck{Election: election, Resubmission: &previous}, nil
+			}
+			if !election.AllowRevote {
+				return nil, ErrDuplicateNullifier
+			}
+			amended = true
+			previousSequence = previous.BulletinSequence
+		}
+		if election.AllowDelegation {
+			delegated, err := hasDelegated(ctx, electionID, commitment)
+			if err != nil {
+				return nil, stateReadError{err}
+			}
+			if delegated {
+				return nil, ErrVoteDelegated
+			}
+		}
+	} else if voterHash != "" {
+		// Multi-limited or Periodic reset: Check participation record
+		participationKey := voterParticipationKey(electionID, voterHash, currentPeriod)
+		participationJSON, err := ctx.GetStub().GetState(participationKey)
+		if err != nil {
+			return nil, stateReadError{fmt.Errorf("failed to check participation: %v", err)}
+		}
+
+		if participationJSON != nil {
+			var participation VoterParticipation
+			if err := json.Unmarshal(participationJSON, &participation); err != nil {
+				return nil, err
+			}
+
+			if election.VotingMode == VotingModePeriodicReset {
+				// Already voted in this period
+				return nil, fmt.Errorf("already voted in this period (%d)", currentPeriod)
+			} else if election.VotingMode == VotingModeMultiLimited {
+				// Check if max votes reached
+				maxTotal := election.MaxCandidatesPerVoter * election.MaxVotesPerCandidate
+				if participation.TotalVotesCast >= maxTotal {
+					return nil, fmt.Errorf("maximum votes (%d) reached", maxTotal)
+				}
+			}
+		}
+	}
+
+	// Reject votes beyond a fixed electorate size. An amendment replaces a
+	// counted vote, so it never exceeds the limit.
+	if election.MaxVoters > 0 && !amended {
+		count, err := countVotes(ctx, &election)
+		if err != nil {
+			return nil, stateReadError{err}
+		}
+		if count >= election.MaxVoters {
+			return nil, fmt.Errorf("electorate limit reached (%d voters)", election.MaxVoters)
+		}
+	}
+
+	if election.UniqueCiphertexts {
+		err := checkCiphertextUnused(ctx, electionID, hashForElection(&election, encryptedVote), commitment)
+		if errors.Is(err, ErrDuplicateCiphertext) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, stateReadError{err}
+		}
+	}
+
+	// 4. Parse candidate selections for MULTI_LIMITED mode
+	var candidateSelections []CandidateSelection
+	if candidateSelectionsJSON != "" {
+		if err := json.Unmarshal([]byte(candidateSelectionsJSON), &candidateSelections); err != nil {
+			return nil, fmt.Errorf("invalid candidate selections: %v", err)
+		}
+	}
+
+	return &voteCheck{
+		Election:            election,
+		EncryptedVote:       encryptedVote,
+		QuestionVotes:       questionVotes,
+		CurrentPeriod:       currentPeriod,
+		Commitment:          commitment,
+		NullifierKey:        nullifierKey,
+		Amended:             amended,
+		PreviousSequence:    previousSequence,
+		CandidateSelections: candidateSelections,
+		WriteIn:             writeIn,
+	}, nil
+}
+
+func (v *VoteContract) castVote(
+	ctx contractapi.TransactionContextInterface,
+	sub voteSubmission,
+) (*VoteReceipt, error) {
+	electionID := sub.ElectionID
+	eligibilityProofHash := sub.EligibilityProofHash
+	validityProofHash := sub.ValidityProofHash
+	voterHash := sub.VoterHash
+
+	// 1-4. Election, window, ballot and eligibility checks
+	check, err := v.checkVoteSubmission(ctx, sub)
+	if errors.Is(err, ErrDuplicateNullifier) {
+		return v.recordDuplicateAttempt(ctx, sub, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	election := check.Election
+	if check.Resubmission != nil {
+		return v.reissueReceipt(ctx, &election, check.Resubmission)
+	}
+	encryptedVote := check.EncryptedVote
+	questionVotes := check.QuestionVotes
+	currentPeriod := check.CurrentPeriod
+	commitment := check.Commitment
+	nullifierKey := check.NullifierKey
+	amended := check.Amended
+	previousSequence := check.PreviousSequence
+	candidateSelections := check.CandidateSelections
+
+	var proof *VoteProof
+	if election.ProofStorage != ProofStorageNone {
+		if proof, err = submittedVoteProof(ctx, &election, sub); err != nil {
+			return nil, err
+		}
+		proof.NullifierCommitment = commitment
+	}
+
+	// 5. Compute encrypted vote hash
+	encryptedVoteHash := hashForElection(&election, encryptedVote)
+
+	// 6. Get transaction context
+	txID := ctx.GetStub().GetTxID()
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC()
+
+	// A ballot submitted late by an offline kiosk carries when it was cast
+	clientCastTime, err := submittedCastTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	castTime, err := checkCastTime(&election, encryptedVoteHash, clientCastTime, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	// The vote's bulletin board entry is the single one appended below
+	sequence, err := bulletinBoardLength(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	sequence++
+
+	// 7. Create vote record
+	vote := Vote{
+		ElectionID:           electionID,
+		EncryptedVote:        encryptedVote,
+		EncryptedVoteHash:    encryptedVoteHash,
+		NullifierCommitment:  commitment,
+		EligibilityProofHash: eligibilityProofHash,
+		ValidityProofHash:    validityProofHash,
+		Timestamp:            timestamp,
+		TxID:                 txID,
+		BlockNumber:          0, // set later by ConfirmVoteBlock
+		VotingPeriod:         currentPeriod,
+		CandidateSelections:  candidateSelections,
+		QuestionVotes:        questionVotes,
+		Weight:               sub.Weight,
+		BulletinSequence:     sequence,
+		InGracePeriod:        inGracePeriod(&election, timestamp),
+		EncryptedWriteIn:     check.WriteIn,
+		CastTime:             castTime,
+		SchemaVersion:        VoteSchemaVersion,
+	}
+
+	// Move the ciphertext into the private data collection
+	if sub.PrivateCollection != "" {
+		privateJSON, err := json.Marshal(PrivateVote{
+			ElectionID:          electionID,
+			NullifierCommitment: commitment,
+			EncryptedVote:       encryptedVote,
+			EncryptedVoteHash:   encryptedVoteHash,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.GetStub().PutPrivateData(sub.PrivateCollection, nullifierKey, privateJSON); err != nil {
+			return nil, fmt.Errorf("failed to store private vote: %v", err)
+		}
+		vote.EncryptedVote = ""
+		vote.PrivateCollection = sub.PrivateCollection
+	}
+
+	voteJSON, err := json.Marshal(vote)
+	if err != nil {
+		return nil, err
+	}
+
+	// 8. Store vote and count it. A failure anywhere below aborts the whole
+	// transaction, so the counter never drifts from the stored votes. An
+	// amendment overwrites the previous vote, which stays in the key history.
+	if err := ctx.GetStub().PutState(nullifierKey, voteJSON); err != nil {
+		return nil, fmt.Errorf("failed to store vote: %v", err)
+	}
+	if proof != nil {
+		if err := storeVoteProof(ctx, &election, proof); err != nil {
+			return nil, err
+		}
+	}
+	if !amended {
+		if err := addToVoteCounters(ctx, map[string]int{voteCounterKey(&election, commitment): 1}); err != nil {
+			return nil, fmt.Errorf("failed to update vote count: %v", err)
+		}
+	}
+	metricsKey := voteMetricsKey(voteCounterKey(&election, commitment))
+	if err := addVoteMetrics(ctx, map[string]voteMetrics{metricsKey: {VotesCast: 1, LastVoteTime: timestamp}}); err != nil {
+		return nil, err
+	}
+	if err := indexVoteSequence(ctx, electionID, sequence, commitment, previousSequence); err != nil {
+		return nil, err
+	}
+	if election.UniqueCiphertexts {
+		if err := indexCiphertext(ctx, electionID, encryptedVoteHash, commitment); err != nil {
+			return nil, err
+		}
+	}
+
+	// 9. Update voter participation (for MULTI_LIMITED and PERIODIC_RESET)
+	if voterHash != "" && election.VotingMode != VotingModeSingle {
+		if err := v.updateVoterParticipation(ctx, electionID, voterHash, currentPeriod, candidateSelections); err != nil {
+			return nil, fmt.Errorf("failed to update participation: %v", err)
+		}
+	}
+
+	// 10. Add to bulletin board
+	entryType := "vote_cast"
+	if amended {
+		entryType = "vote_amended"
+	}
+	if err := v.addBulletinBoardEntry(ctx, electionID, entryType, encryptedVoteHash); err != nil {
+		return nil, fmt.Errorf("failed to update bulletin board: %v", err)
+	}
+
+	// 11. Emit event. seq is the vote's bulletin board sequence, so a
+	// subscriber can order events and fetch any it missed with
+	// GetBulletinBoardRange.
+	eventPayload := map[string]interface{}{
+		"electionId":          electionID,
+		"encryptedVoteHash":   encryptedVoteHash,
+		"nullifierCommitment": commitment,
+		"seq":                 sequence,
+		"txId":              txID,
+		"votingMode":        election.VotingMode,
+		"votingPeriod":      currentPeriod,
+		"weight":            sub.Weight,
+		"amended":           amended,
+	}
+	eventJSON, _ := json.Marshal(eventPayload)
+	if err := ctx.GetStub().SetEvent(eventName(&election, "VoteCast"), eventJSON); err != nil {
+		return nil, fmt.Errorf("failed to emit event: %v", err)
+	}
+
+	// 12. Generate verification code, unique within the election
+	verificationCode, err := assignVerificationCode(ctx, &election, txID, encryptedVoteHash, commitment, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 13. Return receipt, signed when the election has a signing key
+	receipt := &VoteReceipt{
+		Success:           true,
+		VerificationCode:  verificationCode,
+		EncryptedVoteHash: encryptedVoteHash,
+		TxID:              txID,
+		BlockNumber:       0, // unknown at endorsement time
+		Timestamp:         timestamp,
+	}
+	signingKey, err := loadReceiptSigningKey(ctx, &election)
+	if err != nil {
+		return nil, err
+	}
+	if signingKey != nil {
+		receipt.Signature = signReceipt(signingKey, receipt)
+	}
+	return receipt, nil
+}
+
+// recordDuplicateAttempt logs a vote rejected for a used nullifier on the
+// bulletin board when the election records such attempts, and returns an
+// unsuccessful receipt so the entry is committed. Otherwise it returns the
+// rejection.
+func (v *VoteContract) recordDuplicateAttempt(
+	ctx contractapi.TransactionContextInterface,
+	sub voteSubmission,
+	rejection error,
+) (*VoteReceipt, error) {
+	election, err := v.GetElection(ctx, sub.ElectionID)
+	if err != nil {
+		return nil, err
+	}
+	if !election.RecordDuplicateAttempts {
+		return nil, rejection
+	}
+
+	// Only the commitment is published, never the ballot
+	commitment := nullifierCommitment(sub.ElectionID, sub.Nullifier)
+	if err := v.addBulletinBoardEntry(ctx, sub.ElectionID, "duplicate_attempt", commitment); err != nil {
+		return nil, err
+	}
+
+	timestamp, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	metricsKey := voteMetricsKey(voteCounterKey(election, commitment))
+	if err := addVoteMetrics(ctx, map[string]voteMetrics{metricsKey: {DuplicateAttempts: 1}}); err != nil {
+		return nil, err
+	}
+	return &VoteReceipt{
+		Success:   false,
+		TxID:      ctx.GetStub().GetTxID(),
+		Timestamp: timestamp,
+		Error:     rejection.Error(),
+	}, nil
+}
+
+// reissueReceipt rebuilds the receipt of a stored vote for a retried
+// submission. The receipt signature is deterministic, so it matches the one
+// originally returned.
+func (v *VoteContract) reissueReceipt(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	vote *Vote,
+) (*VoteReceipt, error) {
+	verificationCode, err := findVerificationCode(ctx, election, vote.TxID, vote.EncryptedVoteHash, vote.NullifierCommitment)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &VoteReceipt{
+		Success:           true,
+		VerificationCode:  verificationCode,
+		EncryptedVoteHash: vote.EncryptedVoteHash,
+		TxID:              vote.TxID,
+		BlockNumber:       vote.BlockNumber,
+		Timestamp:         vote.Timestamp,
+	}
+	signingKey, err := loadReceiptSigningKey(ctx, election)
+	if err != nil {
+		return nil, err
+	}
+	if signingKey != nil {
+		receipt.Signature = signReceipt(signingKey, receipt)
+	}
+	return receipt, nil
+}
+
+// checkProofVoterRoot rejects eligibility proofs that were not built against
+// the election's current voter merkle root. The root is the caller's word,
+// so the check is advisory; elections with an eligibility verifying key
+// read it from the proof instead (see bindBallotProofs).
+func checkProofVoterRoot(election *Election, proofVoterRoot string) error {
+	if proofVoterRoot != election.VoterMerkleRoot {
+		return fmt.Errorf("eligibility proof references a stale voter root")
+	}
+	return nil
+}
+
+// checkVotingOpen rejects votes unless the election is active and within its
+// voting window, extended by the grace period
+func checkVotingOpen(election *Election, now time.Time) error {
+	if election.Status == "paused" {
+		return ErrElectionPaused
+	}
+	if election.Status == "cancelled" {
+		return ErrElectionCancelled
+	}
+	if election.Status != "active" {
+		return fmt.Errorf("%w (current status: %s)", ErrElectionNotActive, election.Status)
+	}
+
+	// Check time bounds
+	if now.Before(election.StartTime) {
+		return ErrElectionNotStarted
+	}
+	if now.After(votingDeadline(election)) {
+		return ErrElectionEnded
+	}
+	return nil
+}
+
+// votingDeadline is the last moment a vote is accepted
+func votingDeadline(election *Election) time.Time {
+	return election.EndTime.Add(time.Duration(election.GracePeriodSeconds) * time.Second)
+}
+
+// inGracePeriod reports whether a vote cast at now arrived after EndTime
+func inGracePeriod(election *Election, now time.Time) bool {
+	return now.After(election.EndTime)
+}
+
+// currentVotingPeriod returns the PERIODIC_RESET period containing now
+func currentVotingPeriod(election *Election, now time.Time) int {
+	if election.VotingMode == VotingModePeriodicReset && election.ResetIntervalHours > 0 {
+		elapsed := now.Sub(election.StartTime)
+		return int(elapsed.Hours()) / election.ResetIntervalHours
+	}
+	return 0
+}
+
+// EncryptedBallotInput is one ballot of a CastVoteBatch call
+type EncryptedBallotInput struct {
+	EncryptedVote        string `json:"encryptedVote"`
+	Nullifier            string `json:"nullifier"`
+	EligibilityProofHash string `json:"eligibilityProofHash"`
+	ValidityProofHash    string `json:"validityProofHash"`
+	ProofVoterRoot       string `json:"proofVoterRoot"`
+	// ClientCastTime is the kiosk-signed time the ballot was cast, if any
+	ClientCastTime *ClientCastTime `json:"clientCastTime,omitempty"`
+}
+
+// BatchVoteError explains why one ballot of a batch was rejected
+type BatchVoteError struct {
+	Index     int    `json:"index"`
+	Nullifier string `json:"nullifier"`
+	Error     string `json:"error"`
+}
+
+// BatchVoteResult reports a CastVoteBatch call. Receipts are in input order
+// and cover every ballot that is not listed in Errors.
+type BatchVoteResult struct {
+	Receipts []VoteReceipt    `json:"receipts"`
+	Errors   []BatchVoteError `json:"errors"`
+}
+
+// CastVoteBatch records many ballots in one transaction, e.g. when a polling
+// place flushes its offline buffer. The election is checked once; ballots
+// that fail their own checks are reported in Errors and the rest are stored.
+func (v *VoteContract) CastVoteBatch(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	votes []EncryptedBallotInput,
+) (*BatchVoteResult, error) {
+	if len(votes) == 0 {
+		return nil, fmt.Errorf("batch is empty")
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVotingOpen(election, now); err != nil {
+		return nil, err
+	}
+	if election.EligibilityVerifyingKey != "" || election.ValidityVerifyingKey != "" {
+		return nil, fmt.Errorf("election %s requires on-chain proof verification (use CastVoteWithProof)", electionID)
+	}
+	if election.ProofStorage != ProofStorageNone {
+		return nil, fmt.Errorf("election %s stores vote proofs (use CastVote)", electionID)
+	}
+	if election.RangeProofScheme != "" {
+		return nil, fmt.Errorf("election %s requires ballot range proofs (use CastVote)", electionID)
+	}
+	if len(election.Questions) > 0 {
+		return nil, fmt.Errorf("election %s has multiple questions (use CastVoteMultiQuestion)", electionID)
+	}
+	// A transient write-in could not say which ballot it belongs to
+	writeIn, err := submittedWriteIn(ctx, election)
+	if err != nil {
+		return nil, err
+	}
+	if writeIn != "" {
+		return nil, fmt.Errorf("write-ins are cast one at a time with CastVote")
+	}
+	currentPeriod := currentVotingPeriod(election, now)
+
+	// Room left under a fixed electorate size
+	remaining := -1
+	if election.MaxVoters > 0 {
+		count, err := countVotes(ctx, election)
+		if err != nil {
+			return nil, err
+		}
+		remaining = election.MaxVoters - count
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timestamp: %v", err)
+	}
+	timestamp := time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC()
+
+	signingKey, err := loadReceiptSigningKey(ctx, election)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BatchVoteResult{Receipts: []VoteReceipt{}, Errors: []BatchVoteError{}}
+	reject := func(index int, ballot EncryptedBallotInput, reason string) {
+		result.Errors = append(result.Errors, BatchVoteError{Index: index, Nullifier: ballot.Nullifier, Error: reason})
+	}
+
+	// Writes are not visible to GetState within the transaction, so
+	// duplicates inside the batch are tracked here
+	seen := make(map[string]bool)
+	seenCiphertexts := make(map[string]bool)
+	codes := make(map[string]bool)
+	counters := make(map[string]int)
+	metrics := make(map[string]voteMetrics)
+	var hashes, commitments []string
+
+	// Accepted ballots take consecutive bulletin board sequences
+	baseSequence, err := bulletinBoardLength(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	for i, ballot := range votes {
+		if ballot.Nullifier == "" || ballot.EncryptedVote == "" {
+			reject(i, ballot, "nullifier and encrypted vote are required")
+			continue
+		}
+		if seen[ballot.Nullifier] {
+			reject(i, ballot, "duplicate nullifier within batch")
+			continue
+		}
+
+		// Each ballot passes the same checks as a single CastVote
+		check, err := v.checkVoteSubmission(ctx, voteSubmission{
+			ElectionID:           electionID,
+			EncryptedVote:        ballot.EncryptedVote,
+			Nullifier:            ballot.Nullifier,
+			EligibilityProofHash: ballot.EligibilityProofHash,
+			ValidityProofHash:    ballot.ValidityProofHash,
+			ProofVoterRoot:       ballot.ProofVoterRoot,
+		})
+		var readErr stateReadError
+		if errors.As(err, &readErr) {
+			return nil, err
+		}
+		if err != nil {
+			reject(i, ballot, err.Error())
+			continue
+		}
+		// Amendments and resubmissions are cast one at a time with CastVote
+		if check.Amended || check.Resubmission != nil {
+			reject(i, ballot, ErrDuplicateNullifier.Error())
+			continue
+		}
+		key := check.NullifierKey
+		encryptedVoteHash := hashForElection(election, ballot.EncryptedVote)
+		commitment := check.Commitment
+		if election.UniqueCiphertexts && seenCiphertexts[encryptedVoteHash] {
+			reject(i, ballot, ErrDuplicateCiphertext.Error())
+			continue
+		}
+		castTime, err := checkCastTime(election, encryptedVoteHash, ballot.ClientCastTime, timestamp)
+		if err != nil {
+			reject(i, ballot, err.Error())
+			continue
+		}
+		if remaining >= 0 && len(hashes) >= remaining {
+			reject(i, ballot, fmt.Sprintf("electorate limit reached (%d voters)", election.MaxVoters))
+			continue
+		}
+		seen[ballot.Nullifier] = true
+		seenCiphertexts[encryptedVoteHash] = true
+
+		sequence := baseSequence + len(hashes) + 1
+		voteJSON, err := json.Marshal(Vote{
+			ElectionID:           electionID,
+			EncryptedVote:        ballot.EncryptedVote,
+			EncryptedVoteHash:    encryptedVoteHash,
+			NullifierCommitment:  commitment,
+			EligibilityProofHash: ballot.EligibilityProofHash,
+			ValidityProofHash:    ballot.ValidityProofHash,
+			Timestamp:            timestamp,
+			TxID:                 txID,
+			VotingPeriod:         currentPeriod,
+			BulletinSequence:     sequence,
+			InGracePeriod:        inGracePeriod(election, timestamp),
+			CastTime:             castTime,
+			SchemaVersion:        VoteSchemaVersion,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := ctx.GetStub().PutState(key, voteJSON); err != nil {
+			return nil, fmt.Errorf("failed to store vote: %v", err)
+		}
+		if err := indexVoteSequence(ctx, electionID, sequence, commitment, 0); err != nil {
+			return nil, err
+		}
+		if election.UniqueCiphertexts {
+			if err := indexCiphertext(ctx, electionID, encryptedVoteHash, commitment); err != nil {
+				return nil, err
+			}
+		}
+		verificationCode, err := assignVerificationCode(ctx, election, txID, encryptedVoteHash,
+			commitment, codes)
+		if err != nil {
+			return nil, err
+		}
+
+		hashes = append(hashes, encryptedVoteHash)
+		commitments = append(commitments, commitment)
+		counterKey := voteCounterKey(election, commitment)
+		counters[counterKey]++
+		metrics[voteMetricsKey(counterKey)] = voteMetrics{
+			VotesCast:    metrics[voteMetricsKey(counterKey)].VotesCast + 1,
+			LastVoteTime: timestamp,
+		}
+		receipt := VoteReceipt{
+			Success:           true,
+			VerificationCode:  verificationCode,
+			EncryptedVoteHash: encryptedVoteHash,
+			TxID:              txID,
+			Timestamp:         timestamp,
+		}
+		if signingKey != nil {
+			receipt.Signature = signReceipt(signingKey, &receipt)
+		}
+		result.Receipts = append(result.Receipts, receipt)
+	}
+
+	if len(hashes) == 0 {
+		return result, nil
+	}
+
+	// Counter and bulletin board are updated once for the whole batch
+	if err := addToVoteCounters(ctx, counters); err != nil {
+		return nil, fmt.Errorf("failed to update vote count: %v", err)
+	}
+	if err := addVoteMetrics(ctx, metrics); err != nil {
+		return nil, err
+	}
+	if err := v.addBulletinBoardEntries(ctx, electionID, "vote_cast", hashes...); err != nil {
+		return nil, fmt.Errorf("failed to update bulletin board: %v", err)
+	}
+
+	eventJSON, _ := json.Marshal(map[string]interface{}{
+		"electionId":           electionID,
+		"encryptedVoteHashes":  hashes,
+		"nullifierCommitments": commitments,
+		"rejected":             len(result.Errors),
+		"txId":                txID,
+	})
+	if err := ctx.GetStub().SetEvent(eventName(election, "VoteBatchCast"), eventJSON); err != nil {
+		return nil, fmt.Errorf("failed to emit event: %v", err)
+	}
+
+	return result, nil
+}
+
+// ConfirmVoteBlock records the block a vote was committed in. The block
+// height is unknown at endorsement time, so a block listener on the peer
+// side calls this with an admin identity once the CastVote transaction
+// has been committed. The vote is named by the nullifierCommitment of its
+// VoteCast event.
+func (v *VoteContract) ConfirmVoteBlock(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	commitment string,
+	blockNumber uint64,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	if blockNumber == 0 {
+		return fmt.Errorf("invalid block number: 0")
+	}
+
+	key, vote, err := readVoteByCommitment(ctx, electionID, commitment)
+	if err != nil {
+		return err
+	}
+
+	if vote.BlockNumber == blockNumber {
+		return nil
+	}
+	if vote.BlockNumber != 0 {
+		return fmt.Errorf("vote already confirmed in block %d", vote.BlockNumber)
+	}
+
+	vote.BlockNumber = blockNumber
+
+	voteJSON, err := json.Marshal(vote)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, voteJSON); err != nil {
+		return fmt.Errorf("failed to store vote: %v", err)
+	}
+
+	// The index entry keeps the hash and txId of this version, so it still
+	// describes what the block recorded after an amendment
+	entryJSON, err := json.Marshal(VoteBlockEntry{
+		EncryptedVoteHash: vote.EncryptedVoteHash,
+		TxID:              vote.TxID,
+		BlockNumber:       blockNumber,
+	})
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(voteBlockKey(electionID, blockNumber, vote.EncryptedVoteHash), entryJSON); err != nil {
+		return fmt.Errorf("failed to index vote block: %v", err)
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	eventJSON, _ := json.Marshal(map[string]interface{}{
+		"electionId":        electionID,
+		"encryptedVoteHash": vote.EncryptedVoteHash,
+		"txId":              vote.TxID,
+		"blockNumber":       blockNumber,
+	})
+	return ctx.GetStub().SetEvent(eventName(election, "VoteConfirmed"), eventJSON)
+}
+
+// InvalidateVote marks a vote proven fraudulent, e.g. by a forged
+// eligibility proof, as invalid. The vote record is kept with the reason,
+// but aggregation, tally verification and GetAllVotes skip it and it no
+// longer counts towards GetVoteCount. A completed tally must be reopened
+// first. The vote is named by its nullifier commitment.
+func (v *VoteContract) InvalidateVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	commitment string,
+	reason string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	if reason == "" {
+		return fmt.Errorf("a reason for invalidating the vote is required")
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.Status == "completed" || election.Status == "archived" {
+		return fmt.Errorf("votes of a %s election cannot be invalidated", election.Status)
+	}
+
+	key, vote, err := readVoteByCommitment(ctx, electionID, commitment)
+	if err != nil {
+		return err
+	}
+	if vote.Invalidated {
+		return ErrVoteInvalidated
+	}
+
+	vote.Invalidated = true
+	vote.InvalidationReason = reason
+
+	voteJSON, err := json.Marshal(vote)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, voteJSON); err != nil {
+		return fmt.Errorf("failed to store vote: %v", err)
+	}
+	if err := addToVoteCounters(ctx, map[string]int{voteCounterKey(election, commitment): -1}); err != nil {
+		return fmt.Errorf("failed to update vote count: %v", err)
+	}
+
+	// The entry names the vote_cast entry's hash so auditors can pair them
+	if err := v.addBulletinBoardEntry(ctx, electionID, "vote_invalidated", vote.EncryptedVoteHash); err != nil {
+		return err
+	}
+
+	eventJSON, _ := json.Marshal(map[string]interface{}{
+		"electionId":        electionID,
+		"encryptedVoteHash": vote.EncryptedVoteHash,
+		"reason":            reason,
+	})
+	return ctx.GetStub().SetEvent(eventName(election, "VoteInvalidated"), eventJSON)
+}
+
+// updateVoterParticipation updates or creates a voter participation record
+func (v *VoteContract) updateVoterParticipation(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	voterHash string,
+	votingPeriod int,
+	selections []CandidateSelection,
+) error {
+	participationKey := voterParticipationKey(electionID, voterHash, votingPeriod)
+	participationJSON, err := ctx.GetStub().GetState(participationKey)
+	if err != nil {
+		return err
+	}
+
+	var participation VoterParticipation
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	if participationJSON != nil {
+		if err := json.Unmarshal(participationJSON, &participation); err != nil {
+			return err
+		}
 		participation.LastVoteAt = now
 	} else {
-		participation = VoterParticipation{
-			VoterHash:        voterHash,
-			ElectionID:       electionID,
-			VotingPeriod:     votingPeriod,
-			VotesByCandidate: make(map[string]int),
-			TotalVotesCast:   0,
-			FirstVoteAt:      now,
-			LastVoteAt:       now,
+		participation = VoterParticipation{
+			VoterHash:        voterHash,
+			ElectionID:       electionID,
+			VotingPeriod:     votingPeriod,
+			VotesByCandidate: make(map[string]int),
+			TotalVotesCast:   0,
+			FirstVoteAt:      now,
+			LastVoteAt:       now,
+		}
+	}
+
+	// Update vote counts
+	votesInThisSubmission := 0
+	for _, selection := range selections {
+		if participation.VotesByCandidate == nil {
+			participation.VotesByCandidate = make(map[string]int)
+		}
+		participation.VotesByCandidate[selection.CandidateID] += selection.Votes
+		votesInThisSubmission += selection.Votes
+	}
+
+	if votesInThisSubmission == 0 {
+		votesInThisSubmission = 1 // Default 1 vote if no selections
+	}
+	participation.TotalVotesCast += votesInThisSubmission
+
+	updatedJSON, err := json.Marshal(participation)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(participationKey, updatedJSON)
+}
+
+// GetVoterParticipation retrieves voter participation status
+func (v *VoteContract) GetVoterParticipation(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	voterHash string,
+	votingPeriod int,
+) (*VoterParticipation, error) {
+	participationKey := voterParticipationKey(electionID, voterHash, votingPeriod)
+	participationJSON, err := ctx.GetStub().GetState(participationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read participation: %v", err)
+	}
+	if participationJSON == nil {
+		return nil, nil // No participation yet
+	}
+
+	var participation VoterParticipation
+	if err := json.Unmarshal(participationJSON, &participation); err != nil {
+		return nil, err
+	}
+
+	return &participation, nil
+}
+
+// GetVote retrieves a vote by nullifier
+func (v *VoteContract) GetVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	nullifier string,
+) (*Vote, error) {
+	key, err := voteKey(ctx, electionID, nullifier)
+	if err != nil {
+		return nil, err
+	}
+	voteJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vote: %w", err)
+	}
+	if voteJSON == nil {
+		return nil, fmt.Errorf("%w in election %s", ErrVoteNotFound, electionID)
+	}
+
+	var vote Vote
+	if err := json.Unmarshal(voteJSON, &vote); err != nil {
+		return nil, err
+	}
+
+	return &vote, nil
+}
+
+// readVoteByCommitment reads a vote and its key by nullifier commitment, for
+// callers that never learn the nullifier
+func readVoteByCommitment(ctx contractapi.TransactionContextInterface, electionID, commitment string) (string, *Vote, error) {
+	key, err := voteKeyForCommitment(ctx, electionID, commitment)
+	if err != nil {
+		return "", nil, err
+	}
+	voteJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read vote: %w", err)
+	}
+	if voteJSON == nil {
+		return "", nil, fmt.Errorf("%w in election %s", ErrVoteNotFound, electionID)
+	}
+
+	var vote Vote
+	if err := json.Unmarshal(voteJSON, &vote); err != nil {
+		return "", nil, err
+	}
+	return key, &vote, nil
+}
+
+// GetVoteReceipt rebuilds the receipt of a stored vote for a voter who lost
+// theirs. The verification code is recomputed and the signature, when the
+// election signs receipts, is deterministic, so the receipt matches the one
+// CastVote returned except for BlockNumber once the vote is confirmed.
+func (v *VoteContract) GetVoteReceipt(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	nullifier string,
+) (*VoteReceipt, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	vote, err := v.GetVote(ctx, electionID, nullifier)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.reissueReceipt(ctx, election, vote)
+}
+
+// GetVoteProof returns the full proofs a vote was cast with, so an auditor
+// can re-run their verification. Only elections created with ProofStorage
+// keep them.
+func (v *VoteContract) GetVoteProof(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	nullifier string,
+) (*VoteProof, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.ProofStorage == ProofStorageNone {
+		return nil, fmt.Errorf("election %s does not store vote proofs", electionID)
+	}
+	vote, err := v.GetVote(ctx, electionID, nullifier)
+	if err != nil {
+		return nil, err
+	}
+	return readVoteProof(ctx, election, vote)
+}
+
+// GetVoteHistory returns every version of a vote, oldest first. Only
+// elections with AllowRevote have more than one. Requires history to be
+// enabled on the peer (ledger.history.enableHistoryDatabase).
+func (v *VoteContract) GetVoteHistory(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	nullifier string,
+) ([]Vote, error) {
+	key, err := voteKey(ctx, electionID, nullifier)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vote history: %v", err)
+	}
+	defer iterator.Close()
+
+	history := []Vote{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vote history: %v", err)
+		}
+		if modification.IsDelete {
+			continue
+		}
+		var vote Vote
+		if err := json.Unmarshal(modification.Value, &vote); err != nil {
+			return nil, fmt.Errorf("invalid vote record in tx %s: %v", modification.TxId, err)
+		}
+		history = append(history, vote)
+	}
+
+	if len(history) == 0 {
+		return nil, fmt.Errorf("%w in election %s", ErrVoteNotFound, electionID)
+	}
+
+	// The peer makes no ordering promise; present a timeline
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+
+	return history, nil
+}
+
+// GetPrivateVote retrieves the ciphertext of a vote cast with CastVotePrivate.
+// Only clients of an organization whose peers are members of the collection
+// may read it.
+func (v *VoteContract) GetPrivateVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	nullifier string,
+) (*PrivateVote, error) {
+	if err := requireCollectionMember(ctx); err != nil {
+		return nil, err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	vote, err := v.GetVote(ctx, electionID, nullifier)
+	if err != nil {
+		return nil, err
+	}
+	if vote.PrivateCollection == "" {
+		return nil, fmt.Errorf("vote is not stored in a private data collection")
+	}
+
+	return getPrivateVote(ctx, election, vote)
+}
+
+// IsNullifierUsed reports whether a nullifier has already been spent in an
+// election, so wallets can skip a vote that would fail as a duplicate.
+// Read-only: submit it as an evaluate transaction. Only existence is
+// returned, never the vote itself.
+func (v *VoteContract) IsNullifierUsed(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	nullifier string,
+) (bool, error) {
+	key, err := voteKey(ctx, electionID, nullifier)
+	if err != nil {
+		return false, err
+	}
+	voteJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to check nullifier: %v", err)
+	}
+
+	return voteJSON != nil, nil
+}
+
+// GetVoteCount returns the number of votes cast in an election without
+// reading the votes themselves
+func (v *VoteContract) GetVoteCount(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (int, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return 0, err
+	}
+	return countVotes(ctx, election)
+}
+
+// GetAllVotes retrieves all votes for an election, ordered by nullifier
+// commitment so repeated calls and re-tallies see the same sequence.
+// Invalidated votes are left out unless includeInvalidated is set. votes
+// holds the ciphertexts; records holds the same votes with their hashes
+// and nullifier commitments.
+func (v *VoteContract) GetAllVotes(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	includeInvalidated bool,
+) (map[string]interface{}, error) {
+	// Walk every page of the election's votes
+	var records []Vote
+	bookmark := ""
+	for {
+		page, nextBookmark, _, err := v.getVotePage(ctx, electionID, allVotesPageSize, bookmark)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, page...)
+		if nextBookmark == "" {
+			break
+		}
+		bookmark = nextBookmark
+	}
+
+	// Key order already follows the commitment, but sorting here keeps the
+	// order canonical regardless of how the index is walked
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].NullifierCommitment < records[j].NullifierCommitment
+	})
+
+	votes := make([]string, 0, len(records))
+	voteRecords := make([]VoteRecord, 0, len(records))
+	for _, vote := range records {
+		if vote.Invalidated && !includeInvalidated {
+			continue
+		}
+		votes = append(votes, vote.EncryptedVote)
+		voteRecords = append(voteRecords, VoteRecord{
+			EncryptedVote:       vote.EncryptedVote,
+			EncryptedVoteHash:   vote.EncryptedVoteHash,
+			NullifierCommitment: vote.NullifierCommitment,
+			Invalidated:         vote.Invalidated,
+		})
+	}
+
+	return map[string]interface{}{
+		"votes":   votes,
+		"records": voteRecords,
+		"count":   len(votes),
+	}, nil
+}
+
+// GetVotesSince returns the votes whose bulletin board sequence is greater
+// than afterSequence, in sequence order, for incremental off-chain indexing.
+// At most allVotesPageSize sequences are scanned per call; lastSequence is
+// the highest sequence scanned, to pass as afterSequence on the next call.
+// An amended vote is returned at the sequence of its latest version only.
+// Votes cast before sequences were recorded are not returned.
+func (v *VoteContract) GetVotesSince(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	afterSequence int,
+) (map[string]interface{}, error) {
+	if afterSequence < 0 {
+		return nil, fmt.Errorf("invalid sequence %d", afterSequence)
+	}
+
+	total, err := bulletinBoardLength(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	lastSequence := afterSequence + allVotesPageSize
+	if lastSequence > total {
+		lastSequence = total
+	}
+
+	// Sequences are dense, so the index is read key by key
+	votes := []Vote{}
+	for seq := afterSequence + 1; seq <= lastSequence; seq++ {
+		commitment, err := ctx.GetStub().GetState(voteSequenceKey(electionID, seq))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vote sequence index: %v", err)
+		}
+		if commitment == nil {
+			continue
+		}
+
+		key, err := voteKeyForCommitment(ctx, electionID, string(commitment))
+		if err != nil {
+			return nil, err
+		}
+		voteJSON, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vote: %v", err)
+		}
+		if voteJSON == nil {
+			return nil, fmt.Errorf("%w at sequence %d", ErrVoteNotFound, seq)
+		}
+		var vote Vote
+		if err := json.Unmarshal(voteJSON, &vote); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	if lastSequence < afterSequence {
+		lastSequence = afterSequence
+	}
+
+	return map[string]interface{}{
+		"votes":        votes,
+		"lastSequence": lastSequence,
+	}, nil
+}
+
+// GetVotesByBlockRange returns the votes confirmed by ConfirmVoteBlock in
+// blocks fromBlock to toBlock inclusive, ordered by block number. Votes not
+// yet confirmed are not returned.
+func (v *VoteContract) GetVotesByBlockRange(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	fromBlock uint64,
+	toBlock uint64,
+) ([]VoteBlockEntry, error) {
+	if fromBlock == 0 || toBlock < fromBlock {
+		return nil, fmt.Errorf("invalid block range %d-%d", fromBlock, toBlock)
+	}
+
+	// Block numbers are zero-padded, so key order is block order
+	iterator, err := ctx.GetStub().GetStateByRange(voteBlockKey(electionID, fromBlock, ""),
+		voteBlockRangeEnd(electionID, toBlock))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vote block index: %v", err)
+	}
+	defer iterator.Close()
+
+	entries := []VoteBlockEntry{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vote block index: %v", err)
+		}
+		var entry VoteBlockEntry
+		if err := json.Unmarshal(kv.Value, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// GetAllVotesPaginated retrieves one page of votes for an election.
+// Pass the returned bookmark to fetch the next page; an empty bookmark
+// means there are no more votes. Paginated queries are read-only in Fabric,
+// so this must be evaluated rather than submitted.
+func (v *VoteContract) GetAllVotesPaginated(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	pageSize int32,
+	bookmark string,
+) (map[string]interface{}, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+
+	page, nextBookmark, fetchedCount, err := v.getVotePage(ctx, electionID, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make([]string, 0, len(page))
+	for _, vote := range page {
+		votes = append(votes, vote.EncryptedVote)
+	}
+
+	return map[string]interface{}{
+		"votes":        votes,
+		"bookmark":     nextBookmark,
+		"fetchedCount": fetchedCount,
+	}, nil
+}
+
+// getVotePage reads one page of vote records for an election
+func (v *VoteContract) getVotePage(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	pageSize int32,
+	bookmark string,
+) ([]Vote, string, int32, error) {
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		voteObjectType, []string{electionID}, pageSize, bookmark)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("failed to query votes: %v", err)
+	}
+	defer iterator.Close()
+
+	votes := []Vote{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to read vote: %v", err)
+		}
+		var vote Vote
+		if err := json.Unmarshal(kv.Value, &vote); err == nil {
+			votes = append(votes, vote)
+		}
+	}
+
+	nextBookmark := ""
+	fetchedCount := int32(0)
+	if metadata != nil {
+		nextBookmark = metadata.Bookmark
+		fetchedCount = metadata.FetchedRecordsCount
+	}
+
+	return votes, nextBookmark, fetchedCount, nil
+}
+
+// VerifyVote verifies a vote exists and matches the provided hash. When the
+// vote is on the bulletin board it also returns its sequence and Merkle
+// inclusion proof, so the voter can check it against the published root.
+// A missing vote is reported as not verified; a ledger read failure fails
+// the call.
+func (v *VoteContract) VerifyVote(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	nullifier string,
+	expectedHash string,
+) (map[string]interface{}, error) {
+	// Only a missing vote is an answer; a read failure fails the call
+	vote, err := v.GetVote(ctx, electionID, nullifier)
+	if errors.Is(err, ErrNotFound) {
+		return map[string]interface{}{
+			"verified": false,
+			"error":    err.Error(),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	verified := vote.EncryptedVoteHash == expectedHash
+
+	result := map[string]interface{}{
+		"verified":   verified,
+		"txId":       vote.TxID,
+		"timestamp":  vote.Timestamp,
+	}
+
+	// The vote's own entry is found by its sequence, so a re-cast ballot
+	// gets the proof of the entry that counts. Votes stored before sequences
+	// were recorded are found by hash, and ones stored before the bulletin
+	// board existed have no proof.
+	proof, err := v.voteInclusionProof(ctx, electionID, func(entry BulletinBoardEntry) bool {
+		if vote.BulletinSequence > 0 {
+			return entry.Sequence == vote.BulletinSequence
+		}
+		return entry.Hash == vote.EncryptedVoteHash
+	})
+	if err != nil {
+		return nil, err
+	}
+	if proof != nil {
+		result["bulletinSequence"] = proof.Sequence
+		result["inclusionProof"] = proof
+	} else if vote.BulletinSequence > 0 {
+		result["bulletinSequence"] = vote.BulletinSequence
+	}
+
+	return result, nil
+}
+
+// MaxVerifyBatchSize bounds the pairs one VerifyVotesBatch call checks
+const MaxVerifyBatchSize = 5000
+
+// VerifyPair is one receipt of a VerifyVotesBatch call
+type VerifyPair struct {
+	Nullifier    string `json:"nullifier"`
+	ExpectedHash string `json:"expectedHash"`
+}
+
+// VerifyPairResult reports one pair of a VerifyVotesBatch call
+type VerifyPairResult struct {
+	Nullifier string `json:"nullifier"`
+	Verified  bool   `json:"verified"`
+	TxID      string `json:"txId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// VerifyVotesBatch checks many {nullifier, expectedHash} pairs in one call,
+// returning a result per pair in input order. It only reads state. A
+// missing vote fails its own pair; a ledger read failure fails the call, so
+// an auditor never mistakes an outage for a missing vote.
+func (v *VoteContract) VerifyVotesBatch(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	pairs []VerifyPair,
+) ([]VerifyPairResult, error) {
+	if len(pairs) == 0 {
+		return nil, fmt.Errorf("batch is empty")
+	}
+	if len(pairs) > MaxVerifyBatchSize {
+		return nil, fmt.Errorf("batch of %d pairs exceeds the limit of %d", len(pairs), MaxVerifyBatchSize)
+	}
+
+	results := make([]VerifyPairResult, len(pairs))
+	for i, pair := range pairs {
+		results[i].Nullifier = pair.Nullifier
+		vote, err := v.GetVote(ctx, electionID, pair.Nullifier)
+		if errors.Is(err, ErrNotFound) {
+			results[i].Error = err.Error()
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results[i].Verified = vote.EncryptedVoteHash == pair.ExpectedHash
+		results[i].TxID = vote.TxID
+	}
+	return results, nil
+}
+
+// GetNullifierSet returns the nullifier commitments used in an election, so
+// a coordinator can intersect them with other channels running the same
+// election to find voters who voted more than once. Up to
+// NullifierSetExactLimit commitments are returned sorted; larger sets are
+// returned as a Bloom filter (see nullifier_set.go).
+func (v *VoteContract) GetNullifierSet(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*NullifierSet, error) {
+	if _, err := v.GetElection(ctx, electionID); err != nil {
+		return nil, err
+	}
+
+	commitments, err := usedNullifierCommitments(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &NullifierSet{
+		ElectionID: electionID,
+		Count:      len(commitments),
+	}
+	if len(commitments) <= NullifierSetExactLimit {
+		set.Commitments = commitments
+		return set, nil
+	}
+
+	set.Bloom = newBloomFilter(len(commitments), NullifierBloomFalsePositiveRate)
+	for _, commitment := range commitments {
+		set.Bloom.add(commitment)
+	}
+	return set, nil
+}
+
+// ProveNonMembership proves a nullifier has not been used in an election,
+// neither by a vote nor by a delegation, so a voter can show they did not
+// take part. The proof commits to the sorted set of used commitments (see
+// nullifier_set.go); it fails when the nullifier was used.
+func (v *VoteContract) ProveNonMembership(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	nullifier string,
+) (*NonMembershipProof, error) {
+	if err := checkNullifier(nullifier); err != nil {
+		return nil, err
+	}
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	commitments, err := usedNullifierCommitments(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := proveNonMembership(nullifierSetScheme(election), commitments, nullifierCommitment(electionID, nullifier))
+	if err != nil {
+		return nil, err
+	}
+	proof.ElectionID = electionID
+	return proof, nil
+}
+
+// ComputeVerificationCode derives the verification code a receipt carries
+// for a vote, so a voter who kept the txId and vote hash can rebuild it. The
+// code uses the election's hash algorithm and code length; a code that was
+// lengthened after a collision starts with it.
+func (v *VoteContract) ComputeVerificationCode(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	txID string,
+	encryptedVoteHash string,
+) (string, error) {
+	if txID == "" || encryptedVoteHash == "" {
+		return "", fmt.Errorf("txId and encrypted vote hash are required")
+	}
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return "", err
+	}
+	return generateVerificationCode(election.HashAlgorithm, txID, encryptedVoteHash, verificationCodeLength(election)), nil
+}
+
+// VerifyReceiptSignature checks that signature was made by the election's
+// receipt signing key over the receipt's txId, vote hash and timestamp
+func (v *VoteContract) VerifyReceiptSignature(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	receiptJSON string,
+	signature string,
+) (bool, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return false, err
+	}
+	if election.ReceiptPublicKey == "" {
+		return false, fmt.Errorf("election %s does not sign receipts", electionID)
+	}
+
+	var receipt VoteReceipt
+	if err := json.Unmarshal([]byte(receiptJSON), &receipt); err != nil {
+		return false, fmt.Errorf("invalid receipt: %v", err)
+	}
+	if err := verifyReceipt(election.ReceiptPublicKey, &receipt, signature); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// GetVoteByVerificationCode retrieves the vote a receipt's verification code
+// was issued for
+func (v *VoteContract) GetVoteByVerificationCode(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	verificationCode string,
+) (*Vote, error) {
+	codeKey, err := verificationCodeKey(ctx, electionID, verificationCode)
+	if err != nil {
+		return nil, err
+	}
+	commitment, err := ctx.GetStub().GetState(codeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verification code: %v", err)
+	}
+	if commitment == nil {
+		return nil, fmt.Errorf("%w for verification code %s", ErrVoteNotFound, verificationCode)
+	}
+
+	key, err := voteKeyForCommitment(ctx, electionID, string(commitment))
+	if err != nil {
+		return nil, err
+	}
+	voteJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vote: %w", err)
+	}
+	if voteJSON == nil {
+		return nil, fmt.Errorf("%w in election %s", ErrVoteNotFound, electionID)
+	}
+
+	var vote Vote
+	if err := json.Unmarshal(voteJSON, &vote); err != nil {
+		return nil, err
+	}
+	return &vote, nil
+}
+
+// GetVoteByHash retrieves a vote by its encrypted vote hash
+func (v *VoteContract) GetVoteByHash(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	encryptedVoteHash string,
+) (map[string]interface{}, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		var vote Vote
+		if err := json.Unmarshal(kv.Value, &vote); err == nil {
+			if vote.EncryptedVoteHash == encryptedVoteHash {
+				return map[string]interface{}{
+					"found":             true,
+					"encryptedVoteHash": vote.EncryptedVoteHash,
+					"txId":              vote.TxID,
+					"blockNumber":       vote.BlockNumber,
+					"timestamp":         vote.Timestamp,
+				}, nil
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"found": false,
+	}, nil
+}
+
+// QueryVotesByTimeRange returns the votes cast between startStr and endStr
+// (inclusive, RFC3339). It issues a CouchDB rich query, so it requires the
+// CouchDB state database. Suggested index, packaged as
+// META-INF/statedb/couchdb/indexes/indexVoteTimestamp.json:
+//
+//	{"index":{"fields":["electionId","timestamp"]},"ddoc":"indexVoteTimestampDoc","name":"indexVoteTimestamp","type":"json"}
+func (v *VoteContract) QueryVotesByTimeRange(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	startStr string,
+	endStr string,
+) ([]VoteQueryResult, error) {
+	start, err := time.Parse(time.RFC3339, startStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start time: %v", err)
+	}
+	end, err := time.Parse(time.RFC3339, endStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end time: %v", err)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("end time must not be before start time")
+	}
+
+	// Stored timestamps are UTC RFC3339Nano strings, which only sort correctly
+	// to the second, so select whole seconds and filter exactly below
+	const secondLayout = "2006-01-02T15:04:05"
+	selector := map[string]interface{}{
+		"selector": map[string]interface{}{
+			"electionId":        electionID,
+			"encryptedVoteHash": map[string]interface{}{"$exists": true},
+			"timestamp": map[string]interface{}{
+				"$gte": start.UTC().Truncate(time.Second).Format(secondLayout),
+				"$lt":  end.UTC().Truncate(time.Second).Add(time.Second).Format(secondLayout),
+			},
+		},
+		"use_index": []string{"_design/indexVoteTimestampDoc", "indexVoteTimestamp"},
+	}
+	queryJSON, err := json.Marshal(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	iterator, err := ctx.GetStub().GetQueryResult(string(queryJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %v", err)
+	}
+	defer iterator.Close()
+
+	results := []VoteQueryResult{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vote: %v", err)
+		}
+		var vote Vote
+		if err := json.Unmarshal(kv.Value, &vote); err != nil {
+			continue
+		}
+		if vote.ElectionID != electionID || vote.Timestamp.Before(start) || vote.Timestamp.After(end) {
+			continue
+		}
+		results = append(results, VoteQueryResult{
+			EncryptedVoteHash: vote.EncryptedVoteHash,
+			TxID:              vote.TxID,
+			Timestamp:         vote.Timestamp,
+		})
+	}
+
+	return results, nil
+}
+
+// MigrateVoteIndex moves votes stored under the legacy nullifier array index
+// to composite keys and removes the index. It returns the number of votes moved.
+func (v *VoteContract) MigrateVoteIndex(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (int, error) {
+	if err := v.requireAdmin(ctx); err != nil {
+		return 0, err
+	}
+
+	indexKey := legacyVoteIndexKey(electionID)
+	indexJSON, err := ctx.GetStub().GetState(indexKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read vote index: %v", err)
+	}
+	if indexJSON == nil {
+		return 0, nil
+	}
+
+	var nullifiers []string
+	if err := json.Unmarshal(indexJSON, &nullifiers); err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, nullifier := range nullifiers {
+		oldKey := legacyVoteKey(electionID, nullifier)
+		voteJSON, err := ctx.GetStub().GetState(oldKey)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to read vote: %v", err)
+		}
+		if voteJSON == nil {
+			continue
+		}
+
+		// Legacy records carry the plaintext nullifier; store the commitment instead
+		var vote Vote
+		if err := json.Unmarshal(voteJSON, &vote); err != nil {
+			return migrated, err
+		}
+		vote.NullifierCommitment = nullifierCommitment(electionID, nullifier)
+		voteJSON, err = json.Marshal(vote)
+		if err != nil {
+			return migrated, err
+		}
+
+		newKey, err := voteKey(ctx, electionID, nullifier)
+		if err != nil {
+			return migrated, err
+		}
+		if err := ctx.GetStub().PutState(newKey, voteJSON); err != nil {
+			return migrated, fmt.Errorf("failed to store vote: %v", err)
+		}
+		if err := ctx.GetStub().DelState(oldKey); err != nil {
+			return migrated, fmt.Errorf("failed to delete legacy vote: %v", err)
+		}
+		migrated++
+	}
+
+	if err := ctx.GetStub().DelState(indexKey); err != nil {
+		return migrated, fmt.Errorf("failed to delete vote index: %v", err)
+	}
+
+	return migrated, nil
+}
+
+// CloseElection closes an election for voting and returns the closed
+// election with its vote checkpoint
+func (v *VoteContract) CloseElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*Election, error) {
+	if err := v.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read election: %v", err)
+	}
+	if electionJSON == nil {
+		return nil, fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
+
+	var election Election
+	if err := json.Unmarshal(electionJSON, &election); err != nil {
+		return nil, err
+	}
+
+	if !canTransition(election.Status, "closed") {
+		return nil, fmt.Errorf("election is not active")
+	}
+
+	if err := setStatus(&election, "closed"); err != nil {
+		return nil, err
+	}
+
+	// Commit to the final set of votes so none can be added or dropped later
+	closedVoteRoot, err := v.computeClosedVoteRoot(ctx, &election)
+	if err != nil {
+		return nil, err
+	}
+	election.ClosedVoteRoot = closedVoteRoot
+
+	updatedJSON, err := json.Marshal(election)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.GetStub().PutState(electionKey(electionID), updatedJSON); err != nil {
+		return nil, err
+	}
+
+	// From here on only the tally authority may move the election forward
+	if err := applyTallyEndorsementPolicy(ctx, &election); err != nil {
+		return nil, err
+	}
+
+	electionHash, err := hashJSON(election.HashAlgorithm, updatedJSON)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.appendBulletinBoardItems(ctx, electionID, "",
+		bulletinItem{Type: "election_closed", Hash: electionHash},
+		bulletinItem{Type: "votes_checkpoint", Hash: closedVoteRoot}); err != nil {
+		return nil, err
+	}
+
+	if err := v.emitStatusChanged(ctx, &election, "active", election.Status); err != nil {
+		return nil, err
+	}
+	return &election, nil
+}
+
+// computeClosedVoteRoot returns the Merkle root over the hashes of every vote
+// stored for an election, invalidated votes included. Elections closed before
+// checkpoints existed have no ClosedVoteRoot and are not checked.
+func (v *VoteContract) computeClosedVoteRoot(ctx contractapi.TransactionContextInterface, election *Election) (string, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{election.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to query votes: %v", err)
+	}
+	defer iterator.Close()
+
+	var hashes []string
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return "", fmt.Errorf("failed to read vote: %v", err)
+		}
+		var vote Vote
+		if err := json.Unmarshal(kv.Value, &vote); err != nil {
+			return "", err
+		}
+		hashes = append(hashes, vote.EncryptedVoteHash)
+	}
+	return computeVoteSetRoot(electionMerkleScheme(election), hashes), nil
+}
+
+// PauseElection temporarily halts voting on an active election
+func (v *VoteContract) PauseElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) error {
+	return v.setPaused(ctx, electionID, "active", "paused", "election_paused")
+}
+
+// ResumeElection reopens voting on a paused election. The election's
+// start and end times still bound CastVote after resuming.
+func (v *VoteContract) ResumeElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) error {
+	return v.setPaused(ctx, electionID, "paused", "active", "election_resumed")
+}
+
+func (v *VoteContract) setPaused(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	fromStatus string,
+	toStatus string,
+	entryType string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	if election.Status != fromStatus {
+		return fmt.Errorf("election is not %s (current status: %s)", fromStatus, election.Status)
+	}
+
+	if err := setStatus(election, toStatus); err != nil {
+		return err
+	}
+
+	updatedJSON, err := v.putElection(ctx, election)
+	if err != nil {
+		return err
+	}
+
+	electionHash, err := hashJSON(election.HashAlgorithm, updatedJSON)
+	if err != nil {
+		return err
+	}
+	if err := v.addBulletinBoardEntry(ctx, electionID, entryType, electionHash); err != nil {
+		return err
+	}
+
+	return v.emitStatusChanged(ctx, election, fromStatus, toStatus)
+}
+
+// CancelElection abandons an election before tallying. Cancellation is
+// terminal: no further votes or tally results are accepted.
+func (v *VoteContract) CancelElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	reason string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	if reason == "" {
+		return fmt.Errorf("a cancellation reason is required")
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	if !canTransition(election.Status, "cancelled") {
+		return fmt.Errorf("election cannot be cancelled (current status: %s)", election.Status)
+	}
+
+	oldStatus := election.Status
+	if err := setStatus(election, "cancelled"); err != nil {
+		return err
+	}
+	election.CancellationReason = reason
+
+	if _, err := v.putElection(ctx, election); err != nil {
+		return err
+	}
+
+	if err := v.addBulletinBoardEntry(ctx, electionID, "election_cancelled", hashForElection(election, reason)); err != nil {
+		return err
+	}
+
+	return v.emitStatusChanged(ctx, election, oldStatus, election.Status)
+}
+
+// ExtendElection pushes back the end time of an active election
+func (v *VoteContract) ExtendElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	newEndTimeStr string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	if election.Status != "active" {
+		return fmt.Errorf("only active elections can be extended (current status: %s)", election.Status)
+	}
+
+	newEndTime, err := time.Parse(time.RFC3339, newEndTimeStr)
+	if err != nil {
+		return fmt.Errorf("invalid end time: %v", err)
+	}
+	if !newEndTime.After(election.EndTime) {
+		return fmt.Errorf("new end time must be after the current end time %s", election.EndTime.Format(time.RFC3339))
+	}
+
+	election.EndTime = newEndTime
+
+	updatedJSON, err := v.putElection(ctx, election)
+	if err != nil {
+		return err
+	}
+
+	electionHash, err := hashJSON(election.HashAlgorithm, updatedJSON)
+	if err != nil {
+		return err
+	}
+	return v.addBulletinBoardEntry(ctx, electionID, "election_extended", electionHash)
+}
+
+// UpdateVoterMerkleRoot replaces the voter roll root of a pending election.
+// The roll is frozen once voting starts so voters can trust it.
+func (v *VoteContract) UpdateVoterMerkleRoot(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	newRoot string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	if election.Status != "pending" {
+		return fmt.Errorf("voter merkle root can only be updated while pending (current status: %s)", election.Status)
+	}
+	if strings.TrimSpace(newRoot) == "" {
+		return fmt.Errorf("voter merkle root is required")
+	}
+
+	election.VoterMerkleRoot = newRoot
+
+	if _, err := v.putElection(ctx, election); err != nil {
+		return err
+	}
+
+	// The root itself is published so voters can compare it with their proofs
+	return v.addBulletinBoardEntry(ctx, electionID, "voter_root_updated", newRoot)
+}
+
+// UpdateElectionPublicKey replaces the public key of a pending election,
+// e.g. when the trustees' key ceremony had to be redone. The key is frozen
+// once voting starts so no ballot is encrypted under a key that later
+// changes.
+func (v *VoteContract) UpdateElectionPublicKey(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	newPublicKey string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	if err := checkBallotMutable(election); err != nil {
+		return err
+	}
+
+	publicKey, err := canonicalElGamalPublicKey(newPublicKey)
+	if err != nil {
+		return err
+	}
+	// Threshold elections keep their trustees, whose keys must still
+	// combine to the election key
+	if len(election.Trustees) > 0 {
+		if err := validateTrustees(publicKey, election.Trustees, election.Threshold); err != nil {
+			return err
+		}
+	}
+
+	election.PublicKey = publicKey
+
+	if _, err := v.putElection(ctx, election); err != nil {
+		return err
+	}
+
+	return v.addBulletinBoardEntry(ctx, electionID, "public_key_updated", hashForElection(election, publicKey))
+}
+
+// UpdateBallotOptions replaces the options of one question of a pending
+// election (DefaultQuestionID in a single-question election), e.g. to add a
+// late nomination. optionsJSON is a JSON array of option IDs.
+func (v *VoteContract) UpdateBallotOptions(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	questionID string,
+	optionsJSON string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if err := checkBallotMutable(election); err != nil {
+		return err
+	}
+
+	var options []string
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return fmt.Errorf("invalid options: %v", err)
+	}
+	if len(election.Questions) == 0 {
+		if questionID != DefaultQuestionID {
+			return fmt.Errorf("unknown question %s", questionID)
+		}
+		if err := validateOptions(questionID, options); err != nil {
+			return err
+		}
+		election.Options = options
+	} else {
+		question := findQuestion(election.Questions, questionID)
+		if question == nil {
+			return fmt.Errorf("unknown question %s", questionID)
+		}
+		question.Options = options
+		if err := validateQuestions(election.Questions); err != nil {
+			return err
+		}
+	}
+
+	// Ranked ballots and write-ins depend on the option list
+	mode := election.VotingMode
+	if mode == "" {
+		mode = VotingModeSingle
+	}
+	config := ElectionConfig{
+		Questions:             election.Questions,
+		Options:               election.Options,
+		BallotType:            election.BallotType,
+		AllowWriteIns:         election.AllowWriteIns,
+		Weighted:              election.Weighted,
+		AllowDelegation:       election.AllowDelegation,
+		Trustees:              election.Trustees,
+		DecryptionProofScheme: election.DecryptionProofScheme,
+		RangeProofScheme:      election.RangeProofScheme,
+	}
+	if err := validateBallotType(config, mode); err != nil {
+		return err
+	}
+	if err := validateWriteIns(config); err != nil {
+		return err
+	}
+
+	if _, err := v.putElection(ctx, election); err != nil {
+		return err
+	}
+
+	updatedJSON, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	optionsHash, err := hashJSON(election.HashAlgorithm, updatedJSON)
+	if err != nil {
+		return err
+	}
+	return v.addBulletinBoardEntry(ctx, electionID, "ballot_options_updated", optionsHash)
+}
+
+// FreezeBallot locks the questions, options and keys of a pending election
+// ahead of activation, e.g. at the end of a formal ballot ceremony.
+// Activation freezes them anyway. The bulletin board records the hash of
+// the frozen election's public parameters; freezing again does nothing.
+func (v *VoteContract) FreezeBallot(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.BallotFrozen {
+		return nil
+	}
+	if err := checkBallotMutable(election); err != nil {
+		return err
+	}
+
+	params, err := v.GetElectionPublicParameters(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	paramsHash, err := hashJSON(election.HashAlgorithm, paramsJSON)
+	if err != nil {
+		return err
+	}
+
+	election.BallotFrozen = true
+	if _, err := v.putElection(ctx, election); err != nil {
+		return err
+	}
+	return v.addBulletinBoardEntry(ctx, electionID, "ballot_frozen", paramsHash)
+}
+
+// SetTallyEndorsementPolicy designates the organizations that must endorse
+// the tally result. The policy is written as key-level endorsement on the
+// tally and election keys once the election is closed, so the ledger itself
+// rejects tally writes the tally authority did not endorse.
+func (v *VoteContract) SetTallyEndorsementPolicy(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	mspIDs []string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	if election.Status == "cancelled" {
+		return fmt.Errorf("election %s has been cancelled", electionID)
+	}
+	if err := validateMSPIDs(mspIDs); err != nil {
+		return err
+	}
+
+	election.TallyEndorsers = mspIDs
+
+	if _, err := v.putElection(ctx, election); err != nil {
+		return err
+	}
+
+	// Pending and active elections get the policy when they are closed
+	switch election.Status {
+	case "closed", "tallying", "completed":
+		if err := applyTallyEndorsementPolicy(ctx, election); err != nil {
+			return err
+		}
+	}
+
+	sorted := append([]string(nil), mspIDs...)
+	sort.Strings(sorted)
+	return v.addBulletinBoardEntry(ctx, electionID, "tally_endorsers_set", hashForElection(election, strings.Join(sorted, ",")))
+}
+
+// AggregateEncryptedVotes multiplies all stored ballots of a closed election
+// into one aggregate ciphertext vector, using the group from Election.PublicKey
+func (v *VoteContract) AggregateEncryptedVotes(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*EncryptedAggregate, error) {
+	if err := v.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Status != "closed" && election.Status != "tallying" {
+		return nil, fmt.Errorf("election must be closed or tallying to aggregate votes")
+	}
+	if election.BallotType == BallotTypeRanked {
+		return nil, fmt.Errorf("ranked ballots cannot be aggregated (use TallyRankedChoice)")
+	}
+
+	keys, err := questionPublicKeys(election)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delegated votes add to the weight of the ballot they resolve to
+	delegatedWeights := map[string]int{}
+	if election.AllowDelegation {
+		if delegatedWeights, err = resolveDelegatedWeights(ctx, electionID); err != nil {
+			return nil, err
+		}
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %v", err)
+	}
+	defer iterator.Close()
+
+	// Ballots are aggregated per question
+	ballots := make(map[string][][]ElGamalCiphertext)
+	voteCount := 0
+	totalWeight := 0
+	delegatedVotes := 0
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vote: %v", err)
+		}
+		var vote Vote
+		if err := json.Unmarshal(kv.Value, &vote); err != nil {
+			return nil, err
+		}
+		if vote.Invalidated {
+			continue
+		}
+
+		if vote.PrivateCollection != "" {
+			privateVote, err := getPrivateVote(ctx, election, &vote)
+			if err != nil {
+				return nil, err
+			}
+			vote.EncryptedVote = privateVote.EncryptedVote
+		}
+
+		weight := vote.Weight
+		if weight < 1 {
+			weight = 1
+		}
+		delegated := delegatedWeights[vote.NullifierCommitment]
+		weight += delegated
+
+		questionVotes := vote.QuestionVotes
+		if len(questionVotes) == 0 {
+			questionVotes = map[string]string{DefaultQuestionID: vote.EncryptedVote}
+		}
+		for questionID, encryptedVote := range questionVotes {
+			ballot, err := parseBallot(encryptedVote)
+			if err != nil {
+				return nil, fmt.Errorf("vote %s: %v", vote.EncryptedVoteHash, err)
+			}
+			key, ok := keys[questionID]
+			if !ok {
+				return nil, fmt.Errorf("vote %s: unknown question %s", vote.EncryptedVoteHash, questionID)
+			}
+			// A ballot of weight w counts as w identical ballots
+			if weight > 1 {
+				ballot = scaleCiphertexts(key, ballot, weight)
+			}
+			ballots[questionID] = append(ballots[questionID], ballot)
+		}
+		voteCount++
+		delegatedVotes += delegated
+		if election.Weighted || election.AllowDelegation {
+			totalWeight += weight
+		}
+	}
+
+	if voteCount == 0 {
+		return nil, fmt.Errorf("election %s has no votes to aggregate", electionID)
+	}
+
+	result := &EncryptedAggregate{
+		ElectionID:     electionID,
+		VoteCount:      voteCount,
+		TxID:           ctx.GetStub().GetTxID(),
+		TotalWeight:    totalWeight,
+		DelegatedVotes: delegatedVotes,
+	}
+
+	var hashInput interface{}
+	if len(election.Questions) == 0 {
+		aggregate, err := aggregateCiphertexts(keys[DefaultQuestionID], ballots[DefaultQuestionID])
+		if err != nil {
+			return nil, err
+		}
+		result.Ciphertexts = ciphertextsToJSON(aggregate)
+		hashInput = result.Ciphertexts
+	} else {
+		result.Questions = make(map[string][]CiphertextJSON)
+		for questionID, questionBallots := range ballots {
+			aggregate, err := aggregateCiphertexts(keys[questionID], questionBallots)
+			if err != nil {
+				return nil, fmt.Errorf("question %s: %v", questionID, err)
+			}
+			result.Questions[questionID] = ciphertextsToJSON(aggregate)
+		}
+		hashInput = result.Questions
+	}
+
+	ciphertextsJSON, err := canonicalJSON(hashInput)
+	if err != nil {
+		return nil, err
+	}
+	result.AggregateHash = hashForElection(election, string(ciphertextsJSON))
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(aggregateKey(electionID), resultJSON); err != nil {
+		return nil, fmt.Errorf("failed to store aggregate: %v", err)
+	}
+
+	if err := v.addBulletinBoardEntry(ctx, electionID, "votes_aggregated", result.AggregateHash); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetEncryptedAggregate retrieves the aggregate ciphertext of an election
+func (v *VoteContract) GetEncryptedAggregate(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*EncryptedAggregate, error) {
+	aggregateJSON, err := ctx.GetStub().GetState(aggregateKey(electionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aggregate: %v", err)
+	}
+	if aggregateJSON == nil {
+		return nil, fmt.Errorf("aggregate not found for election %s", electionID)
+	}
+
+	var aggregate EncryptedAggregate
+	if err := json.Unmarshal(aggregateJSON, &aggregate); err != nil {
+		return nil, err
+	}
+
+	return &aggregate, nil
+}
+
+// GetEncryptedWriteIns lists the encrypted write-ins of the counted votes
+// of a closed election, for the tally authority to decrypt
+func (v *VoteContract) GetEncryptedWriteIns(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]WriteInBallot, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if !election.AllowWriteIns {
+		return nil, fmt.Errorf("election %s does not allow write-ins", electionID)
+	}
+	if election.Status == "pending" || election.Status == "active" || election.Status == "paused" {
+		return nil, fmt.Errorf("election must be closed to decrypt write-ins (current status: %s)", election.Status)
+	}
+	return writeInBallots(ctx, electionID)
+}
+
+// StartTallying moves a closed election to tallying while the tally
+// authority aggregates and decrypts its ballots. StoreTallyResult accepts
+// closed elections too, so this step is optional.
+func (v *VoteContract) StartTallying(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.Status != "closed" {
+		return fmt.Errorf("only closed elections can start tallying (current status: %s)", election.Status)
+	}
+
+	oldStatus := election.Status
+	if err := setStatus(election, "tallying"); err != nil {
+		return err
+	}
+
+	updatedJSON, err := v.putElection(ctx, election)
+	if err != nil {
+		return err
+	}
+
+	electionHash, err := hashJSON(election.HashAlgorithm, updatedJSON)
+	if err != nil {
+		return err
+	}
+	if err := v.addBulletinBoardEntry(ctx, electionID, "tally_started", electionHash); err != nil {
+		return err
+	}
+
+	return v.emitStatusChanged(ctx, election, oldStatus, election.Status)
+}
+
+// StoreTallyResult stores the tally result after decryption
+func (v *VoteContract) StoreTallyResult(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	voteCountsJSON string,
+	aggregatedHash string,
+	decryptionProof string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	// Verify election is closed
+	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
+	if err != nil {
+		return fmt.Errorf("failed to read election: %v", err)
+	}
+	if electionJSON == nil {
+		return fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
+
+	var election Election
+	if err := json.Unmarshal(electionJSON, &election); err != nil {
+		return err
+	}
+
+	if election.Status == "cancelled" {
+		return fmt.Errorf("election %s has been cancelled", electionID)
+	}
+	if !canTransition(election.Status, "completed") {
+		return fmt.Errorf("election must be closed or tallying to store results")
+	}
+
+	voteCounts, err := parseVoteCounts(voteCountsJSON)
+	if err != nil {
+		return err
+	}
+
+	// Threshold elections are decrypted by the trustees, never by one authority
+	if len(election.Trustees) > 0 {
+		return fmt.Errorf("election %s uses threshold decryption; submit partial decryptions instead", electionID)
+	}
+	if election.BallotType == BallotTypeRanked {
+		return fmt.Errorf("election %s uses ranked ballots (use TallyRankedChoice)", electionID)
+	}
+
+	return v.storeTallyResult(ctx, &election, voteCounts, aggregatedHash, decryptionProof, nil, nil)
+}
+
+// TallyRankedChoice tallies a closed ranked election by instant runoff.
+// The decrypted preference lists, one per counted vote, are read from the
+// transient field "rankedBallots". Every round and the winner are stored
+// with the tally result.
+func (v *VoteContract) TallyRankedChoice(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.BallotType != BallotTypeRanked {
+		return fmt.Errorf("election %s does not use ranked ballots", electionID)
+	}
+	if election.Status == "cancelled" {
+		return fmt.Errorf("election %s has been cancelled", electionID)
+	}
+	if !canTransition(election.Status, "completed") {
+		return fmt.Errorf("election must be closed or tallying to store results")
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("failed to read transient data: %v", err)
+	}
+	ballotsJSON, ok := transient["rankedBallots"]
+	if !ok || len(ballotsJSON) == 0 {
+		return fmt.Errorf("rankedBallots must be passed in the transient map")
+	}
+	ballots, err := parseRankedBallots(ballotsJSON, election.Options)
+	if err != nil {
+		return err
+	}
+
+	// Every counted ballot must be decrypted, no more and no fewer
+	votes, err := countedVotes(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if len(ballots) != len(votes) {
+		return fmt.Errorf("got %d decrypted ballots for %d votes", len(ballots), len(votes))
+	}
+
+	runoff := runInstantRunoff(election.Options, ballots)
+
+	// First choices are the vote counts; ballots ranking no one abstained
+	counts := runoff.Rounds[0].Counts
+	firstChoices := make(map[string]int, len(counts)+1)
+	for option, count := range counts {
+		firstChoices[option] = count
+	}
+	if abstained := runoff.Rounds[0].Exhausted; abstained > 0 {
+		firstChoices[OptionAbstain] = abstained
+	}
+
+	voteCounts := map[string]map[string]int{DefaultQuestionID: firstChoices}
+	return v.storeTallyResult(ctx, election, voteCounts, computeAggregatedHash(election, votes), "", nil, &runoff)
+}
+
+// parseVoteCounts parses {"questionId": {"option": n}}, or a flat
+// {"option": n} map for the default question. Every count must be a
+// non-negative integer; the error names the offending option.
+func parseVoteCounts(voteCountsJSON string) (map[string]map[string]int, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(voteCountsJSON), &raw); err != nil {
+		return nil, fmt.Errorf("invalid vote counts: %v", err)
+	}
+
+	nested := 0
+	for _, value := range raw {
+		if trimmed := strings.TrimSpace(string(value)); strings.HasPrefix(trimmed, "{") {
+			nested++
+		}
+	}
+	if nested == 0 {
+		counts, err := parseOptionCounts(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vote counts: %v", err)
+		}
+		return map[string]map[string]int{DefaultQuestionID: counts}, nil
+	}
+	if nested != len(raw) {
+		return nil, fmt.Errorf("invalid vote counts: mix of per-question and per-option counts")
+	}
+
+	voteCounts := make(map[string]map[string]int, len(raw))
+	for questionID, value := range raw {
+		var options map[string]json.RawMessage
+		if err := json.Unmarshal(value, &options); err != nil {
+			return nil, fmt.Errorf("invalid vote counts for question %s: %v", questionID, err)
+		}
+		counts, err := parseOptionCounts(options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vote counts for question %s: %v", questionID, err)
+		}
+		voteCounts[questionID] = counts
+	}
+	return voteCounts, nil
+}
+
+func parseOptionCounts(raw map[string]json.RawMessage) (map[string]int, error) {
+	counts := make(map[string]int, len(raw))
+	for option, value := range raw {
+		count, err := strconv.Atoi(strings.TrimSpace(string(value)))
+		if err != nil {
+			return nil, fmt.Errorf("count for option %s must be an integer, got %s", option, value)
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("count for option %s must not be negative, got %d", option, count)
+		}
+		counts[option] = count
+	}
+	return counts, nil
+}
+
+// storeTallyResult validates vote counts, records the tally and completes
+// the election. trustees lists the trustees whose partial decryptions were
+// combined, if any; runoff is the outcome of a ranked election.
+func (v *VoteContract) storeTallyResult(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	voteCounts map[string]map[string]int,
+	aggregatedHash string,
+	decryptionProof string,
+	trustees []string,
+	runoff *instantRunoff,
+) error {
+	electionID := election.ID
+
+	// Calculate total votes: the number of ballots counted in the largest
+	// race, abstentions and spoiled ballots included
+	questions := electionQuestions(election)
+	totalVotes := 0
+	abstentions := make(map[string]int)
+	spoiled := make(map[string]int)
+	for questionID, counts := range voteCounts {
+		question := findQuestion(questions, questionID)
+		if question == nil {
+			return fmt.Errorf("unknown question %s in vote counts", questionID)
+		}
+		questionTotal := 0
+		for option, count := range counts {
+			if !isReservedOption(option) && len(question.Options) > 0 && !containsString(question.Options, option) {
+				return fmt.Errorf("unknown option %s for question %s", option, questionID)
+			}
+			if option == OptionWriteIn && !election.AllowWriteIns {
+				return fmt.Errorf("election %s does not allow write-ins", electionID)
+			}
+			questionTotal += count
+		}
+		if questionTotal > totalVotes {
+			totalVotes = questionTotal
+		}
+
+		if count, ok := counts[OptionAbstain]; ok {
+			abstentions[questionID] = count
+			delete(counts, OptionAbstain)
+		}
+		if count, ok := counts[OptionSpoiled]; ok {
+			spoiled[questionID] = count
+			delete(counts, OptionSpoiled)
+		}
+	}
+
+	if election.DecryptionProofScheme != "" {
+		if err := v.verifyDecryptionProof(ctx, election, voteCounts, decryptionProof); err != nil {
+			return err
+		}
+	}
+
+	// The raw ballot count is kept apart from weighted totals
+	ballotCount, err := countVotes(ctx, election)
+	if err != nil {
+		return err
+	}
+
+	// The tally must cover exactly the votes committed to at close
+	if election.ClosedVoteRoot != "" {
+		voteRoot, err := v.computeClosedVoteRoot(ctx, election)
+		if err != nil {
+			return err
+		}
+		if voteRoot != election.ClosedVoteRoot {
+			return fmt.Errorf("votes changed since the election closed: root %s does not match checkpoint %s",
+				voteRoot, election.ClosedVoteRoot)
+		}
+	}
+
+	// A tally may not count more votes than the ledger holds
+	maxTotal, err := v.maxTallyTotal(ctx, election, ballotCount)
+	if err != nil {
+		return err
+	}
+	if totalVotes > maxTotal {
+		return fmt.Errorf("tally counts %d votes but the ledger allows at most %d", totalVotes, maxTotal)
+	}
+
+	// Write-ins are decrypted one by one and grouped by name
+	var writeIns map[string]int
+	if election.AllowWriteIns {
+		if writeIns, err = groupWriteIns(ctx, election); err != nil {
+			return err
+		}
+	}
+
+	// A runoff settles its own ties
+	var tieBreaks map[string][]string
+	if runoff == nil {
+		if tieBreaks, err = v.tieBreakOrders(ctx, election, voteCounts); err != nil {
+			return err
+		}
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Recounts after ReopenTally get the next version
+	election.TallyVersion++
+
+	result := TallyResult{
+		ElectionID:      electionID,
+		VoteCounts:      voteCounts,
+		TotalVotes:      totalVotes,
+		AggregatedHash:  aggregatedHash,
+		DecryptionProof: decryptionProof,
+		TallyTimestamp:  now,
+		TxID:            txID,
+		Version:         election.TallyVersion,
+		BallotCount:     ballotCount,
+		Weighted:        election.Weighted,
+		Trustees:        trustees,
+		QuorumMet:       quorumMet(election, ballotCount),
+		SchemaVersion:   TallySchemaVersion,
+	}
+	result.Valid = result.QuorumMet
+	if runoff != nil {
+		result.Rounds = runoff.Rounds
+		result.Winner = runoff.Winner
+	}
+	result.TieBreakOrder = tieBreaks
+	result.WriteIns = writeIns
+	if len(abstentions) > 0 {
+		result.Abstentions = abstentions
+	}
+	if len(spoiled) > 0 {
+		result.Spoiled = spoiled
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	// Store tally result
+	if err := ctx.GetStub().PutState(tallyKey(electionID), resultJSON); err != nil {
+		return err
+	}
+
+	// The tally key only exists now, so its key-level policy is set again here
+	if err := applyTallyEndorsementPolicy(ctx, election); err != nil {
+		return err
+	}
+
+	// Update election status
+	oldStatus := election.Status
+	if err := setStatus(election, "completed"); err != nil {
+		return err
+	}
+	updatedJSON, err := json.Marshal(election)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(electionKey(electionID), updatedJSON); err != nil {
+		return err
+	}
+
+	// Add to bulletin board. The entry's sequence is the event's seq.
+	sequence, err := bulletinBoardLength(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	sequence++
+	resultHash, err := hashJSON(election.HashAlgorithm, resultJSON)
+	if err != nil {
+		return err
+	}
+	if err := v.addBulletinBoardEntry(ctx, electionID, "tally_completed", resultHash); err != nil {
+		return err
+	}
+
+	// Only one event survives per transaction, so the tally summary rides
+	// on the status change
+	return v.publishStatusChange(ctx, election, ElectionStatusChange{
+		OldStatus: oldStatus,
+		NewStatus: election.Status,
+		Tally: &TallyCompletion{
+			Seq:         sequence,
+			TotalVotes:  totalVotes,
+			BallotCount: ballotCount,
+			QuorumMet:   result.QuorumMet,
+			Version:     result.Version,
+		},
+	})
+}
+
+// maxTallyTotal is the largest total a tally of the election may report: one
+// vote per ballot on the ledger (the aggregate's total weight in weighted and
+// delegation elections), times the selections a multi-limited ballot may
+// make, plus the election's TallyAllowance
+func (v *VoteContract) maxTallyTotal(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	ballotCount int,
+) (int, error) {
+	votes := ballotCount
+	if election.Weighted || election.AllowDelegation {
+		aggregate, err := v.GetEncryptedAggregate(ctx, election.ID)
+		if err != nil {
+			return 0, fmt.Errorf("weighted and delegation elections must be aggregated before tallying: %v", err)
+		}
+		votes = aggregate.TotalWeight
+	}
+	if election.VotingMode == VotingModeMultiLimited && election.MaxCandidatesPerVoter > 1 {
+		votes *= election.MaxCandidatesPerVoter
+	}
+	return votes + election.TallyAllowance, nil
+}
+
+// verifyDecryptionProof checks that the stored encrypted aggregate decrypts
+// to the submitted counts under the election's decryption proof scheme.
+// Ciphertext i of a question is the count of its option i; questions
+// without options are counted by decimal index.
+func (v *VoteContract) verifyDecryptionProof(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	voteCounts map[string]map[string]int,
+	decryptionProof string,
+) error {
+	verifier, err := getDecryptionProofVerifier(election.DecryptionProofScheme)
+	if err != nil {
+		return err
+	}
+	keys, err := questionPublicKeys(election)
+	if err != nil {
+		return err
+	}
+	aggregate, err := v.GetEncryptedAggregate(ctx, election.ID)
+	if err != nil {
+		return fmt.Errorf("decryption proof requires the encrypted aggregate: %v", err)
+	}
+
+	aggregates, err := aggregateByQuestion(election, aggregate)
+	if err != nil {
+		return err
+	}
+	for questionID := range voteCounts {
+		if _, ok := aggregates[questionID]; !ok {
+			return fmt.Errorf("no encrypted aggregate for question %s", questionID)
+		}
+	}
+
+	claimed := make(map[string][]int64, len(aggregates))
+	questions := electionQuestions(election)
+	for questionID, ciphertexts := range aggregates {
+		claims := make([]int64, len(ciphertexts))
+		question := findQuestion(questions, questionID)
+		for option, count := range voteCounts[questionID] {
+			index := -1
+			if question != nil && len(question.Options) > 0 {
+				for i, o := range question.Options {
+					if o == option {
+						index = i
+					}
+				}
+				// The write-in channel follows the candidates
+				if option == OptionWriteIn && election.AllowWriteIns {
+					index = len(question.Options)
+				}
+			} else if n, err := strconv.Atoi(option); err == nil {
+				index = n
+			}
+			if index < 0 || index >= len(claims) {
+				return fmt.Errorf("option %s of question %s has no aggregate ciphertext", option, questionID)
+			}
+			claims[index] = int64(count)
+		}
+		claimed[questionID] = claims
+	}
+
+	// Each contest is proven under its own key
+	questionIDs := make([]string, 0, len(aggregates))
+	for questionID := range aggregates {
+		questionIDs = append(questionIDs, questionID)
+	}
+	sort.Strings(questionIDs)
+	for _, questionID := range questionIDs {
+		key, ok := keys[questionID]
+		if !ok {
+			return fmt.Errorf("unknown question %s in encrypted aggregate", questionID)
+		}
+		err := verifier.Verify(key,
+			map[string][]ElGamalCiphertext{questionID: aggregates[questionID]},
+			map[string][]int64{questionID: claimed[questionID]},
+			decryptionProof)
+		if err != nil {
+			return fmt.Errorf("decryption proof rejected: %v", err)
+		}
+	}
+	return nil
+}
+
+// aggregateByQuestion parses an encrypted aggregate into ciphertexts keyed
+// by question ID
+func aggregateByQuestion(election *Election, aggregate *EncryptedAggregate) (map[string][]ElGamalCiphertext, error) {
+	rawAggregates := aggregate.Questions
+	if len(election.Questions) == 0 {
+		rawAggregates = map[string][]CiphertextJSON{DefaultQuestionID: aggregate.Ciphertexts}
+	}
+
+	aggregates := make(map[string][]ElGamalCiphertext, len(rawAggregates))
+	for questionID, raw := range rawAggregates {
+		ciphertexts, err := ciphertextsFromJSON(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid aggregate for question %s: %v", questionID, err)
+		}
+		aggregates[questionID] = ciphertexts
+	}
+	return aggregates, nil
+}
+
+// SubmitPartialDecryption records a trustee's partial decryption of the
+// encrypted aggregate of a threshold election. The shares are verified
+// against the trustee's public key before they are stored; a trustee may
+// resubmit, e.g. after the votes are aggregated again.
+func (v *VoteContract) SubmitPartialDecryption(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	trusteeID string,
+	partialDecryption string,
+	proof string,
+) error {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if len(election.Trustees) == 0 {
+		return fmt.Errorf("election %s does not use threshold decryption", electionID)
+	}
+	if !canTransition(election.Status, "completed") {
+		return fmt.Errorf("election must be closed or tallying to submit partial decryptions")
+	}
+
+	trustee := findTrustee(election.Trustees, trusteeID)
+	if trustee == nil {
+		return fmt.Errorf("trustee %s is not a trustee of election %s", trusteeID, electionID)
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to read client MSP: %v", err)
+	}
+	if mspID != trustee.MSPID {
+		return fmt.Errorf("%w: trustee %s submits from %s", ErrPermissionDenied, trusteeID, trustee.MSPID)
+	}
+
+	key, err := parseElGamalPublicKey(election.PublicKey)
+	if err != nil {
+		return err
+	}
+	aggregate, err := v.GetEncryptedAggregate(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	aggregates, err := aggregateByQuestion(election, aggregate)
+	if err != nil {
+		return err
+	}
+
+	shares, err := verifyPartialDecryption(key, trustee, aggregates, partialDecryption, proof)
+	if err != nil {
+		return fmt.Errorf("partial decryption rejected: %v", err)
+	}
+
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	partial := PartialDecryption{
+		ElectionID:    electionID,
+		TrusteeID:     trusteeID,
+		Shares:        make(map[string][]string, len(shares)),
+		Proof:         proof,
+		AggregateHash: aggregate.AggregateHash,
+		TxID:          ctx.GetStub().GetTxID(),
+		SubmittedAt:   now,
+	}
+	for questionID, questionShares := range shares {
+		for _, d := range questionShares {
+			partial.Shares[questionID] = append(partial.Shares[questionID], d.String())
+		}
+	}
+	partialJSON, err := json.Marshal(partial)
+	if err != nil {
+		return err
+	}
+
+	partialKey, err := partialDecryptionKey(ctx, electionID, trusteeID)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(partialKey, partialJSON); err != nil {
+		return fmt.Errorf("failed to store partial decryption: %v", err)
+	}
+
+	partialHash, err := hashJSON(election.HashAlgorithm, partialJSON)
+	if err != nil {
+		return err
+	}
+	if err := v.addBulletinBoardEntry(ctx, electionID, "partial_decryption", partialHash); err != nil {
+		return err
+	}
+
+	eventJSON, _ := json.Marshal(map[string]interface{}{
+		"electionId": electionID,
+		"trusteeId":  trusteeID,
+		"txId":       partial.TxID,
+	})
+	return ctx.GetStub().SetEvent(eventName(election, "PartialDecryptionSubmitted"), eventJSON)
+}
+
+// CombinePartialDecryptions combines the partial decryptions of a threshold
+// election into the final tally. At least the threshold of trustees must
+// have submitted partials for the current aggregate; the quorum with the
+// lowest indices is used.
+func (v *VoteContract) CombinePartialDecryptions(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if len(election.Trustees) == 0 {
+		return fmt.Errorf("election %s does not use threshold decryption", electionID)
+	}
+	if !canTransition(election.Status, "completed") {
+		return fmt.Errorf("election must be closed or tallying to store results")
+	}
+
+	key, err := parseElGamalPublicKey(election.PublicKey)
+	if err != nil {
+		return err
+	}
+	aggregate, err := v.GetEncryptedAggregate(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	aggregates, err := aggregateByQuestion(election, aggregate)
+	if err != nil {
+		return err
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(partialDecryptionObjectType, []string{electionID})
+	if err != nil {
+		return fmt.Errorf("failed to query partial decryptions: %v", err)
+	}
+	defer iterator.Close()
+
+	// Partials made for an earlier aggregate no longer apply
+	partials := make(map[string]PartialDecryption)
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to read partial decryption: %v", err)
+		}
+		var partial PartialDecryption
+		if err := json.Unmarshal(kv.Value, &partial); err != nil {
+			return err
+		}
+		if partial.AggregateHash == aggregate.AggregateHash {
+			partials[partial.TrusteeID] = partial
+		}
+	}
+
+	quorum := []Trustee{}
+	for _, trustee := range sortedTrustees(election.Trustees) {
+		if _, ok := partials[trustee.ID]; ok && len(quorum) < election.Threshold {
+			quorum = append(quorum, trustee)
+		}
+	}
+	if len(quorum) < election.Threshold {
+		return fmt.Errorf("%d of %d required partial decryptions submitted", len(quorum), election.Threshold)
+	}
+
+	// No count can exceed the number of ballots, or their total weight
+	maxCount := aggregate.VoteCount
+	if aggregate.TotalWeight > 0 {
+		maxCount = aggregate.TotalWeight
+	}
+
+	questions := electionQuestions(election)
+	voteCounts := make(map[string]map[string]int, len(aggregates))
+	for questionID, ciphertexts := range aggregates {
+		question := findQuestion(questions, questionID)
+		counts := make(map[string]int, len(ciphertexts))
+		for i, c := range ciphertexts {
+			shares := make([]*big.Int, len(quorum))
+			for j, trustee := range quorum {
+				shares[j], err = parseBigInt("share", partials[trustee.ID].Shares[questionID][i])
+				if err != nil {
+					return fmt.Errorf("trustee %s: %v", trustee.ID, err)
+				}
+			}
+			d := combineShares(key, quorum, shares)
+			gm := new(big.Int).Mul(c.C2, new(big.Int).ModInverse(d, key.P))
+			count, err := discreteLog(key, gm.Mod(gm, key.P), maxCount)
+			if err != nil {
+				return fmt.Errorf("question %s, ciphertext %d: %v", questionID, i, err)
+			}
+
+			option := strconv.Itoa(i)
+			if question != nil && i < len(question.Options) {
+				option = question.Options[i]
+			} else if question != nil && i == len(question.Options) && election.AllowWriteIns {
+				option = OptionWriteIn
+			}
+			counts[option] = count
+		}
+		voteCounts[questionID] = counts
+	}
+
+	trusteeIDs := make([]string, len(quorum))
+	for i, trustee := range quorum {
+		trusteeIDs[i] = trustee.ID
+	}
+
+	// The result records the hash VerifyTallyResult recomputes, not the
+	// aggregate's ciphertext hash
+	votes, err := countedVotes(ctx, election.ID)
+	if err != nil {
+		return err
+	}
+	return v.storeTallyResult(ctx, election, voteCounts, computeAggregatedHash(election, votes), "", trusteeIDs, nil)
+}
+
+// ReopenTally moves a completed election back to tallying so the result can
+// be recounted. The current result is kept under its versioned key and the
+// next StoreTallyResult stores the following version.
+func (v *VoteContract) ReopenTally(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	reason string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	if reason == "" {
+		return fmt.Errorf("a reason for reopening the tally is required")
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	if election.Status != "completed" {
+		return fmt.Errorf("only completed elections can be reopened (current status: %s)", election.Status)
+	}
+
+	result, err := v.GetTallyResult(ctx, electionID)
+	if err != nil {
+		return err
+	}
+
+	// Results stored before versioning count as the first version
+	if result.Version == 0 {
+		result.Version = 1
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(tallyVersionKey(electionID, result.Version), resultJSON); err != nil {
+		return fmt.Errorf("failed to archive tally result: %v", err)
+	}
+	if err := ctx.GetStub().DelState(tallyKey(electionID)); err != nil {
+		return fmt.Errorf("failed to clear tally result: %v", err)
+	}
+
+	oldStatus := election.Status
+	if err := setStatus(election, "tallying"); err != nil {
+		return err
+	}
+	election.TallyVersion = result.Version
+
+	if _, err := v.putElection(ctx, election); err != nil {
+		return err
+	}
+
+	if err := v.addBulletinBoardEntry(ctx, electionID, "tally_reopened", hashForElection(election, reason)); err != nil {
+		return err
+	}
+
+	return v.emitStatusChanged(ctx, election, oldStatus, election.Status)
+}
+
+// GetTallyResultVersion retrieves a tally result by version, including
+// results superseded by a recount
+func (v *VoteContract) GetTallyResultVersion(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	version int,
+) (*TallyResult, error) {
+	resultJSON, err := ctx.GetStub().GetState(tallyVersionKey(electionID, version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tally result: %v", err)
+	}
+	if resultJSON == nil {
+		// The current result is only archived once the tally is reopened
+		current, err := v.GetTallyResult(ctx, electionID)
+		if err != nil {
+			return nil, err
+		}
+		currentVersion := current.Version
+		if currentVersion == 0 {
+			currentVersion = 1
+		}
+		if currentVersion != version {
+			return nil, fmt.Errorf("tally result version %d not found for election %s", version, electionID)
+		}
+		return current, nil
+	}
+
+	var result TallyResult
+	if err := json.Unmarshal(resultJSON, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// VerifyTallyResult recomputes the aggregated ballot hash from the votes on
+// the ledger and compares it with the hash stored with the tally, so anyone
+// can confirm no ballot was added or dropped
+func (v *VoteContract) VerifyTallyResult(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*TallyVerification, error) {
+	result, err := v.GetTallyResult(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	votes, err := countedVotes(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	recomputedHash := computeAggregatedHash(election, votes)
+	return &TallyVerification{
+		ElectionID:     electionID,
+		Verified:       recomputedHash == result.AggregatedHash,
+		StoredHash:     result.AggregatedHash,
+		RecomputedHash: recomputedHash,
+		VoteCount:      len(votes),
+	}, nil
+}
+
+// countedVotes returns the votes of an election that count towards its
+// tally, leaving out invalidated ones
+func countedVotes(ctx contractapi.TransactionContextInterface, electionID string) ([]Vote, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(voteObjectType, []string{electionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query votes: %v", err)
+	}
+	defer iterator.Close()
+
+	var votes []Vote
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vote: %v", err)
+		}
+		var vote Vote
+		if err := json.Unmarshal(kv.Value, &vote); err != nil {
+			return nil, err
+		}
+		if vote.Invalidated {
+			continue
+		}
+		votes = append(votes, vote)
+	}
+	return votes, nil
+}
+
+// GetTallyProofBundle gathers the tally, encrypted aggregate, decryption
+// proof, bulletin board root and public key of a completed election into
+// one structure. Struct fields and sorted map keys keep its JSON
+// deterministic.
+func (v *VoteContract) GetTallyProofBundle(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*TallyProofBundle, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Status != "completed" && election.Status != "archived" {
+		return nil, fmt.Errorf("election is not completed (current status: %s)", election.Status)
+	}
+
+	tally, err := v.GetTallyResult(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	aggregate, err := v.GetEncryptedAggregate(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := v.loadBulletinBoardTree(ctx, electionID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &TallyProofBundle{
+		ElectionID:            electionID,
+		PublicKey:             election.PublicKey,
+		DecryptionProofScheme: election.DecryptionProofScheme,
+		Tally:                 *tally,
+		Aggregate:             *aggregate,
+		DecryptionProof:       tally.DecryptionProof,
+		MerkleRoot:            tree.Root,
+		MerkleScheme:          tree.Scheme,
+		BulletinSize:          tree.Size,
+	}
+	for _, question := range election.Questions {
+		if question.PublicKey != "" {
+			if bundle.QuestionKeys == nil {
+				bundle.QuestionKeys = make(map[string]string)
+			}
+			bundle.QuestionKeys[question.ID] = question.PublicKey
+		}
+	}
+
+	for _, trusteeID := range tally.Trustees {
+		key, err := partialDecryptionKey(ctx, electionID, trusteeID)
+		if err != nil {
+			return nil, err
+		}
+		partialJSON, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read partial decryption: %v", err)
+		}
+		if partialJSON == nil {
+			return nil, fmt.Errorf("partial decryption of trustee %s not found", trusteeID)
+		}
+		var partial PartialDecryption
+		if err := json.Unmarshal(partialJSON, &partial); err != nil {
+			return nil, err
+		}
+		bundle.PartialDecryptions = append(bundle.PartialDecryptions, partial)
+	}
+	if len(tally.Trustees) > 0 {
+		bundle.Trustees = election.Trustees
+		bundle.Threshold = election.Threshold
+	}
+
+	return bundle, nil
+}
+
+// GetElectionResults derives percentages and winners from the stored tally
+// of a completed or archived election, so every client presents the same
+// numbers
+func (v *VoteContract) GetElectionResults(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*ElectionResults, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if election.Status != "completed" && election.Status != "archived" {
+		return nil, fmt.Errorf("election results are not available (current status: %s)", election.Status)
+	}
+
+	tally, err := v.GetTallyResult(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Quorum is judged here rather than read from the tally, which covers
+	// tallies stored before results carried it
+	results := &ElectionResults{
+		ElectionID:      electionID,
+		Questions:       []QuestionResult{},
+		TotalVotes:      tally.TotalVotes,
+		Turnout:         tally.BallotCount,
+		RequiredTurnout: requiredTurnout(election),
+		QuorumMet:       quorumMet(election, tally.BallotCount),
+	}
+	results.Valid = results.QuorumMet
+	for _, question := range electionQuestions(election) {
+		counts, ok := tally.VoteCounts[question.ID]
+		abstentions, abstained := tally.Abstentions[question.ID]
+		spoiled, spoilt := tally.Spoiled[question.ID]
+		if !ok && !abstained && !spoilt && len(question.Options) == 0 {
+			continue
+		}
+		result := questionResult(question, counts)
+		// A ranked election is won by instant runoff, not first choices
+		if tally.Winner != "" {
+			result.Winners = []string{tally.Winner}
+		}
+		result.TieBreakOrder = tally.TieBreakOrder[question.ID]
+		result.Abstentions = abstentions
+		result.Spoiled = spoiled
+		result.TotalVotes += abstentions + spoiled + counts[OptionWriteIn]
+		results.Questions = append(results.Questions, result)
+	}
+	results.WriteIns = tally.WriteIns
+
+	return results, nil
+}
+
+// questionResult lists options in ballot order (or by name when the question
+// has no fixed options) with their share of the question's votes
+func questionResult(question Question, counts map[string]int) QuestionResult {
+	options := question.Options
+	if len(options) == 0 {
+		for option := range counts {
+			options = append(options, option)
+		}
+		sort.Strings(options)
+	}
+
+	result := QuestionResult{
+		QuestionID: question.ID,
+		Options:    make([]OptionResult, 0, len(options)),
+		Winners:    []string{},
+	}
+	for _, option := range options {
+		result.ValidVotes += counts[option]
+	}
+	result.TotalVotes = result.ValidVotes
+
+	maxVotes := 0
+	for _, option := range options {
+		votes := counts[option]
+		percentage := 0.0
+		if result.ValidVotes > 0 {
+			// Rounded to two decimal places
+			percentage = math.Round(float64(votes)*10000/float64(result.ValidVotes)) / 100
+		}
+		result.Options = append(result.Options, OptionResult{Option: option, Votes: votes, Percentage: percentage})
+
+		if votes > maxVotes {
+			maxVotes = votes
+			result.Winners = []string{option}
+		} else if votes == maxVotes && votes > 0 {
+			result.Winners = append(result.Winners, option)
+		}
+	}
+
+	return result
+}
+
+// GetTallyResult retrieves the tally result for an election
+func (v *VoteContract) GetTallyResult(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*TallyResult, error) {
+	resultJSON, err := ctx.GetStub().GetState(tallyKey(electionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tally: %w", err)
+	}
+	if resultJSON == nil {
+		election, err := v.GetElection(ctx, electionID)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if err == nil && election.Status == "cancelled" {
+			return nil, fmt.Errorf("%w: election %s was cancelled before tallying: %s", ErrTallyNotFound,
+				electionID, election.CancellationReason)
+		}
+		return nil, fmt.Errorf("%w for election %s", ErrTallyNotFound, electionID)
+	}
+
+	var result TallyResult
+	if err := json.Unmarshal(resultJSON, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// GetAllTalliesPaginated retrieves one page of the current tally results of
+// all elections, ordered by election ID. Results superseded by ReopenTally
+// are skipped but still count towards the page size, so a page can hold
+// fewer tallies than requested. Like GetAllVotesPaginated it must be
+// evaluated, not submitted.
+func (v *VoteContract) GetAllTalliesPaginated(
+	ctx contractapi.TransactionContextInterface,
+	pageSize int32,
+	bookmark string,
+) (map[string]interface{}, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+
+	// Tally keys are simple keys; ';' is the byte after ':'
+	iterator, metadata, err := ctx.GetStub().GetStateByRangeWithPagination(
+		tallyKey(""), "tally;", pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tallies: %v", err)
+	}
+	defer iterator.Close()
+
+	tallies := []*TallyResult{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tally: %v", err)
+		}
+		var result TallyResult
+		if err := json.Unmarshal(kv.Value, &result); err != nil {
+			return nil, fmt.Errorf("invalid tally %q: %v", kv.Key, err)
+		}
+		// Versioned keys hold superseded results
+		if kv.Key != tallyKey(result.ElectionID) {
+			continue
+		}
+		tallies = append(tallies, &result)
+	}
+
+	return map[string]interface{}{
+		"tallies":      tallies,
+		"bookmark":     metadata.Bookmark,
+		"fetchedCount": metadata.FetchedRecordsCount,
+	}, nil
+}
+
+// ArchiveElection replaces the per-vote state of an election completed more
+// than ArchiveRetention ago with an ElectionArchive and deletes the votes,
+// verification codes, delegations, partial decryptions, participation
+// records and bulletin board entries from the world state. The deleted
+// values remain in the block history (see archive.go).
+func (v *VoteContract) ArchiveElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*ElectionArchive, error) {
+	if err := v.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if !canTransition(election.Status, "archived") {
+		return nil, fmt.Errorf("only completed elections can be archived (current status: %s)", election.Status)
+	}
+
+	tally, err := v.GetTallyResult(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if now.Before(tally.TallyTimestamp.Add(ArchiveRetention)) {
+		return nil, fmt.Errorf("election cannot be archived before %s",
+			tally.TallyTimestamp.Add(ArchiveRetention).Format(time.RFC3339))
+	}
+
+	tree, err := v.loadBulletinBoardTree(ctx, electionID, "")
+	if err != nil {
+		return nil, err
+	}
+	voteCount, err := countVotes(ctx, election)
+	if err != nil {
+		return nil, err
+	}
+
+	purged, err := purgeVotes(ctx, election)
+	if err != nil {
+		return nil, err
+	}
+	for _, objectType := range []string{
+		verificationCodeObjectType,
+		ciphertextObjectType,
+		voteProofObjectType,
+		delegationObjectType,
+		partialDecryptionObjectType,
+		bulletinBoardObjectType,
+	} {
+		n, err := purgeCompositeKeys(ctx, objectType, electionID)
+		if err != nil {
+			return nil, err
+		}
+		purged += n
+	}
+	n, err := purgeParticipation(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	purged += n
+	n, err = purgeVoteBlockIndex(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	purged += n
+
+	archive := &ElectionArchive{
+		ElectionID:   electionID,
+		Title:        election.Title,
+		Tally:        *tally,
+		MerkleRoot:   tree.Root,
+		MerkleScheme: tree.Scheme,
+		BulletinSize: tree.Size,
+		VoteCount:    voteCount,
+		PurgedKeys:   purged,
+		TxID:         ctx.GetStub().GetTxID(),
+		ArchivedAt:   now,
+	}
+	archiveJSON, err := json.Marshal(archive)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(archiveKey(electionID), archiveJSON); err != nil {
+		return nil, fmt.Errorf("failed to store archive: %v", err)
+	}
+
+	if err := setStatus(election, "archived"); err != nil {
+		return nil, err
+	}
+	if _, err := v.putElection(ctx, election); err != nil {
+		return nil, err
+	}
+
+	if err := v.emitStatusChanged(ctx, election, "completed", election.Status); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// GetElectionArchive retrieves the summary stored by ArchiveElection
+func (v *VoteContract) GetElectionArchive(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*ElectionArchive, error) {
+	archiveJSON, err := ctx.GetStub().GetState(archiveKey(electionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %v", err)
+	}
+	if archiveJSON == nil {
+		return nil, fmt.Errorf("election %s has not been archived", electionID)
+	}
+
+	var archive ElectionArchive
+	if err := json.Unmarshal(archiveJSON, &archive); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// GetBulletinBoard retrieves the public bulletin board for an election
+func (v *VoteContract) GetBulletinBoard(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (map[string]interface{}, error) {
+	entries, err := v.getBulletinBoardEntries(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	// Every election's board starts with its election_created entry
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w for election %s", ErrBulletinBoardNotFound, electionID)
+	}
+
+	tree, err := v.loadBulletinBoardTree(ctx, electionID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"entries":      entries,
+		"merkleRoot":   tree.Root,
+		"merkleScheme": tree.Scheme,
+	}, nil
+}
+
+// GetBulletinBoardRoot returns the stored bulletin board root without
+// reading any entries
+func (v *VoteContract) GetBulletinBoardRoot(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (map[string]interface{}, error) {
+	tree, err := v.loadBulletinBoardTree(ctx, electionID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"merkleRoot":   tree.Root,
+		"merkleScheme": tree.Scheme,
+		"size":         tree.Size,
+	}, nil
+}
+
+// GetLatestBulletinCheckpoint returns the bulletin board's current size and
+// root at the transaction time, signed with the election's receipt signing
+// key when it has one
+func (v *VoteContract) GetLatestBulletinCheckpoint(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*BulletinCheckpoint, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := v.loadBulletinBoardTree(ctx, electionID, "")
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &BulletinCheckpoint{
+		ElectionID:   electionID,
+		Size:         tree.Size,
+		MerkleRoot:   tree.Root,
+		MerkleScheme: tree.Scheme,
+		Timestamp:    now,
+	}
+	signingKey, err := loadReceiptSigningKey(ctx, election)
+	if err != nil {
+		return nil, err
+	}
+	if signingKey != nil {
+		checkpoint.Signature = signCheckpoint(signingKey, checkpoint)
+	}
+	return checkpoint, nil
+}
+
+// RecomputeBulletinBoardRoot rebuilds the root from every entry and compares
+// it with the stored root, for auditors who do not trust the incremental one
+func (v *VoteContract) RecomputeBulletinBoardRoot(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (map[string]interface{}, error) {
+	tree, err := v.loadBulletinBoardTree(ctx, electionID, "")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := v.getBulletinBoardEntries(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	merkleRoot := computeMerkleRoot(tree.Scheme, entries)
+
+	return map[string]interface{}{
+		"merkleRoot":   merkleRoot,
+		"storedRoot":   tree.Root,
+		"matches":      merkleRoot == tree.Root,
+		"merkleScheme": tree.Scheme,
+		"size":         len(entries),
+	}, nil
+}
+
+// VerifyBulletinChain walks the bulletin board and checks that every entry
+// commits to the hash of the one before it, reporting the first sequence
+// where an entry is missing, out of place or does not match. Entries written
+// before the chain existed are skipped up to the first chained entry.
+func (v *VoteContract) VerifyBulletinChain(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (map[string]interface{}, error) {
+	total, err := bulletinBoardLength(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	scheme, err := v.merkleSchemeOf(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	broken := func(sequence int, reason string) map[string]interface{} {
+		return map[string]interface{}{
+			"valid":      false,
+			"size":       total,
+			"firstBreak": sequence,
+			"reason":     reason,
+		}
+	}
+
+	prevEntryHash := ""
+	chained := false
+	for seq := 1; seq <= total; seq++ {
+		key, err := bulletinBoardEntryKey(ctx, electionID, seq)
+		if err != nil {
+			return nil, err
+		}
+		entryJSON, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bulletin board: %v", err)
+		}
+		if entryJSON == nil {
+			return broken(seq, "entry missing"), nil
+		}
+		var entry BulletinBoardEntry
+		if err := json.Unmarshal(entryJSON, &entry); err != nil {
+			return nil, err
+		}
+		if entry.Sequence != seq {
+			return broken(seq, fmt.Sprintf("entry carries sequence %d", entry.Sequence)), nil
+		}
+
+		if entry.PrevEntryHash != "" {
+			chained = true
+		}
+		if (chained || seq == 1) && entry.PrevEntryHash != prevEntryHash {
+			return broken(seq, "previous entry hash does not match"), nil
+		}
+		prevEntryHash = bulletinEntryHash(scheme, entry)
+	}
+
+	return map[string]interface{}{
+		"valid":      true,
+		"size":       total,
+		"firstBreak": 0,
+	}, nil
+}
+
+// GetBulletinBoardRange returns the entries with sequence numbers fromSeq
+// through toSeq and the Merkle root over just that range. A toSeq past the
+// end of the board is clamped to the last entry.
+func (v *VoteContract) GetBulletinBoardRange(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	fromSeq int,
+	toSeq int,
+) (map[string]interface{}, error) {
+	if fromSeq < 1 || toSeq < fromSeq {
+		return nil, fmt.Errorf("invalid sequence range %d-%d", fromSeq, toSeq)
+	}
+
+	total, err := bulletinBoardLength(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	if toSeq > total {
+		toSeq = total
+	}
+
+	// Entries are keyed by sequence, so the range is read key by key
+	entries := []BulletinBoardEntry{}
+	for seq := fromSeq; seq <= toSeq; seq++ {
+		entry, err := getBulletinBoardEntry(ctx, electionID, seq)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	scheme, err := v.merkleSchemeOf(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"entries":      entries,
+		"total":        total,
+		"merkleRoot":   computeMerkleRoot(scheme, entries),
+		"merkleScheme": scheme,
+	}, nil
+}
+
+// GetBulletinBoardPaginated retrieves one page of bulletin board entries in
+// sequence order. Like GetAllVotesPaginated it must be evaluated, not submitted.
+func (v *VoteContract) GetBulletinBoardPaginated(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	pageSize int32,
+	bookmark string,
+) (map[string]interface{}, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be positive")
+	}
+
+	iterator, metadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(
+		bulletinBoardObjectType, []string{electionID}, pageSize, bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bulletin board: %v", err)
+	}
+	defer iterator.Close()
+
+	entries, err := readBulletinBoardEntries(iterator)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"entries":      entries,
+		"bookmark":     metadata.Bookmark,
+		"fetchedCount": metadata.FetchedRecordsCount,
+	}, nil
+}
+
+// MigrateBulletinBoard moves a bulletin board stored as a single JSON array
+// to one key per entry and removes the array. It returns the number of
+// entries moved.
+func (v *VoteContract) MigrateBulletinBoard(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (int, error) {
+	if err := v.requireAdmin(ctx); err != nil {
+		return 0, err
+	}
+
+	legacyKey := legacyBulletinBoardKey(electionID)
+	bbJSON, err := ctx.GetStub().GetState(legacyKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read bulletin board: %v", err)
+	}
+	if bbJSON == nil {
+		return 0, nil
+	}
+
+	var entries []BulletinBoardEntry
+	if err := json.Unmarshal(bbJSON, &entries); err != nil {
+		return 0, err
+	}
+
+	for i := range entries {
+		// Sequences are renumbered from the array order so keys stay contiguous
+		entries[i].Sequence = i + 1
+		if err := putBulletinBoardEntry(ctx, electionID, entries[i]); err != nil {
+			return i, err
+		}
+	}
+
+	if err := ctx.GetStub().PutState(bulletinBoardSeqKey(electionID), []byte(strconv.Itoa(len(entries)))); err != nil {
+		return len(entries), fmt.Errorf("failed to store bulletin board length: %v", err)
+	}
+
+	// The entries written above cannot be read back in this transaction
+	scheme, err := v.merkleSchemeOf(ctx, electionID)
+	if err != nil {
+		return len(entries), err
+	}
+	tree, err := newMerkleFrontier(scheme, entries)
+	if err != nil {
+		return len(entries), err
+	}
+	if err := putBulletinBoardTree(ctx, electionID, tree); err != nil {
+		return len(entries), err
+	}
+	if err := ctx.GetStub().DelState(legacyKey); err != nil {
+		return len(entries), fmt.Errorf("failed to delete legacy bulletin board: %v", err)
+	}
+
+	return len(entries), nil
+}
+
+// GetVoteInclusionProof returns the Merkle path from a vote's bulletin board
+// entry to the bulletin board root, so a voter can check inclusion without
+// trusting the peer. Re-cast ballots are found by their "vote_amended" entry.
+func (v *VoteContract) GetVoteInclusionProof(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	encryptedVoteHash string,
+) (*MerkleInclusionProof, error) {
+	proof, err := v.voteInclusionProof(ctx, electionID, func(entry BulletinBoardEntry) bool {
+		return entry.Hash == encryptedVoteHash
+	})
+	if err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		return nil, fmt.Errorf("vote %s not found on bulletin board", encryptedVoteHash)
+	}
+	return proof, nil
+}
+
+// voteInclusionProof returns the inclusion proof of the first vote entry
+// that matches, or nil when none does
+func (v *VoteContract) voteInclusionProof(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	match func(BulletinBoardEntry) bool,
+) (*MerkleInclusionProof, error) {
+	entries, err := v.getBulletinBoardEntries(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	scheme, err := v.merkleSchemeOf(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, entry := range entries {
+		if (entry.Type == "vote_cast" || entry.Type == "vote_amended") && match(entry) {
+			levels := buildMerkleLevels(scheme, merkleLeaves(scheme, entries))
+			return &MerkleInclusionProof{
+				ElectionID:        electionID,
+				EncryptedVoteHash: entry.Hash,
+				Sequence:          entry.Sequence,
+				LeafIndex:         i,
+				LeafHash:          levels[0][i],
+				Path:              merklePath(scheme, levels, i),
+				MerkleRoot:        levels[len(levels)-1][0],
+				Scheme:            scheme,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// merkleSchemeOf returns the bulletin board hashing scheme of an election.
+// Boards without an election record predate schemes and use v1.
+func (v *VoteContract) merkleSchemeOf(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (string, error) {
+	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
+	if err != nil {
+		return "", fmt.Errorf("failed to read election: %w", err)
+	}
+	if electionJSON == nil {
+		return MerkleSchemeV1, nil
+	}
+
+	var election Election
+	if err := json.Unmarshal(electionJSON, &election); err != nil {
+		return "", err
+	}
+	return electionMerkleScheme(&election), nil
+}
+
+// GetElection retrieves election details
+func (v *VoteContract) GetElection(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*Election, error) {
+	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read election: %w", err)
+	}
+	if electionJSON == nil {
+		return nil, fmt.Errorf("%w: %s", ErrElectionNotFound, electionID)
+	}
+
+	var election Election
+	if err := json.Unmarshal(electionJSON, &election); err != nil {
+		return nil, err
+	}
+
+	return &election, nil
+}
+
+// ElectionSummary is what a dashboard shows for one election
+type ElectionSummary struct {
+	ElectionID  string    `json:"electionId"`
+	Title       string    `json:"title"`
+	Status      string    `json:"status"`
+	StartTime   time.Time `json:"startTime"`
+	EndTime     time.Time `json:"endTime"`
+	VoteCount   int       `json:"voteCount"`
+	TallyExists bool      `json:"tallyExists"`
+	MerkleRoot  string    `json:"merkleRoot"`
+}
+
+// GetElectionSummary combines the election record, vote count, tally
+// presence and bulletin board root in one read
+func (v *VoteContract) GetElectionSummary(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*ElectionSummary, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	voteCount, err := countVotes(ctx, election)
+	if err != nil {
+		return nil, err
+	}
+	tallyJSON, err := ctx.GetStub().GetState(tallyKey(electionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tally: %w", err)
+	}
+	tree, err := v.loadBulletinBoardTree(ctx, electionID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ElectionSummary{
+		ElectionID:  electionID,
+		Title:       election.Title,
+		Status:      election.Status,
+		StartTime:   election.StartTime,
+		EndTime:     election.EndTime,
+		VoteCount:   voteCount,
+		TallyExists: tallyJSON != nil,
+		MerkleRoot:  tree.Root,
+	}, nil
+}
+
+// QuestionParameters is the ballot spec of one question
+type QuestionParameters struct {
+	ID      string   `json:"id"`
+	Options []string `json:"options"`
+	// PublicKey is set when the question is encrypted under its own key
+	PublicKey *PublicKeyJSON `json:"publicKey,omitempty"`
+}
+
+// ElectionPublicParameters is what a voting client needs to build an
+// encrypted ballot and its proofs, with every group parameter spelled out
+type ElectionPublicParameters struct {
+	ElectionID      string               `json:"electionId"`
+	PublicKey       PublicKeyJSON        `json:"publicKey"`
+	HashAlgorithm   string               `json:"hashAlgorithm"`
+	VoterMerkleRoot string               `json:"voterMerkleRoot"`
+	VotingMode      VotingMode           `json:"votingMode"`
+	BallotType      string               `json:"ballotType"`
+	Questions       []QuestionParameters `json:"questions"`
+	// RangeProofScheme and BallotRange say what the validity proof must show
+	RangeProofScheme string       `json:"rangeProofScheme,omitempty"`
+	BallotRange      *BallotRange `json:"ballotRange,omitempty"`
+	ProofSystem      string       `json:"proofSystem,omitempty"`
+	ProofStorage     string       `json:"proofStorage,omitempty"`
+	Weighted         bool         `json:"weighted,omitempty"`
+	NullifierFormat  string       `json:"nullifierFormat,omitempty"`
+	NullifierLength  int          `json:"nullifierLength,omitempty"`
+	// AllowWriteIns adds a write-in ciphertext after the options
+	AllowWriteIns bool `json:"allowWriteIns,omitempty"`
+	// The public inputs each proof must commit to, in order
+	EligibilityPublicInputs []string `json:"eligibilityPublicInputs,omitempty"`
+	ValidityPublicInputs    []string `json:"validityPublicInputs,omitempty"`
+}
+
+// publicKeyParameters spells out every group parameter of a public key
+func publicKeyParameters(publicKey string) (PublicKeyJSON, error) {
+	key, err := parseElGamalPublicKey(publicKey)
+	if err != nil {
+		return PublicKeyJSON{}, err
+	}
+	return PublicKeyJSON{P: key.P.String(), Q: key.Q.String(), G: key.G.String(), H: key.H.String()}, nil
+}
+
+// GetElectionPublicParameters returns the cryptographic parameters a voting
+// client builds a ballot from, in one structure whose JSON is the same on
+// every call
+func (v *VoteContract) GetElectionPublicParameters(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*ElectionPublicParameters, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := publicKeyParameters(election.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Records written before voting modes and ballot types existed carry neither
+	votingMode := election.VotingMode
+	if votingMode == "" {
+		votingMode = VotingModeSingle
+	}
+	ballotType := election.BallotType
+	if ballotType == "" {
+		ballotType = BallotTypePlurality
+	}
+	proofSystem := election.ProofSystem
+	if proofSystem == "" && (election.EligibilityVerifyingKey != "" || election.ValidityVerifyingKey != "") {
+		proofSystem = ProofSystemGroth16
+	}
+
+	questions := []QuestionParameters{}
+	for _, question := range electionQuestions(election) {
+		params := QuestionParameters{ID: question.ID, Options: question.Options}
+		if params.Options == nil {
+			params.Options = []string{}
+		}
+		if question.PublicKey != "" {
+			key, err := publicKeyParameters(question.PublicKey)
+			if err != nil {
+				return nil, fmt.Errorf("question %s: %v", question.ID, err)
+			}
+			params.PublicKey = &key
+		}
+		questions = append(questions, params)
+	}
+
+	return &ElectionPublicParameters{
+		ElectionID:       electionID,
+		PublicKey:        publicKey,
+		HashAlgorithm:    normalizeHashAlgorithm(election.HashAlgorithm),
+		VoterMerkleRoot:  election.VoterMerkleRoot,
+		VotingMode:       votingMode,
+		BallotType:       ballotType,
+		Questions:        questions,
+		RangeProofScheme: election.RangeProofScheme,
+		BallotRange:      election.BallotRange,
+		ProofSystem:      proofSystem,
+		ProofStorage:     election.ProofStorage,
+		Weighted:         election.Weighted,
+		NullifierFormat:  election.NullifierFormat,
+		NullifierLength:  election.NullifierLength,
+		AllowWriteIns:    election.AllowWriteIns,
+
+		EligibilityPublicInputs: election.EligibilityPublicInputs,
+		ValidityPublicInputs:    election.ValidityPublicInputs,
+	}, nil
+}
+
+// ElectionMetrics are an election's monitoring counters
+type ElectionMetrics struct {
+	ElectionID string `json:"electionId"`
+	Status     string `json:"status"`
+	// VoteCount is the number of counted ballots; VotesCast also counts
+	// amendments under AllowRevote
+	VoteCount          int        `json:"voteCount"`
+	VotesCast          int        `json:"votesCast"`
+	DuplicateAttempts  int        `json:"duplicateAttempts"`
+	LastVoteTime       *time.Time `json:"lastVoteTime,omitempty"`
+	StatusTransitions  int        `json:"statusTransitions"`
+	LastTransitionTime *time.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// GetElectionMetrics returns the counters CastVote and status transitions
+// maintain for an election, for monitoring scrapers
+func (v *VoteContract) GetElectionMetrics(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) (*ElectionMetrics, error) {
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	voteCount, err := countVotes(ctx, election)
+	if err != nil {
+		return nil, err
+	}
+	votes, err := sumVoteMetrics(ctx, election)
+	if err != nil {
+		return nil, err
+	}
+	var status statusMetrics
+	if err := readMetrics(ctx, statusMetricsKey(electionID), &status); err != nil {
+		return nil, err
+	}
+
+	metrics := &ElectionMetrics{
+		ElectionID:        electionID,
+		Status:            election.Status,
+		VoteCount:         voteCount,
+		VotesCast:         votes.VotesCast,
+		DuplicateAttempts: votes.DuplicateAttempts,
+		StatusTransitions: status.Transitions,
+	}
+	if !votes.LastVoteTime.IsZero() {
+		metrics.LastVoteTime = &votes.LastVoteTime
+	}
+	if !status.LastTransitionTime.IsZero() {
+		metrics.LastTransitionTime = &status.LastTransitionTime
+	}
+	return metrics, nil
+}
+
+// GetCurrentTime returns the transaction timestamp (RFC3339, UTC), the
+// clock CastVote checks voting windows against
+func (v *VoteContract) GetCurrentTime(
+	ctx contractapi.TransactionContextInterface,
+) (string, error) {
+	now, err := txTime(ctx)
+	if err != nil {
+		return "", err
+	}
+	return now.Format(time.RFC3339Nano), nil
+}
+
+// GetElectionsByStatus returns the elections currently in status, ordered by ID
+func (v *VoteContract) GetElectionsByStatus(
+	ctx contractapi.TransactionContextInterface,
+	status string,
+) ([]*Election, error) {
+	if status == "" {
+		return nil, fmt.Errorf("status is required")
+	}
+
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(electionStatusObjectType, []string{status})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status index: %v", err)
+	}
+	defer iterator.Close()
+
+	elections := []*Election{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read status index: %v", err)
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(kv.Key)
+		if err != nil || len(attributes) != 2 {
+			return nil, fmt.Errorf("invalid status index key %q", kv.Key)
+		}
+
+		election, err := v.GetElection(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		elections = append(elections, election)
+	}
+
+	return elections, nil
+}
+
+// MigrateElectionStatusIndex adds an election created before the status
+// index existed to the index under its current status
+func (v *VoteContract) MigrateElectionStatusIndex(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) error {
+	if err := v.requireAdmin(ctx); err != nil {
+		return err
+	}
+
+	election, err := v.GetElection(ctx, electionID)
+	if err != nil {
+		return err
+	}
+	return updateElectionStatusIndex(ctx, electionID, "", election.Status)
+}
+
+// GetElectionHistory returns every committed version of the election record,
+// oldest first, using the peer's key history. Requires history to be enabled
+// on the peer (ledger.history.enableHistoryDatabase).
+func (v *VoteContract) GetElectionHistory(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]ElectionHistoryEntry, error) {
+	iterator, err := ctx.GetStub().GetHistoryForKey(electionKey(electionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read election history: %v", err)
+	}
+	defer iterator.Close()
+
+	history := []ElectionHistoryEntry{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read election history: %v", err)
+		}
+
+		entry := ElectionHistoryEntry{
+			TxID:     modification.TxId,
+			IsDelete: modification.IsDelete,
+		}
+		if ts := modification.Timestamp; ts != nil {
+			entry.Timestamp = time.Unix(ts.Seconds, int64(ts.Nanos)).UTC()
+		}
+		if !modification.IsDelete {
+			var election Election
+			if err := json.Unmarshal(modification.Value, &election); err != nil {
+				return nil, fmt.Errorf("invalid election record in tx %s: %v", modification.TxId, err)
+			}
+			entry.Status = election.Status
+		}
+		history = append(history, entry)
+	}
+
+	if len(history) == 0 {
+		return nil, fmt.Errorf("election %s has no history", electionID)
+	}
+
+	// The peer makes no ordering promise; present a timeline
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+
+	return history, nil
+}
+
+// Helper functions
+
+// AdminAttribute is the certificate attribute that grants administrative access
+const AdminAttribute = "electionadmin"
+
+// requireAdmin rejects callers that neither carry the electionadmin=true
+// attribute nor belong to one of the configured admin MSPs
+func (v *VoteContract) requireAdmin(ctx contractapi.TransactionContextInterface) error {
+	identity := ctx.GetClientIdentity()
+
+	value, found, err := identity.GetAttributeValue(AdminAttribute)
+	if err != nil {
+		return fmt.Errorf("failed to read client attributes: %v", err)
+	}
+	if found && value == "true" {
+		return nil
+	}
+
+	mspID, err := identity.GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to read client MSP: %v", err)
+	}
+	for _, adminMSP := range v.AdminMSPIDs {
+		if mspID == adminMSP {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: caller from %s lacks the %s attribute", ErrPermissionDenied, mspID, AdminAttribute)
+}
+
+// ParseAdminMSPIDs reads a comma separated list of admin MSP IDs, as set in
+// VOTE_ADMIN_MSP_IDS. Entries are trimmed and empty ones skipped, so every
+// peer grants the same rights whatever the spacing of its setting.
+func ParseAdminMSPIDs(value string) ([]string, error) {
+	mspIDs := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		mspID := strings.TrimSpace(entry)
+		if mspID == "" {
+			continue
+		}
+		for _, r := range mspID {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return nil, fmt.Errorf("invalid admin MSP ID %q", mspID)
+			}
 		}
+		mspIDs = append(mspIDs, mspID)
+	}
+	return mspIDs, nil
+}
+
+// allVotesPageSize is the page size GetAllVotes uses to walk the vote range
+const allVotesPageSize = 1000
+
+// requireCollectionMember allows only clients from the executing peer's
+// organization. Collections are memberOnlyRead, so a peer holding the data
+// implies its organization is a collection member.
+func requireCollectionMember(ctx contractapi.TransactionContextInterface) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
 	}
+	peerMSPID, err := shim.GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get peer MSP ID: %v", err)
+	}
+	if clientMSPID != peerMSPID {
+		return fmt.Errorf("%w: client from %s is not a member of the collection held by %s",
+			ErrPermissionDenied, clientMSPID, peerMSPID)
+	}
+	return nil
+}
 
-	// Update vote counts
-	votesInThisSubmission := 0
-	for _, selection := range selections {
-		if participation.VotesByCandidate == nil {
-			participation.VotesByCandidate = make(map[string]int)
+func validateMSPIDs(mspIDs []string) error {
+	if len(mspIDs) == 0 {
+		return fmt.Errorf("at least one MSP ID is required")
+	}
+	for _, mspID := range mspIDs {
+		if strings.TrimSpace(mspID) == "" {
+			return fmt.Errorf("MSP ID must not be empty")
 		}
-		participation.VotesByCandidate[selection.CandidateID] += selection.Votes
-		votesInThisSubmission += selection.Votes
 	}
+	return nil
+}
 
-	if votesInThisSubmission == 0 {
-		votesInThisSubmission = 1 // Default 1 vote if no selections
+// validateDisplayMetadata checks an election's optional display timezone
+// and locale. "Local" is rejected because it names each peer's own zone.
+func validateDisplayMetadata(timezone, locale string) error {
+	if timezone != "" {
+		if timezone == "Local" {
+			return fmt.Errorf("invalid timezone %q: use an IANA name", timezone)
+		}
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q: %v", timezone, err)
+		}
 	}
-	participation.TotalVotesCast += votesInThisSubmission
 
-	updatedJSON, err := json.Marshal(participation)
-	if err != nil {
-		return err
+	if locale != "" && !isLanguageTag(locale) {
+		return fmt.Errorf("invalid locale %q: use a BCP 47 tag such as ko-KR", locale)
 	}
+	return nil
+}
 
-	return ctx.GetStub().PutState(participationKey, updatedJSON)
+// isLanguageTag reports whether tag has the shape of a BCP 47 language tag:
+// a 2-3 letter language followed by 1-8 character alphanumeric subtags
+func isLanguageTag(tag string) bool {
+	if len(tag) > 35 {
+		return false
+	}
+	for i, subtag := range strings.Split(tag, "-") {
+		if i == 0 && (len(subtag) < 2 || len(subtag) > 3) {
+			return false
+		}
+		if len(subtag) < 1 || len(subtag) > 8 {
+			return false
+		}
+		for _, r := range subtag {
+			isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+			if !isLetter && (i == 0 || r < '0' || r > '9') {
+				return false
+			}
+		}
+	}
+	return true
 }
 
-// GetVoterParticipation retrieves voter participation status
-func (v *VoteContract) GetVoterParticipation(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-	voterHash string,
-	votingPeriod int,
-) (*VoterParticipation, error) {
-	participationKey := voterParticipationKey(electionID, voterHash, votingPeriod)
-	participationJSON, err := ctx.GetStub().GetState(participationKey)
+// tallyEndorsementPolicy builds a key-level policy that requires a peer of
+// every listed organization to endorse
+func tallyEndorsementPolicy(mspIDs []string) ([]byte, error) {
+	ep, err := statebased.NewStateEP(nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read participation: %v", err)
+		return nil, err
 	}
-	if participationJSON == nil {
-		return nil, nil // No participation yet
+	if err := ep.AddOrgs(statebased.RoleTypePeer, mspIDs...); err != nil {
+		return nil, fmt.Errorf("invalid tally endorsers: %v", err)
 	}
+	return ep.Policy()
+}
 
-	var participation VoterParticipation
-	if err := json.Unmarshal(participationJSON, &participation); err != nil {
-		return nil, err
+// applyTallyEndorsementPolicy sets the election's tally endorsement policy on
+// its tally and election keys. The ledger ignores validation parameters of
+// keys that do not exist yet, so callers repeat this once the tally is stored.
+func applyTallyEndorsementPolicy(ctx contractapi.TransactionContextInterface, election *Election) error {
+	if len(election.TallyEndorsers) == 0 {
+		return nil
 	}
 
-	return &participation, nil
+	policy, err := tallyEndorsementPolicy(election.TallyEndorsers)
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{tallyKey(election.ID), electionKey(election.ID)} {
+		if err := ctx.GetStub().SetStateValidationParameter(key, policy); err != nil {
+			return fmt.Errorf("failed to set endorsement policy: %v", err)
+		}
+	}
+	return nil
 }
 
-// GetVote retrieves a vote by nullifier
-func (v *VoteContract) GetVote(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-	nullifier string,
-) (*Vote, error) {
-	voteJSON, err := ctx.GetStub().GetState(voteKey(electionID, nullifier))
+// getPrivateVote reads a vote's ciphertext from its collection and checks
+// it against the public hash
+func getPrivateVote(ctx contractapi.TransactionContextInterface, election *Election, vote *Vote) (*PrivateVote, error) {
+	key, err := voteKeyForCommitment(ctx, vote.ElectionID, vote.NullifierCommitment)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read vote: %v", err)
+		return nil, err
 	}
-	if voteJSON == nil {
-		return nil, fmt.Errorf("vote not found")
+	privateJSON, err := ctx.GetStub().GetPrivateData(vote.PrivateCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private vote: %v", err)
+	}
+	if privateJSON == nil {
+		return nil, fmt.Errorf("private vote not found in collection %s", vote.PrivateCollection)
 	}
 
-	var vote Vote
-	if err := json.Unmarshal(voteJSON, &vote); err != nil {
+	var privateVote PrivateVote
+	if err := json.Unmarshal(privateJSON, &privateVote); err != nil {
 		return nil, err
 	}
+	if hashForElection(election, privateVote.EncryptedVote) != vote.EncryptedVoteHash {
+		return nil, fmt.Errorf("private vote does not match the public hash")
+	}
 
-	return &vote, nil
+	return &privateVote, nil
 }
 
-// GetAllVotes retrieves all votes for an election
-func (v *VoteContract) GetAllVotes(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-) (map[string]interface{}, error) {
-	// Get vote index
-	indexKey := voteIndexKey(electionID)
-	indexJSON, err := ctx.GetStub().GetState(indexKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read vote index: %v", err)
-	}
-
-	var nullifiers []string
-	if indexJSON != nil {
-		if err := json.Unmarshal(indexJSON, &nullifiers); err != nil {
-			return nil, err
+// validateQuestions checks question IDs and options are present and unique
+// and puts per-question public keys in canonical form
+func validateQuestions(questions []Question) error {
+	seen := make(map[string]bool)
+	for i, question := range questions {
+		if question.ID == "" {
+			return fmt.Errorf("question ID is required")
 		}
-	}
+		if seen[question.ID] {
+			return fmt.Errorf("duplicate question ID: %s", question.ID)
+		}
+		seen[question.ID] = true
 
-	// Collect all encrypted votes
-	votes := make([]string, 0, len(nullifiers))
-	for _, nullifier := range nullifiers {
-		voteJSON, err := ctx.GetStub().GetState(voteKey(electionID, nullifier))
-		if err != nil {
-			continue
+		if len(question.Options) == 0 {
+			return fmt.Errorf("question %s has no options", question.ID)
+		}
+		if err := validateOptions(question.ID, question.Options); err != nil {
+			return err
 		}
-		if voteJSON != nil {
-			var vote Vote
-			if err := json.Unmarshal(voteJSON, &vote); err == nil {
-				votes = append(votes, vote.EncryptedVote)
+		if question.PublicKey != "" {
+			publicKey, err := canonicalElGamalPublicKey(question.PublicKey)
+			if err != nil {
+				return fmt.Errorf("question %s: %v", question.ID, err)
 			}
+			questions[i].PublicKey = publicKey
 		}
 	}
+	return nil
+}
 
-	return map[string]interface{}{
-		"votes": votes,
-		"count": len(votes),
-	}, nil
+// checkBallotMutable rejects changes to the questions, options and keys of
+// an election once ballots may depend on them: after FreezeBallot or once
+// the election leaves pending
+func checkBallotMutable(election *Election) error {
+	if election.Status != "pending" {
+		return fmt.Errorf("%w: election %s is %s", ErrBallotFrozen, election.ID, election.Status)
+	}
+	if election.BallotFrozen {
+		return fmt.Errorf("%w: election %s was frozen with FreezeBallot", ErrBallotFrozen, election.ID)
+	}
+	return nil
 }
 
-// VerifyVote verifies a vote exists and matches the provided hash
-func (v *VoteContract) VerifyVote(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-	nullifier string,
-	expectedHash string,
-) (map[string]interface{}, error) {
-	vote, err := v.GetVote(ctx, electionID, nullifier)
-	if err != nil {
-		return map[string]interface{}{
-			"verified": false,
-			"error":    err.Error(),
-		}, nil
+// checkElectionConfigured rejects an election that could not be voted in
+// or tallied: one without a usable public key or voter root, or with a
+// question that has no options. CreateElection leaves the options of a
+// single-question election to UpdateBallotOptions, and records written
+// directly or by older versions may not have been checked at all.
+func checkElectionConfigured(election *Election) error {
+	if strings.TrimSpace(election.VoterMerkleRoot) == "" {
+		return fmt.Errorf("%w: election %s has no voter Merkle root", ErrElectionIncomplete, election.ID)
+	}
+	if strings.TrimSpace(election.PublicKey) == "" {
+		return fmt.Errorf("%w: election %s has no public key", ErrElectionIncomplete, election.ID)
 	}
+	if _, err := questionPublicKeys(election); err != nil {
+		return fmt.Errorf("%w: %v", ErrElectionIncomplete, err)
+	}
+	// validateQuestions canonicalizes keys in place, so it checks a copy
+	if err := validateQuestions(append([]Question(nil), election.Questions...)); err != nil {
+		return fmt.Errorf("%w: %v", ErrElectionIncomplete, err)
+	}
+	if len(election.Questions) == 0 && len(election.Options) == 0 {
+		return fmt.Errorf("%w: election %s has no options (set them with UpdateBallotOptions)", ErrElectionIncomplete, election.ID)
+	}
+	if err := validateOptions(DefaultQuestionID, election.Options); err != nil {
+		return fmt.Errorf("%w: %v", ErrElectionIncomplete, err)
+	}
+	return nil
+}
 
-	verified := vote.EncryptedVoteHash == expectedHash
+// validateOptions rejects empty, duplicate and reserved option IDs
+func validateOptions(questionID string, options []string) error {
+	seen := make(map[string]bool)
+	for _, option := range options {
+		if option == "" || seen[option] {
+			return fmt.Errorf("question %s has an empty or duplicate option", questionID)
+		}
+		if isReservedOption(option) {
+			return fmt.Errorf("question %s uses the reserved option %s", questionID, option)
+		}
+		seen[option] = true
+	}
+	return nil
+}
 
-	return map[string]interface{}{
-		"verified":   verified,
-		"txId":       vote.TxID,
-		"timestamp":  vote.Timestamp,
-	}, nil
+// electionQuestions returns the election's questions, or the implicit
+// default question with the election's options for single-question elections
+func electionQuestions(election *Election) []Question {
+	if len(election.Questions) == 0 {
+		return []Question{{ID: DefaultQuestionID, Options: election.Options}}
+	}
+	return election.Questions
 }
 
-// GetVoteByHash retrieves a vote by its encrypted vote hash
-func (v *VoteContract) GetVoteByHash(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-	encryptedVoteHash string,
-) (map[string]interface{}, error) {
-	// This requires iterating through votes - in production, use a composite key index
-	indexKey := voteIndexKey(electionID)
-	indexJSON, err := ctx.GetStub().GetState(indexKey)
+// questionPublicKey is the key ballots for a question are encrypted under
+func questionPublicKey(election *Election, questionID string) string {
+	if question := findQuestion(election.Questions, questionID); question != nil && question.PublicKey != "" {
+		return question.PublicKey
+	}
+	return election.PublicKey
+}
+
+// checkBallotWidth rejects a ballot without exactly one ciphertext per
+// option of its question, plus the write-in channel. Ballots are
+// aggregated component-wise, so one of another width would stop the whole
+// election from being tallied. Ranked ballots are checked by
+// checkRankedBallot, and questions without options have no width to check.
+func checkBallotWidth(election *Election, questionID string, encryptedVote string) error {
+	question := findQuestion(electionQuestions(election), questionID)
+	if election.BallotType == BallotTypeRanked || question == nil || len(question.Options) == 0 {
+		return nil
+	}
+	width := len(question.Options)
+	if election.AllowWriteIns {
+		width++
+	}
+	ballot, err := parseBallot(encryptedVote)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	if len(ballot) != width {
+		return fmt.Errorf("ballot has %d ciphertexts, expected %d", len(ballot), width)
+	}
+	return nil
+}
 
-	var nullifiers []string
-	if indexJSON != nil {
-		if err := json.Unmarshal(indexJSON, &nullifiers); err != nil {
-			return nil, err
+// questionPublicKeys parses the key of every question of an election
+func questionPublicKeys(election *Election) (map[string]*ElGamalPublicKey, error) {
+	keys := make(map[string]*ElGamalPublicKey)
+	for _, question := range electionQuestions(election) {
+		key, err := parseElGamalPublicKey(questionPublicKey(election, question.ID))
+		if err != nil {
+			return nil, fmt.Errorf("question %s: %v", question.ID, err)
 		}
+		keys[question.ID] = key
 	}
+	return keys, nil
+}
 
-	for _, nullifier := range nullifiers {
-		voteJSON, err := ctx.GetStub().GetState(voteKey(electionID, nullifier))
-		if err != nil {
-			continue
+func findQuestion(questions []Question, questionID string) *Question {
+	for i := range questions {
+		if questions[i].ID == questionID {
+			return &questions[i]
 		}
-		if voteJSON != nil {
-			var vote Vote
-			if err := json.Unmarshal(voteJSON, &vote); err == nil {
-				if vote.EncryptedVoteHash == encryptedVoteHash {
-					return map[string]interface{}{
-						"found":             true,
-						"encryptedVoteHash": vote.EncryptedVoteHash,
-						"txId":              vote.TxID,
-						"blockNumber":       vote.BlockNumber,
-						"timestamp":         vote.Timestamp,
-					}, nil
-				}
-			}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
 	}
+	return false
+}
 
-	return map[string]interface{}{
-		"found": false,
-	}, nil
+func electionKey(electionID string) string {
+	return fmt.Sprintf("election:%s", electionID)
 }
 
-// CloseElection closes an election for voting
-func (v *VoteContract) CloseElection(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-) error {
-	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
+// voteObjectType is the composite key namespace for vote records
+const voteObjectType = "vote"
+
+// verificationCodeObjectType is the composite key namespace mapping receipt
+// verification codes to nullifier commitments
+const verificationCodeObjectType = "vcode"
+
+func verificationCodeKey(ctx contractapi.TransactionContextInterface, electionID, code string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(verificationCodeObjectType, []string{electionID, code})
 	if err != nil {
-		return fmt.Errorf("failed to read election: %v", err)
+		return "", fmt.Errorf("failed to create verification code key: %v", err)
 	}
-	if electionJSON == nil {
-		return fmt.Errorf("election %s does not exist", electionID)
+	return key, nil
+}
+
+// ciphertextObjectType is the composite key namespace mapping encrypted
+// vote hashes to the nullifier commitment that cast them, kept only in
+// elections with UniqueCiphertexts
+const ciphertextObjectType = "ciphertext"
+
+func ciphertextKey(ctx contractapi.TransactionContextInterface, electionID, encryptedVoteHash string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(ciphertextObjectType, []string{electionID, encryptedVoteHash})
+	if err != nil {
+		return "", fmt.Errorf("failed to create ciphertext key: %v", err)
 	}
+	return key, nil
+}
 
-	var election Election
-	if err := json.Unmarshal(electionJSON, &election); err != nil {
+// checkCiphertextUnused rejects a ciphertext already cast under a
+// nullifier other than the one committed to by commitment
+func checkCiphertextUnused(ctx contractapi.TransactionContextInterface, electionID, encryptedVoteHash, commitment string) error {
+	key, err := ciphertextKey(ctx, electionID, encryptedVoteHash)
+	if err != nil {
 		return err
 	}
-
-	if election.Status != "active" {
-		return fmt.Errorf("election is not active")
+	owner, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return fmt.Errorf("failed to check ciphertext: %v", err)
 	}
+	if owner != nil && string(owner) != commitment {
+		return ErrDuplicateCiphertext
+	}
+	return nil
+}
 
-	election.Status = "closed"
-
-	updatedJSON, err := json.Marshal(election)
+func indexCiphertext(ctx contractapi.TransactionContextInterface, electionID, encryptedVoteHash, commitment string) error {
+	key, err := ciphertextKey(ctx, electionID, encryptedVoteHash)
 	if err != nil {
 		return err
 	}
+	if err := ctx.GetStub().PutState(key, []byte(commitment)); err != nil {
+		return fmt.Errorf("failed to index ciphertext: %v", err)
+	}
+	return nil
+}
 
-	if err := ctx.GetStub().PutState(electionKey(electionID), updatedJSON); err != nil {
-		return err
+// statusTransitions lists the statuses an election may move to from each
+// status. A new election starts pending; cancelled and archived are final.
+var statusTransitions = map[string][]string{
+	"pending":   {"active", "cancelled"},
+	"active":    {"paused", "closed", "cancelled"},
+	"paused":    {"active", "cancelled"},
+	"closed":    {"tallying", "completed"},
+	"tallying":  {"completed"},
+	"completed": {"tallying", "archived"},
+}
+
+// canTransition reports whether an election may move from one status to another
+func canTransition(from, to string) bool {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return true
+		}
 	}
+	return false
+}
 
-	return v.addBulletinBoardEntry(ctx, electionID, "election_closed", hashString(string(updatedJSON)))
+// setStatus moves an election to status if the transition table allows it
+func setStatus(election *Election, status string) error {
+	if !canTransition(election.Status, status) {
+		return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, election.Status, status)
+	}
+	election.Status = status
+	return nil
 }
 
-// StoreTallyResult stores the tally result after decryption
-func (v *VoteContract) StoreTallyResult(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-	voteCountsJSON string,
-	aggregatedHash string,
-	decryptionProof string,
-) error {
-	// Verify election is closed
-	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
+// electionStatusObjectType is the composite key namespace of the
+// status~electionID index used by GetElectionsByStatus
+const electionStatusObjectType = "status~election"
+
+func electionStatusKey(ctx contractapi.TransactionContextInterface, status, electionID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(electionStatusObjectType, []string{status, electionID})
 	if err != nil {
-		return fmt.Errorf("failed to read election: %v", err)
-	}
-	if electionJSON == nil {
-		return fmt.Errorf("election %s does not exist", electionID)
+		return "", fmt.Errorf("failed to create status index key: %v", err)
 	}
+	return key, nil
+}
 
-	var election Election
-	if err := json.Unmarshal(electionJSON, &election); err != nil {
+// updateElectionStatusIndex moves an election from oldStatus to newStatus in
+// the status index and counts the transition. An empty oldStatus means the
+// election is new.
+func updateElectionStatusIndex(ctx contractapi.TransactionContextInterface, electionID, oldStatus, newStatus string) error {
+	if oldStatus != "" {
+		oldKey, err := electionStatusKey(ctx, oldStatus, electionID)
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().DelState(oldKey); err != nil {
+			return fmt.Errorf("failed to update status index: %v", err)
+		}
+		if err := recordStatusTransition(ctx, electionID); err != nil {
+			return err
+		}
+	}
+	newKey, err := electionStatusKey(ctx, newStatus, electionID)
+	if err != nil {
 		return err
 	}
-
-	if election.Status != "closed" && election.Status != "tallying" {
-		return fmt.Errorf("election must be closed or tallying to store results")
+	if err := ctx.GetStub().PutState(newKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("failed to update status index: %v", err)
 	}
+	return nil
+}
 
-	// Parse vote counts
-	var voteCounts map[string]int
-	if err := json.Unmarshal([]byte(voteCountsJSON), &voteCounts); err != nil {
-		return fmt.Errorf("invalid vote counts: %v", err)
+// putElection stores an election and returns the serialized record
+func (v *VoteContract) putElection(ctx contractapi.TransactionContextInterface, election *Election) ([]byte, error) {
+	electionJSON, err := json.Marshal(election)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(electionKey(election.ID), electionJSON); err != nil {
+		return nil, fmt.Errorf("failed to store election: %v", err)
 	}
+	return electionJSON, nil
+}
 
-	// Calculate total votes
-	totalVotes := 0
-	for _, count := range voteCounts {
-		totalVotes += count
+// nullifierCommitment hashes a nullifier salted with its election ID, so the
+// ledger never holds the nullifier and the same nullifier maps to unrelated
+// keys in different elections
+func nullifierCommitment(electionID, nullifier string) string {
+	return hashString("nullifier:" + electionID + ":" + nullifier)
+}
+
+// checkProofHashes requires the hex digests of both proofs a vote
+// references, under the election's hash algorithm
+func checkProofHashes(eligibilityProofHash, validityProofHash string) error {
+	if eligibilityProofHash == "" || validityProofHash == "" {
+		return fmt.Errorf("eligibility and validity proof hashes are required")
 	}
+	if !isHexDigest(eligibilityProofHash) {
+		return fmt.Errorf("eligibility proof hash must be a %d-character hex digest", hashSize*2)
+	}
+	if !isHexDigest(validityProofHash) {
+		return fmt.Errorf("validity proof hash must be a %d-character hex digest", hashSize*2)
+	}
+	return nil
+}
 
-	txID := ctx.GetStub().GetTxID()
+func isHexDigest(s string) bool {
+	_, err := hex.DecodeString(s)
+	return err == nil && len(s) == hashSize*2
+}
 
-	result := TallyResult{
-		ElectionID:      electionID,
-		VoteCounts:      voteCounts,
-		TotalVotes:      totalVotes,
-		AggregatedHash:  aggregatedHash,
-		DecryptionProof: decryptionProof,
-		TallyTimestamp:  time.Now(),
-		TxID:            txID,
+// checkElectionID rejects empty, oversized and non-printable election IDs,
+// and IDs containing ':', which separates the parts of simple keys such as
+// "tally:<id>:v<n>" and would let one election's keys collide with another's
+func checkElectionID(electionID string) error {
+	if strings.TrimSpace(electionID) == "" {
+		return fmt.Errorf("election ID is required")
+	}
+	if len(electionID) > MaxElectionIDLength {
+		return fmt.Errorf("election ID exceeds %d characters", MaxElectionIDLength)
+	}
+	if !utf8.ValidString(electionID) {
+		return fmt.Errorf("election ID is not valid UTF-8")
+	}
+	for _, r := range electionID {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("election ID contains non-printable characters")
+		}
 	}
-
-	resultJSON, err := json.Marshal(result)
-	if err != nil {
-		return err
+	if strings.Contains(electionID, ":") {
+		return fmt.Errorf("election ID must not contain ':'")
 	}
+	return nil
+}
 
-	// Store tally result
-	if err := ctx.GetStub().PutState(tallyKey(electionID), resultJSON); err != nil {
-		return err
+// checkNullifier rejects empty, oversized and non-printable nullifiers
+func checkNullifier(nullifier string) error {
+	if nullifier == "" {
+		return fmt.Errorf("nullifier is required")
 	}
-
-	// Update election status
-	election.Status = "completed"
-	updatedJSON, err := json.Marshal(election)
-	if err != nil {
-		return err
+	if len(nullifier) > MaxNullifierLength {
+		return fmt.Errorf("nullifier exceeds %d characters", MaxNullifierLength)
 	}
-	if err := ctx.GetStub().PutState(electionKey(electionID), updatedJSON); err != nil {
-		return err
+	if !utf8.ValidString(nullifier) {
+		return fmt.Errorf("nullifier is not valid UTF-8")
+	}
+	for _, r := range nullifier {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("nullifier contains non-printable characters")
+		}
 	}
+	return nil
+}
 
-	// Add to bulletin board
-	if err := v.addBulletinBoardEntry(ctx, electionID, "tally_completed", hashString(string(resultJSON))); err != nil {
+// checkNullifierFormat applies checkNullifier and the election's nullifier
+// format and length
+func checkNullifierFormat(election *Election, nullifier string) error {
+	if err := checkNullifier(nullifier); err != nil {
 		return err
 	}
+	if election.NullifierLength > 0 && len(nullifier) != election.NullifierLength {
+		return fmt.Errorf("nullifier must be %d characters, got %d", election.NullifierLength, len(nullifier))
+	}
+	if election.NullifierFormat == NullifierFormatHex {
+		if _, err := hex.DecodeString(nullifier); err != nil {
+			return fmt.Errorf("nullifier must be hex encoded")
+		}
+	}
+	return nil
+}
 
-	// Emit event
-	eventJSON, _ := json.Marshal(map[string]interface{}{
-		"electionId": electionID,
-		"totalVotes": totalVotes,
-		"txId":       txID,
-	})
-	return ctx.GetStub().SetEvent("TallyCompleted", eventJSON)
+// voteKey derives the composite key vote~electionID~commitment
+func voteKey(ctx contractapi.TransactionContextInterface, electionID, nullifier string) (string, error) {
+	return voteKeyForCommitment(ctx, electionID, nullifierCommitment(electionID, nullifier))
 }
 
-// GetTallyResult retrieves the tally result for an election
-func (v *VoteContract) GetTallyResult(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-) (*TallyResult, error) {
-	resultJSON, err := ctx.GetStub().GetState(tallyKey(electionID))
+func voteKeyForCommitment(ctx contractapi.TransactionContextInterface, electionID, commitment string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(voteObjectType, []string{electionID, commitment})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read tally: %v", err)
-	}
-	if resultJSON == nil {
-		return nil, fmt.Errorf("tally not found for election %s", electionID)
+		return "", fmt.Errorf("failed to create vote key: %v", err)
 	}
+	return key, nil
+}
 
-	var result TallyResult
-	if err := json.Unmarshal(resultJSON, &result); err != nil {
-		return nil, err
-	}
+// legacyVoteKey and legacyVoteIndexKey are the pre-composite-key layout,
+// read only by MigrateVoteIndex
+func legacyVoteKey(electionID, nullifier string) string {
+	return fmt.Sprintf("vote:%s:%s", electionID, nullifier)
+}
 
-	return &result, nil
+func legacyVoteIndexKey(electionID string) string {
+	return fmt.Sprintf("voteindex:%s", electionID)
 }
 
-// GetBulletinBoard retrieves the public bulletin board for an election
-func (v *VoteContract) GetBulletinBoard(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-) (map[string]interface{}, error) {
-	bbKey := bulletinBoardKey(electionID)
-	bbJSON, err := ctx.GetStub().GetState(bbKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read bulletin board: %v", err)
-	}
+// VoteBlockEntry is a vote's entry in the block number index
+type VoteBlockEntry struct {
+	EncryptedVoteHash string `json:"encryptedVoteHash"`
+	TxID              string `json:"txId"`
+	BlockNumber       uint64 `json:"blockNumber"`
+}
 
-	var entries []BulletinBoardEntry
-	if bbJSON != nil {
-		if err := json.Unmarshal(bbJSON, &entries); err != nil {
-			return nil, err
-		}
-	}
+// voteBlockKey is a simple key rather than a composite one because Fabric
+// only range-queries simple keys
+func voteBlockKey(electionID string, blockNumber uint64, encryptedVoteHash string) string {
+	return fmt.Sprintf("voteblock:%s:%020d:%s", electionID, blockNumber, encryptedVoteHash)
+}
 
-	// Compute merkle root of entries
-	merkleRoot := computeMerkleRoot(entries)
+// voteBlockRangeEnd sorts after every index entry of toBlock
+func voteBlockRangeEnd(electionID string, toBlock uint64) string {
+	return fmt.Sprintf("voteblock:%s:%020d;", electionID, toBlock)
+}
 
-	return map[string]interface{}{
-		"entries":    entries,
-		"merkleRoot": merkleRoot,
-	}, nil
+// voteSequenceKey maps a bulletin board sequence to the nullifier commitment
+// of the vote cast at it
+func voteSequenceKey(electionID string, sequence int) string {
+	return fmt.Sprintf("voteseq:%s:%d", electionID, sequence)
 }
 
-// GetElection retrieves election details
-func (v *VoteContract) GetElection(
-	ctx contractapi.TransactionContextInterface,
-	electionID string,
-) (*Election, error) {
-	electionJSON, err := ctx.GetStub().GetState(electionKey(electionID))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read election: %v", err)
+// indexVoteSequence records the sequence of a stored vote. An amendment
+// drops the index entry of the version it replaces.
+func indexVoteSequence(ctx contractapi.TransactionContextInterface, electionID string, sequence int, commitment string, previousSequence int) error {
+	if previousSequence > 0 {
+		if err := ctx.GetStub().DelState(voteSequenceKey(electionID, previousSequence)); err != nil {
+			return fmt.Errorf("failed to update vote sequence index: %v", err)
+		}
 	}
-	if electionJSON == nil {
-		return nil, fmt.Errorf("election %s does not exist", electionID)
+	if err := ctx.GetStub().PutState(voteSequenceKey(electionID, sequence), []byte(commitment)); err != nil {
+		return fmt.Errorf("failed to index vote sequence: %v", err)
 	}
+	return nil
+}
 
-	var election Election
-	if err := json.Unmarshal(electionJSON, &election); err != nil {
-		return nil, err
-	}
+func voteCountKey(electionID string) string {
+	return fmt.Sprintf("votecount:%s", electionID)
+}
 
-	return &election, nil
+func aggregateKey(electionID string) string {
+	return fmt.Sprintf("aggregate:%s", electionID)
 }
 
-// Helper functions
+func tallyKey(electionID string) string {
+	return fmt.Sprintf("tally:%s", electionID)
+}
 
-func electionKey(electionID string) string {
-	return fmt.Sprintf("election:%s", electionID)
+// tallyVersionKey holds a tally result superseded by ReopenTally
+func tallyVersionKey(electionID string, version int) string {
+	return fmt.Sprintf("tally:%s:v%d", electionID, version)
 }
 
-func voteKey(electionID, nullifier string) string {
-	return fmt.Sprintf("vote:%s:%s", electionID, nullifier)
+func partialDecryptionKey(ctx contractapi.TransactionContextInterface, electionID, trusteeID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(partialDecryptionObjectType, []string{electionID, trusteeID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create partial decryption key: %v", err)
+	}
+	return key, nil
 }
 
-func voteIndexKey(electionID string) string {
-	return fmt.Sprintf("voteindex:%s", electionID)
+// bulletinBoardObjectType is the composite key namespace for bulletin board entries
+const bulletinBoardObjectType = "bb"
+
+// bulletinBoardEntryKey derives the composite key bb~electionID~sequence. The
+// sequence is zero padded so key order is sequence order.
+func bulletinBoardEntryKey(ctx contractapi.TransactionContextInterface, electionID string, sequence int) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(bulletinBoardObjectType, []string{electionID, fmt.Sprintf("%010d", sequence)})
+	if err != nil {
+		return "", fmt.Errorf("failed to create bulletin board key: %v", err)
+	}
+	return key, nil
 }
 
-func tallyKey(electionID string) string {
-	return fmt.Sprintf("tally:%s", electionID)
+func bulletinBoardSeqKey(electionID string) string {
+	return fmt.Sprintf("bulletinboardseq:%s", electionID)
 }
 
-func bulletinBoardKey(electionID string) string {
+func bulletinBoardTreeKey(electionID string) string {
+	return fmt.Sprintf("bulletinboardtree:%s", electionID)
+}
+
+// legacyBulletinBoardKey held the whole board as one JSON array, read only
+// by MigrateBulletinBoard
+func legacyBulletinBoardKey(electionID string) string {
 	return fmt.Sprintf("bulletinboard:%s", electionID)
 }
 
@@ -861,43 +6865,173 @@ func voterParticipationKey(electionID, voterHash string, votingPeriod int) strin
 	return fmt.Sprintf("participation:%s:%s:%d", electionID, voterHash, votingPeriod)
 }
 
+// computeAggregatedHash is the hash a tally's AggregatedHash must match:
+// the election's hash over the concatenated encrypted vote hashes, sorted
+// by nullifier commitment
+func computeAggregatedHash(election *Election, votes []Vote) string {
+	sorted := make([]Vote, len(votes))
+	copy(sorted, votes)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].NullifierCommitment < sorted[j].NullifierCommitment
+	})
+
+	hashes := make([]string, len(sorted))
+	for i, vote := range sorted {
+		hashes[i] = vote.EncryptedVoteHash
+	}
+	return hashForElection(election, strings.Join(hashes, ""))
+}
+
+// txTime is the transaction timestamp proposed by the client. Unlike the
+// local clock it is the same on every endorsing peer.
+func txTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get timestamp: %v", err)
+	}
+	return time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC(), nil
+}
+
 func hashString(s string) string {
 	hash := sha256.Sum256([]byte(s))
 	return hex.EncodeToString(hash[:])
 }
 
-func generateVerificationCode(txID, hash string) string {
-	combined := txID + hash
-	h := sha256.Sum256([]byte(combined))
-	return hex.EncodeToString(h[:8]) // 16 character code
+// generateVerificationCode returns the first length hex characters of
+// the digest of txID + hash under algorithm
+func generateVerificationCode(algorithm, txID, hash string, length int) string {
+	return hashStringWith(algorithm, txID+hash)[:length]
 }
 
-func (v *VoteContract) addVoteToIndex(
+// verificationCodeLength is the election's code length; elections created
+// before it was configurable use the default
+func verificationCodeLength(election *Election) int {
+	if election.VerificationCodeLength == 0 {
+		return DefaultVerificationCodeLength
+	}
+	return election.VerificationCodeLength
+}
+
+// assignVerificationCode indexes the verification code of a vote and returns
+// it. A code already issued in the election is lengthened until it is
+// unique. taken holds codes assigned earlier in the same transaction, which
+// GetState cannot see.
+func assignVerificationCode(
 	ctx contractapi.TransactionContextInterface,
-	electionID string,
-	nullifier string,
-) error {
-	indexKey := voteIndexKey(electionID)
-	indexJSON, err := ctx.GetStub().GetState(indexKey)
-	if err != nil {
-		return err
+	election *Election,
+	txID string,
+	encryptedVoteHash string,
+	commitment string,
+	taken map[string]bool,
+) (string, error) {
+	for length := verificationCodeLength(election); length <= hashSize*2; length += verificationCodeLengthStep {
+		code := generateVerificationCode(election.HashAlgorithm, txID, encryptedVoteHash, length)
+		if taken[code] {
+			continue
+		}
+		key, err := verificationCodeKey(ctx, election.ID, code)
+		if err != nil {
+			return "", err
+		}
+		existing, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return "", fmt.Errorf("failed to read verification code: %v", err)
+		}
+		if existing != nil {
+			continue
+		}
+
+		if err := ctx.GetStub().PutState(key, []byte(commitment)); err != nil {
+			return "", fmt.Errorf("failed to index verification code: %v", err)
+		}
+		if taken != nil {
+			taken[code] = true
+		}
+		return code, nil
 	}
+	return "", fmt.Errorf("verification code for vote %s collides at every length", encryptedVoteHash)
+}
 
-	var nullifiers []string
-	if indexJSON != nil {
-		if err := json.Unmarshal(indexJSON, &nullifiers); err != nil {
-			return err
+// findVerificationCode returns the code assignVerificationCode indexed for a
+// vote, trying the same lengths in the same order
+func findVerificationCode(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	txID string,
+	encryptedVoteHash string,
+	commitment string,
+) (string, error) {
+	for length := verificationCodeLength(election); length <= hashSize*2; length += verificationCodeLengthStep {
+		code := generateVerificationCode(election.HashAlgorithm, txID, encryptedVoteHash, length)
+		key, err := verificationCodeKey(ctx, election.ID, code)
+		if err != nil {
+			return "", err
+		}
+		indexed, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return "", fmt.Errorf("failed to read verification code: %v", err)
+		}
+		if string(indexed) == commitment {
+			return code, nil
 		}
 	}
+	// Votes cast before codes were indexed kept the unextended code
+	return generateVerificationCode(election.HashAlgorithm, txID, encryptedVoteHash, verificationCodeLength(election)), nil
+}
+
+// emitStatusChanged moves the election in the status index and emits
+// ElectionStatusChangedEvent. Fabric delivers only the last event set in a
+// transaction, so transitions set no other event.
+// The transaction timestamp keeps the payload identical on every endorser.
+func (v *VoteContract) emitStatusChanged(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	oldStatus string,
+	newStatus string,
+) error {
+	return v.emitStatusChangedWithWarning(ctx, election, oldStatus, newStatus, "")
+}
+
+// emitStatusChangedWithWarning is emitStatusChanged with a warning in the payload
+func (v *VoteContract) emitStatusChangedWithWarning(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	oldStatus string,
+	newStatus string,
+	warning string,
+) error {
+	return v.publishStatusChange(ctx, election, ElectionStatusChange{
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		Warning:   warning,
+	})
+}
+
+// publishStatusChange indexes and emits change, filling in the election,
+// transaction ID and timestamp
+func (v *VoteContract) publishStatusChange(
+	ctx contractapi.TransactionContextInterface,
+	election *Election,
+	change ElectionStatusChange,
+) error {
+	if err := updateElectionStatusIndex(ctx, election.ID, change.OldStatus, change.NewStatus); err != nil {
+		return err
+	}
 
-	nullifiers = append(nullifiers, nullifier)
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get timestamp: %v", err)
+	}
 
-	updatedJSON, err := json.Marshal(nullifiers)
+	change.ElectionID = election.ID
+	change.TxID = ctx.GetStub().GetTxID()
+	change.Timestamp = time.Unix(txTimestamp.Seconds, int64(txTimestamp.Nanos)).UTC()
+	eventJSON, err := json.Marshal(change)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(indexKey, updatedJSON)
+	return ctx.GetStub().SetEvent(eventName(election, ElectionStatusChangedEvent), eventJSON)
 }
 
 func (v *VoteContract) addBulletinBoardEntry(
@@ -906,60 +7040,244 @@ func (v *VoteContract) addBulletinBoardEntry(
 	entryType string,
 	hash string,
 ) error {
-	bbKey := bulletinBoardKey(electionID)
-	bbJSON, err := ctx.GetStub().GetState(bbKey)
+	return v.addBulletinBoardEntries(ctx, electionID, entryType, hash)
+}
+
+// addBulletinBoardEntries appends several entries of one type. Each entry is
+// written under its own key, so earlier entries are never rewritten.
+func (v *VoteContract) addBulletinBoardEntries(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	entryType string,
+	hashes ...string,
+) error {
+	return v.appendBulletinBoard(ctx, electionID, "", entryType, hashes...)
+}
+
+// appendBulletinBoard appends entries and advances the stored Merkle root.
+// scheme is only used to start a board; "" reads it from the election.
+func (v *VoteContract) appendBulletinBoard(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	scheme string,
+	entryType string,
+	hashes ...string,
+) error {
+	items := make([]bulletinItem, len(hashes))
+	for i, hash := range hashes {
+		items[i] = bulletinItem{Type: entryType, Hash: hash}
+	}
+	return v.appendBulletinBoardItems(ctx, electionID, scheme, items...)
+}
+
+// bulletinItem is the type and hash of an entry to append
+type bulletinItem struct {
+	Type string
+	Hash string
+}
+
+// appendBulletinBoardItems appends entries of any types. A transaction does
+// not read its own writes, so all entries of a transaction go in one call.
+func (v *VoteContract) appendBulletinBoardItems(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	scheme string,
+	items ...bulletinItem,
+) error {
+	sequence, err := bulletinBoardLength(ctx, electionID)
 	if err != nil {
 		return err
 	}
+	if sequence == 0 {
+		// Appending to an unmigrated board would restart the sequence
+		legacyJSON, err := ctx.GetStub().GetState(legacyBulletinBoardKey(electionID))
+		if err != nil {
+			return err
+		}
+		if legacyJSON != nil {
+			return fmt.Errorf("bulletin board of election %s must be migrated first (MigrateBulletinBoard)", electionID)
+		}
+	}
 
-	var entries []BulletinBoardEntry
-	if bbJSON != nil {
-		if err := json.Unmarshal(bbJSON, &entries); err != nil {
+	tree, err := v.loadBulletinBoardTree(ctx, electionID, scheme)
+	if err != nil {
+		return err
+	}
+
+	// The sequence key is the board's only allocator. Concurrent appends all
+	// read it, so MVCC lets just one of them commit each sequence; an entry
+	// already under the next sequence means the key fell behind, and it is
+	// never overwritten.
+	nextKey, err := bulletinBoardEntryKey(ctx, electionID, sequence+1)
+	if err != nil {
+		return err
+	}
+	taken, err := ctx.GetStub().GetState(nextKey)
+	if err != nil {
+		return fmt.Errorf("failed to read bulletin board: %v", err)
+	}
+	if taken != nil {
+		return fmt.Errorf("bulletin board sequence %d of election %s is already taken", sequence+1, electionID)
+	}
+
+	// Each entry commits to the one before it
+	prevEntryHash := ""
+	if sequence > 0 {
+		last, err := getBulletinBoardEntry(ctx, electionID, sequence)
+		if err != nil {
 			return err
 		}
+		prevEntryHash = bulletinEntryHash(tree.Scheme, *last)
 	}
 
 	txID := ctx.GetStub().GetTxID()
-	entry := BulletinBoardEntry{
-		Sequence:  len(entries) + 1,
-		Type:      entryType,
-		Hash:      hash,
-		TxID:      txID,
-		Timestamp: time.Now(),
+	now, err := txTime(ctx)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		sequence++
+		entry := BulletinBoardEntry{
+			Sequence:      sequence,
+			Type:          item.Type,
+			Hash:          item.Hash,
+			TxID:          txID,
+			Timestamp:     now,
+			PrevEntryHash: prevEntryHash,
+		}
+		if err := putBulletinBoardEntry(ctx, electionID, entry); err != nil {
+			return err
+		}
+		prevEntryHash = bulletinEntryHash(tree.Scheme, entry)
+		if err := tree.Append(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := putBulletinBoardTree(ctx, electionID, tree); err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(bulletinBoardSeqKey(electionID), []byte(strconv.Itoa(sequence)))
+}
+
+// loadBulletinBoardTree reads the incremental Merkle state of a board. Boards
+// stored before the state existed are folded in from their entries once.
+func (v *VoteContract) loadBulletinBoardTree(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+	scheme string,
+) (*MerkleFrontier, error) {
+	treeJSON, err := ctx.GetStub().GetState(bulletinBoardTreeKey(electionID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bulletin board root: %w", err)
+	}
+	if treeJSON != nil {
+		var tree MerkleFrontier
+		if err := json.Unmarshal(treeJSON, &tree); err != nil {
+			return nil, err
+		}
+		return &tree, nil
+	}
+
+	if scheme == "" {
+		if scheme, err = v.merkleSchemeOf(ctx, electionID); err != nil {
+			return nil, err
+		}
+	}
+	entries, err := v.getBulletinBoardEntries(ctx, electionID)
+	if err != nil {
+		return nil, err
 	}
+	return newMerkleFrontier(scheme, entries)
+}
 
-	entries = append(entries, entry)
+func putBulletinBoardTree(ctx contractapi.TransactionContextInterface, electionID string, tree *MerkleFrontier) error {
+	treeJSON, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(bulletinBoardTreeKey(electionID), treeJSON)
+}
 
-	updatedJSON, err := json.Marshal(entries)
+func putBulletinBoardEntry(ctx contractapi.TransactionContextInterface, electionID string, entry BulletinBoardEntry) error {
+	key, err := bulletinBoardEntryKey(ctx, electionID, entry.Sequence)
+	if err != nil {
+		return err
+	}
+	entryJSON, err := json.Marshal(entry)
 	if err != nil {
 		return err
 	}
+	return ctx.GetStub().PutState(key, entryJSON)
+}
 
-	return ctx.GetStub().PutState(bbKey, updatedJSON)
+// getBulletinBoardEntry reads the entry with the given sequence number
+func getBulletinBoardEntry(ctx contractapi.TransactionContextInterface, electionID string, sequence int) (*BulletinBoardEntry, error) {
+	key, err := bulletinBoardEntryKey(ctx, electionID, sequence)
+	if err != nil {
+		return nil, err
+	}
+	entryJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bulletin board: %v", err)
+	}
+	if entryJSON == nil {
+		return nil, fmt.Errorf("bulletin board entry %d not found", sequence)
+	}
+	var entry BulletinBoardEntry
+	if err := json.Unmarshal(entryJSON, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
 }
 
-func computeMerkleRoot(entries []BulletinBoardEntry) string {
-	if len(entries) == 0 {
-		return ""
+// bulletinEntryHash is the hash the next entry's PrevEntryHash commits to:
+// the hash, under the board scheme's algorithm, of every field of the entry
+// separated by "|"
+func bulletinEntryHash(scheme string, entry BulletinBoardEntry) string {
+	_, algorithm := splitMerkleScheme(scheme)
+	return hashStringWith(algorithm, fmt.Sprintf("%d|%s|%s|%s|%s|%s", entry.Sequence, entry.Type, entry.Hash, entry.TxID,
+		entry.Timestamp.UTC().Format(time.RFC3339Nano), entry.PrevEntryHash))
+}
+
+// bulletinBoardLength returns the sequence number of the last entry
+func bulletinBoardLength(ctx contractapi.TransactionContextInterface, electionID string) (int, error) {
+	seqBytes, err := ctx.GetStub().GetState(bulletinBoardSeqKey(electionID))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read bulletin board length: %v", err)
+	}
+	if seqBytes == nil {
+		return 0, nil
 	}
+	return strconv.Atoi(string(seqBytes))
+}
 
-	// Build merkle tree from entry hashes
-	hashes := make([]string, len(entries))
-	for i, entry := range entries {
-		hashes[i] = hashString(entry.Hash + entry.TxID)
+// getBulletinBoardEntries reads every entry of an election's board in sequence order
+func (v *VoteContract) getBulletinBoardEntries(
+	ctx contractapi.TransactionContextInterface,
+	electionID string,
+) ([]BulletinBoardEntry, error) {
+	iterator, err := ctx.GetStub().GetStateByPartialCompositeKey(bulletinBoardObjectType, []string{electionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bulletin board: %w", err)
 	}
+	defer iterator.Close()
 
-	for len(hashes) > 1 {
-		var newHashes []string
-		for i := 0; i < len(hashes); i += 2 {
-			if i+1 < len(hashes) {
-				newHashes = append(newHashes, hashString(hashes[i]+hashes[i+1]))
-			} else {
-				newHashes = append(newHashes, hashes[i])
-			}
+	return readBulletinBoardEntries(iterator)
+}
+
+func readBulletinBoardEntries(iterator shim.StateQueryIteratorInterface) ([]BulletinBoardEntry, error) {
+	entries := []BulletinBoardEntry{}
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bulletin board: %v", err)
+		}
+		var entry BulletinBoardEntry
+		if err := json.Unmarshal(kv.Value, &entry); err != nil {
+			return nil, err
 		}
-		hashes = newHashes
+		entries = append(entries, entry)
 	}
-
-	return hashes[0]
+	return entries, nil
 }
@@ -5,12 +5,25 @@
 package contracts
 
 import (
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -19,34 +32,284 @@ import (
 type MockTransactionContext struct {
 	mock.Mock
 	contractapi.TransactionContextInterface
+	// Identity is the calling client; nil means an election admin
+	Identity *MockClientIdentity
+}
+
+// MockClientIdentity is a mock implementation of cid.ClientIdentity
+type MockClientIdentity struct {
+	MSPID      string
+	Attributes map[string]string
+}
+
+func (m *MockClientIdentity) GetID() (string, error) {
+	return "mock-client", nil
+}
+
+func (m *MockClientIdentity) GetMSPID() (string, error) {
+	return m.MSPID, nil
+}
+
+func (m *MockClientIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := m.Attributes[attrName]
+	return value, found, nil
+}
+
+func (m *MockClientIdentity) AssertAttributeValue(attrName, attrValue string) error {
+	if value, found := m.Attributes[attrName]; !found || value != attrValue {
+		return fmt.Errorf("attribute %s is not %s", attrName, attrValue)
+	}
+	return nil
+}
+
+func (m *MockClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return nil, nil
 }
 
 type MockStub struct {
 	mock.Mock
 	shim.ChaincodeStubInterface
-	State map[string][]byte
+	State  map[string][]byte
+	Events map[string][]byte
+	// QueryResults is returned by GetQueryResult, which records the query it was given
+	QueryResults []*queryresult.KV
+	LastQuery    string
+	// PrivateState holds private data per collection
+	PrivateState map[string]map[string][]byte
+	Transient    map[string][]byte
+	// History is returned by GetHistoryForKey
+	History map[string][]*queryresult.KeyModification
+	// ValidationParameters holds key-level endorsement policies
+	ValidationParameters map[string][]byte
+	// Reads and Writes list the keys passed to GetState and PutState, in order
+	Reads  []string
+	Writes []string
+	// TxTimestamp overrides the transaction timestamp, which is otherwise now
+	TxTimestamp *timestamp.Timestamp
+	// ReadErr, when set, fails every state read as an unreachable peer would
+	ReadErr error
+	// RangeReadErr, when set, fails range queries only
+	RangeReadErr error
+	// WriteErr, when set, is called by PutState and fails the write when it
+	// returns an error
+	WriteErr func(key string) error
 }
 
 func NewMockStub() *MockStub {
 	return &MockStub{
-		State: make(map[string][]byte),
+		State:                make(map[string][]byte),
+		Events:               make(map[string][]byte),
+		PrivateState:         make(map[string]map[string][]byte),
+		Transient:            make(map[string][]byte),
+		History:              make(map[string][]*queryresult.KeyModification),
+		ValidationParameters: make(map[string][]byte),
 	}
 }
 
 func (m *MockStub) GetState(key string) ([]byte, error) {
+	if m.ReadErr != nil {
+		return nil, m.ReadErr
+	}
+	m.Reads = append(m.Reads, key)
 	return m.State[key], nil
 }
 
 func (m *MockStub) PutState(key string, value []byte) error {
+	if m.WriteErr != nil {
+		if err := m.WriteErr(key); err != nil {
+			return err
+		}
+	}
 	m.State[key] = value
+	m.Writes = append(m.Writes, key)
+	return nil
+}
+
+// MockStateIterator iterates over a snapshot of key/value pairs
+type MockStateIterator struct {
+	results []*queryresult.KV
+	index   int
+}
+
+func (it *MockStateIterator) HasNext() bool {
+	return it.index < len(it.results)
+}
+
+func (it *MockStateIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more results")
+	}
+	kv := it.results[it.index]
+	it.index++
+	return kv, nil
+}
+
+func (it *MockStateIterator) Close() error {
+	return nil
+}
+
+// sortedKeys returns the state keys in [startKey, endKey) in lexical order
+func (m *MockStub) sortedKeys(startKey, endKey string) []string {
+	keys := []string{}
+	for key := range m.State {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (m *MockStub) iteratorFor(keys []string) *MockStateIterator {
+	results := make([]*queryresult.KV, 0, len(keys))
+	for _, key := range keys {
+		results = append(results, &queryresult.KV{Key: key, Value: m.State[key]})
+	}
+	return &MockStateIterator{results: results}
+}
+
+func (m *MockStub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if m.ReadErr != nil {
+		return nil, m.ReadErr
+	}
+	if m.RangeReadErr != nil {
+		return nil, m.RangeReadErr
+	}
+	return m.iteratorFor(m.sortedKeys(startKey, endKey)), nil
+}
+
+func (m *MockStub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32,
+	bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	keys := m.sortedKeys(startKey, endKey)
+
+	nextBookmark := ""
+	if int32(len(keys)) > pageSize {
+		nextBookmark = keys[pageSize]
+		keys = keys[:pageSize]
+	}
+
+	return m.iteratorFor(keys), &peer.QueryResponseMetadata{
+		FetchedRecordsCount: int32(len(keys)),
+		Bookmark:            nextBookmark,
+	}, nil
+}
+
+func (m *MockStub) DelState(key string) error {
+	delete(m.State, key)
+	return nil
+}
+
+func (m *MockStub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+func (m *MockStub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	parts := strings.Split(strings.TrimPrefix(compositeKey, "\x00"), "\x00")
+	if len(parts) < 2 {
+		return "", nil, fmt.Errorf("invalid composite key %q", compositeKey)
+	}
+	return parts[0], parts[1 : len(parts)-1], nil
+}
+
+// partialCompositeKeyRange returns the key range matching a partial composite key
+func partialCompositeKeyRange(objectType string, attributes []string) (string, string, error) {
+	startKey, err := shim.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return "", "", err
+	}
+	return startKey, startKey + string(rune(0x10FFFF)), nil
+}
+
+func (m *MockStub) GetStateByPartialCompositeKey(objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetStateByRange(startKey, endKey)
+}
+
+func (m *MockStub) GetStateByPartialCompositeKeyWithPagination(objectType string, attributes []string,
+	pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+}
+
+func (m *MockStub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	m.LastQuery = query
+	return &MockStateIterator{results: m.QueryResults}, nil
+}
+
+func (m *MockStub) GetPrivateData(collection, key string) ([]byte, error) {
+	return m.PrivateState[collection][key], nil
+}
+
+func (m *MockStub) PutPrivateData(collection, key string, value []byte) error {
+	if m.PrivateState[collection] == nil {
+		m.PrivateState[collection] = make(map[string][]byte)
+	}
+	m.PrivateState[collection][key] = value
+	return nil
+}
+
+func (m *MockStub) DelPrivateData(collection, key string) error {
+	delete(m.PrivateState[collection], key)
+	return nil
+}
+
+func (m *MockStub) GetTransient() (map[string][]byte, error) {
+	return m.Transient, nil
+}
+
+// MockHistoryIterator iterates over recorded key modifications
+type MockHistoryIterator struct {
+	results []*queryresult.KeyModification
+	index   int
+}
+
+func (it *MockHistoryIterator) HasNext() bool {
+	return it.index < len(it.results)
+}
+
+func (it *MockHistoryIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more results")
+	}
+	modification := it.results[it.index]
+	it.index++
+	return modification, nil
+}
+
+func (it *MockHistoryIterator) Close() error {
+	return nil
+}
+
+func (m *MockStub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &MockHistoryIterator{results: m.History[key]}, nil
+}
+
+func (m *MockStub) SetStateValidationParameter(key string, ep []byte) error {
+	m.ValidationParameters[key] = ep
 	return nil
 }
 
+func (m *MockStub) GetStateValidationParameter(key string) ([]byte, error) {
+	return m.ValidationParameters[key], nil
+}
+
 func (m *MockStub) GetTxID() string {
 	return "mock-tx-id-12345"
 }
 
 func (m *MockStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	if m.TxTimestamp != nil {
+		return m.TxTimestamp, nil
+	}
 	return &timestamp.Timestamp{
 		Seconds: time.Now().Unix(),
 		Nanos:   0,
@@ -54,6 +317,7 @@ func (m *MockStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
 }
 
 func (m *MockStub) SetEvent(name string, payload []byte) error {
+	m.Events[name] = payload
 	return nil
 }
 
@@ -62,20 +326,66 @@ func (m *MockTransactionContext) GetStub() shim.ChaincodeStubInterface {
 	return args.Get(0).(shim.ChaincodeStubInterface)
 }
 
+func (m *MockTransactionContext) GetClientIdentity() cid.ClientIdentity {
+	if m.Identity == nil {
+		return &MockClientIdentity{
+			MSPID:      "Org1MSP",
+			Attributes: map[string]string{AdminAttribute: "true"},
+		}
+	}
+	return m.Identity
+}
+
+// compositeKey builds the same composite key the chaincode derives
+func compositeKey(objectType string, attributes ...string) string {
+	key, _ := shim.CreateCompositeKey(objectType, attributes)
+	return key
+}
+
+// voteStateKey is the ledger key of the vote cast with nullifier
+func voteStateKey(electionID, nullifier string) string {
+	return compositeKey("vote", electionID, nullifierCommitment(electionID, nullifier))
+}
+
+// putBulletinBoard stores entries under their per-sequence keys
+func putBulletinBoard(stub *MockStub, electionID string, entries []BulletinBoardEntry) {
+	for _, entry := range entries {
+		entryJSON, _ := json.Marshal(entry)
+		stub.State[compositeKey("bb", electionID, fmt.Sprintf("%010d", entry.Sequence))] = entryJSON
+	}
+	stub.State["bulletinboardseq:"+electionID] = []byte(strconv.Itoa(len(entries)))
+}
+
+// testVoterRoot is the voter merkle root of the elections tests create
+const testVoterRoot = "root"
+
+// Proof hashes referenced by test votes
+var (
+	testEligibilityHash = hashString("eligibility proof")
+	testValidityHash    = hashString("validity proof")
+)
+
 // Test helper to create a mock election
 func createMockElection() *Election {
 	return &Election{
 		ID:              "election-001",
 		Title:           "Test Election 2024",
 		Status:          "active",
-		VoterMerkleRoot: "0x" + string(make([]byte, 64)),
-		PublicKey:       `{"p":"123","g":"2","h":"456"}`,
+		VoterMerkleRoot: testVoterRoot,
+		PublicKey:       testPublicKeyJSON(),
 		StartTime:       time.Now().Add(-1 * time.Hour),
 		EndTime:         time.Now().Add(24 * time.Hour),
 		CreatedAt:       time.Now().Add(-2 * time.Hour),
 	}
 }
 
+// setTestOptions gives an election created by CreateElection, which has no
+// options, the single option "1" that testVote ballots select and tallies
+// such as {"1": 3} count, so that it can be activated
+func setTestOptions(t *testing.T, ctx *MockTransactionContext, electionID string) {
+	assert.NoError(t, new(VoteContract).UpdateBallotOptions(ctx, electionID, DefaultQuestionID, `["1"]`))
+}
+
 func TestInitLedger(t *testing.T) {
 	contract := new(VoteContract)
 	ctx := new(MockTransactionContext)
@@ -97,12 +407,12 @@ func TestCreateElection(t *testing.T) {
 	startTime := time.Now().Add(1 * time.Hour).Format(time.RFC3339)
 	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
 
-	err := contract.CreateElection(
+	_, err := contract.CreateElection(
 		ctx,
 		"election-001",
 		"Test Election",
 		"0xmerkleroot",
-		"publickey",
+		testPublicKeyJSON(),
 		startTime,
 		endTime,
 	)
@@ -118,6 +428,52 @@ func TestCreateElection(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Test Election", election.Title)
 	assert.Equal(t, "pending", election.Status)
+	assert.Equal(t, testPublicKeyJSON(), election.PublicKey)
+}
+
+func TestCreateElectionValidatesPublicKey(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	// q may be omitted for a safe prime; it is stored explicitly
+	var key PublicKeyJSON
+	_ = json.Unmarshal([]byte(testPublicKeyJSON()), &key)
+	withoutQ := fmt.Sprintf(`{"p":"%s","g":"%s","h":"%s"}`, key.P, key.G, key.H)
+	_, err := new(VoteContract).CreateElection(ctx, "election-001", "Test", "root", withoutQ, startTime, endTime)
+	assert.NoError(t, err)
+	election, _ := new(VoteContract).GetElection(ctx, "election-001")
+	assert.Equal(t, testPublicKeyJSON(), election.PublicKey)
+
+	for publicKey, message := range map[string]string{
+		"publickey":                               "invalid public key",
+		`{"p":"123","g":"2","h":"456"}`:           "p is not prime",
+		`{"p":"2039","q":"1018","g":"4","h":"1"}`: "q is not prime",
+		`{"p":"2039","q":"7","g":"4","h":"1"}`:    "q does not divide p-1",
+		`{"p":"2039","g":"1","h":"4"}`:            "g does not generate",
+		`{"p":"2039","g":"7","h":"4"}`:            "g does not generate",
+		`{"p":"2039","g":"4","h":"1"}`:            "h is not a non-trivial element",
+		`{"p":"2039","g":"4","h":"2040"}`:         "h is not a non-trivial element",
+		`{"p":"2039","g":"4"}`:                    "missing h",
+	} {
+		_, err := new(VoteContract).CreateElection(ctx, "election-002", "Test", "root", publicKey, startTime, endTime)
+		assert.Error(t, err, publicKey)
+		if err != nil {
+			assert.Contains(t, err.Error(), message, publicKey)
+		}
+	}
+
+	// Per-question keys are held to the same checks
+	config, _ := json.Marshal(ElectionConfig{Questions: []Question{
+		{ID: "q1", Options: []string{"a", "b"}, PublicKey: `{"p":"2039","g":"4","h":"1"}`},
+	}})
+	_, err = new(VoteContract).CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "question q1")
 }
 
 func TestCreateDuplicateElection(t *testing.T) {
@@ -131,14 +487,52 @@ func TestCreateDuplicateElection(t *testing.T) {
 	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
 
 	// Create first election
-	_ = contract.CreateElection(ctx, "election-001", "Test", "root", "key", startTime, endTime)
+	_, _ = contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
 
 	// Try to create duplicate
-	err := contract.CreateElection(ctx, "election-001", "Test", "root", "key", startTime, endTime)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "already exists")
 }
 
+func TestCreateElectionRequiredFields(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	for _, tc := range []struct {
+		name                                    string
+		electionID, title, voterRoot, publicKey string
+		want                                    string
+	}{
+		{"empty ID", "", "Test", "root", testPublicKeyJSON(), "election ID is required"},
+		{"blank ID", "  ", "Test", "root", testPublicKeyJSON(), "election ID is required"},
+		{"long ID", strings.Repeat("e", MaxElectionIDLength+1), "Test", "root", testPublicKeyJSON(), "exceeds"},
+		{"ID with separator", "election:v1", "Test", "root", testPublicKeyJSON(), "must not contain"},
+		{"ID with control character", "election\n001", "Test", "root", testPublicKeyJSON(), "non-printable"},
+		{"empty title", "election-001", "", "root", testPublicKeyJSON(), "title is required"},
+		{"empty voter root", "election-001", "Test", "", testPublicKeyJSON(), "voter Merkle root is required"},
+		{"empty public key", "election-001", "Test", "root", "", "public key is required"},
+	} {
+		_, err := contract.CreateElection(ctx, tc.electionID, tc.title, tc.voterRoot, tc.publicKey, startTime, endTime)
+		assert.Error(t, err, tc.name)
+		if err != nil {
+			assert.Contains(t, err.Error(), tc.want, tc.name)
+		}
+	}
+	assert.Empty(t, stub.State)
+
+	// The longest allowed ID is accepted
+	longest := strings.Repeat("e", MaxElectionIDLength)
+	_, err := contract.CreateElection(ctx, longest, "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+}
+
 func TestActivateElection(t *testing.T) {
 	contract := new(VoteContract)
 	ctx := new(MockTransactionContext)
@@ -148,22 +542,123 @@ func TestActivateElection(t *testing.T) {
 
 	// Create election first
 	election := &Election{
-		ID:     "election-001",
-		Title:  "Test Election",
-		Status: "pending",
+		ID:              "election-001",
+		Title:           "Test Election",
+		Status:          "pending",
+		VoterMerkleRoot: testVoterRoot,
+		PublicKey:       testPublicKeyJSON(),
+		StartTime:       time.Now().Add(-1 * time.Hour),
+		EndTime:         time.Now().Add(24 * time.Hour),
+		Options:         []string{"yes"},
 	}
 	electionJSON, _ := json.Marshal(election)
 	stub.State["election:election-001"] = electionJSON
 
 	// Activate
-	err := contract.ActivateElection(ctx, "election-001")
+	_, err := contract.ActivateElection(ctx, "election-001")
 	assert.NoError(t, err)
+	assert.Empty(t, statusChangeEvent(t, stub).Warning)
 
 	// Verify status changed
 	stored := stub.State["election:election-001"]
 	var updated Election
 	_ = json.Unmarshal(stored, &updated)
 	assert.Equal(t, "active", updated.Status)
+
+	// Activating twice reports the status, not a generic failure
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.True(t, errors.Is(err, ErrElectionNotPending))
+	assert.Contains(t, err.Error(), "current status: active")
+
+	_, err = contract.ActivateElection(ctx, "election-002")
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+	assert.Contains(t, err.Error(), "election-002")
+}
+
+func TestActivateElectionChecksWindow(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	activate := func(start, end time.Time) error {
+		election := createMockElection()
+		election.Status = "pending"
+		election.Options = []string{"yes"}
+		election.StartTime, election.EndTime = start, end
+		electionJSON, _ := json.Marshal(election)
+		stub.State["election:election-001"] = electionJSON
+		_, err := contract.ActivateElection(ctx, "election-001")
+		return err
+	}
+
+	// An already expired election stays pending
+	now := time.Now()
+	err := activate(now.Add(-48*time.Hour), now.Add(-1*time.Hour))
+	assert.True(t, errors.Is(err, ErrElectionEnded))
+	assert.Contains(t, err.Error(), "cannot activate")
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "pending", election.Status)
+	assert.Nil(t, stub.Events[ElectionStatusChangedEvent])
+
+	// Activating early succeeds with a warning in the status event
+	assert.NoError(t, activate(now.Add(time.Hour), now.Add(24*time.Hour)))
+	change := statusChangeEvent(t, stub)
+	assert.Equal(t, "active", change.NewStatus)
+	assert.Contains(t, change.Warning, "before the start time")
+}
+
+func TestActivateElectionRequiresConfiguration(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	activate := func(configure func(*Election)) error {
+		election := createMockElection()
+		election.Status = "pending"
+		configure(election)
+		electionJSON, _ := json.Marshal(election)
+		stub.State["election:election-001"] = electionJSON
+		_, err := contract.ActivateElection(ctx, "election-001")
+		return err
+	}
+
+	// Placeholders left by a lax creation path stay pending
+	err := activate(func(election *Election) { election.PublicKey = "" })
+	assert.True(t, errors.Is(err, ErrElectionIncomplete))
+	assert.Contains(t, err.Error(), "no public key")
+	err = activate(func(election *Election) { election.PublicKey = `{"p": "23", "g": "4"}` })
+	assert.True(t, errors.Is(err, ErrElectionIncomplete))
+	err = activate(func(election *Election) { election.VoterMerkleRoot = " " })
+	assert.True(t, errors.Is(err, ErrElectionIncomplete))
+	assert.Contains(t, err.Error(), "no voter Merkle root")
+	err = activate(func(election *Election) { election.Questions = []Question{{ID: "mayor"}} })
+	assert.True(t, errors.Is(err, ErrElectionIncomplete))
+	assert.Contains(t, err.Error(), "has no options")
+
+	// A single-question election needs options too, which CreateElection
+	// leaves to UpdateBallotOptions
+	err = activate(func(election *Election) {})
+	assert.True(t, errors.Is(err, ErrElectionIncomplete))
+	assert.Contains(t, err.Error(), "UpdateBallotOptions")
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "pending", election.Status)
+	_, emitted := stub.Events[ElectionStatusChangedEvent]
+	assert.False(t, emitted)
+
+	// A fully configured election goes active
+	assert.NoError(t, activate(func(election *Election) {
+		election.Questions = []Question{{ID: "mayor", Options: []string{"alice", "bob"}}}
+	}))
+	assert.NoError(t, activate(func(election *Election) {
+		election.Options = []string{"alice", "bob"}
+	}))
+	election, _ = contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "active", election.Status)
 }
 
 func TestCastVote(t *testing.T) {
@@ -178,17 +673,15 @@ func TestCastVote(t *testing.T) {
 	electionJSON, _ := json.Marshal(election)
 	stub.State["election:election-001"] = electionJSON
 
-	// Initialize vote index
-	stub.State["voteindex:election-001"] = []byte("[]")
-
 	// Cast vote
 	receipt, err := contract.CastVote(
 		ctx,
 		"election-001",
-		`{"ciphertext":"encrypted"}`,
+		testVote(4),
 		"nullifier123",
-		"eligibilityproof",
-		"validityproof",
+		testEligibilityHash,
+		testValidityHash,
+		testVoterRoot,
 	)
 
 	assert.NoError(t, err)
@@ -198,6 +691,43 @@ func TestCastVote(t *testing.T) {
 	assert.NotEmpty(t, receipt.TxID)
 }
 
+func TestCastVoteRequiresProofHashes(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	for _, tc := range []struct {
+		name, eligibility, validity, reason string
+	}{
+		{"empty eligibility hash", "", testValidityHash, "proof hashes are required"},
+		{"empty validity hash", testEligibilityHash, "", "proof hashes are required"},
+		{"placeholder", "proof1", testValidityHash, "eligibility proof hash must be"},
+		{"short hash", testEligibilityHash, testValidityHash[:40], "validity proof hash must be"},
+		{"non-hex hash", testEligibilityHash, strings.Repeat("g", 64), "validity proof hash must be"},
+	} {
+		_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", tc.eligibility, tc.validity, testVoterRoot)
+		if assert.Error(t, err, tc.name) {
+			assert.Contains(t, err.Error(), tc.reason, tc.name)
+		}
+	}
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 0, count)
+
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(1), Nullifier: "nullifier1", ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(2), Nullifier: "nullifier2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Receipts, 1)
+	assert.Equal(t, 0, result.Errors[0].Index)
+	assert.Contains(t, result.Errors[0].Error, "proof hashes are required")
+}
+
 func TestCastVoteDuplicateNullifier(t *testing.T) {
 	contract := new(VoteContract)
 	ctx := new(MockTransactionContext)
@@ -209,117 +739,686 @@ func TestCastVoteDuplicateNullifier(t *testing.T) {
 	election := createMockElection()
 	electionJSON, _ := json.Marshal(election)
 	stub.State["election:election-001"] = electionJSON
-	stub.State["voteindex:election-001"] = []byte("[]")
 
 	// First vote
-	_, _ = contract.CastVote(ctx, "election-001", "{}", "nullifier123", "proof1", "proof2")
+	_, _ = contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
 
-	// Second vote with same nullifier
-	_, err := contract.CastVote(ctx, "election-001", "{}", "nullifier123", "proof1", "proof2")
+	// Second vote with same nullifier and a different ballot
+	_, err := contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "duplicate")
+	assert.True(t, errors.Is(err, ErrDuplicateNullifier))
 }
 
-func TestCastVoteInactiveElection(t *testing.T) {
+func TestCastVoteIdempotentResubmit(t *testing.T) {
 	contract := new(VoteContract)
 	ctx := new(MockTransactionContext)
 	stub := NewMockStub()
 
 	ctx.On("GetStub").Return(stub)
 
-	// Setup inactive election
-	election := &Election{
-		ID:     "election-001",
-		Status: "closed",
-	}
-	electionJSON, _ := json.Marshal(election)
+	electionJSON, _ := json.Marshal(createMockElection())
 	stub.State["election:election-001"] = electionJSON
 
-	// Try to cast vote
-	_, err := contract.CastVote(ctx, "election-001", "{}", "nullifier", "proof1", "proof2")
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "not active")
+	first, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := len(board["entries"].([]BulletinBoardEntry))
+
+	// A gateway retry of the same ballot in a later transaction gets the
+	// original receipt and changes nothing
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: time.Now().Add(time.Minute).Unix()}
+	retry, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, first, retry)
+
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 1, count)
+	board, _ = contract.GetBulletinBoard(ctx, "election-001")
+	assert.Len(t, board["entries"].([]BulletinBoardEntry), entries)
+
+	// A different ballot under the same nullifier is still a double vote
+	_, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrDuplicateNullifier))
 }
 
-func TestGetVote(t *testing.T) {
+func TestValidateVote(t *testing.T) {
 	contract := new(VoteContract)
 	ctx := new(MockTransactionContext)
 	stub := NewMockStub()
 
 	ctx.On("GetStub").Return(stub)
 
-	// Store a vote
-	vote := &Vote{
-		ElectionID:        "election-001",
-		EncryptedVote:     "encrypted_data",
-		EncryptedVoteHash: "hash123",
-		Nullifier:         "nullifier123",
-		TxID:              "tx123",
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	valid := func(vote, nullifier, eligibility, validity, root string) []string {
+		t.Helper()
+		writes, events := len(stub.Writes), len(stub.Events)
+		result, err := contract.ValidateVote(ctx, "election-001", vote, nullifier, eligibility, validity, root)
+		assert.NoError(t, err)
+		assert.Equal(t, writes, len(stub.Writes), "ValidateVote must not write state")
+		assert.Equal(t, events, len(stub.Events), "ValidateVote must not emit events")
+		reasons := result["reasons"].([]string)
+		assert.Equal(t, len(reasons) == 0, result["valid"])
+		return reasons
 	}
-	voteJSON, _ := json.Marshal(vote)
-	stub.State["vote:election-001:nullifier123"] = voteJSON
 
-	// Get vote
-	retrieved, err := contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.Empty(t, valid(testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot))
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 0, count)
+
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
 	assert.NoError(t, err)
-	assert.Equal(t, "hash123", retrieved.EncryptedVoteHash)
+
+	for _, tc := range []struct {
+		name                                   string
+		vote, nullifier, eligibility, validity string
+		root                                   string
+		reason                                 string
+	}{
+		{"nullifier used", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot, ErrDuplicateNullifier.Error()},
+		{"malformed ciphertext", "not-a-ciphertext", "nullifier456", testEligibilityHash, testValidityHash, testVoterRoot, "invalid"},
+		{"missing proof hash", testVote(2), "nullifier456", testEligibilityHash, "", testVoterRoot, "proof hashes are required"},
+		{"stale voter root", testVote(2), "nullifier456", testEligibilityHash, testValidityHash, "oldroot", "voter"},
+	} {
+		reasons := valid(tc.vote, tc.nullifier, tc.eligibility, tc.validity, tc.root)
+		if assert.Len(t, reasons, 1, tc.name) {
+			assert.Contains(t, strings.ToLower(reasons[0]), strings.ToLower(tc.reason), tc.name)
+		}
+	}
+
+	// Outside the voting window
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: time.Now().Add(48 * time.Hour).Unix()}
+	reasons := valid(testVote(2), "nullifier456", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Contains(t, reasons[0], ErrElectionEnded.Error())
+	stub.TxTimestamp = nil
+
+	// Election not active or missing
+	election := createMockElection()
+	election.Status = "pending"
+	electionJSON, _ = json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+	reasons = valid(testVote(2), "nullifier456", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Contains(t, reasons[0], ErrElectionNotActive.Error())
+
+	result, err := contract.ValidateVote(ctx, "election-404", testVote(2), "nullifier456", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.False(t, result["valid"].(bool))
+	assert.Contains(t, result["reasons"].([]string)[0], ErrElectionNotFound.Error())
 }
 
-func TestGetVoteNotFound(t *testing.T) {
+func TestEventsCarryBulletinSequence(t *testing.T) {
 	contract := new(VoteContract)
 	ctx := new(MockTransactionContext)
 	stub := NewMockStub()
 
 	ctx.On("GetStub").Return(stub)
 
-	_, err := contract.GetVote(ctx, "election-001", "nonexistent")
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "not found")
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	var event struct {
+		Seq int `json:"seq"`
+	}
+	for i := 0; i < 3; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+1)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		assert.NoError(t, json.Unmarshal(stub.Events["VoteCast"], &event))
+		assert.Equal(t, i+1, event.Seq)
+	}
+
+	_, err := contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 3}`, "hash", "proof"))
+	change := statusChangeEvent(t, stub)
+	assert.NotNil(t, change.Tally)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	last := entries[len(entries)-1]
+	assert.Equal(t, "tally_completed", last.Type)
+	assert.Equal(t, last.Sequence, change.Tally.Seq)
 }
 
-func TestVerifyVote(t *testing.T) {
+func TestCastVoteRecordsDuplicateAttempts(t *testing.T) {
+	for _, record := range []bool{false, true} {
+		contract := new(VoteContract)
+		ctx := new(MockTransactionContext)
+		stub := NewMockStub()
+
+		ctx.On("GetStub").Return(stub)
+
+		election := createMockElection()
+		election.RecordDuplicateAttempts = record
+		electionJSON, _ := json.Marshal(election)
+		stub.State["election:election-001"] = electionJSON
+
+		_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		board, _ := contract.GetBulletinBoard(ctx, "election-001")
+		entries := len(board["entries"].([]BulletinBoardEntry))
+
+		receipt, err := contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+		board, _ = contract.GetBulletinBoard(ctx, "election-001")
+		logged := board["entries"].([]BulletinBoardEntry)
+		if !record {
+			assert.True(t, errors.Is(err, ErrDuplicateNullifier))
+			assert.Len(t, logged, entries)
+			continue
+		}
+
+		// The rejection comes back in the receipt so the entry commits
+		assert.NoError(t, err)
+		assert.False(t, receipt.Success)
+		assert.Contains(t, receipt.Error, ErrDuplicateNullifier.Error())
+		assert.Empty(t, receipt.EncryptedVoteHash)
+
+		// Only the nullifier commitment and tx time are logged
+		assert.Len(t, logged, entries+1)
+		attempt := logged[entries]
+		assert.Equal(t, "duplicate_attempt", attempt.Type)
+		assert.Equal(t, nullifierCommitment("election-001", "nullifier123"), attempt.Hash)
+		assert.Equal(t, stub.GetTxID(), attempt.TxID)
+
+		// The original vote stands
+		count, _ := contract.GetVoteCount(ctx, "election-001")
+		assert.Equal(t, 1, count)
+	}
+}
+
+func TestCastVoteNullifierFormat(t *testing.T) {
 	contract := new(VoteContract)
 	ctx := new(MockTransactionContext)
 	stub := NewMockStub()
 
 	ctx.On("GetStub").Return(stub)
 
-	// Store a vote
-	vote := &Vote{
-		ElectionID:        "election-001",
-		EncryptedVoteHash: "correcthash",
-		Nullifier:         "nullifier123",
-		TxID:              "tx123",
+	election := createMockElection()
+	election.NullifierFormat = NullifierFormatHex
+	election.NullifierLength = DefaultHexNullifierLength
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	valid := strings.Repeat("ab", 32)
+	for _, tc := range []struct {
+		nullifier string
+		reason    string
+	}{
+		{"", "nullifier is required"},
+		{strings.Repeat("ab", 16), "must be 64 characters"},
+		{strings.Repeat("zz", 32), "must be hex"},
+		{strings.Repeat("a", MaxNullifierLength+1), "exceeds"},
+		{"ab\x00" + strings.Repeat("a", 61), "non-printable"},
+	} {
+		_, err := contract.CastVote(ctx, "election-001", testVote(1), tc.nullifier, testEligibilityHash, testValidityHash, testVoterRoot)
+		if assert.Error(t, err, tc.nullifier) {
+			assert.Contains(t, err.Error(), tc.reason)
+		}
 	}
-	voteJSON, _ := json.Marshal(vote)
-	stub.State["vote:election-001:nullifier123"] = voteJSON
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 0, count)
 
-	// Verify with correct hash
-	result, err := contract.VerifyVote(ctx, "election-001", "nullifier123", "correcthash")
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), valid, testEligibilityHash, testValidityHash, testVoterRoot)
 	assert.NoError(t, err)
-	assert.True(t, result["verified"].(bool))
 
-	// Verify with incorrect hash
-	result, err = contract.VerifyVote(ctx, "election-001", "nullifier123", "wronghash")
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(2), Nullifier: "nullifier456", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
 	assert.NoError(t, err)
-	assert.False(t, result["verified"].(bool))
+	assert.Empty(t, result.Receipts)
+	assert.Contains(t, result.Errors[0].Error, "must be 64 characters")
 }
 
-func TestStoreTallyResult(t *testing.T) {
-	contract := new(VoteContract)
+func TestCreateElectionNullifierFormat(t *testing.T) {
 	ctx := new(MockTransactionContext)
 	stub := NewMockStub()
-
 	ctx.On("GetStub").Return(stub)
 
-	// Setup closed election
-	election := &Election{
-		ID:     "election-001",
-		Status: "closed",
+	startTime := time.Now().Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, `{"nullifierFormat": "hex"}`)
+	assert.NoError(t, err)
+	election, _ := new(VoteContract).GetElection(ctx, "election-001")
+	assert.Equal(t, DefaultHexNullifierLength, election.NullifierLength)
+
+	for _, config := range []string{`{"nullifierFormat": "base58"}`, `{"nullifierLength": 1000}`} {
+		_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestCreateElectionDisplayMetadata(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, `{"timezone": "Asia/Seoul", "locale": "ko-KR"}`)
+	assert.NoError(t, err)
+	election, _ := new(VoteContract).GetElection(ctx, "election-001")
+	assert.Equal(t, "Asia/Seoul", election.Timezone)
+	assert.Equal(t, "ko-KR", election.Locale)
+
+	// Voting windows stay in UTC
+	assert.Equal(t, time.UTC, election.StartTime.Location())
+
+	for _, config := range []string{
+		`{"timezone": "Asia/Atlantis"}`,
+		`{"timezone": "Local"}`,
+		`{"timezone": "+09:00"}`,
+		`{"locale": "korean"}`,
+		`{"locale": "ko_KR"}`,
+	} {
+		_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestCastVoteInactiveElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Setup inactive election
+	election := &Election{
+		ID:     "election-001",
+		Status: "closed",
+	}
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	// Try to cast vote
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not active")
+	assert.True(t, errors.Is(err, ErrElectionNotActive))
+
+	_, err = contract.CastVote(ctx, "election-002", testVote(1), "nullifier", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+}
+
+func TestGetVote(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Store a vote
+	vote := &Vote{
+		ElectionID:          "election-001",
+		EncryptedVote:       "encrypted_data",
+		EncryptedVoteHash:   "hash123",
+		NullifierCommitment: nullifierCommitment("election-001", "nullifier123"),
+		TxID:                "tx123",
+	}
+	voteJSON, _ := json.Marshal(vote)
+	stub.State[voteStateKey("election-001", "nullifier123")] = voteJSON
+
+	// Get vote
+	retrieved, err := contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+	assert.Equal(t, "hash123", retrieved.EncryptedVoteHash)
+}
+
+func TestGetVoteNotFound(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	_, err := contract.GetVote(ctx, "election-001", "nonexistent")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+	assert.True(t, errors.Is(err, ErrVoteNotFound))
+}
+
+func TestVerifyVote(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Store a vote
+	vote := &Vote{
+		ElectionID:          "election-001",
+		EncryptedVoteHash:   "correcthash",
+		NullifierCommitment: nullifierCommitment("election-001", "nullifier123"),
+		TxID:                "tx123",
+	}
+	voteJSON, _ := json.Marshal(vote)
+	stub.State[voteStateKey("election-001", "nullifier123")] = voteJSON
+
+	// Verify with correct hash
+	result, err := contract.VerifyVote(ctx, "election-001", "nullifier123", "correcthash")
+	assert.NoError(t, err)
+	assert.True(t, result["verified"].(bool))
+
+	// Verify with incorrect hash
+	result, err = contract.VerifyVote(ctx, "election-001", "nullifier123", "wronghash")
+	assert.NoError(t, err)
+	assert.False(t, result["verified"].(bool))
+}
+
+func TestVerifyVoteReadErrors(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(10), "nullifier1",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	// A vote that is not there is an answer
+	result, err := contract.VerifyVote(ctx, "election-001", "nullifier2", receipt.EncryptedVoteHash)
+	assert.NoError(t, err)
+	assert.False(t, result["verified"].(bool))
+
+	// A ledger that cannot be read is not
+	readErr := errors.New("peer unavailable")
+	stub.ReadErr = readErr
+	_, err = contract.VerifyVote(ctx, "election-001", "nullifier1", receipt.EncryptedVoteHash)
+	assert.True(t, errors.Is(err, readErr))
+	stub.ReadErr = nil
+
+	// Nor is a bulletin board that cannot be read for the proof
+	stub.RangeReadErr = readErr
+	_, err = contract.VerifyVote(ctx, "election-001", "nullifier1", receipt.EncryptedVoteHash)
+	assert.True(t, errors.Is(err, readErr))
+}
+
+func TestVerifyVoteReturnsInclusionProof(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	for i := 0; i < 3; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	result, err := contract.VerifyVote(ctx, "election-001", "nullifier1", hashString(testVote(11)))
+	assert.NoError(t, err)
+	assert.True(t, result["verified"].(bool))
+	assert.Equal(t, 2, result["bulletinSequence"])
+
+	proof := result["inclusionProof"].(*MerkleInclusionProof)
+	assert.Equal(t, hashString(testVote(11)), proof.EncryptedVoteHash)
+	assert.True(t, proof.Verify())
+	root, _ := contract.GetBulletinBoardRoot(ctx, "election-001")
+	assert.Equal(t, root["merkleRoot"], proof.MerkleRoot)
+
+	// A hash mismatch is still reported with the stored vote's proof
+	result, err = contract.VerifyVote(ctx, "election-001", "nullifier1", "wronghash")
+	assert.NoError(t, err)
+	assert.False(t, result["verified"].(bool))
+	assert.NotNil(t, result["inclusionProof"])
+}
+
+func TestCastVoteDuplicateCiphertext(t *testing.T) {
+	for _, unique := range []bool{false, true} {
+		contract := new(VoteContract)
+		ctx := new(MockTransactionContext)
+		stub := NewMockStub()
+
+		ctx.On("GetStub").Return(stub)
+
+		election := createMockElection()
+		election.UniqueCiphertexts = unique
+		election.AllowRevote = true
+		electionJSON, _ := json.Marshal(election)
+		stub.State["election:election-001"] = electionJSON
+
+		_, err := contract.CastVote(ctx, "election-001", testVote(10), "nullifier0",
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+
+		// The same ciphertext under another nullifier
+		_, err = contract.CastVote(ctx, "election-001", testVote(10), "nullifier1",
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		result, _ := contract.ValidateVote(ctx, "election-001", testVote(10), "nullifier2",
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		batch, batchErr := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+			{EncryptedVote: testVote(10), Nullifier: "nullifier3", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+			{EncryptedVote: testVote(20), Nullifier: "nullifier4", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+			{EncryptedVote: testVote(20), Nullifier: "nullifier5", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		})
+		assert.NoError(t, batchErr)
+		count, _ := contract.GetVoteCount(ctx, "election-001")
+
+		if !unique {
+			assert.NoError(t, err)
+			assert.True(t, result["valid"].(bool))
+			assert.Empty(t, batch.Errors)
+			assert.Equal(t, 5, count)
+			continue
+		}
+
+		assert.True(t, errors.Is(err, ErrDuplicateCiphertext))
+		assert.False(t, result["valid"].(bool))
+		assert.Len(t, batch.Receipts, 1)
+		assert.Len(t, batch.Errors, 2)
+		assert.Equal(t, "nullifier3", batch.Errors[0].Nullifier)
+		assert.Equal(t, "nullifier5", batch.Errors[1].Nullifier)
+		assert.Equal(t, 2, count)
+
+		// The original voter may still retry or amend their own ballot
+		_, err = contract.CastVote(ctx, "election-001", testVote(10), "nullifier0",
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		_, err = contract.CastVote(ctx, "election-001", testVote(30), "nullifier0",
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		_, err = contract.CastVote(ctx, "election-001", testVote(30), "nullifier1",
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.True(t, errors.Is(err, ErrDuplicateCiphertext))
+	}
+}
+
+func TestCastVoteGracePeriod(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.GracePeriodSeconds = 120
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	cast := func(at time.Time, nullifier string) error {
+		stub.TxTimestamp = &timestamp.Timestamp{Seconds: at.Unix()}
+		_, err := contract.CastVote(ctx, "election-001", testVote(10), nullifier,
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		return err
+	}
+
+	// Before EndTime the vote is not flagged
+	assert.NoError(t, cast(election.EndTime.Add(-time.Second), "nullifier0"))
+	vote, _ := contract.GetVote(ctx, "election-001", "nullifier0")
+	assert.False(t, vote.InGracePeriod)
+
+	// Within the grace window the vote is accepted and flagged
+	assert.NoError(t, cast(election.EndTime.Add(time.Minute), "nullifier1"))
+	vote, _ = contract.GetVote(ctx, "election-001", "nullifier1")
+	assert.True(t, vote.InGracePeriod)
+
+	batch, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(20), Nullifier: "nullifier2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, batch.Errors)
+	vote, _ = contract.GetVote(ctx, "election-001", "nullifier2")
+	assert.True(t, vote.InGracePeriod)
+
+	// Past the grace window
+	err = cast(election.EndTime.Add(121*time.Second), "nullifier3")
+	assert.True(t, errors.Is(err, ErrElectionEnded))
+
+	// Without a grace period EndTime is the deadline
+	election.GracePeriodSeconds = 0
+	electionJSON, _ = json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+	err = cast(election.EndTime.Add(time.Minute), "nullifier3")
+	assert.True(t, errors.Is(err, ErrElectionEnded))
+}
+
+func TestCreateElectionGracePeriod(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	ctx.On("GetStub").Return(NewMockStub())
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	for _, grace := range []int{-1, MaxGracePeriodSeconds + 1} {
+		config, _ := json.Marshal(ElectionConfig{GracePeriodSeconds: grace})
+		_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, string(config))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "gracePeriodSeconds")
+	}
+
+	config, _ := json.Marshal(ElectionConfig{GracePeriodSeconds: 300})
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	election, _ := new(VoteContract).GetElection(ctx, "election-001")
+	assert.Equal(t, 300, election.GracePeriodSeconds)
+}
+
+func TestCreateElectionBoardWriteFailure(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	boardPrefix := compositeKey(bulletinBoardObjectType)
+	stub.WriteErr = func(key string) error {
+		if strings.HasPrefix(key, boardPrefix) {
+			return fmt.Errorf("board unavailable")
+		}
+		return nil
+	}
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "bulletin board")
+
+	// No election is left behind without its board
+	_, err = contract.GetElection(ctx, "election-001")
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+	elections, err := contract.GetElectionsByStatus(ctx, "pending")
+	assert.NoError(t, err)
+	assert.Empty(t, elections)
+
+	// Once the board is writable the election starts with its genesis entry
+	stub.WriteErr = nil
+	_, err = contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	board, err := contract.GetBulletinBoard(ctx, "election-001")
+	assert.NoError(t, err)
+	entries := board["entries"].([]BulletinBoardEntry)
+	if assert.NotEmpty(t, entries) {
+		assert.Equal(t, "election_created", entries[0].Type)
+		assert.Equal(t, 1, entries[0].Sequence)
+	}
+	assert.Equal(t, []byte("0"), stub.State[voteCountShardKey("election-001", DefaultVoteCountShards-1)])
+}
+
+func TestVerifyVotesBatch(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	var hashes []string
+	for i := 0; i < 2; i++ {
+		receipt, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		hashes = append(hashes, receipt.EncryptedVoteHash)
+	}
+
+	writes := len(stub.Writes)
+	results, err := contract.VerifyVotesBatch(ctx, "election-001", []VerifyPair{
+		{Nullifier: "nullifier0", ExpectedHash: hashes[0]},
+		{Nullifier: "nullifier1", ExpectedHash: hashes[0]},
+		{Nullifier: "missing", ExpectedHash: hashes[1]},
+		{Nullifier: "nullifier1", ExpectedHash: hashes[1]},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, writes, len(stub.Writes), "VerifyVotesBatch must not write state")
+
+	assert.Len(t, results, 4)
+	assert.Equal(t, VerifyPairResult{Nullifier: "nullifier0", Verified: true, TxID: "mock-tx-id-12345"}, results[0])
+	assert.Equal(t, VerifyPairResult{Nullifier: "nullifier1", Verified: false, TxID: "mock-tx-id-12345"}, results[1])
+	assert.Equal(t, "missing", results[2].Nullifier)
+	assert.False(t, results[2].Verified)
+	assert.Contains(t, results[2].Error, "not found")
+	assert.True(t, results[3].Verified)
+
+	_, err = contract.VerifyVotesBatch(ctx, "election-001", nil)
+	assert.Error(t, err)
+	_, err = contract.VerifyVotesBatch(ctx, "election-001", make([]VerifyPair, MaxVerifyBatchSize+1))
+	assert.Error(t, err)
+
+	// A read failure is not reported as a missing vote
+	stub.ReadErr = errors.New("peer unavailable")
+	_, err = contract.VerifyVotesBatch(ctx, "election-001", []VerifyPair{{Nullifier: "nullifier0", ExpectedHash: hashes[0]}})
+	assert.Error(t, err)
+}
+
+// setVoteCount stands in for the ballots a tally test does not cast
+func setVoteCount(stub *MockStub, electionID string, count int) {
+	stub.State[voteCountKey(electionID)] = []byte(strconv.Itoa(count))
+}
+
+func TestStoreTallyResult(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Setup closed election
+	election := &Election{
+		ID:     "election-001",
+		Status: "closed",
 	}
 	electionJSON, _ := json.Marshal(election)
 	stub.State["election:election-001"] = electionJSON
-	stub.State["bulletinboard:election-001"] = []byte("[]")
+	setVoteCount(stub, "election-001", 225)
 
 	// Store tally
 	voteCounts := `{"1": 100, "2": 75, "3": 50}`
@@ -342,66 +1441,204 @@ func TestStoreTallyResult(t *testing.T) {
 	assert.Equal(t, 225, result.TotalVotes)
 }
 
-func TestGetTallyResult(t *testing.T) {
+func TestStoreTallyResultChecksLedgerBallotCount(t *testing.T) {
 	contract := new(VoteContract)
 	ctx := new(MockTransactionContext)
 	stub := NewMockStub()
 
 	ctx.On("GetStub").Return(stub)
 
-	// Store tally result
-	result := &TallyResult{
-		ElectionID:      "election-001",
-		VoteCounts:      map[string]int{"1": 100, "2": 50},
-		TotalVotes:      150,
-		AggregatedHash:  "hash",
-		DecryptionProof: "proof",
-	}
-	resultJSON, _ := json.Marshal(result)
-	stub.State["tally:election-001"] = resultJSON
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
 
-	// Get tally
-	retrieved, err := contract.GetTallyResult(ctx, "election-001")
+	for i := 0; i < 3; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+1)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+	_, err := contract.CloseElection(ctx, "election-001")
 	assert.NoError(t, err)
-	assert.Equal(t, 150, retrieved.TotalVotes)
-	assert.Equal(t, 100, retrieved.VoteCounts["1"])
+
+	// Four votes from three ballots overstate the ledger
+	err = contract.StoreTallyResult(ctx, "election-001", `{"1": 2, "2": 1, "__spoiled__": 1}`, "hash", "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "tally counts 4 votes but the ledger allows at most 3")
+
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 2, "2": 1}`, "hash", "proof"))
+	result, _ := contract.GetTallyResult(ctx, "election-001")
+	assert.Equal(t, 3, result.TotalVotes)
+	assert.Equal(t, 3, result.BallotCount)
 }
 
-func TestGetBulletinBoard(t *testing.T) {
+func TestStoreTallyResultAllowance(t *testing.T) {
 	contract := new(VoteContract)
 	ctx := new(MockTransactionContext)
 	stub := NewMockStub()
 
 	ctx.On("GetStub").Return(stub)
 
-	// Store bulletin board entries
-	entries := []BulletinBoardEntry{
-		{Sequence: 1, Type: "election_created", Hash: "hash1", TxID: "tx1"},
-		{Sequence: 2, Type: "vote_cast", Hash: "hash2", TxID: "tx2"},
-	}
-	entriesJSON, _ := json.Marshal(entries)
-	stub.State["bulletinboard:election-001"] = entriesJSON
+	// Two abstentions were recorded on paper
+	election := createMockElection()
+	election.Status = "closed"
+	election.TallyAllowance = 2
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+	setVoteCount(stub, "election-001", 10)
 
-	// Get bulletin board
-	result, err := contract.GetBulletinBoard(ctx, "election-001")
-	assert.NoError(t, err)
-	assert.NotNil(t, result["entries"])
-	assert.NotEmpty(t, result["merkleRoot"])
-}
+	err := contract.StoreTallyResult(ctx, "election-001", `{"1": 8, "2": 2, "__abstain__": 3}`, "hash", "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at most 12")
 
-func TestComputeMerkleRoot(t *testing.T) {
-	entries := []BulletinBoardEntry{
-		{Sequence: 1, Type: "test1", Hash: "hash1", TxID: "tx1"},
-		{Sequence: 2, Type: "test2", Hash: "hash2", TxID: "tx2"},
-		{Sequence: 3, Type: "test3", Hash: "hash3", TxID: "tx3"},
-	}
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 8, "2": 2, "__abstain__": 2}`, "hash", "proof"))
+}
 
-	root := computeMerkleRoot(entries)
-	assert.NotEmpty(t, root)
+func TestStoreTallyResultValidatesCounts(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := &Election{ID: "election-001", Status: "closed"}
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+	setVoteCount(stub, "election-001", 10)
+
+	for counts, message := range map[string]string{
+		`{"1": 5, "2": -5}`:              "count for option 2 must not be negative",
+		`{"1": "abc"}`:                   `count for option 1 must be an integer, got "abc"`,
+		`{"1": 2.5}`:                     "count for option 1 must be an integer",
+		`{"1": null}`:                    "count for option 1 must be an integer",
+		`{"default": {"1": 3, "2": -1}}`: "question default: count for option 2 must not be negative",
+		`{"default": {"1": 3}, "2": 1}`:  "mix of per-question and per-option counts",
+		`[1, 2]`:                         "invalid vote counts",
+	} {
+		err := contract.StoreTallyResult(ctx, "election-001", counts, "hash", "proof")
+		assert.Error(t, err, counts)
+		if err != nil {
+			assert.Contains(t, err.Error(), message, counts)
+		}
+	}
+
+	election, _ = contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "closed", election.Status)
+
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 6, "2": 0, "3": 4}`, "hash", "proof"))
+	result, _ := contract.GetTallyResult(ctx, "election-001")
+	assert.Equal(t, 10, result.TotalVotes)
+	assert.Equal(t, 0, result.VoteCounts[DefaultQuestionID]["2"])
+}
+
+func TestGetTallyResult(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Store tally result
+	result := &TallyResult{
+		ElectionID:      "election-001",
+		VoteCounts:      map[string]map[string]int{DefaultQuestionID: {"1": 100, "2": 50}},
+		TotalVotes:      150,
+		AggregatedHash:  "hash",
+		DecryptionProof: "proof",
+	}
+	resultJSON, _ := json.Marshal(result)
+	stub.State["tally:election-001"] = resultJSON
+
+	// Get tally
+	retrieved, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 150, retrieved.TotalVotes)
+	assert.Equal(t, 100, retrieved.VoteCounts[DefaultQuestionID]["1"])
+}
+
+func TestGetAllTalliesPaginated(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	ids := []string{"election-001", "election-002", "election-003"}
+	for i, id := range ids {
+		election := createMockElection()
+		election.ID = id
+		election.Status = "closed"
+		electionJSON, _ := json.Marshal(election)
+		stub.State[electionKey(id)] = electionJSON
+		setVoteCount(stub, id, 1000)
+		counts := fmt.Sprintf(`{"1": %d, "2": 10}`, i+1)
+		assert.NoError(t, contract.StoreTallyResult(ctx, id, counts, "hash", "proof"))
+	}
+	// A recount leaves the superseded result under a versioned key
+	assert.NoError(t, contract.ReopenTally(ctx, "election-002", "recount"))
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-002", `{"1": 5, "2": 10}`, "hash", "proof"))
+	// Other keys sharing the prefix are not tallies
+	stub.State["tallying"] = []byte("not a tally")
+
+	var tallies []*TallyResult
+	bookmark := ""
+	for pages := 0; pages < 10; pages++ {
+		page, err := contract.GetAllTalliesPaginated(ctx, 2, bookmark)
+		assert.NoError(t, err)
+		tallies = append(tallies, page["tallies"].([]*TallyResult)...)
+		bookmark = page["bookmark"].(string)
+		if bookmark == "" {
+			break
+		}
+	}
+	assert.Empty(t, bookmark)
+
+	if assert.Len(t, tallies, 3) {
+		for i, id := range ids {
+			assert.Equal(t, id, tallies[i].ElectionID)
+		}
+		assert.Equal(t, 1, tallies[0].VoteCounts[DefaultQuestionID]["1"])
+		assert.Equal(t, 2, tallies[1].Version)
+		assert.Equal(t, 5, tallies[1].VoteCounts[DefaultQuestionID]["1"])
+		assert.Equal(t, 3, tallies[2].VoteCounts[DefaultQuestionID]["1"])
+	}
+
+	_, err := contract.GetAllTalliesPaginated(ctx, 0, "")
+	assert.Error(t, err)
+}
+
+func TestGetBulletinBoard(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Store bulletin board entries
+	entries := []BulletinBoardEntry{
+		{Sequence: 1, Type: "election_created", Hash: "hash1", TxID: "tx1"},
+		{Sequence: 2, Type: "vote_cast", Hash: "hash2", TxID: "tx2"},
+	}
+	putBulletinBoard(stub, "election-001", entries)
+
+	// Get bulletin board
+	result, err := contract.GetBulletinBoard(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.NotNil(t, result["entries"])
+	assert.NotEmpty(t, result["merkleRoot"])
+}
+
+func TestComputeMerkleRoot(t *testing.T) {
+	entries := []BulletinBoardEntry{
+		{Sequence: 1, Type: "test1", Hash: "hash1", TxID: "tx1"},
+		{Sequence: 2, Type: "test2", Hash: "hash2", TxID: "tx2"},
+		{Sequence: 3, Type: "test3", Hash: "hash3", TxID: "tx3"},
+	}
+
+	root := computeMerkleRoot(MerkleSchemeV1, entries)
+	assert.NotEmpty(t, root)
 	assert.Len(t, root, 64) // SHA256 hex
 
 	// Same entries should give same root
-	root2 := computeMerkleRoot(entries)
+	root2 := computeMerkleRoot(MerkleSchemeV1, entries)
 	assert.Equal(t, root, root2)
 }
 
@@ -416,11 +1653,3615 @@ func TestHashString(t *testing.T) {
 }
 
 func TestGenerateVerificationCode(t *testing.T) {
-	code1 := generateVerificationCode("tx1", "hash1")
-	code2 := generateVerificationCode("tx1", "hash1")
-	code3 := generateVerificationCode("tx2", "hash2")
+	code1 := generateVerificationCode("", "tx1", "hash1", DefaultVerificationCodeLength)
+	code2 := generateVerificationCode("", "tx1", "hash1", DefaultVerificationCodeLength)
+	code3 := generateVerificationCode("", "tx2", "hash2", DefaultVerificationCodeLength)
 
 	assert.Equal(t, code1, code2)
 	assert.NotEqual(t, code1, code3)
 	assert.Len(t, code1, 16)
+
+	// Longer codes extend shorter ones
+	long := generateVerificationCode("", "tx1", "hash1", MaxVerificationCodeLength)
+	assert.Len(t, long, 32)
+	assert.True(t, strings.HasPrefix(long, code1))
+}
+
+func TestGetAllVotesPaginated(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	for i := 0; i < 5; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	// First page
+	page, err := contract.GetAllVotesPaginated(ctx, "election-001", 2, "")
+	assert.NoError(t, err)
+	assert.Len(t, page["votes"], 2)
+	assert.Equal(t, int32(2), page["fetchedCount"])
+	assert.NotEmpty(t, page["bookmark"])
+
+	// Walk the remaining pages
+	total := len(page["votes"].([]string))
+	bookmark := page["bookmark"].(string)
+	for bookmark != "" {
+		page, err = contract.GetAllVotesPaginated(ctx, "election-001", 2, bookmark)
+		assert.NoError(t, err)
+		total += len(page["votes"].([]string))
+		bookmark = page["bookmark"].(string)
+	}
+	assert.Equal(t, 5, total)
+
+	// GetAllVotes still returns everything
+	all, err := contract.GetAllVotes(ctx, "election-001", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, all["count"])
+}
+
+func TestGetAllVotesPaginatedEmptyElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	page, err := contract.GetAllVotesPaginated(ctx, "election-001", 10, "")
+	assert.NoError(t, err)
+	assert.Empty(t, page["votes"])
+	assert.Equal(t, "", page["bookmark"])
+	assert.Equal(t, int32(0), page["fetchedCount"])
+
+	_, err = contract.GetAllVotesPaginated(ctx, "election-001", 0, "")
+	assert.Error(t, err)
+}
+
+func TestGetVotesSince(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.AllowRevote = true
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		receipt, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		hashes = append(hashes, receipt.EncryptedVoteHash)
+	}
+
+	all, err := contract.GetVotesSince(ctx, "election-001", 0)
+	assert.NoError(t, err)
+	votes := all["votes"].([]Vote)
+	assert.Len(t, votes, 3)
+	assert.Equal(t, hashes[0], votes[0].EncryptedVoteHash)
+	checkpoint := votes[0].BulletinSequence
+
+	// Only the votes after the checkpoint are returned, in sequence order
+	since, err := contract.GetVotesSince(ctx, "election-001", checkpoint)
+	assert.NoError(t, err)
+	votes = since["votes"].([]Vote)
+	assert.Len(t, votes, 2)
+	assert.Equal(t, hashes[1], votes[0].EncryptedVoteHash)
+	assert.Equal(t, hashes[2], votes[1].EncryptedVoteHash)
+	assert.Less(t, votes[0].BulletinSequence, votes[1].BulletinSequence)
+	assert.Equal(t, votes[1].BulletinSequence, since["lastSequence"])
+
+	// An amendment moves the vote to its new sequence
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(20), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	last := since["lastSequence"].(int)
+	since, err = contract.GetVotesSince(ctx, "election-001", last)
+	assert.NoError(t, err)
+	votes = since["votes"].([]Vote)
+	assert.Len(t, votes, 1)
+	assert.Equal(t, receipt.EncryptedVoteHash, votes[0].EncryptedVoteHash)
+
+	all, _ = contract.GetVotesSince(ctx, "election-001", 0)
+	assert.Len(t, all["votes"], 3)
+
+	// Nothing new after the end of the board
+	since, err = contract.GetVotesSince(ctx, "election-001", since["lastSequence"].(int))
+	assert.NoError(t, err)
+	assert.Empty(t, since["votes"])
+
+	_, err = contract.GetVotesSince(ctx, "election-001", -1)
+	assert.Error(t, err)
+}
+
+func TestCastVoteBatchRecordsSequences(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(1), Nullifier: "n1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(2), Nullifier: "n2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Receipts, 2)
+
+	since, err := contract.GetVotesSince(ctx, "election-001", 0)
+	assert.NoError(t, err)
+	votes := since["votes"].([]Vote)
+	assert.Len(t, votes, 2)
+
+	// Each vote's sequence is its own bulletin board entry
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	for _, vote := range votes {
+		assert.Equal(t, vote.EncryptedVoteHash, entries[vote.BulletinSequence-1].Hash)
+	}
+}
+
+func TestCastVoteUsesCompositeKey(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(2), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	_, err = contract.CastVote(ctx, "election-001", testVote(3), "nullifier2", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	// Votes live under composite keys and no index key is written
+	assert.NotNil(t, stub.State[voteStateKey("election-001", "nullifier1")])
+	assert.NotNil(t, stub.State[voteStateKey("election-001", "nullifier2")])
+	assert.Nil(t, stub.State["voteindex:election-001"])
+
+	objectType, attributes, err := stub.SplitCompositeKey(voteStateKey("election-001", "nullifier1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "vote", objectType)
+	assert.Equal(t, []string{"election-001", nullifierCommitment("election-001", "nullifier1")}, attributes)
+
+	found, err := contract.GetVoteByHash(ctx, "election-001", receipt.EncryptedVoteHash)
+	assert.NoError(t, err)
+	assert.True(t, found["found"].(bool))
+
+	missing, err := contract.GetVoteByHash(ctx, "election-001", "unknownhash")
+	assert.NoError(t, err)
+	assert.False(t, missing["found"].(bool))
+}
+
+func TestMigrateVoteIndex(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Legacy layout: nullifier array index plus plain vote keys
+	for _, nullifier := range []string{"nullifier1", "nullifier2"} {
+		voteJSON := []byte(fmt.Sprintf(`{"electionId":"election-001","nullifier":"%s","encryptedVoteHash":"hash-%s"}`, nullifier, nullifier))
+		stub.State["vote:election-001:"+nullifier] = voteJSON
+	}
+	stub.State["voteindex:election-001"] = []byte(`["nullifier1","nullifier2"]`)
+
+	migrated, err := contract.MigrateVoteIndex(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+
+	assert.Nil(t, stub.State["voteindex:election-001"])
+	assert.Nil(t, stub.State["vote:election-001:nullifier1"])
+
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier2")
+	assert.NoError(t, err)
+	assert.Equal(t, "hash-nullifier2", vote.EncryptedVoteHash)
+
+	all, err := contract.GetAllVotes(ctx, "election-001", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, all["count"])
+
+	// Nothing left to migrate
+	migrated, err = contract.MigrateVoteIndex(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+}
+
+func TestConfirmVoteBlock(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), receipt.BlockNumber)
+
+	// The block listener names the vote by the commitment in VoteCast
+	var cast map[string]interface{}
+	assert.NoError(t, json.Unmarshal(stub.Events["VoteCast"], &cast))
+	commitment := cast["nullifierCommitment"].(string)
+	assert.Equal(t, nullifierCommitment("election-001", "nullifier123"), commitment)
+
+	err = contract.ConfirmVoteBlock(ctx, "election-001", commitment, 42)
+	assert.NoError(t, err)
+
+	// Block number is persisted on the vote
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(42), vote.BlockNumber)
+
+	var event map[string]interface{}
+	_ = json.Unmarshal(stub.Events["VoteConfirmed"], &event)
+	assert.Equal(t, float64(42), event["blockNumber"])
+	assert.Equal(t, receipt.EncryptedVoteHash, event["encryptedVoteHash"])
+
+	// Re-confirming with the same block is a no-op, a different block is rejected
+	assert.NoError(t, contract.ConfirmVoteBlock(ctx, "election-001", commitment, 42))
+	err = contract.ConfirmVoteBlock(ctx, "election-001", commitment, 43)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already confirmed")
+}
+
+func TestConfirmVoteBlockInvalid(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	err := contract.ConfirmVoteBlock(ctx, "election-001", nullifierCommitment("election-001", "nullifier123"), 0)
+	assert.Error(t, err)
+
+	err = contract.ConfirmVoteBlock(ctx, "election-001", nullifierCommitment("election-001", "missing"), 10)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestGetVotesByBlockRange(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	blocks := []uint64{999, 1050, 1000, 10000, 1025, 1000, 0}
+	hashes := make([]string, len(blocks))
+	for i, block := range blocks {
+		nullifier := fmt.Sprintf("nullifier%d", i)
+		receipt, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)), nullifier,
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		hashes[i] = receipt.EncryptedVoteHash
+		if block > 0 {
+			assert.NoError(t, contract.ConfirmVoteBlock(ctx, "election-001", nullifierCommitment("election-001", nullifier), block))
+		}
+	}
+
+	entries, err := contract.GetVotesByBlockRange(ctx, "election-001", 1000, 1050)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4)
+
+	// Ordered by block; block 10000 is not mistaken for one in range
+	var got []uint64
+	found := make(map[string]bool)
+	for _, entry := range entries {
+		got = append(got, entry.BlockNumber)
+		found[entry.EncryptedVoteHash] = true
+		assert.Equal(t, "mock-tx-id-12345", entry.TxID)
+	}
+	assert.Equal(t, []uint64{1000, 1000, 1025, 1050}, got)
+	for _, i := range []int{1, 2, 4, 5} {
+		assert.True(t, found[hashes[i]])
+	}
+
+	entries, err = contract.GetVotesByBlockRange(ctx, "election-001", 1025, 1025)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, hashes[4], entries[0].EncryptedVoteHash)
+
+	entries, err = contract.GetVotesByBlockRange(ctx, "election-002", 1, 20000)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = contract.GetVotesByBlockRange(ctx, "election-001", 1050, 1000)
+	assert.Error(t, err)
+	_, err = contract.GetVotesByBlockRange(ctx, "election-001", 0, 1000)
+	assert.Error(t, err)
+}
+
+func TestInvalidateVote(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.PublicKey = testPublicKeyJSON()
+	election.AllowRevote = true
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	for i, choice := range []int{0, 1, 0} {
+		_, err := contract.CastVote(ctx, "election-001", testBallot(choice, 2, int64(10*i+3)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	// Votes are named by the commitment published on the vote record
+	commitment := nullifierCommitment("election-001", "nullifier0")
+	err := contract.InvalidateVote(ctx, "election-001", commitment, "")
+	assert.Error(t, err)
+	err = contract.InvalidateVote(ctx, "election-001", "nullifier0", "forged eligibility proof")
+	assert.True(t, errors.Is(err, ErrVoteNotFound))
+
+	ctx.Identity = &MockClientIdentity{MSPID: "Org2MSP"}
+	err = contract.InvalidateVote(ctx, "election-001", commitment, "forged eligibility proof")
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+	ctx.Identity = nil
+
+	assert.NoError(t, contract.InvalidateVote(ctx, "election-001", commitment, "forged eligibility proof"))
+	assert.True(t, errors.Is(contract.InvalidateVote(ctx, "election-001", commitment, "again"), ErrVoteInvalidated))
+
+	// The record stays retrievable with its reason
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier0")
+	assert.NoError(t, err)
+	assert.True(t, vote.Invalidated)
+	assert.Equal(t, "forged eligibility proof", vote.InvalidationReason)
+
+	length, _ := bulletinBoardLength(ctx, "election-001")
+	entry, err := getBulletinBoardEntry(ctx, "election-001", length)
+	assert.NoError(t, err)
+	assert.Equal(t, "vote_invalidated", entry.Type)
+	assert.Equal(t, vote.EncryptedVoteHash, entry.Hash)
+
+	var event map[string]interface{}
+	_ = json.Unmarshal(stub.Events["VoteInvalidated"], &event)
+	assert.Equal(t, "forged eligibility proof", event["reason"])
+
+	// A revote cannot replace the invalidated vote
+	_, err = contract.CastVote(ctx, "election-001", testBallot(1, 2, 40), "nullifier0",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrVoteInvalidated))
+
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 2, count)
+
+	all, err := contract.GetAllVotes(ctx, "election-001", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, all["count"])
+	all, err = contract.GetAllVotes(ctx, "election-001", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, all["count"])
+	invalidated := 0
+	for _, record := range all["records"].([]VoteRecord) {
+		if record.Invalidated {
+			invalidated++
+		}
+	}
+	assert.Equal(t, 1, invalidated)
+
+	// The tally counts only the remaining votes
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, aggregate.VoteCount)
+	assert.Equal(t, 1, testDecrypt(aggregate.Ciphertexts[0]))
+	assert.Equal(t, 1, testDecrypt(aggregate.Ciphertexts[1]))
+}
+
+func TestGettersDistinguishNotFoundFromReadErrors(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	getters := map[string]func() error{
+		"GetElection": func() error {
+			_, err := contract.GetElection(ctx, "election-001")
+			return err
+		},
+		"GetVote": func() error {
+			_, err := contract.GetVote(ctx, "election-001", "nullifier0")
+			return err
+		},
+		"GetTallyResult": func() error {
+			_, err := contract.GetTallyResult(ctx, "election-001")
+			return err
+		},
+		"GetBulletinBoard": func() error {
+			_, err := contract.GetBulletinBoard(ctx, "election-001")
+			return err
+		},
+	}
+	sentinels := map[string]error{
+		"GetElection":      ErrElectionNotFound,
+		"GetVote":          ErrVoteNotFound,
+		"GetTallyResult":   ErrTallyNotFound,
+		"GetBulletinBoard": ErrBulletinBoardNotFound,
+	}
+
+	// Absent keys are a not-found
+	for name, get := range getters {
+		err := get()
+		assert.True(t, errors.Is(err, ErrNotFound), name)
+		assert.True(t, errors.Is(err, sentinels[name]), name)
+	}
+
+	// A failed read is propagated and never mistaken for one
+	readErr := errors.New("peer unavailable")
+	stub.ReadErr = readErr
+	for name, get := range getters {
+		err := get()
+		assert.True(t, errors.Is(err, readErr), name)
+		assert.False(t, errors.Is(err, ErrNotFound), name)
+	}
+}
+
+func TestAdminFunctionsRequirePermission(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+	ctx.Identity = &MockClientIdentity{MSPID: "VoterMSP", Attributes: map[string]string{}}
+
+	startTime := time.Now().Add(1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+	assert.Nil(t, stub.State["election:election-001"])
+
+	election := createMockElection()
+	election.Status = "pending"
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+
+	err = contract.StoreTallyResult(ctx, "election-001", `{"1": 1}`, "hash", "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+
+	// An attribute with any other value does not grant access
+	ctx.Identity.Attributes[AdminAttribute] = "false"
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "permission denied")
+}
+
+func TestAdminFunctionsAuthorized(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	// Admin by certificate attribute
+	contract := new(VoteContract)
+	ctx.Identity = &MockClientIdentity{MSPID: "Org1MSP", Attributes: map[string]string{AdminAttribute: "true"}}
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	setTestOptions(t, ctx, "election-001")
+
+	// Admin by configured MSP
+	contract = &VoteContract{AdminMSPIDs: []string{"ElectionCommissionMSP"}}
+	ctx.Identity = &MockClientIdentity{MSPID: "ElectionCommissionMSP", Attributes: map[string]string{}}
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// Voters do not need admin rights to cast a vote
+	election, _ := contract.GetElection(ctx, "election-001")
+	election.StartTime = time.Now().Add(-1 * time.Hour)
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	ctx.Identity = &MockClientIdentity{MSPID: "VoterMSP", Attributes: map[string]string{}}
+	_, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+}
+
+func TestParseAdminMSPIDs(t *testing.T) {
+	mspIDs, err := ParseAdminMSPIDs("Org1MSP, NECMSP,,\tElection-Commission.MSP ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Org1MSP", "NECMSP", "Election-Commission.MSP"}, mspIDs)
+
+	mspIDs, err = ParseAdminMSPIDs(" , ")
+	assert.NoError(t, err)
+	assert.Empty(t, mspIDs)
+
+	_, err = ParseAdminMSPIDs("Org1MSP, NEC MSP")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "NEC MSP")
+}
+
+func TestGetVoteInclusionProof(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	var receipts []*VoteReceipt
+	for i := 0; i < 3; i++ {
+		receipt, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		receipts = append(receipts, receipt)
+	}
+
+	board, err := contract.GetBulletinBoard(ctx, "election-001")
+	assert.NoError(t, err)
+
+	for i, receipt := range receipts {
+		proof, err := contract.GetVoteInclusionProof(ctx, "election-001", receipt.EncryptedVoteHash)
+		assert.NoError(t, err)
+		assert.Equal(t, i+1, proof.Sequence)
+		assert.Equal(t, board["merkleRoot"], proof.MerkleRoot)
+		assert.True(t, proof.Verify())
+	}
+
+	_, err = contract.GetVoteInclusionProof(ctx, "election-001", "unknownhash")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestExtendElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	newEndTime := election.EndTime.Add(2 * time.Hour).UTC().Truncate(time.Second)
+	err := contract.ExtendElection(ctx, "election-001", newEndTime.Format(time.RFC3339))
+	assert.NoError(t, err)
+
+	updated, _ := contract.GetElection(ctx, "election-001")
+	assert.True(t, newEndTime.Equal(updated.EndTime))
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "election_extended", entries[len(entries)-1].Type)
+}
+
+func TestExtendElectionRejectsShortening(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.ExtendElection(ctx, "election-001", election.EndTime.Add(-1*time.Hour).Format(time.RFC3339))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "must be after")
+
+	err = contract.ExtendElection(ctx, "election-001", election.EndTime.Format(time.RFC3339Nano))
+	assert.Error(t, err)
+}
+
+func TestExtendClosedElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Status = "closed"
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.ExtendElection(ctx, "election-001", election.EndTime.Add(2*time.Hour).Format(time.RFC3339))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only active elections")
+}
+
+func TestPauseAndResumeElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.PauseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// Votes are rejected while paused
+	_, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "election is paused")
+	assert.True(t, errors.Is(err, ErrElectionPaused))
+
+	// Pausing twice is rejected
+	err = contract.PauseElection(ctx, "election-001")
+	assert.Error(t, err)
+
+	err = contract.ResumeElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	_, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "election_paused", entries[0].Type)
+	assert.Equal(t, "election_resumed", entries[1].Type)
+}
+
+func TestResumeElectionKeepsTimeBounds(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Status = "paused"
+	election.EndTime = time.Now().Add(-1 * time.Minute)
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.ResumeElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	_, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ended")
+
+	// Resuming an election that is not paused is rejected
+	err = contract.ResumeElection(ctx, "election-001")
+	assert.Error(t, err)
+}
+
+func TestCancelElection(t *testing.T) {
+	for _, status := range []string{"pending", "active", "paused"} {
+		contract := new(VoteContract)
+		ctx := new(MockTransactionContext)
+		stub := NewMockStub()
+
+		ctx.On("GetStub").Return(stub)
+
+		election := createMockElection()
+		election.Status = status
+		electionJSON, _ := json.Marshal(election)
+		stub.State["election:election-001"] = electionJSON
+
+		err := contract.CancelElection(ctx, "election-001", "fraud in voter roll")
+		assert.NoError(t, err, status)
+
+		updated, _ := contract.GetElection(ctx, "election-001")
+		assert.Equal(t, "cancelled", updated.Status)
+		assert.Equal(t, "fraud in voter roll", updated.CancellationReason)
+
+		board, _ := contract.GetBulletinBoard(ctx, "election-001")
+		entries := board["entries"].([]BulletinBoardEntry)
+		assert.Equal(t, "election_cancelled", entries[len(entries)-1].Type)
+		assert.Equal(t, hashString("fraud in voter roll"), entries[len(entries)-1].Hash)
+
+		// No votes or tally after cancellation
+		_, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cancelled")
+
+		err = contract.StoreTallyResult(ctx, "election-001", `{"1": 1}`, "hash", "proof")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cancelled")
+
+		_, err = contract.GetTallyResult(ctx, "election-001")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "was cancelled")
+	}
+}
+
+func TestCancelCompletedElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Status = "completed"
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.CancelElection(ctx, "election-001", "too late")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot be cancelled")
+
+	err = contract.CancelElection(ctx, "election-001", "")
+	assert.Error(t, err)
+}
+
+func TestQueryVotesByTimeRange(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	for i, offset := range []time.Duration{0, 30 * time.Minute, 90 * time.Minute} {
+		voteJSON, _ := json.Marshal(&Vote{
+			ElectionID:        "election-001",
+			EncryptedVoteHash: fmt.Sprintf("hash%d", i),
+			TxID:              fmt.Sprintf("tx%d", i),
+			Timestamp:         base.Add(offset).Add(500 * time.Millisecond),
+		})
+		stub.QueryResults = append(stub.QueryResults, &queryresult.KV{Key: fmt.Sprintf("vote%d", i), Value: voteJSON})
+	}
+
+	// CouchDB narrows to whole seconds; the exact bounds are applied in chaincode
+	results, err := contract.QueryVotesByTimeRange(ctx, "election-001",
+		base.Format(time.RFC3339), base.Add(time.Hour).Format(time.RFC3339))
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "hash0", results[0].EncryptedVoteHash)
+	assert.Equal(t, "tx1", results[1].TxID)
+
+	var query struct {
+		Selector map[string]interface{} `json:"selector"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(stub.LastQuery), &query))
+	assert.Equal(t, "election-001", query.Selector["electionId"])
+	timeRange := query.Selector["timestamp"].(map[string]interface{})
+	assert.Equal(t, "2024-03-01T09:00:00", timeRange["$gte"])
+	assert.Equal(t, "2024-03-01T10:00:01", timeRange["$lt"])
+}
+
+func TestQueryVotesByTimeRangeInvalid(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	_, err := contract.QueryVotesByTimeRange(ctx, "election-001", "yesterday", "2024-03-01T10:00:00Z")
+	assert.Error(t, err)
+
+	_, err = contract.QueryVotesByTimeRange(ctx, "election-001", "2024-03-01T10:00:00Z", "2024-03-01T09:00:00Z")
+	assert.Error(t, err)
+}
+
+func TestGetVoteCount(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+
+	// Counter starts at zero
+	assert.Equal(t, []byte("0"), stub.State["votecount:election-001"])
+	count, err := contract.GetVoteCount(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	// A rejected vote is not counted
+	_, err = contract.CastVote(ctx, "election-001", testVote(1), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+
+	count, err = contract.GetVoteCount(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func setupMultiQuestionElection(t *testing.T, ctx *MockTransactionContext, publicKey string) {
+	config, _ := json.Marshal(ElectionConfig{
+		Questions: []Question{
+			{ID: "mayor", Text: "Who should be mayor?", Options: []string{"alice", "bob"}},
+			{ID: "budget", Text: "Approve the budget?", Options: []string{"yes", "no", "abstain"}},
+		},
+	})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", publicKey,
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	_, err = new(VoteContract).ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+}
+
+func TestMultiQuestionElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	setupMultiQuestionElection(t, ctx, testPublicKeyJSON())
+
+	election, err := contract.GetElection(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Len(t, election.Questions, 2)
+
+	// mayor: alice, alice, bob; budget: yes, no (third voter skips it)
+	ballots := []map[string]string{
+		{"mayor": testBallot(0, 2, 3), "budget": testBallot(0, 3, 5)},
+		{"mayor": testBallot(0, 2, 7), "budget": testBallot(1, 3, 11)},
+		{"mayor": testBallot(1, 2, 13)},
+	}
+	for i, ballot := range ballots {
+		ballotJSON, _ := json.Marshal(ballot)
+		_, err := contract.CastVoteMultiQuestion(ctx, "election-001", string(ballotJSON),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	// Each race is stored separately on the vote record
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier0")
+	assert.NoError(t, err)
+	assert.Equal(t, ballots[0], vote.QuestionVotes)
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, aggregate.VoteCount)
+	assert.Equal(t, 2, testDecrypt(aggregate.Questions["mayor"][0]))
+	assert.Equal(t, 1, testDecrypt(aggregate.Questions["mayor"][1]))
+	assert.Equal(t, 1, testDecrypt(aggregate.Questions["budget"][0]))
+	assert.Equal(t, 1, testDecrypt(aggregate.Questions["budget"][1]))
+	assert.Equal(t, 0, testDecrypt(aggregate.Questions["budget"][2]))
+
+	err = contract.StoreTallyResult(ctx, "election-001",
+		`{"mayor": {"alice": 2, "bob": 1}, "budget": {"yes": 1, "no": 1, "abstain": 0}}`, aggregate.AggregateHash, "proof")
+	assert.NoError(t, err)
+
+	result, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.VoteCounts["mayor"]["alice"])
+	assert.Equal(t, 1, result.VoteCounts["budget"]["no"])
+	assert.Equal(t, 3, result.TotalVotes)
+}
+
+func TestMultiQuestionElectionRejectsBadBallots(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	setupMultiQuestionElection(t, ctx, testPublicKeyJSON())
+
+	// A single unnamed ballot is ambiguous here
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "CastVoteMultiQuestion")
+
+	_, err = contract.CastVoteMultiQuestion(ctx, "election-001", `{"governor": "{}"}`, "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown question")
+
+	_, err = contract.CastVoteMultiQuestion(ctx, "election-001", `{}`, "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+
+	// Each race takes one ciphertext per option
+	ballotJSON, _ := json.Marshal(map[string]string{"mayor": testBallot(0, 2, 3), "budget": testBallot(0, 2, 5)})
+	_, err = contract.CastVoteMultiQuestion(ctx, "election-001", string(ballotJSON), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "question budget: ballot has 2 ciphertexts, expected 3")
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	err = contract.StoreTallyResult(ctx, "election-001", `{"mayor": {"carol": 1}}`, "hash", "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown option")
+}
+
+func TestMultiQuestionElectionPerQuestionKeys(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// The local contest is decrypted by its own trustees under its own key
+	config, _ := json.Marshal(ElectionConfig{
+		Questions: []Question{
+			{ID: "federal", Options: []string{"alice", "bob"}},
+			{ID: "local", Options: []string{"yes", "no"}, PublicKey: testLocalPublicKeyJSON()},
+		},
+	})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	ballot := func(federal string, local []CiphertextJSON) string {
+		localJSON, _ := json.Marshal(local)
+		ballotJSON, _ := json.Marshal(map[string]string{"federal": federal, "local": string(localJSON)})
+		return string(ballotJSON)
+	}
+
+	// federal: alice, bob, alice; local: yes, yes, no
+	for i, choices := range [][2]int{{0, 0}, {1, 0}, {0, 1}} {
+		_, err := contract.CastVoteMultiQuestion(ctx, "election-001",
+			ballot(testBallot(choices[0], 2, int64(10*i+3)), testLocalBallot(choices[1], 2, int64(10*i+4))),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	// A local-key ciphertext is not in the federal group
+	localAsFederal, _ := json.Marshal(testLocalBallot(0, 2, 2))
+	_, err = contract.CastVoteMultiQuestion(ctx, "election-001",
+		ballot(string(localAsFederal), testLocalBallot(0, 2, 4)),
+		"nullifier9", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrForeignCiphertext))
+	assert.Contains(t, err.Error(), "question federal")
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, testDecrypt(aggregate.Questions["federal"][0]))
+	assert.Equal(t, 1, testDecrypt(aggregate.Questions["federal"][1]))
+	assert.Equal(t, 2, testLocalDecrypt(aggregate.Questions["local"][0]))
+	assert.Equal(t, 1, testLocalDecrypt(aggregate.Questions["local"][1]))
+}
+
+func TestCreateElectionInvalidQuestions(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	for _, config := range []string{
+		`{"questions": [{"id": "q1", "options": ["a"], "publicKey": "not-a-key"}]}`,
+		`{"questions": [{"id": "", "options": ["a"]}]}`,
+		`{"questions": [{"id": "q1", "options": ["a"]}, {"id": "q1", "options": ["b"]}]}`,
+		`{"questions": [{"id": "q1", "options": []}]}`,
+		`{"questions": [{"id": "q1", "options": ["a", "a"]}]}`,
+	} {
+		_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime, config)
+		assert.Error(t, err, config)
+	}
+}
+
+func TestDefaultQuestionBackwardCompatibility(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// The implicit question accepts the map form as well
+	ballotJSON, _ := json.Marshal(map[string]string{DefaultQuestionID: testVote(2)})
+	_, err := contract.CastVoteMultiQuestion(ctx, "election-001", string(ballotJSON), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier0")
+	assert.NoError(t, err)
+	assert.Equal(t, testVote(2), vote.EncryptedVote)
+	assert.Empty(t, vote.QuestionVotes)
+}
+
+func TestIsNullifierUsed(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	used, err := contract.IsNullifierUsed(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	_, err = contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	used, err = contract.IsNullifierUsed(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	// Nullifiers are scoped to their election
+	used, err = contract.IsNullifierUsed(ctx, "election-002", "nullifier123")
+	assert.NoError(t, err)
+	assert.False(t, used)
+}
+
+func TestCastVotePrivate(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+	t.Setenv("CORE_PEER_LOCALMSPID", "Org1MSP")
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// The ciphertext must come through the transient map
+	_, err := contract.CastVotePrivate(ctx, "election-001", "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+
+	secret := testVote(5)
+	stub.Transient["encryptedVote"] = []byte(secret)
+	receipt, err := contract.CastVotePrivate(ctx, "election-001", "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, hashString(secret), receipt.EncryptedVoteHash)
+
+	// World state holds only the hash
+	key := voteStateKey("election-001", "nullifier123")
+	var publicVote Vote
+	_ = json.Unmarshal(stub.State[key], &publicVote)
+	assert.Empty(t, publicVote.EncryptedVote)
+	assert.Equal(t, hashString(secret), publicVote.EncryptedVoteHash)
+	var storedPrivate PrivateVote
+	_ = json.Unmarshal(stub.PrivateState[PrivateBallotCollection][key], &storedPrivate)
+	assert.Equal(t, secret, storedPrivate.EncryptedVote)
+
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+	assert.Empty(t, vote.EncryptedVote)
+	assert.Equal(t, PrivateBallotCollection, vote.PrivateCollection)
+
+	privateVote, err := contract.GetPrivateVote(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+	assert.Equal(t, secret, privateVote.EncryptedVote)
+
+	// Nullifiers are shared with public votes
+	_, err = contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+}
+
+func TestGetPrivateVoteRequiresCollectionMember(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+	t.Setenv("CORE_PEER_LOCALMSPID", "Org1MSP")
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+	stub.Transient["encryptedVote"] = []byte(testVote(5))
+	_, err := contract.CastVotePrivate(ctx, "election-001", "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	ctx.Identity = &MockClientIdentity{MSPID: "Org2MSP"}
+	_, err = contract.GetPrivateVote(ctx, "election-001", "nullifier123")
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+	assert.Contains(t, err.Error(), "not a member of the collection")
+
+	// Public votes have nothing in the collection
+	ctx.Identity = nil
+	_, err = contract.CastVote(ctx, "election-001", testVote(1), "nullifier456", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	_, err = contract.GetPrivateVote(ctx, "election-001", "nullifier456")
+	assert.Error(t, err)
+}
+
+func TestAggregateEncryptedVotesReadsPrivateVotes(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.PublicKey = testPublicKeyJSON()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	stub.Transient["encryptedVote"] = []byte(testBallot(1, 2, 3))
+	_, err := contract.CastVotePrivate(ctx, "election-001", "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	_, err = contract.CastVote(ctx, "election-001", testBallot(1, 2, 5), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, testDecrypt(aggregate.Ciphertexts[0]))
+	assert.Equal(t, 2, testDecrypt(aggregate.Ciphertexts[1]))
+}
+
+func TestGetElectionHistory(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	version := func(txID, status string, minutes int) *queryresult.KeyModification {
+		election := createMockElection()
+		election.Status = status
+		value, _ := json.Marshal(election)
+		return &queryresult.KeyModification{
+			TxId:      txID,
+			Value:     value,
+			Timestamp: &timestamp.Timestamp{Seconds: base.Add(time.Duration(minutes) * time.Minute).Unix()},
+		}
+	}
+
+	// Peers return history newest first
+	stub.History["election:election-001"] = []*queryresult.KeyModification{
+		version("tx5", "completed", 240),
+		version("tx4", "closed", 180),
+		version("tx3", "paused", 60),
+		version("tx2", "active", 30),
+		version("tx1", "pending", 0),
+	}
+
+	history, err := contract.GetElectionHistory(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Len(t, history, 5)
+
+	statuses := []string{}
+	for _, entry := range history {
+		statuses = append(statuses, entry.Status)
+	}
+	assert.Equal(t, []string{"pending", "active", "paused", "closed", "completed"}, statuses)
+	assert.Equal(t, "tx1", history[0].TxID)
+	assert.Equal(t, base, history[0].Timestamp)
+	assert.False(t, history[4].IsDelete)
+
+	_, err = contract.GetElectionHistory(ctx, "election-002")
+	assert.Error(t, err)
+}
+
+func TestGetElectionHistoryDeleted(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	value, _ := json.Marshal(createMockElection())
+	stub.History["election:election-001"] = []*queryresult.KeyModification{
+		{TxId: "tx2", IsDelete: true, Timestamp: &timestamp.Timestamp{Seconds: 200}},
+		{TxId: "tx1", Value: value, Timestamp: &timestamp.Timestamp{Seconds: 100}},
+	}
+
+	history, err := contract.GetElectionHistory(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, "active", history[0].Status)
+	assert.True(t, history[1].IsDelete)
+	assert.Empty(t, history[1].Status)
+}
+
+func TestCastVoteBatch(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+	stub.State["votecount:election-001"] = []byte("0")
+
+	// nullifier0 is already spent in state
+	_, err := contract.CastVote(ctx, "election-001", testVote(20), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	batch := []EncryptedBallotInput{
+		{EncryptedVote: testVote(21), Nullifier: "nullifier1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(22), Nullifier: "nullifier2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(23), Nullifier: "nullifier1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(24), Nullifier: "nullifier0", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(25), Nullifier: "nullifier5", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	}
+	result, err := contract.CastVoteBatch(ctx, "election-001", batch)
+	assert.NoError(t, err)
+
+	assert.Len(t, result.Receipts, 3)
+	assert.Equal(t, hashString(testVote(25)), result.Receipts[2].EncryptedVoteHash)
+	assert.Len(t, result.Errors, 2)
+	assert.Equal(t, 2, result.Errors[0].Index)
+	assert.Contains(t, result.Errors[0].Error, "within batch")
+	assert.Equal(t, 3, result.Errors[1].Index)
+	assert.Contains(t, result.Errors[1].Error, "duplicate nullifier")
+
+	// The first ballot for a nullifier wins; the in-batch duplicate is not stored
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier1")
+	assert.NoError(t, err)
+	assert.Equal(t, testVote(21), vote.EncryptedVote)
+	vote, err = contract.GetVote(ctx, "election-001", "nullifier0")
+	assert.NoError(t, err)
+	assert.Equal(t, testVote(20), vote.EncryptedVote)
+
+	count, err := contract.GetVoteCount(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Len(t, entries, 4)
+	assert.Equal(t, 4, entries[3].Sequence)
+	assert.Contains(t, string(stub.Events["VoteBatchCast"]), `"rejected":2`)
+	assert.Contains(t, string(stub.Events["VoteBatchCast"]), nullifierCommitment("election-001", "nullifier5"))
+}
+
+func TestCastVoteBatchClosedElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Status = "closed"
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	_, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(1), Nullifier: "nullifier1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not active")
+
+	_, err = contract.CastVoteBatch(ctx, "election-001", nil)
+	assert.Error(t, err)
+}
+
+func TestCastVoteRejectsWrongWidthBallot(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Options = []string{"alice", "bob", "carol"}
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	_, err := contract.CastVote(ctx, "election-001", testBallot(0, 2, 10), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "ballot has 2 ciphertexts, expected 3")
+
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testBallot(0, 3, 20), Nullifier: "nullifier1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testBallot(0, 4, 30), Nullifier: "nullifier2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Receipts, 1)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, 1, result.Errors[0].Index)
+	assert.Contains(t, result.Errors[0].Error, "expected 3")
+
+	// The accepted ballots still aggregate
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	aggregate, err := contract.AggregateEncryptedVotes(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, aggregate.VoteCount)
+}
+
+func TestVerifyTallyResult(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// The hash follows nullifier commitment order, not cast order
+	nullifiers := []string{"nullifier-c", "nullifier-a", "nullifier-b"}
+	ballots := map[string]string{}
+	for i, nullifier := range nullifiers {
+		ballots[nullifier] = testVote(int64(i + 1))
+		_, err := contract.CastVote(ctx, "election-001", ballots[nullifier], nullifier, testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+	_, err := contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	sort.Slice(nullifiers, func(i, j int) bool {
+		return nullifierCommitment("election-001", nullifiers[i]) < nullifierCommitment("election-001", nullifiers[j])
+	})
+	concatenated := ""
+	for _, nullifier := range nullifiers {
+		concatenated += hashString(ballots[nullifier])
+	}
+	expected := hashString(concatenated)
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 3}`, expected, "proof"))
+
+	verification, err := contract.VerifyTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.True(t, verification.Verified)
+	assert.Equal(t, expected, verification.RecomputedHash)
+	assert.Equal(t, 3, verification.VoteCount)
+}
+
+func TestVerifyTallyResultMismatch(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	for i := 0; i < 2; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+1)), fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+	_, err := contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// Tally computed over only the first ballot
+	dropped := hashString(hashString(testVote(1)))
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 1}`, dropped, "proof"))
+
+	verification, err := contract.VerifyTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.False(t, verification.Verified)
+	assert.Equal(t, dropped, verification.StoredHash)
+	assert.NotEqual(t, dropped, verification.RecomputedHash)
+
+	_, err = contract.VerifyTallyResult(ctx, "election-002")
+	assert.Error(t, err)
+}
+
+func storeCompletedTally(t *testing.T, ctx *MockTransactionContext, stub *MockStub, election *Election, voteCounts string) {
+	election.Status = "closed"
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+	setVoteCount(stub, "election-001", 1000)
+	assert.NoError(t, new(VoteContract).StoreTallyResult(ctx, "election-001", voteCounts, "hash", "proof"))
+}
+
+func TestGetElectionResultsClearWinner(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	storeCompletedTally(t, ctx, stub, createMockElection(), `{"1": 50, "2": 30, "3": 20}`)
+
+	results, err := contract.GetElectionResults(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 100, results.TotalVotes)
+	assert.Len(t, results.Questions, 1)
+
+	question := results.Questions[0]
+	assert.Equal(t, DefaultQuestionID, question.QuestionID)
+	assert.Equal(t, []string{"1"}, question.Winners)
+	assert.Equal(t, OptionResult{Option: "1", Votes: 50, Percentage: 50}, question.Options[0])
+	assert.Equal(t, 20.0, question.Options[2].Percentage)
+}
+
+func TestGetElectionResultsTie(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Questions = []Question{
+		{ID: "mayor", Options: []string{"alice", "bob", "carol"}},
+		{ID: "budget", Options: []string{"yes", "no"}},
+	}
+	storeCompletedTally(t, ctx, stub, election, `{"mayor": {"alice": 2, "bob": 1, "carol": 2}, "budget": {}}`)
+
+	results, err := contract.GetElectionResults(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Len(t, results.Questions, 2)
+
+	mayor := results.Questions[0]
+	assert.Equal(t, []string{"alice", "carol"}, mayor.Winners)
+	assert.Equal(t, 40.0, mayor.Options[0].Percentage)
+	assert.Equal(t, 20.0, mayor.Options[1].Percentage)
+
+	// No votes means no winner and no division by zero
+	budget := results.Questions[1]
+	assert.Empty(t, budget.Winners)
+	assert.Equal(t, 0.0, budget.Options[0].Percentage)
+}
+
+func TestGetElectionResultsAbstentionsAndSpoiled(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Questions = []Question{
+		{ID: "mayor", Options: []string{"alice", "bob"}},
+		{ID: "budget", Options: []string{"yes", "no"}},
+	}
+	storeCompletedTally(t, ctx, stub, election,
+		`{"mayor": {"alice": 3, "bob": 2, "__abstain__": 4, "__spoiled__": 1}, "budget": {"yes": 6, "__spoiled__": 2}}`)
+
+	tally, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"alice": 3, "bob": 2}, tally.VoteCounts["mayor"])
+	assert.Equal(t, map[string]int{"mayor": 4}, tally.Abstentions)
+	assert.Equal(t, map[string]int{"mayor": 1, "budget": 2}, tally.Spoiled)
+	assert.Equal(t, 10, tally.TotalVotes)
+
+	results, err := contract.GetElectionResults(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// Abstentions outnumber every candidate but never win
+	mayor := results.Questions[0]
+	assert.Equal(t, []string{"alice"}, mayor.Winners)
+	assert.Equal(t, 10, mayor.TotalVotes)
+	assert.Equal(t, 5, mayor.ValidVotes)
+	assert.Equal(t, 4, mayor.Abstentions)
+	assert.Equal(t, 1, mayor.Spoiled)
+	assert.Equal(t, 60.0, mayor.Options[0].Percentage)
+	assert.Len(t, mayor.Options, 2)
+
+	budget := results.Questions[1]
+	assert.Equal(t, 8, budget.TotalVotes)
+	assert.Equal(t, 0, budget.Abstentions)
+	assert.Equal(t, 100.0, budget.Options[0].Percentage)
+}
+
+func TestReservedOptionsAreNotCandidates(t *testing.T) {
+	err := validateQuestions([]Question{{ID: "mayor", Options: []string{"alice", OptionAbstain}}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "reserved option")
+}
+
+func TestStoreTallyResultRejectsUnregisteredOption(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Status = "closed"
+	election.Options = []string{"Candidate 1", "Candidate 2", "Candidate 3"}
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+	setVoteCount(stub, "election-001", 6)
+
+	err := contract.StoreTallyResult(ctx, "election-001", `{"Candidate 1": 5, "Canddiate 4": 2}`, "hash", "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown option Canddiate 4")
+
+	// Reserved keys are not candidates and stay allowed
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"Candidate 1": 5, "__abstain__": 1}`, "hash", "proof"))
+}
+
+func TestGetElectionResultsListsZeroVoteOptions(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Options = []string{"Candidate 1", "Candidate 2", "Candidate 3"}
+	storeCompletedTally(t, ctx, stub, election, `{"Candidate 1": 4, "Candidate 3": 6}`)
+
+	results, err := contract.GetElectionResults(ctx, "election-001")
+	assert.NoError(t, err)
+	question := results.Questions[0]
+	assert.Len(t, question.Options, 3)
+	assert.Equal(t, OptionResult{Option: "Candidate 2", Votes: 0, Percentage: 0}, question.Options[1])
+	assert.Equal(t, []string{"Candidate 3"}, question.Winners)
+}
+
+func TestCreateElectionValidatesOptions(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	ctx.On("GetStub").Return(NewMockStub())
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	create := func(config ElectionConfig) error {
+		configJSON, _ := json.Marshal(config)
+		_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, string(configJSON))
+		return err
+	}
+
+	assert.Error(t, create(ElectionConfig{Options: []string{"A", "A"}}))
+	assert.Error(t, create(ElectionConfig{Options: []string{"A", OptionSpoiled}}))
+	assert.Error(t, create(ElectionConfig{
+		Options:   []string{"A"},
+		Questions: []Question{{ID: "q1", Options: []string{"X", "Y"}}},
+	}))
+	assert.NoError(t, create(ElectionConfig{Options: []string{"A", "B"}}))
+
+	election, err := new(VoteContract).GetElection(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"A", "B"}, election.Options)
+}
+
+func TestGetElectionResultsNotCompleted(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	_, err := contract.GetElectionResults(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not available")
+}
+
+func TestMaxVoters(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, `{"maxVoters": 2}`)
+	assert.NoError(t, err)
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, 2, election.MaxVoters)
+
+	for i := 0; i < 2; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+1)), fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	_, err = contract.CastVote(ctx, "election-001", testVote(3), "nullifier2", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "electorate limit reached")
+
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 2, count)
+}
+
+func TestMaxVotersBatch(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.MaxVoters = 2
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(2), Nullifier: "nullifier1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(3), Nullifier: "nullifier2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Receipts, 1)
+	assert.Len(t, result.Errors, 1)
+	assert.Contains(t, result.Errors[0].Error, "electorate limit reached")
+}
+
+func TestMaxVotersUnlimitedByDefault(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, `{"maxVoters": -1}`)
+	assert.Error(t, err)
+
+	_, err = contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, 0, election.MaxVoters)
+}
+
+func TestUpdateVoterMerkleRoot(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "placeholder", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+
+	err = contract.UpdateVoterMerkleRoot(ctx, "election-001", "finalroot")
+	assert.NoError(t, err)
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "finalroot", election.VoterMerkleRoot)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "voter_root_updated", entries[len(entries)-1].Type)
+	assert.Equal(t, "finalroot", entries[len(entries)-1].Hash)
+
+	err = contract.UpdateVoterMerkleRoot(ctx, "election-001", "")
+	assert.Error(t, err)
+	err = contract.UpdateVoterMerkleRoot(ctx, "election-001", "  \t")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "voter merkle root is required")
+
+	// Non-admins cannot change the roll
+	ctx.Identity = &MockClientIdentity{MSPID: "Org2MSP"}
+	err = contract.UpdateVoterMerkleRoot(ctx, "election-001", "otherroot")
+	assert.Error(t, err)
+}
+
+func TestUpdateVoterMerkleRootRejectedOnceActive(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.UpdateVoterMerkleRoot(ctx, "election-001", "newroot")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only be updated while pending")
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.NotEqual(t, "newroot", election.VoterMerkleRoot)
+}
+
+func TestUpdateElectionPublicKey(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+
+	err = contract.UpdateElectionPublicKey(ctx, "election-001", " "+testLocalPublicKeyJSON())
+	assert.NoError(t, err)
+
+	// The key is stored in canonical form and published on the board
+	canonical, _ := canonicalElGamalPublicKey(testLocalPublicKeyJSON())
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, canonical, election.PublicKey)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "public_key_updated", entries[len(entries)-1].Type)
+	assert.Equal(t, hashString(canonical), entries[len(entries)-1].Hash)
+
+	// Keys are validated like at creation
+	for _, key := range []string{"", "not json", `{"p":"23","q":"11","g":"4","h":"0"}`} {
+		err = contract.UpdateElectionPublicKey(ctx, "election-001", key)
+		assert.Error(t, err, key)
+	}
+	election, _ = contract.GetElection(ctx, "election-001")
+	assert.Equal(t, canonical, election.PublicKey)
+
+	// Non-admins cannot change the key
+	ctx.Identity = &MockClientIdentity{MSPID: "Org2MSP"}
+	err = contract.UpdateElectionPublicKey(ctx, "election-001", testPublicKeyJSON())
+	assert.Error(t, err)
+}
+
+func TestUpdateElectionPublicKeyRejectedOnceActive(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.UpdateElectionPublicKey(ctx, "election-001", testLocalPublicKeyJSON())
+	assert.True(t, errors.Is(err, ErrBallotFrozen))
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, createMockElection().PublicKey, election.PublicKey)
+}
+
+func TestUpdateBallotOptions(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	config, _ := json.Marshal(ElectionConfig{Questions: []Question{
+		{ID: "mayor", Options: []string{"alice", "bob"}},
+		{ID: "budget", Options: []string{"yes", "no"}},
+	}})
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+
+	// A late nomination extends one question
+	assert.NoError(t, contract.UpdateBallotOptions(ctx, "election-001", "mayor", `["alice","bob","carol"]`))
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, []string{"alice", "bob", "carol"}, election.Questions[0].Options)
+	assert.Equal(t, []string{"yes", "no"}, election.Questions[1].Options)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "ballot_options_updated", entries[len(entries)-1].Type)
+
+	for _, update := range [][2]string{
+		{"mayor", `[]`},
+		{"mayor", `["alice","alice"]`},
+		{"mayor", `["` + OptionAbstain + `"]`},
+		{"treasurer", `["dave"]`},
+		{"mayor", `not json`},
+	} {
+		assert.Error(t, contract.UpdateBallotOptions(ctx, "election-001", update[0], update[1]), update[1])
+	}
+	election, _ = contract.GetElection(ctx, "election-001")
+	assert.Equal(t, []string{"alice", "bob", "carol"}, election.Questions[0].Options)
+}
+
+func TestFreezeBallot(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	config, _ := json.Marshal(ElectionConfig{Options: []string{"alice", "bob"}})
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+
+	// Mutations are allowed until the freeze
+	assert.NoError(t, contract.UpdateBallotOptions(ctx, "election-001", DefaultQuestionID, `["alice","bob","carol"]`))
+	assert.NoError(t, contract.UpdateElectionPublicKey(ctx, "election-001", testLocalPublicKeyJSON()))
+
+	assert.NoError(t, contract.FreezeBallot(ctx, "election-001"))
+	params, _ := contract.GetElectionPublicParameters(ctx, "election-001")
+	paramsJSON, _ := json.Marshal(params)
+	paramsHash, _ := hashJSON(HashAlgorithmSHA256, paramsJSON)
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "ballot_frozen", entries[len(entries)-1].Type)
+	assert.Equal(t, paramsHash, entries[len(entries)-1].Hash)
+
+	// Freezing again records nothing new
+	assert.NoError(t, contract.FreezeBallot(ctx, "election-001"))
+	board, _ = contract.GetBulletinBoard(ctx, "election-001")
+	assert.Len(t, board["entries"], len(entries))
+
+	err = contract.UpdateBallotOptions(ctx, "election-001", DefaultQuestionID, `["alice"]`)
+	assert.True(t, errors.Is(err, ErrBallotFrozen))
+	err = contract.UpdateElectionPublicKey(ctx, "election-001", testPublicKeyJSON())
+	assert.True(t, errors.Is(err, ErrBallotFrozen))
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.True(t, election.BallotFrozen)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, election.Options)
+
+	// A frozen election still activates, and the voter roll is not part of the ballot
+	assert.NoError(t, contract.UpdateVoterMerkleRoot(ctx, "election-001", "root2"))
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// An election activated without a freeze is frozen all the same
+	_, err = contract.CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	_, err = contract.ActivateElection(ctx, "election-002")
+	assert.NoError(t, err)
+	err = contract.UpdateBallotOptions(ctx, "election-002", DefaultQuestionID, `["alice","bob","carol"]`)
+	assert.True(t, errors.Is(err, ErrBallotFrozen))
+	assert.True(t, errors.Is(contract.FreezeBallot(ctx, "election-002"), ErrBallotFrozen))
+}
+
+func TestGetAllVotesSortedByNullifierCommitment(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// Inserted out of nullifier order
+	order := []string{"nullifier-d", "nullifier-a", "nullifier-c", "nullifier-b"}
+	ballots := map[string]string{}
+	for i, nullifier := range order {
+		ballots[nullifier] = testVote(int64(i + 1))
+		_, err := contract.CastVote(ctx, "election-001", ballots[nullifier], nullifier, testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		return nullifierCommitment("election-001", order[i]) < nullifierCommitment("election-001", order[j])
+	})
+	expected := make([]string, len(order))
+	for i, nullifier := range order {
+		expected[i] = ballots[nullifier]
+	}
+
+	result, err := contract.GetAllVotes(ctx, "election-001", false)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result["votes"])
+	assert.Equal(t, len(order), result["count"])
+
+	// Records carry what links each ballot back to its vote record
+	records := result["records"].([]VoteRecord)
+	if assert.Len(t, records, len(order)) {
+		for i, nullifier := range order {
+			vote, _ := contract.GetVote(ctx, "election-001", nullifier)
+			assert.Equal(t, VoteRecord{
+				EncryptedVote:       ballots[nullifier],
+				EncryptedVoteHash:   vote.EncryptedVoteHash,
+				NullifierCommitment: nullifierCommitment("election-001", nullifier),
+			}, records[i])
+			assert.Equal(t, hashString(ballots[nullifier]), records[i].EncryptedVoteHash)
+		}
+	}
+
+	// Stable across calls
+	again, _ := contract.GetAllVotes(ctx, "election-001", false)
+	assert.Equal(t, result["votes"], again["votes"])
+	assert.Equal(t, result["records"], again["records"])
+}
+
+// statusChangeEvent returns the status change event, whether emitted under
+// the flat or the namespaced name
+func statusChangeEvent(t *testing.T, stub *MockStub) ElectionStatusChange {
+	name := ElectionStatusChangedEvent
+	for emitted := range stub.Events {
+		if strings.HasPrefix(emitted, ElectionStatusChangedEvent+":") {
+			name = emitted
+		}
+	}
+	payload, ok := stub.Events[name]
+	assert.True(t, ok, "no %s event", ElectionStatusChangedEvent)
+	var change ElectionStatusChange
+	assert.NoError(t, json.Unmarshal(payload, &change))
+	// Clear so the next transition must emit its own event
+	delete(stub.Events, name)
+	return change
+}
+
+func TestElectionStatusChangedEvents(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	steps := []struct {
+		name      string
+		run       func() error
+		oldStatus string
+		newStatus string
+	}{
+		{"create", func() error {
+			_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+			return err
+		}, "", "pending"},
+		{"activate", func() error {
+			setTestOptions(t, ctx, "election-001")
+			_, err := contract.ActivateElection(ctx, "election-001")
+			return err
+		}, "pending", "active"},
+		{"pause", func() error { return contract.PauseElection(ctx, "election-001") }, "active", "paused"},
+		{"resume", func() error { return contract.ResumeElection(ctx, "election-001") }, "paused", "active"},
+		{"close", func() error {
+			_, err := contract.CloseElection(ctx, "election-001")
+			return err
+		}, "active", "closed"},
+	}
+	for _, step := range steps {
+		assert.NoError(t, step.run(), step.name)
+		change := statusChangeEvent(t, stub)
+		assert.Equal(t, "election-001", change.ElectionID, step.name)
+		assert.Equal(t, step.oldStatus, change.OldStatus, step.name)
+		assert.Equal(t, step.newStatus, change.NewStatus, step.name)
+		assert.Equal(t, "mock-tx-id-12345", change.TxID, step.name)
+		assert.False(t, change.Timestamp.IsZero(), step.name)
+	}
+
+	// Completing the tally reports the transition with the tally summary
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 0}`, "hash", "proof"))
+	change := statusChangeEvent(t, stub)
+	assert.Equal(t, "closed", change.OldStatus)
+	assert.Equal(t, "completed", change.NewStatus)
+	assert.Equal(t, 0, change.Tally.TotalVotes)
+	assert.Equal(t, 1, change.Tally.Version)
+
+	// Failed transitions emit nothing
+	assert.Error(t, contract.PauseElection(ctx, "election-001"))
+	_, emitted := stub.Events[ElectionStatusChangedEvent+":election-001"]
+	assert.False(t, emitted)
+}
+
+func TestNamespacedEventNames(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	assert.Contains(t, stub.Events, "ElectionStatusChanged:election-001")
+
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+	_, err = contract.CastVote(ctx, "election-001", testVote(42), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.NotContains(t, stub.Events, "VoteCast")
+	// The payload still names the election
+	var event map[string]interface{}
+	assert.NoError(t, json.Unmarshal(stub.Events["VoteCast:election-001"], &event))
+	assert.Equal(t, "election-001", event["electionId"])
+
+	// The legacy option keeps the flat names
+	config, _ := json.Marshal(ElectionConfig{LegacyEventNames: true})
+	_, err = contract.CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	setTestOptions(t, ctx, "election-002")
+	_, err = contract.ActivateElection(ctx, "election-002")
+	assert.NoError(t, err)
+	_, err = contract.CastVote(ctx, "election-002", testVote(43), "nullifier2", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.Contains(t, stub.Events, "VoteCast")
+	assert.Contains(t, stub.Events, ElectionStatusChangedEvent)
+	assert.NotContains(t, stub.Events, "VoteCast:election-002")
+}
+
+func TestCancelElectionEmitsStatusChanged(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	assert.NoError(t, contract.CancelElection(ctx, "election-001", "fraud in voter rolls"))
+	// Payloads stay small whatever the election holds
+	assert.Less(t, len(stub.Events[ElectionStatusChangedEvent]), 1024)
+	change := statusChangeEvent(t, stub)
+	assert.Equal(t, "active", change.OldStatus)
+	assert.Equal(t, "cancelled", change.NewStatus)
+}
+
+func TestCanTransition(t *testing.T) {
+	statuses := []string{"pending", "active", "paused", "closed", "tallying", "completed", "cancelled", "archived"}
+	allowed := map[string]bool{
+		"pending->active":     true,
+		"pending->cancelled":  true,
+		"active->paused":      true,
+		"active->closed":      true,
+		"active->cancelled":   true,
+		"paused->active":      true,
+		"paused->cancelled":   true,
+		"closed->tallying":    true,
+		"closed->completed":   true,
+		"tallying->completed": true,
+		"completed->tallying": true,
+		"completed->archived": true,
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			transition := from + "->" + to
+			assert.Equal(t, allowed[transition], canTransition(from, to), transition)
+
+			election := &Election{Status: from}
+			err := setStatus(election, to)
+			if allowed[transition] {
+				assert.NoError(t, err, transition)
+				assert.Equal(t, to, election.Status, transition)
+			} else {
+				assert.True(t, errors.Is(err, ErrInvalidTransition), transition)
+				assert.Equal(t, from, election.Status, transition)
+			}
+		}
+	}
+}
+
+func TestStartTallying(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	for _, status := range []string{"pending", "active", "paused", "tallying", "completed", "cancelled", "archived"} {
+		election.Status = status
+		electionJSON, _ := json.Marshal(election)
+		stub.State["election:election-001"] = electionJSON
+		assert.Error(t, contract.StartTallying(ctx, "election-001"), status)
+	}
+
+	election.Status = "closed"
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	ctx.Identity = &MockClientIdentity{MSPID: "Org2MSP"}
+	assert.True(t, errors.Is(contract.StartTallying(ctx, "election-001"), ErrPermissionDenied))
+	ctx.Identity = nil
+
+	assert.NoError(t, contract.StartTallying(ctx, "election-001"))
+	stored, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "tallying", stored.Status)
+	change := statusChangeEvent(t, stub)
+	assert.Equal(t, "closed", change.OldStatus)
+	assert.Equal(t, "tallying", change.NewStatus)
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "tally_started", entries[len(entries)-1].Type)
+
+	// The tally is stored from tallying
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 0}`, "hash", "proof"))
+	stored, _ = contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "completed", stored.Status)
+}
+
+func TestCloseElectionVoteCheckpoint(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		nullifier := fmt.Sprintf("nullifier%d", i)
+		receipt, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)), nullifier,
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		hashes = append(hashes, receipt.EncryptedVoteHash)
+	}
+	// Cast order does not matter, only the set of votes
+	hashes[0], hashes[2] = hashes[2], hashes[0]
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, computeVoteSetRoot(MerkleSchemeV2, hashes), election.ClosedVoteRoot)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "election_closed", entries[len(entries)-2].Type)
+	assert.Equal(t, "votes_checkpoint", entries[len(entries)-1].Type)
+	assert.Equal(t, election.ClosedVoteRoot, entries[len(entries)-1].Hash)
+
+	// A vote slipped in after close is detected against the checkpoint
+	injected, _ := json.Marshal(Vote{ElectionID: "election-001", EncryptedVoteHash: hashString("forged")})
+	injectedKey := voteStateKey("election-001", "forged")
+	stub.State[injectedKey] = injected
+	err = contract.StoreTallyResult(ctx, "election-001", `{"1": 3}`, "hash", "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "changed since the election closed")
+	delete(stub.State, injectedKey)
+
+	// So is a dropped vote
+	droppedKey := voteStateKey("election-001", "nullifier1")
+	dropped := stub.State[droppedKey]
+	delete(stub.State, droppedKey)
+	err = contract.StoreTallyResult(ctx, "election-001", `{"1": 3}`, "hash", "proof")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "changed since the election closed")
+	stub.State[droppedKey] = dropped
+
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 3}`, "hash", "proof"))
+}
+
+func TestStoreTallyResultWithoutVoteCheckpoint(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Elections closed before checkpoints existed are not checked
+	election := createMockElection()
+	election.Status = "closed"
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+	setVoteCount(stub, "election-001", 1)
+	injected, _ := json.Marshal(Vote{ElectionID: "election-001", EncryptedVoteHash: hashString("vote")})
+	stub.State[voteStateKey("election-001", "nullifier0")] = injected
+
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 1}`, "hash", "proof"))
+}
+
+func TestNullifierCommitmentIsElectionScoped(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	for _, electionID := range []string{"election-001", "election-002"} {
+		election := createMockElection()
+		election.ID = electionID
+		electionJSON, _ := json.Marshal(election)
+		stub.State["election:"+electionID] = electionJSON
+	}
+
+	// The same nullifier is usable once in each election
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	_, err = contract.CastVote(ctx, "election-002", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.NotEqual(t, nullifierCommitment("election-001", "nullifier123"), nullifierCommitment("election-002", "nullifier123"))
+
+	_, err = contract.CastVote(ctx, "election-001", testVote(3), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "duplicate nullifier")
+
+	// Neither the key nor the stored record reveals the nullifier
+	key := voteStateKey("election-001", "nullifier123")
+	assert.NotContains(t, key, "nullifier123")
+	assert.NotContains(t, string(stub.State[key]), "nullifier123")
+	for stateKey := range stub.State {
+		assert.NotContains(t, stateKey, "nullifier123")
+	}
+
+	// Lookups still take the plaintext nullifier
+	vote, err := contract.GetVote(ctx, "election-002", "nullifier123")
+	assert.NoError(t, err)
+	assert.Equal(t, hashString(testVote(2)), vote.EncryptedVoteHash)
+	assert.Equal(t, nullifierCommitment("election-002", "nullifier123"), vote.NullifierCommitment)
+
+	verified, err := contract.VerifyVote(ctx, "election-001", "nullifier123", hashString(testVote(1)))
+	assert.NoError(t, err)
+	assert.True(t, verified["verified"].(bool))
+
+	used, err := contract.IsNullifierUsed(ctx, "election-002", "nullifier123")
+	assert.NoError(t, err)
+	assert.True(t, used)
+	used, err = contract.IsNullifierUsed(ctx, "election-003", "nullifier123")
+	assert.NoError(t, err)
+	assert.False(t, used)
+}
+
+// endorsingMSPs decodes a key-level policy into its MSP IDs, checking each
+// principal is a peer role and every one of them must sign
+func endorsingMSPs(t *testing.T, policy []byte) []string {
+	var envelope common.SignaturePolicyEnvelope
+	assert.NoError(t, proto.Unmarshal(policy, &envelope))
+	assert.Equal(t, int32(len(envelope.Identities)), envelope.Rule.GetNOutOf().GetN())
+
+	var mspIDs []string
+	for _, identity := range envelope.Identities {
+		var role msp.MSPRole
+		assert.NoError(t, proto.Unmarshal(identity.Principal, &role))
+		assert.Equal(t, msp.MSPRole_PEER, role.Role)
+		mspIDs = append(mspIDs, role.MspIdentifier)
+	}
+	return mspIDs
+}
+
+func TestSetTallyEndorsementPolicy(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.SetTallyEndorsementPolicy(ctx, "election-001", []string{"TallyOrgMSP", "AuditOrgMSP"})
+	assert.NoError(t, err)
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, []string{"TallyOrgMSP", "AuditOrgMSP"}, election.TallyEndorsers)
+
+	// Voting is still open, so no key-level policy is written yet
+	assert.Empty(t, stub.ValidationParameters)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "tally_endorsers_set", entries[len(entries)-1].Type)
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	expected, err := tallyEndorsementPolicy([]string{"AuditOrgMSP", "TallyOrgMSP"})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, stub.ValidationParameters[electionKey("election-001")])
+	assert.Equal(t, expected, stub.ValidationParameters[tallyKey("election-001")])
+	assert.Equal(t, []string{"AuditOrgMSP", "TallyOrgMSP"}, endorsingMSPs(t, expected))
+
+	// Storing the tally sets the policy on the now existing tally key
+	delete(stub.ValidationParameters, tallyKey("election-001"))
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 0}`, "hash", "proof"))
+	assert.Equal(t, expected, stub.ValidationParameters[tallyKey("election-001")])
+}
+
+func TestSetTallyEndorsementPolicyOnClosedElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.Status = "closed"
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.SetTallyEndorsementPolicy(ctx, "election-001", []string{"TallyOrgMSP"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"TallyOrgMSP"}, endorsingMSPs(t, stub.ValidationParameters[tallyKey("election-001")]))
+	assert.Equal(t, []string{"TallyOrgMSP"}, endorsingMSPs(t, stub.ValidationParameters[electionKey("election-001")]))
+
+	err = contract.SetTallyEndorsementPolicy(ctx, "election-001", nil)
+	assert.Error(t, err)
+	err = contract.SetTallyEndorsementPolicy(ctx, "election-001", []string{"TallyOrgMSP", " "})
+	assert.Error(t, err)
+
+	ctx.Identity = &MockClientIdentity{MSPID: "Org2MSP"}
+	err = contract.SetTallyEndorsementPolicy(ctx, "election-001", []string{"Org2MSP"})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"TallyOrgMSP"}, endorsingMSPs(t, stub.ValidationParameters[tallyKey("election-001")]))
+}
+
+func TestCreateElectionWithTallyEndorsers(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime,
+		`{"tallyEndorsers": ["TallyOrgMSP"]}`)
+	assert.NoError(t, err)
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, []string{"TallyOrgMSP"}, election.TallyEndorsers)
+	assert.Empty(t, stub.ValidationParameters)
+
+	_, err = contract.CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(), startTime, endTime,
+		`{"tallyEndorsers": [""]}`)
+	assert.Error(t, err)
+}
+
+func TestComputeVerificationCodeMatchesReceipt(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	code, err := contract.ComputeVerificationCode(ctx, "election-001", receipt.TxID, receipt.EncryptedVoteHash)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.VerificationCode, code)
+	assert.Len(t, code, 16)
+
+	_, err = contract.ComputeVerificationCode(ctx, "election-001", "", receipt.EncryptedVoteHash)
+	assert.Error(t, err)
+	_, err = contract.ComputeVerificationCode(ctx, "election-002", receipt.TxID, receipt.EncryptedVoteHash)
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+}
+
+func TestComputeVerificationCodeFollowsElectionHash(t *testing.T) {
+	for _, algorithm := range []string{HashAlgorithmKeccak256, HashAlgorithmSHA3256} {
+		contract := new(VoteContract)
+		ctx := new(MockTransactionContext)
+		stub := NewMockStub()
+
+		ctx.On("GetStub").Return(stub)
+
+		election := createMockElection()
+		election.HashAlgorithm = algorithm
+		election.VerificationCodeLength = 24
+		electionJSON, _ := json.Marshal(election)
+		stub.State["election:election-001"] = electionJSON
+
+		receipt, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+
+		code, err := contract.ComputeVerificationCode(ctx, "election-001", receipt.TxID, receipt.EncryptedVoteHash)
+		assert.NoError(t, err)
+		assert.Equal(t, receipt.VerificationCode, code, algorithm)
+		assert.Len(t, code, 24)
+		assert.NotEqual(t, generateVerificationCode(HashAlgorithmSHA256, receipt.TxID, receipt.EncryptedVoteHash, 24), code)
+	}
+}
+
+func TestCastVoteRejectsStaleVoterRoot(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "oldroot", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+
+	// The roll is replaced before voting opens
+	assert.NoError(t, contract.UpdateVoterMerkleRoot(ctx, "election-001", "newroot"))
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// A proof built against the superseded roll is rejected on every path
+	_, err = contract.CastVote(ctx, "election-001", testVote(1), "nullifier0", testEligibilityHash, testValidityHash, "oldroot")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "stale voter root")
+	_, err = contract.CastVote(ctx, "election-001", testVote(1), "nullifier0", testEligibilityHash, testValidityHash, "")
+	assert.Error(t, err)
+
+	stub.Transient["encryptedVote"] = []byte(testVote(2))
+	_, err = contract.CastVotePrivate(ctx, "election-001", "nullifier0", testEligibilityHash, testValidityHash, "oldroot")
+	assert.Error(t, err)
+
+	used, _ := contract.IsNullifierUsed(ctx, "election-001", "nullifier0")
+	assert.False(t, used)
+
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier0", testEligibilityHash, testValidityHash, "newroot")
+	assert.NoError(t, err)
+	assert.True(t, receipt.Success)
+
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(3), Nullifier: "nullifier1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: "oldroot"},
+		{EncryptedVote: testVote(4), Nullifier: "nullifier2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: "newroot"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Receipts, 1)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, "nullifier1", result.Errors[0].Nullifier)
+	assert.Contains(t, result.Errors[0].Error, "stale voter root")
+}
+
+func TestVerifyBulletinChain(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	for i := 0; i < 4; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+1)), fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Empty(t, entries[0].PrevEntryHash)
+	assert.Equal(t, bulletinEntryHash(MerkleSchemeV2, entries[0]), entries[1].PrevEntryHash)
+
+	result, err := contract.VerifyBulletinChain(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, true, result["valid"])
+	assert.Equal(t, 4, result["size"])
+
+	// Rewriting an entry breaks the link from the entry after it
+	key := compositeKey("bb", "election-001", "0000000002")
+	original := stub.State[key]
+	tampered := entries[1]
+	tampered.Hash = "forged"
+	stub.State[key], _ = json.Marshal(tampered)
+	result, err = contract.VerifyBulletinChain(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, false, result["valid"])
+	assert.Equal(t, 3, result["firstBreak"])
+	stub.State[key] = original
+
+	// So does removing an entry and shifting the rest down
+	for seq := 2; seq < 4; seq++ {
+		shifted := entries[seq]
+		shifted.Sequence = seq
+		stub.State[compositeKey("bb", "election-001", fmt.Sprintf("%010d", seq))], _ = json.Marshal(shifted)
+	}
+	delete(stub.State, compositeKey("bb", "election-001", "0000000004"))
+	stub.State["bulletinboardseq:election-001"] = []byte("3")
+	result, err = contract.VerifyBulletinChain(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, false, result["valid"])
+	assert.Equal(t, 2, result["firstBreak"])
+
+	// A gap in the keys is reported as a missing entry
+	stub.State["bulletinboardseq:election-001"] = []byte("5")
+	delete(stub.State, compositeKey("bb", "election-001", "0000000002"))
+	result, _ = contract.VerifyBulletinChain(ctx, "election-001")
+	assert.Equal(t, 2, result["firstBreak"])
+	assert.Equal(t, "entry missing", result["reason"])
+}
+
+func TestVerifyBulletinChainSkipsLegacyEntries(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// Entries written before the chain carry no previous hash
+	putBulletinBoard(stub, "election-001", []BulletinBoardEntry{
+		{Sequence: 1, Type: "election_created", Hash: "hash1", TxID: "tx1"},
+		{Sequence: 2, Type: "vote_cast", Hash: "hash2", TxID: "tx2"},
+	})
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	result, err := contract.VerifyBulletinChain(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, true, result["valid"])
+	assert.Equal(t, 3, result["size"])
+}
+
+func TestBulletinBoardEntriesStoredPerKey(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	for i := 0; i < 3; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+1)), fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+	firstEntry := stub.State[compositeKey("bb", "election-001", "0000000001")]
+	assert.NotNil(t, firstEntry)
+
+	// A new vote writes its own entry, the root state and the length, nothing
+	// else of the board
+	stub.Writes = nil
+	_, err := contract.CastVote(ctx, "election-001", testVote(4), "nullifier3", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	var boardWrites []string
+	for _, key := range stub.Writes {
+		if strings.HasPrefix(key, compositeKey("bb", "election-001")) || strings.HasPrefix(key, "bulletinboard") {
+			boardWrites = append(boardWrites, key)
+		}
+	}
+	assert.Equal(t, []string{
+		compositeKey("bb", "election-001", "0000000004"),
+		"bulletinboardtree:election-001",
+		"bulletinboardseq:election-001",
+	}, boardWrites)
+	assert.Equal(t, firstEntry, stub.State[compositeKey("bb", "election-001", "0000000001")])
+	assert.Nil(t, stub.State["bulletinboard:election-001"])
+
+	board, err := contract.GetBulletinBoard(ctx, "election-001")
+	assert.NoError(t, err)
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Len(t, entries, 4)
+	for i, entry := range entries {
+		assert.Equal(t, i+1, entry.Sequence)
+	}
+}
+
+// endorse simulates a transaction against a copy of the committed state, as
+// an endorsing peer does, and returns its stub holding the read and write set
+func endorse(committed map[string][]byte, run func(ctx *MockTransactionContext) error) (*MockStub, error) {
+	stub := NewMockStub()
+	for key, value := range committed {
+		stub.State[key] = value
+	}
+	ctx := new(MockTransactionContext)
+	ctx.On("GetStub").Return(stub)
+	return stub, run(ctx)
+}
+
+// commit applies an endorsed transaction unless a key it read has changed
+// since it was endorsed, which is Fabric's MVCC check
+func commit(committed, snapshot map[string][]byte, tx *MockStub) bool {
+	for _, key := range tx.Reads {
+		if string(committed[key]) != string(snapshot[key]) {
+			return false
+		}
+	}
+	for _, key := range tx.Writes {
+		committed[key] = tx.State[key]
+	}
+	return true
+}
+
+func TestBulletinSequenceUnderInterleavedEndorsement(t *testing.T) {
+	contract := new(VoteContract)
+	committed := make(map[string][]byte)
+	electionJSON, _ := json.Marshal(createMockElection())
+	committed["election:election-001"] = electionJSON
+
+	castVote := func(nullifier string, r int64) func(ctx *MockTransactionContext) error {
+		return func(ctx *MockTransactionContext) error {
+			_, err := contract.CastVote(ctx, "election-001", testVote(r), nullifier, testEligibilityHash, testValidityHash, testVoterRoot)
+			return err
+		}
+	}
+
+	// Both transactions read the board before either commits, so both
+	// propose the same next sequence
+	snapshot := make(map[string][]byte)
+	for key, value := range committed {
+		snapshot[key] = value
+	}
+	first, err := endorse(snapshot, castVote("nullifier1", 1))
+	assert.NoError(t, err)
+	second, err := endorse(snapshot, castVote("nullifier2", 2))
+	assert.NoError(t, err)
+	entryKey := compositeKey("bb", "election-001", "0000000001")
+	assert.Contains(t, first.Writes, entryKey)
+	assert.Contains(t, second.Writes, entryKey)
+
+	// Both read the sequence key, so only the first commits and the second
+	// cannot clobber its entry
+	assert.True(t, commit(committed, snapshot, first))
+	assert.False(t, commit(committed, snapshot, second))
+
+	// Resubmitted against the committed state, it gets the next sequence
+	retry, err := endorse(committed, castVote("nullifier2", 2))
+	assert.NoError(t, err)
+	assert.True(t, commit(committed, committed, retry))
+
+	stub := NewMockStub()
+	stub.State = committed
+	ctx := new(MockTransactionContext)
+	ctx.On("GetStub").Return(stub)
+	board, err := contract.GetBulletinBoard(ctx, "election-001")
+	assert.NoError(t, err)
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Len(t, entries, 2)
+	for i, entry := range entries {
+		assert.Equal(t, i+1, entry.Sequence)
+	}
+	assert.NotEqual(t, entries[0].Hash, entries[1].Hash)
+	verification, err := contract.VerifyBulletinChain(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, true, verification["valid"])
+
+	// A sequence key behind its entries is refused rather than overwriting
+	stub.State["bulletinboardseq:election-001"] = []byte("1")
+	_, err = contract.CastVote(ctx, "election-001", testVote(3), "nullifier3", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "sequence 2 of election election-001 is already taken")
+}
+
+func TestGetBulletinBoardRange(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	entries := make([]BulletinBoardEntry, 12)
+	for i := range entries {
+		entries[i] = BulletinBoardEntry{Sequence: i + 1, Type: "vote_cast", Hash: fmt.Sprintf("hash%d", i+1), TxID: "tx"}
+	}
+	putBulletinBoard(stub, "election-001", entries)
+
+	result, err := contract.GetBulletinBoardRange(ctx, "election-001", 3, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, entries[2:10], result["entries"])
+	assert.Equal(t, 12, result["total"])
+	assert.Equal(t, computeMerkleRoot(MerkleSchemeV1, entries[2:10]), result["merkleRoot"])
+
+	// The end of the range is clamped to the board
+	result, err = contract.GetBulletinBoardRange(ctx, "election-001", 11, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, entries[10:], result["entries"])
+
+	result, err = contract.GetBulletinBoardRange(ctx, "election-001", 13, 20)
+	assert.NoError(t, err)
+	assert.Empty(t, result["entries"])
+
+	_, err = contract.GetBulletinBoardRange(ctx, "election-001", 0, 5)
+	assert.Error(t, err)
+	_, err = contract.GetBulletinBoardRange(ctx, "election-001", 5, 4)
+	assert.Error(t, err)
+
+	// Numeric sequences order correctly past nine entries
+	var paged []BulletinBoardEntry
+	bookmark := ""
+	for {
+		page, err := contract.GetBulletinBoardPaginated(ctx, "election-001", 5, bookmark)
+		assert.NoError(t, err)
+		paged = append(paged, page["entries"].([]BulletinBoardEntry)...)
+		bookmark = page["bookmark"].(string)
+		if bookmark == "" {
+			break
+		}
+	}
+	assert.Equal(t, entries, paged)
+
+	_, err = contract.GetBulletinBoardPaginated(ctx, "election-001", 0, "")
+	assert.Error(t, err)
+}
+
+func TestMigrateBulletinBoard(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// Legacy layout: the whole board as one array
+	legacy := []BulletinBoardEntry{
+		{Sequence: 1, Type: "election_created", Hash: "hash1", TxID: "tx1"},
+		{Sequence: 2, Type: "vote_cast", Hash: "hash2", TxID: "tx2"},
+	}
+	stub.State["bulletinboard:election-001"], _ = json.Marshal(legacy)
+
+	// Appending before migration would restart the sequence
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier0", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "MigrateBulletinBoard")
+
+	migrated, err := contract.MigrateBulletinBoard(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, migrated)
+	assert.Nil(t, stub.State["bulletinboard:election-001"])
+
+	// The mock does not roll back the failed transaction, so use a fresh nullifier
+	_, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, legacy, entries[:2])
+	assert.Equal(t, 3, entries[2].Sequence)
+	assert.Equal(t, computeMerkleRoot(MerkleSchemeV1, entries), board["merkleRoot"])
+
+	// Nothing left to migrate
+	migrated, err = contract.MigrateBulletinBoard(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, migrated)
+}
+
+func TestReopenTally(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	storeCompletedTally(t, ctx, stub, createMockElection(), `{"1": 50, "2": 30}`)
+	first, _ := contract.GetTallyResult(ctx, "election-001")
+	assert.Equal(t, 1, first.Version)
+
+	err := contract.ReopenTally(ctx, "election-001", "")
+	assert.Error(t, err)
+
+	err = contract.ReopenTally(ctx, "election-001", "decryption share 3 was invalid")
+	assert.NoError(t, err)
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, "tallying", election.Status)
+	change := statusChangeEvent(t, stub)
+	assert.Equal(t, "completed", change.OldStatus)
+	assert.Equal(t, "tallying", change.NewStatus)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "tally_reopened", entries[len(entries)-1].Type)
+	assert.Equal(t, hashString("decryption share 3 was invalid"), entries[len(entries)-1].Hash)
+
+	// The superseded result is kept and no result is current until the recount
+	_, err = contract.GetTallyResult(ctx, "election-001")
+	assert.Error(t, err)
+	archived, err := contract.GetTallyResultVersion(ctx, "election-001", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, first, archived)
+	assert.NotNil(t, stub.State["tally:election-001:v1"])
+
+	// The recount is stored as the next version
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 49, "2": 31}`, "hash", "proof"))
+	second, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, second.Version)
+	assert.Equal(t, 49, second.VoteCounts[DefaultQuestionID]["1"])
+
+	current, err := contract.GetTallyResultVersion(ctx, "election-001", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, second, current)
+	archived, _ = contract.GetTallyResultVersion(ctx, "election-001", 1)
+	assert.Equal(t, 50, archived.VoteCounts[DefaultQuestionID]["1"])
+	_, err = contract.GetTallyResultVersion(ctx, "election-001", 3)
+	assert.Error(t, err)
+
+	// A second recount archives version 2 next to version 1
+	assert.NoError(t, contract.ReopenTally(ctx, "election-001", "recount requested"))
+	assert.NotNil(t, stub.State["tally:election-001:v1"])
+	assert.NotNil(t, stub.State["tally:election-001:v2"])
+}
+
+func TestReopenTallyRequiresCompletedElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	err := contract.ReopenTally(ctx, "election-001", "recount")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "only completed elections")
+
+	// Results stored before versioning are archived as version 1
+	election := createMockElection()
+	election.Status = "completed"
+	electionJSON, _ = json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+	legacyJSON, _ := json.Marshal(&TallyResult{ElectionID: "election-001", TotalVotes: 10})
+	stub.State["tally:election-001"] = legacyJSON
+
+	ctx.Identity = &MockClientIdentity{MSPID: "Org2MSP"}
+	assert.Error(t, contract.ReopenTally(ctx, "election-001", "recount"))
+	ctx.Identity = nil
+
+	assert.NoError(t, contract.ReopenTally(ctx, "election-001", "recount"))
+	archived, err := contract.GetTallyResultVersion(ctx, "election-001", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, archived.Version)
+	assert.Equal(t, 10, archived.TotalVotes)
+}
+
+func TestCreateElectionValidatesVotingWindow(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	now := time.Now()
+	format := func(d time.Duration) string { return now.Add(d).Format(time.RFC3339) }
+
+	for name, window := range map[string][2]string{
+		"reversed":      {format(2 * time.Hour), format(time.Hour)},
+		"zero length":   {format(time.Hour), format(time.Hour)},
+		"already ended": {format(-3 * time.Hour), format(-time.Hour)},
+		"distant past":  {format(-30 * 24 * time.Hour), format(24 * time.Hour)},
+	} {
+		_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), window[0], window[1])
+		assert.Error(t, err, name)
+		assert.Nil(t, stub.State["election:election-001"], name)
+	}
+
+	// A window that opened recently or opens later is accepted
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), format(-time.Hour), format(time.Hour))
+	assert.NoError(t, err)
+	_, err = contract.CreateElection(ctx, "election-002", "Test", "root", testPublicKeyJSON(), format(24*time.Hour), format(48*time.Hour))
+	assert.NoError(t, err)
+}
+
+func TestCreateElectionMinimumDuration(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	start := time.Now().Add(time.Hour)
+	startTime := start.Format(time.RFC3339)
+
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, start.Add(30*time.Minute).Format(time.RFC3339), `{"minDurationMinutes": 60}`)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "at least 60 minutes")
+
+	_, err = contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, start.Add(time.Hour).Format(time.RFC3339), `{"minDurationMinutes": 60}`)
+	assert.NoError(t, err)
+
+	_, err = contract.CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+		startTime, start.Add(time.Hour).Format(time.RFC3339), `{"minDurationMinutes": -1}`)
+	assert.Error(t, err)
+}
+
+func TestGetElectionsByStatus(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	for _, id := range []string{"election-001", "election-002", "election-003", "election-004"} {
+		_, err := contract.CreateElection(ctx, id, "Test", "root", testPublicKeyJSON(), startTime, endTime)
+		assert.NoError(t, err)
+	}
+	setTestOptions(t, ctx, "election-002")
+	_, err := contract.ActivateElection(ctx, "election-002")
+	assert.NoError(t, err)
+	setTestOptions(t, ctx, "election-003")
+	_, err = contract.ActivateElection(ctx, "election-003")
+	assert.NoError(t, err)
+	_, err = contract.CloseElection(ctx, "election-003")
+	assert.NoError(t, err)
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-003", `{"1": 0}`, "hash", "proof"))
+	assert.NoError(t, contract.CancelElection(ctx, "election-004", "duplicate"))
+
+	ids := func(status string) []string {
+		elections, err := contract.GetElectionsByStatus(ctx, status)
+		assert.NoError(t, err)
+		result := []string{}
+		for _, election := range elections {
+			assert.Equal(t, status, election.Status)
+			result = append(result, election.ID)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"election-001"}, ids("pending"))
+	assert.Equal(t, []string{"election-002"}, ids("active"))
+	assert.Equal(t, []string{}, ids("closed"))
+	assert.Equal(t, []string{"election-003"}, ids("completed"))
+	assert.Equal(t, []string{"election-004"}, ids("cancelled"))
+
+	// Pausing and resuming keeps the index in step
+	assert.NoError(t, contract.PauseElection(ctx, "election-002"))
+	assert.Equal(t, []string{}, ids("active"))
+	assert.Equal(t, []string{"election-002"}, ids("paused"))
+	assert.NoError(t, contract.ResumeElection(ctx, "election-002"))
+	assert.Equal(t, []string{"election-002"}, ids("active"))
+
+	_, err = contract.GetElectionsByStatus(ctx, "")
+	assert.Error(t, err)
+}
+
+func TestMigrateElectionStatusIndex(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Stored before the index existed
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	elections, err := contract.GetElectionsByStatus(ctx, "active")
+	assert.NoError(t, err)
+	assert.Empty(t, elections)
+
+	assert.NoError(t, contract.MigrateElectionStatusIndex(ctx, "election-001"))
+	elections, err = contract.GetElectionsByStatus(ctx, "active")
+	assert.NoError(t, err)
+	assert.Len(t, elections, 1)
+
+	// Later transitions move the migrated entry
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	elections, _ = contract.GetElectionsByStatus(ctx, "active")
+	assert.Empty(t, elections)
+	elections, _ = contract.GetElectionsByStatus(ctx, "closed")
+	assert.Len(t, elections, 1)
+}
+
+func TestVerificationCodeLength(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	// Out of range lengths are rejected
+	for _, length := range []int{4, 40} {
+		config, _ := json.Marshal(ElectionConfig{VerificationCodeLength: length})
+		_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, string(config))
+		assert.Error(t, err)
+	}
+
+	// The effective length is stored, including the default
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, DefaultVerificationCodeLength, election.VerificationCodeLength)
+
+	config, _ := json.Marshal(ElectionConfig{VerificationCodeLength: 24})
+	_, err = contract.CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	setTestOptions(t, ctx, "election-002")
+	_, err = contract.ActivateElection(ctx, "election-002")
+	assert.NoError(t, err)
+
+	receipt, err := contract.CastVote(ctx, "election-002", testVote(1), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.Len(t, receipt.VerificationCode, 24)
+
+	vote, err := contract.GetVoteByVerificationCode(ctx, "election-002", receipt.VerificationCode)
+	assert.NoError(t, err)
+	assert.Equal(t, receipt.EncryptedVoteHash, vote.EncryptedVoteHash)
+
+	_, err = contract.GetVoteByVerificationCode(ctx, "election-002", "0000")
+	assert.True(t, errors.Is(err, ErrVoteNotFound))
+}
+
+func TestVerificationCodeCollision(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	// Another vote already holds the code this ballot would get
+	encryptedVote := testVote(1)
+	code := generateVerificationCode("", stub.GetTxID(), hashString(encryptedVote), DefaultVerificationCodeLength)
+	codeKey, _ := verificationCodeKey(ctx, "election-001", code)
+	stub.State[codeKey] = []byte("other-commitment")
+
+	receipt, err := contract.CastVote(ctx, "election-001", encryptedVote, "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.Len(t, receipt.VerificationCode, DefaultVerificationCodeLength+4)
+	assert.True(t, strings.HasPrefix(receipt.VerificationCode, code))
+	assert.Equal(t, []byte("other-commitment"), stub.State[codeKey])
+
+	vote, err := contract.GetVoteByVerificationCode(ctx, "election-001", receipt.VerificationCode)
+	assert.NoError(t, err)
+	assert.Equal(t, nullifierCommitment("election-001", "nullifier1"), vote.NullifierCommitment)
+
+	// Two ballots of one batch that would share a code get distinct ones
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(2), Nullifier: "nullifier2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+		{EncryptedVote: testVote(2), Nullifier: "nullifier3", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, result.Receipts, 2)
+	assert.NotEqual(t, result.Receipts[0].VerificationCode, result.Receipts[1].VerificationCode)
+	assert.True(t, strings.HasPrefix(result.Receipts[1].VerificationCode, result.Receipts[0].VerificationCode))
+}
+
+func TestCastVoteRevote(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.AllowRevote = true
+	election.MaxVoters = 1
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	first, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	// The repeat replaces the vote without counting a second voter
+	second, err := contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.EncryptedVoteHash, second.EncryptedVoteHash)
+
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+	assert.Equal(t, second.EncryptedVoteHash, vote.EncryptedVoteHash)
+
+	count, _ := contract.GetVoteCount(ctx, "election-001")
+	assert.Equal(t, 1, count)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "vote_cast", entries[len(entries)-2].Type)
+	assert.Equal(t, "vote_amended", entries[len(entries)-1].Type)
+	assert.Equal(t, second.EncryptedVoteHash, entries[len(entries)-1].Hash)
+
+	// A new voter is still held to the electorate limit
+	_, err = contract.CastVote(ctx, "election-001", testVote(3), "nullifier456", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "electorate limit")
+
+	// Both versions remain in the key history, newest first from the peer
+	key := voteStateKey("election-001", "nullifier123")
+	base := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	version := func(txID, hash string, minutes int) *queryresult.KeyModification {
+		value, _ := json.Marshal(Vote{EncryptedVoteHash: hash, TxID: txID, Timestamp: base.Add(time.Duration(minutes) * time.Minute)})
+		return &queryresult.KeyModification{TxId: txID, Value: value}
+	}
+	stub.History[key] = []*queryresult.KeyModification{
+		version("tx2", second.EncryptedVoteHash, 10),
+		version("tx1", first.EncryptedVoteHash, 0),
+	}
+	history, err := contract.GetVoteHistory(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, first.EncryptedVoteHash, history[0].EncryptedVoteHash)
+	assert.Equal(t, second.EncryptedVoteHash, history[1].EncryptedVoteHash)
+
+	_, err = contract.GetVoteHistory(ctx, "election-001", "nullifier456")
+	assert.True(t, errors.Is(err, ErrVoteNotFound))
+}
+
+func TestVerifyVoteAfterRevote(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.AllowRevote = true
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	// The voter changes their mind and then returns to the first ballot
+	for _, r := range []int64{1, 2, 1} {
+		_, err := contract.CastVote(ctx, "election-001", testVote(r), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.NoError(t, err)
+
+	result, err := contract.VerifyVote(ctx, "election-001", "nullifier123", vote.EncryptedVoteHash)
+	assert.NoError(t, err)
+	assert.True(t, result["verified"].(bool))
+	proof, ok := result["inclusionProof"].(*MerkleInclusionProof)
+	assert.True(t, ok)
+	assert.Equal(t, vote.BulletinSequence, proof.Sequence)
+	assert.True(t, proof.Verify())
+
+	// The proof is of the amended entry, not the identical first one
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	entries := board["entries"].([]BulletinBoardEntry)
+	assert.Equal(t, "vote_amended", entries[proof.LeafIndex].Type)
+
+	// Looking the ballot up by hash finds re-cast ballots too
+	_, err = contract.CastVote(ctx, "election-001", testVote(3), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	byHash, err := contract.GetVoteInclusionProof(ctx, "election-001", hashString(testVote(3)))
+	assert.NoError(t, err)
+	vote, _ = contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.Equal(t, vote.BulletinSequence, byHash.Sequence)
+	assert.True(t, byHash.Verify())
+}
+func TestCastVoteRevoteDisabled(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	first, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	_, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier123", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrDuplicateNullifier))
+
+	vote, _ := contract.GetVote(ctx, "election-001", "nullifier123")
+	assert.Equal(t, first.EncryptedVoteHash, vote.EncryptedVoteHash)
+
+	board, _ := contract.GetBulletinBoard(ctx, "election-001")
+	for _, entry := range board["entries"].([]BulletinBoardEntry) {
+		assert.NotEqual(t, "vote_amended", entry.Type)
+	}
+}
+
+func TestCastVoteUsesTxTimestamp(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// The wall clock is inside the window throughout
+	election := createMockElection()
+	election.StartTime = time.Now().Add(-1 * time.Hour).Truncate(time.Second)
+	election.EndTime = time.Now().Add(1 * time.Hour).Truncate(time.Second)
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	at := func(t time.Time) {
+		stub.TxTimestamp = &timestamp.Timestamp{Seconds: t.Unix()}
+	}
+
+	at(election.EndTime.Add(time.Second))
+	_, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrElectionEnded))
+
+	at(election.StartTime.Add(-time.Second))
+	_, err = contract.CastVote(ctx, "election-001", testVote(1), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.True(t, errors.Is(err, ErrElectionNotStarted))
+
+	_, err = contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(1), Nullifier: "nullifier1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.True(t, errors.Is(err, ErrElectionNotStarted))
+
+	// Both bounds are inclusive
+	at(election.EndTime)
+	_, err = contract.CastVote(ctx, "election-001", testVote(1), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	at(election.StartTime)
+	_, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier2", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+}
+
+func TestGetElectionSummary(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	_, err := contract.GetElectionSummary(ctx, "election-001")
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err = contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)),
+			fmt.Sprintf("nullifier%d", i), testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+	}
+
+	matchesReads := func(summary *ElectionSummary) {
+		election, _ := contract.GetElection(ctx, "election-001")
+		count, _ := contract.GetVoteCount(ctx, "election-001")
+		root, _ := contract.GetBulletinBoardRoot(ctx, "election-001")
+		_, tallyErr := contract.GetTallyResult(ctx, "election-001")
+
+		assert.Equal(t, "election-001", summary.ElectionID)
+		assert.Equal(t, election.Title, summary.Title)
+		assert.Equal(t, election.Status, summary.Status)
+		assert.Equal(t, election.StartTime, summary.StartTime)
+		assert.Equal(t, election.EndTime, summary.EndTime)
+		assert.Equal(t, count, summary.VoteCount)
+		assert.Equal(t, root["merkleRoot"], summary.MerkleRoot)
+		assert.Equal(t, tallyErr == nil, summary.TallyExists)
+	}
+
+	summary, err := contract.GetElectionSummary(ctx, "election-001")
+	assert.NoError(t, err)
+	matchesReads(summary)
+	assert.Equal(t, "active", summary.Status)
+	assert.Equal(t, 2, summary.VoteCount)
+	assert.False(t, summary.TallyExists)
+
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 2}`, "hash", "proof"))
+
+	summary, err = contract.GetElectionSummary(ctx, "election-001")
+	assert.NoError(t, err)
+	matchesReads(summary)
+	assert.Equal(t, "completed", summary.Status)
+	assert.True(t, summary.TallyExists)
+}
+
+func TestGetElectionPublicParameters(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	_, err := contract.GetElectionPublicParameters(ctx, "election-001")
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+
+	config, _ := json.Marshal(ElectionConfig{
+		Questions: []Question{
+			{ID: "federal", Options: []string{"alice", "bob"}},
+			{ID: "local", Options: []string{"yes", "no"}, PublicKey: testLocalPublicKeyJSON()},
+		},
+		HashAlgorithm:    HashAlgorithmKeccak256,
+		RangeProofScheme: RangeProofDisjunctiveChaumPedersen,
+		NullifierFormat:  "hex",
+	})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err = contract.CreateElectionWithConfig(ctx, "election-001", "Test", "voter-root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+
+	params, err := contract.GetElectionPublicParameters(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// The test keys list every group parameter, q included
+	var publicKey, localKey PublicKeyJSON
+	_ = json.Unmarshal([]byte(testPublicKeyJSON()), &publicKey)
+	_ = json.Unmarshal([]byte(testLocalPublicKeyJSON()), &localKey)
+	assert.Equal(t, &ElectionPublicParameters{
+		ElectionID:      "election-001",
+		PublicKey:       publicKey,
+		HashAlgorithm:   HashAlgorithmKeccak256,
+		VoterMerkleRoot: "voter-root",
+		VotingMode:      VotingModeSingle,
+		BallotType:      BallotTypePlurality,
+		Questions: []QuestionParameters{
+			{ID: "federal", Options: []string{"alice", "bob"}},
+			{ID: "local", Options: []string{"yes", "no"}, PublicKey: &localKey},
+		},
+		RangeProofScheme: RangeProofDisjunctiveChaumPedersen,
+		BallotRange:      &BallotRange{MaxPerOption: 1},
+		NullifierFormat:  "hex",
+		NullifierLength:  64,
+	}, params)
+
+	// Repeated reads serialize identically
+	first, _ := json.Marshal(params)
+	again, _ := contract.GetElectionPublicParameters(ctx, "election-001")
+	second, _ := json.Marshal(again)
+	assert.Equal(t, string(first), string(second))
+
+	// A single-question election lists its options under the default question
+	stub = NewMockStub()
+	ctx = new(MockTransactionContext)
+	ctx.On("GetStub").Return(stub)
+	config, _ = json.Marshal(ElectionConfig{Options: []string{"1", "2"}})
+	_, err = contract.CreateElectionWithConfig(ctx, "election-001", "Test", "voter-root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	params, err = contract.GetElectionPublicParameters(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, []QuestionParameters{{ID: DefaultQuestionID, Options: []string{"1", "2"}}}, params.Questions)
+	assert.Equal(t, HashAlgorithmSHA256, params.HashAlgorithm)
+	assert.Nil(t, params.BallotRange)
+}
+
+func TestGetCurrentTime(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	stub.TxTimestamp = &timestamp.Timestamp{Seconds: 1767258000, Nanos: 500}
+	now, err := contract.GetCurrentTime(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-01-01T09:00:00.0000005Z", now)
+}
+
+func TestEndorsementsProduceIdenticalState(t *testing.T) {
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	txTimestamp := &timestamp.Timestamp{Seconds: time.Now().Unix(), Nanos: 1234}
+
+	// Each run stands for one endorsing peer simulating the same transactions
+	endorse := func() *MockStub {
+		contract := new(VoteContract)
+		ctx := new(MockTransactionContext)
+		stub := NewMockStub()
+		stub.TxTimestamp = txTimestamp
+
+		ctx.On("GetStub").Return(stub)
+
+		_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+		assert.NoError(t, err)
+		setTestOptions(t, ctx, "election-001")
+		_, err = contract.ActivateElection(ctx, "election-001")
+		assert.NoError(t, err)
+		_, err = contract.CastVoteWithMode(ctx, "election-001", testVote(1), "nullifier1", testEligibilityHash, testValidityHash,
+			"voter1", "", 0, "root")
+		assert.NoError(t, err)
+		_, err = contract.CloseElection(ctx, "election-001")
+		assert.NoError(t, err)
+		assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", `{"1": 1}`, "hash", "proof"))
+		return stub
+	}
+
+	first := endorse()
+	time.Sleep(2 * time.Millisecond)
+	second := endorse()
+
+	assert.Equal(t, first.State, second.State)
+	assert.Equal(t, first.Events, second.Events)
+}
+
+func TestSignedVoteReceipts(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	// The seed must be a full Ed25519 seed
+	stub.Transient["receiptSigningKey"] = []byte("short")
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.Error(t, err)
+
+	stub.Transient["receiptSigningKey"] = []byte(strings.Repeat("k", 32))
+	_, err = contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	delete(stub.Transient, "receiptSigningKey")
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Len(t, election.ReceiptPublicKey, 64)
+	assert.NotContains(t, string(stub.State["election:election-001"]), strings.Repeat("k", 32))
+
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier1", testEligibilityHash, testValidityHash, "root")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, receipt.Signature)
+
+	receiptJSON, _ := json.Marshal(receipt)
+	valid, err := contract.VerifyReceiptSignature(ctx, "election-001", string(receiptJSON), receipt.Signature)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// A gateway cannot alter what the receipt attests to
+	tampered := *receipt
+	tampered.EncryptedVoteHash = hashString("other ballot")
+	tamperedJSON, _ := json.Marshal(tampered)
+	valid, err = contract.VerifyReceiptSignature(ctx, "election-001", string(tamperedJSON), receipt.Signature)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	tampered = *receipt
+	tampered.Timestamp = receipt.Timestamp.Add(time.Second)
+	tamperedJSON, _ = json.Marshal(tampered)
+	valid, _ = contract.VerifyReceiptSignature(ctx, "election-001", string(tamperedJSON), receipt.Signature)
+	assert.False(t, valid)
+
+	valid, _ = contract.VerifyReceiptSignature(ctx, "election-001", string(receiptJSON), strings.Repeat("0", 128))
+	assert.False(t, valid)
+
+	// Batch receipts are signed too
+	result, err := contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(2), Nullifier: "nullifier2", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: "root"},
+	})
+	assert.NoError(t, err)
+	batchJSON, _ := json.Marshal(result.Receipts[0])
+	valid, _ = contract.VerifyReceiptSignature(ctx, "election-001", string(batchJSON), result.Receipts[0].Signature)
+	assert.True(t, valid)
+}
+
+func TestGetVoteReceipt(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	stub.Transient["receiptSigningKey"] = []byte(strings.Repeat("k", 32))
+	_, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	delete(stub.Transient, "receiptSigningKey")
+	setTestOptions(t, ctx, "election-001")
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	original, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier1", testEligibilityHash, testValidityHash, "root")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, original.Signature)
+
+	receipt, err := contract.GetVoteReceipt(ctx, "election-001", "nullifier1")
+	assert.NoError(t, err)
+	assert.Equal(t, original, receipt)
+
+	_, err = contract.GetVoteReceipt(ctx, "election-001", "nullifier404")
+	assert.True(t, errors.Is(err, ErrVoteNotFound))
+	_, err = contract.GetVoteReceipt(ctx, "election-404", "nullifier1")
+	assert.True(t, errors.Is(err, ErrElectionNotFound))
+
+	// Unsigned receipts are rebuilt the same way
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+	original, err = contract.CastVote(ctx, "election-001", testVote(2), "nullifier2", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	receipt, err = contract.GetVoteReceipt(ctx, "election-001", "nullifier2")
+	assert.NoError(t, err)
+	assert.Equal(t, original, receipt)
+	assert.Empty(t, receipt.Signature)
+}
+
+func TestUnsignedVoteReceipts(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+
+	receipt, err := contract.CastVote(ctx, "election-001", testVote(1), "nullifier1", testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.Empty(t, receipt.Signature)
+
+	receiptJSON, _ := json.Marshal(receipt)
+	_, err = contract.VerifyReceiptSignature(ctx, "election-001", string(receiptJSON), "00")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not sign receipts")
+}
+
+func TestAdminCallsReturnElection(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+
+	created, err := contract.CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(), startTime, endTime)
+	assert.NoError(t, err)
+	assert.Equal(t, "election-001", created.ID)
+	assert.Equal(t, "pending", created.Status)
+	stored, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, stored, created)
+
+	setTestOptions(t, ctx, "election-001")
+	activated, err := contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, "active", activated.Status)
+
+	_, err = contract.CastVote(ctx, "election-001", testVote(10), "nullifier1",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+
+	closed, err := contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, "closed", closed.Status)
+	assert.NotEmpty(t, closed.ClosedVoteRoot)
+	stored, _ = contract.GetElection(ctx, "election-001")
+	assert.Equal(t, stored, closed)
+
+	// The bulletin board still records the lifecycle
+	entries, err := contract.getBulletinBoardEntries(ctx, "election-001")
+	assert.NoError(t, err)
+	var types []string
+	for _, entry := range entries {
+		types = append(types, entry.Type)
+	}
+	assert.Contains(t, types, "election_created")
+	assert.Contains(t, types, "election_closed")
+
+	// A failed call returns no election
+	again, err := contract.CloseElection(ctx, "election-001")
+	assert.Error(t, err)
+	assert.Nil(t, again)
 }
@@ -0,0 +1,105 @@
+/*
+ * Vote Count - Sharded vote counter
+ *
+ * A single counter key is read and written by every CastVote, so concurrent
+ * votes in different transactions fail MVCC validation against each other.
+ * The count is instead striped over VoteCountShards keys; each vote updates
+ * only the shard its nullifier hashes to, and GetVoteCount sums the shards.
+ * Two votes conflict on the counter only when their nullifiers share a shard.
+ *
+ * This takes the counter out of the way, but it does not make CastVote
+ * scale: every vote also appends to the bulletin board, and every append
+ * reads and writes the board's sequence and tree keys (see
+ * appendBulletinBoardItems). Concurrent votes therefore still commit one
+ * per board key version and the rest fail MVCC validation and must be
+ * resubmitted; CastVoteBatch is the way to raise throughput.
+ *
+ * The unsharded votecount:electionID key is still read as the base of the
+ * sum, so elections created before sharding keep their counts.
+ */
+
+package contracts
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Vote counter shards per election. 0 in a stored election means the single
+// unsharded counter key.
+const (
+	DefaultVoteCountShards = 16
+	MaxVoteCountShards     = 256
+)
+
+func voteCountShardKey(electionID string, shard int) string {
+	return fmt.Sprintf("votecount:%s:shard%d", electionID, shard)
+}
+
+// voteCounterKey returns the counter key a vote with this nullifier
+// commitment updates
+func voteCounterKey(election *Election, commitment string) string {
+	if election.VoteCountShards <= 0 {
+		return voteCountKey(election.ID)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(commitment))
+	return voteCountShardKey(election.ID, int(h.Sum32()%uint32(election.VoteCountShards)))
+}
+
+// countVotes sums the unsharded counter and every shard of an election
+func countVotes(ctx contractapi.TransactionContextInterface, election *Election) (int, error) {
+	total, err := readCounter(ctx, voteCountKey(election.ID))
+	if err != nil {
+		return 0, err
+	}
+	for shard := 0; shard < election.VoteCountShards; shard++ {
+		count, err := readCounter(ctx, voteCountShardKey(election.ID, shard))
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// addToVoteCounters adds each delta to its counter key. Keys are updated in
+// sorted order so the write set does not depend on map iteration.
+func addToVoteCounters(ctx contractapi.TransactionContextInterface, deltas map[string]int) error {
+	keys := make([]string, 0, len(deltas))
+	for key := range deltas {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		count, err := readCounter(ctx, key)
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().PutState(key, []byte(strconv.Itoa(count+deltas[key]))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readCounter(ctx contractapi.TransactionContextInterface, key string) (int, error) {
+	countBytes, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read vote count: %v", err)
+	}
+	if countBytes == nil {
+		return 0, nil
+	}
+
+	count, err := strconv.Atoi(string(countBytes))
+	if err != nil {
+		return 0, fmt.Errorf("invalid vote count: %v", err)
+	}
+	return count, nil
+}
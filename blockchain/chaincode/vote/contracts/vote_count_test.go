@@ -0,0 +1,134 @@
+/*
+ * Vote Count Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counterWrites returns the vote counter keys among the stub's writes
+func counterWrites(stub *MockStub) []string {
+	var keys []string
+	for _, key := range stub.Writes {
+		if strings.HasPrefix(key, "votecount:") {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestShardedVoteCount(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	config, _ := json.Marshal(ElectionConfig{Options: []string{"yes"}, VoteCountShards: 4})
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	election, _ := contract.GetElection(ctx, "election-001")
+	assert.Equal(t, 4, election.VoteCountShards)
+
+	// Each vote is its own transaction and writes only its own shard, so
+	// votes on different shards never conflict on the counter
+	shards := make(map[string]int)
+	for i := 0; i < 12; i++ {
+		nullifier := fmt.Sprintf("nullifier%d", i)
+		stub.Writes = nil
+		_, err := contract.CastVote(ctx, "election-001", testVote(int64(i+10)), nullifier,
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+
+		key := voteCounterKey(election, nullifierCommitment("election-001", nullifier))
+		assert.Equal(t, []string{key}, counterWrites(stub))
+		assert.NotEqual(t, voteCountKey("election-001"), key)
+		shards[key]++
+	}
+	assert.Greater(t, len(shards), 1)
+	for key, count := range shards {
+		assert.Equal(t, fmt.Sprint(count), string(stub.State[key]))
+	}
+
+	count, err := contract.GetVoteCount(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 12, count)
+
+	// A batch writes each shard it touches once
+	stub.Writes = nil
+	var batch []EncryptedBallotInput
+	for i := 12; i < 20; i++ {
+		batch = append(batch, EncryptedBallotInput{EncryptedVote: testVote(int64(i + 10)), Nullifier: fmt.Sprintf("nullifier%d", i),
+			EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot})
+	}
+	result, err := contract.CastVoteBatch(ctx, "election-001", batch)
+	assert.NoError(t, err)
+	assert.Len(t, result.Receipts, 8)
+
+	written := make(map[string]bool)
+	for _, key := range counterWrites(stub) {
+		assert.False(t, written[key], "shard %s written twice", key)
+		written[key] = true
+	}
+
+	count, err = contract.GetVoteCount(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 20, count)
+}
+
+func TestVoteCountIncludesUnshardedCounter(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	// Elections stored before sharding count on the single key
+	electionJSON, _ := json.Marshal(createMockElection())
+	stub.State["election:election-001"] = electionJSON
+	setVoteCount(stub, "election-001", 5)
+
+	_, err := contract.CastVote(ctx, "election-001", testVote(10), "nullifier0",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{voteCountKey("election-001")}, counterWrites(stub))
+
+	count, err := contract.GetVoteCount(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, 6, count)
+}
+
+func TestCreateElectionVoteCountShards(t *testing.T) {
+	ctx := new(MockTransactionContext)
+	ctx.On("GetStub").Return(NewMockStub())
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	for _, shards := range []int{-1, MaxVoteCountShards + 1} {
+		config, _ := json.Marshal(ElectionConfig{Options: []string{"yes"}, VoteCountShards: shards})
+		_, err := new(VoteContract).CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+			startTime, endTime, string(config))
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "voteCountShards")
+	}
+
+	_, err := new(VoteContract).CreateElection(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime)
+	assert.NoError(t, err)
+	election, _ := new(VoteContract).GetElection(ctx, "election-001")
+	assert.Equal(t, DefaultVoteCountShards, election.VoteCountShards)
+}
@@ -0,0 +1,152 @@
+/*
+ * Vote Proof - Full proof storage for post-hoc audits
+ *
+ * A vote record only carries the hashes of its eligibility and validity
+ * proofs. Elections created with a ProofStorage mode also keep the proofs
+ * themselves, keyed by nullifier commitment, so an auditor can fetch them
+ * with GetVoteProof and re-run verification long after the vote was cast.
+ *
+ * CastVoteWithProof and CastVoteWeighted store the proofs they verified.
+ * Every other cast function reads them from the transient fields
+ * "eligibilityProof" and "validityProof", which keeps them out of the
+ * proposal; each must hash to the proof hash recorded on the vote.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Where an election keeps the full proofs of its votes
+const (
+	ProofStorageNone    = ""
+	ProofStorageState   = "state"
+	ProofStoragePrivate = "private"
+)
+
+// VoteProofCollection is the private data collection holding proofs of
+// elections with ProofStoragePrivate (see collections_config.json)
+const VoteProofCollection = "voteProofs"
+
+// MaxStoredProofSize bounds each stored proof in bytes
+const MaxStoredProofSize = 64 * 1024
+
+const voteProofObjectType = "voteproof"
+
+// VoteProof holds the full proofs a vote was cast with
+type VoteProof struct {
+	ElectionID          string `json:"electionId"`
+	NullifierCommitment string `json:"nullifierCommitment"`
+	EligibilityProof    string `json:"eligibilityProof"`
+	ValidityProof       string `json:"validityProof"`
+}
+
+func voteProofKey(ctx contractapi.TransactionContextInterface, electionID, commitment string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(voteProofObjectType, []string{electionID, commitment})
+	if err != nil {
+		return "", fmt.Errorf("failed to create vote proof key: %v", err)
+	}
+	return key, nil
+}
+
+func validateProofStorage(mode string) error {
+	switch mode {
+	case ProofStorageNone, ProofStorageState, ProofStoragePrivate:
+		return nil
+	}
+	return fmt.Errorf("unknown proof storage %q", mode)
+}
+
+// submittedVoteProof returns the proofs of a submission, from the
+// submission itself or the transient map, checked against its proof hashes
+func submittedVoteProof(ctx contractapi.TransactionContextInterface, election *Election, sub voteSubmission) (*VoteProof, error) {
+	eligibilityProof, validityProof := sub.EligibilityProof, sub.ValidityProof
+	if eligibilityProof == "" && validityProof == "" {
+		transient, err := ctx.GetStub().GetTransient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transient data: %v", err)
+		}
+		eligibilityProof = string(transient["eligibilityProof"])
+		validityProof = string(transient["validityProof"])
+	}
+
+	for _, proof := range []struct {
+		name, proof, hash string
+	}{
+		{"eligibility", eligibilityProof, sub.EligibilityProofHash},
+		{"validity", validityProof, sub.ValidityProofHash},
+	} {
+		if proof.proof == "" {
+			return nil, fmt.Errorf("election stores proofs; the %s proof must be passed in the transient map", proof.name)
+		}
+		if len(proof.proof) > MaxStoredProofSize {
+			return nil, fmt.Errorf("%s proof of %d bytes exceeds the limit of %d", proof.name, len(proof.proof), MaxStoredProofSize)
+		}
+		if hashForElection(election, proof.proof) != proof.hash {
+			return nil, fmt.Errorf("%s proof does not match its hash", proof.name)
+		}
+	}
+
+	return &VoteProof{
+		ElectionID:       sub.ElectionID,
+		EligibilityProof: eligibilityProof,
+		ValidityProof:    validityProof,
+	}, nil
+}
+
+// storeVoteProof writes a vote's proofs where the election keeps them. An
+// amended vote replaces the proofs of the previous one.
+func storeVoteProof(ctx contractapi.TransactionContextInterface, election *Election, proof *VoteProof) error {
+	key, err := voteProofKey(ctx, election.ID, proof.NullifierCommitment)
+	if err != nil {
+		return err
+	}
+	proofJSON, err := json.Marshal(proof)
+	if err != nil {
+		return err
+	}
+	if election.ProofStorage == ProofStoragePrivate {
+		err = ctx.GetStub().PutPrivateData(VoteProofCollection, key, proofJSON)
+	} else {
+		err = ctx.GetStub().PutState(key, proofJSON)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store vote proof: %v", err)
+	}
+	return nil
+}
+
+// readVoteProof reads the stored proofs of a vote and checks them against
+// the hashes on the vote record
+func readVoteProof(ctx contractapi.TransactionContextInterface, election *Election, vote *Vote) (*VoteProof, error) {
+	key, err := voteProofKey(ctx, election.ID, vote.NullifierCommitment)
+	if err != nil {
+		return nil, err
+	}
+	var proofJSON []byte
+	if election.ProofStorage == ProofStoragePrivate {
+		proofJSON, err = ctx.GetStub().GetPrivateData(VoteProofCollection, key)
+	} else {
+		proofJSON, err = ctx.GetStub().GetState(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vote proof: %w", err)
+	}
+	if proofJSON == nil {
+		return nil, fmt.Errorf("%w for vote %s", ErrVoteProofNotFound, vote.EncryptedVoteHash)
+	}
+
+	var proof VoteProof
+	if err := json.Unmarshal(proofJSON, &proof); err != nil {
+		return nil, err
+	}
+	if hashForElection(election, proof.EligibilityProof) != vote.EligibilityProofHash ||
+		hashForElection(election, proof.ValidityProof) != vote.ValidityProofHash {
+		return nil, fmt.Errorf("stored proof does not match the vote's proof hashes")
+	}
+	return &proof, nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Vote Proof Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupProofStorageElection(storage string) (*VoteContract, *MockTransactionContext, *MockStub) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	election.ProofStorage = storage
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	return contract, ctx, stub
+}
+
+func TestGetVoteProof(t *testing.T) {
+	for _, storage := range []string{ProofStorageState, ProofStoragePrivate} {
+		contract, ctx, stub := setupProofStorageElection(storage)
+
+		eligibilityProof := `{"proof":"eligibility"}`
+		validityProof := `{"proof":"validity"}`
+		stub.Transient["eligibilityProof"] = []byte(eligibilityProof)
+		stub.Transient["validityProof"] = []byte(validityProof)
+		_, err := contract.CastVote(ctx, "election-001", testVote(10), "nullifier0",
+			hashString(eligibilityProof), hashString(validityProof), testVoterRoot)
+		assert.NoError(t, err, storage)
+
+		proof, err := contract.GetVoteProof(ctx, "election-001", "nullifier0")
+		assert.NoError(t, err, storage)
+		assert.Equal(t, eligibilityProof, proof.EligibilityProof)
+		assert.Equal(t, validityProof, proof.ValidityProof)
+
+		// The stored proofs hash to the ones recorded on the vote
+		vote, _ := contract.GetVote(ctx, "election-001", "nullifier0")
+		assert.Equal(t, vote.EligibilityProofHash, hashString(proof.EligibilityProof))
+		assert.Equal(t, vote.ValidityProofHash, hashString(proof.ValidityProof))
+		assert.Equal(t, vote.NullifierCommitment, proof.NullifierCommitment)
+
+		key := compositeKey("voteproof", "election-001", vote.NullifierCommitment)
+		if storage == ProofStoragePrivate {
+			assert.Nil(t, stub.State[key])
+			assert.NotNil(t, stub.PrivateState[VoteProofCollection][key])
+		} else {
+			assert.NotNil(t, stub.State[key])
+		}
+
+		// A proof altered after the fact no longer matches the vote
+		tampered, _ := json.Marshal(VoteProof{ElectionID: "election-001", NullifierCommitment: vote.NullifierCommitment,
+			EligibilityProof: eligibilityProof, ValidityProof: `{"proof":"forged"}`})
+		if storage == ProofStoragePrivate {
+			stub.PrivateState[VoteProofCollection][key] = tampered
+		} else {
+			stub.State[key] = tampered
+		}
+		_, err = contract.GetVoteProof(ctx, "election-001", "nullifier0")
+		assert.Error(t, err, storage)
+	}
+}
+
+func TestCastVoteRejectsBadStoredProofs(t *testing.T) {
+	contract, ctx, stub := setupProofStorageElection(ProofStorageState)
+
+	eligibilityProof := "eligibility"
+	validityProof := "validity"
+	cast := func(nullifier string) error {
+		_, err := contract.CastVote(ctx, "election-001", testVote(10), nullifier,
+			hashString(eligibilityProof), hashString(validityProof), testVoterRoot)
+		return err
+	}
+
+	// Proofs are required
+	err := cast("nullifier0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "transient")
+
+	// Proofs must match the hashes recorded on the vote
+	stub.Transient["eligibilityProof"] = []byte(eligibilityProof)
+	stub.Transient["validityProof"] = []byte("other")
+	err = cast("nullifier0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match")
+
+	// Oversized proofs are rejected
+	validityProof = strings.Repeat("v", MaxStoredProofSize+1)
+	stub.Transient["validityProof"] = []byte(validityProof)
+	err = cast("nullifier0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds")
+
+	_, err = contract.GetVote(ctx, "election-001", "nullifier0")
+	assert.True(t, errors.Is(err, ErrVoteNotFound))
+
+	// A batch cannot carry proofs
+	_, err = contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testVote(10), Nullifier: "nullifier1", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.Error(t, err)
+}
+
+func TestGetVoteProofNotStored(t *testing.T) {
+	contract, ctx, stub := setupProofStorageElection(ProofStorageNone)
+
+	_, err := contract.CastVote(ctx, "election-001", testVote(10), "nullifier0",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	_, err = contract.GetVoteProof(ctx, "election-001", "nullifier0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not store")
+
+	// A vote cast before the election stored proofs has none
+	election := createMockElection()
+	election.ProofStorage = ProofStorageState
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+	_, err = contract.GetVoteProof(ctx, "election-001", "nullifier0")
+	assert.True(t, errors.Is(err, ErrVoteProofNotFound))
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = contract.GetVoteProof(ctx, "election-001", "nullifier404")
+	assert.True(t, errors.Is(err, ErrVoteNotFound))
+
+	config, _ := json.Marshal(ElectionConfig{ProofStorage: "ipfs"})
+	_, err = contract.CreateElectionWithConfig(ctx, "election-002", "Test", "root", testPublicKeyJSON(),
+		time.Now().Format(time.RFC3339), time.Now().Add(24*time.Hour).Format(time.RFC3339), string(config))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "proof storage")
+}
@@ -0,0 +1,153 @@
+/*
+ * Write-In - Encrypted write-in candidates
+ *
+ * Elections created with AllowWriteIns reserve a write-in channel on the
+ * ballot: the ciphertext after the last of the election's Options, counted
+ * in the tally under OptionWriteIn. A ballot may carry the name it writes
+ * in, passed to CastVote in the transient field "writeIn" so it never
+ * appears in the proposal. The name is a ciphertext vector under the
+ * election key (how it is encoded into plaintexts is up to the client) and
+ * is stored with the vote.
+ *
+ * Write-ins cannot be added homomorphically. The tally authority decrypts
+ * the ones GetEncryptedWriteIns lists and passes the names to
+ * StoreTallyResult (or CombinePartialDecryptions) in the transient field
+ * "writeIns", keyed by encrypted vote hash, e.g. {"9f2c...": "Jane Doe"}.
+ * Every counted write-in must be decrypted. The tally groups the names,
+ * with whitespace collapsed, into TallyResult.WriteIns; blank names are
+ * left out.
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// OptionWriteIn is the reserved option key of the write-in channel
+const OptionWriteIn = "__write_in__"
+
+// MaxWriteInCiphertexts bounds the ciphertexts of one encrypted write-in
+const MaxWriteInCiphertexts = 64
+
+// WriteInBallot is an encrypted write-in with the vote it was cast with
+type WriteInBallot struct {
+	EncryptedVoteHash string `json:"encryptedVoteHash"`
+	EncryptedWriteIn  string `json:"encryptedWriteIn"`
+}
+
+// validateWriteIns checks the settings a write-in election depends on
+func validateWriteIns(config ElectionConfig) error {
+	if !config.AllowWriteIns {
+		return nil
+	}
+	// The write-in channel sits after the candidates of a single question
+	if len(config.Questions) > 0 {
+		return fmt.Errorf("write-ins do not support multiple questions")
+	}
+	if len(config.Options) == 0 {
+		return fmt.Errorf("write-ins need a fixed option list")
+	}
+	if config.BallotType == BallotTypeRanked {
+		return fmt.Errorf("write-ins are not supported with ranked ballots")
+	}
+	return nil
+}
+
+// submittedWriteIn returns the encrypted write-in passed with a ballot, or
+// "" when there is none
+func submittedWriteIn(ctx contractapi.TransactionContextInterface, election *Election) (string, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("failed to read transient data: %v", err)
+	}
+	writeIn := string(transient["writeIn"])
+	if writeIn == "" {
+		return "", nil
+	}
+	if !election.AllowWriteIns {
+		return "", fmt.Errorf("election %s does not allow write-ins", election.ID)
+	}
+
+	ciphertexts, err := parseBallot(writeIn)
+	if err != nil {
+		return "", fmt.Errorf("invalid write-in: %v", err)
+	}
+	if len(ciphertexts) > MaxWriteInCiphertexts {
+		return "", fmt.Errorf("write-in has %d ciphertexts, at most %d allowed", len(ciphertexts), MaxWriteInCiphertexts)
+	}
+	if err := validateCiphertext(writeIn, election.PublicKey); err != nil {
+		return "", fmt.Errorf("invalid write-in: %w", err)
+	}
+	return writeIn, nil
+}
+
+// writeInBallots lists the write-ins of the votes counted in an election
+func writeInBallots(ctx contractapi.TransactionContextInterface, electionID string) ([]WriteInBallot, error) {
+	votes, err := countedVotes(ctx, electionID)
+	if err != nil {
+		return nil, err
+	}
+	ballots := []WriteInBallot{}
+	for _, vote := range votes {
+		if vote.EncryptedWriteIn == "" {
+			continue
+		}
+		ballots = append(ballots, WriteInBallot{
+			EncryptedVoteHash: vote.EncryptedVoteHash,
+			EncryptedWriteIn:  vote.EncryptedWriteIn,
+		})
+	}
+	return ballots, nil
+}
+
+// groupWriteIns counts the decrypted write-ins in the transient field
+// "writeIns" by name, or returns nil when there are none
+func groupWriteIns(ctx contractapi.TransactionContextInterface, election *Election) (map[string]int, error) {
+	ballots, err := writeInBallots(ctx, election.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transient data: %v", err)
+	}
+	var names map[string]string
+	if raw, ok := transient["writeIns"]; ok {
+		if err := json.Unmarshal(raw, &names); err != nil {
+			return nil, fmt.Errorf("invalid write-ins: %v", err)
+		}
+	}
+
+	cast := make(map[string]bool, len(ballots))
+	for _, ballot := range ballots {
+		cast[ballot.EncryptedVoteHash] = true
+	}
+	for hash := range names {
+		if !cast[hash] {
+			return nil, fmt.Errorf("decrypted write-in for unknown vote %s", hash)
+		}
+	}
+
+	var grouped map[string]int
+	for _, ballot := range ballots {
+		name, ok := names[ballot.EncryptedVoteHash]
+		if !ok {
+			return nil, fmt.Errorf("write-in of vote %s was not decrypted", ballot.EncryptedVoteHash)
+		}
+		name = strings.Join(strings.Fields(name), " ")
+		if name == "" {
+			continue
+		}
+		if grouped == nil {
+			grouped = make(map[string]int)
+		}
+		grouped[name]++
+	}
+	return grouped, nil
+}
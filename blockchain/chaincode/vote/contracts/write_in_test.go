@@ -0,0 +1,145 @@
+/*
+ * Write-In Tests
+ */
+
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testWriteIn encrypts each byte of a name under the test key
+func testWriteIn(name string, r int64) string {
+	writeIn := make([]CiphertextJSON, len(name))
+	for i := 0; i < len(name); i++ {
+		writeIn[i] = testEncrypt(int64(name[i]), r+int64(i))
+	}
+	writeInJSON, _ := json.Marshal(writeIn)
+	return string(writeInJSON)
+}
+
+func TestValidateWriteIns(t *testing.T) {
+	assert.NoError(t, validateWriteIns(ElectionConfig{}))
+	assert.NoError(t, validateWriteIns(ElectionConfig{AllowWriteIns: true, Options: []string{"alice", "bob"}}))
+
+	for _, config := range []ElectionConfig{
+		{AllowWriteIns: true},
+		{AllowWriteIns: true, Questions: []Question{{ID: "mayor", Options: []string{"alice"}}}},
+		{AllowWriteIns: true, Options: []string{"alice", "bob"}, BallotType: BallotTypeRanked},
+	} {
+		assert.Error(t, validateWriteIns(config))
+	}
+}
+
+func TestCastAndTallyWriteIns(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	startTime := time.Now().Add(-1 * time.Hour).Format(time.RFC3339)
+	endTime := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+	config, _ := json.Marshal(ElectionConfig{AllowWriteIns: true, Options: []string{"alice", "bob"}})
+	_, err := contract.CreateElectionWithConfig(ctx, "election-001", "Test", "root", testPublicKeyJSON(),
+		startTime, endTime, string(config))
+	assert.NoError(t, err)
+	_, err = contract.ActivateElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	// One ballot for a candidate, three writing a name in
+	_, err = contract.CastVote(ctx, "election-001", testBallot(0, 3, 10), "nullifier0",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.NoError(t, err)
+	hashes := make([]string, 3)
+	for i, name := range []string{"Jane Doe", "jane", "Jane Doe"} {
+		stub.Transient["writeIn"] = []byte(testWriteIn(name, int64(100*i+20)))
+		receipt, err := contract.CastVote(ctx, "election-001", testBallot(2, 3, int64(i+30)), fmt.Sprintf("nullifier%d", i+1),
+			testEligibilityHash, testValidityHash, testVoterRoot)
+		assert.NoError(t, err)
+		hashes[i] = receipt.EncryptedVoteHash
+	}
+
+	// A write-in must be a ciphertext vector in the election's group
+	stub.Transient["writeIn"] = []byte(`[{"c1": "0", "c2": "1"}]`)
+	_, err = contract.CastVote(ctx, "election-001", testBallot(2, 3, 40), "nullifier9",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+
+	// A batch cannot say which of its ballots a write-in belongs to
+	stub.Transient["writeIn"] = []byte(testWriteIn("Jane Doe", 50))
+	_, err = contract.CastVoteBatch(ctx, "election-001", []EncryptedBallotInput{
+		{EncryptedVote: testBallot(2, 3, 41), Nullifier: "nullifier9", EligibilityProofHash: testEligibilityHash, ValidityProofHash: testValidityHash, ProofVoterRoot: testVoterRoot},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "one at a time")
+	delete(stub.Transient, "writeIn")
+
+	vote, err := contract.GetVote(ctx, "election-001", "nullifier1")
+	assert.NoError(t, err)
+	assert.Equal(t, testWriteIn("Jane Doe", 20), vote.EncryptedWriteIn)
+
+	_, err = contract.GetEncryptedWriteIns(ctx, "election-001")
+	assert.Error(t, err, "write-ins stay sealed while voting is open")
+	_, err = contract.CloseElection(ctx, "election-001")
+	assert.NoError(t, err)
+
+	writeIns, err := contract.GetEncryptedWriteIns(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Len(t, writeIns, 3)
+
+	// Every write-in must be decrypted
+	counts := `{"alice": 1, "bob": 0, "__write_in__": 3}`
+	names := map[string]string{hashes[0]: "Jane Doe", hashes[1]: "jane"}
+	namesJSON, _ := json.Marshal(names)
+	stub.Transient["writeIns"] = namesJSON
+	err = contract.StoreTallyResult(ctx, "election-001", counts, "hash", "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "was not decrypted")
+
+	names[hashes[2]] = "  Jane   Doe "
+	namesJSON, _ = json.Marshal(names)
+	stub.Transient["writeIns"] = namesJSON
+	assert.NoError(t, contract.StoreTallyResult(ctx, "election-001", counts, "hash", ""))
+
+	tally, err := contract.GetTallyResult(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"Jane Doe": 2, "jane": 1}, tally.WriteIns)
+	assert.Equal(t, 4, tally.TotalVotes)
+
+	results, err := contract.GetElectionResults(ctx, "election-001")
+	assert.NoError(t, err)
+	assert.Equal(t, tally.WriteIns, results.WriteIns)
+	assert.Equal(t, []string{"alice"}, results.Questions[0].Winners)
+	assert.Equal(t, 4, results.Questions[0].TotalVotes)
+}
+
+func TestWriteInsRequireElectionOptIn(t *testing.T) {
+	contract := new(VoteContract)
+	ctx := new(MockTransactionContext)
+	stub := NewMockStub()
+
+	ctx.On("GetStub").Return(stub)
+
+	election := createMockElection()
+	electionJSON, _ := json.Marshal(election)
+	stub.State["election:election-001"] = electionJSON
+
+	stub.Transient["writeIn"] = []byte(testWriteIn("Jane", 20))
+	_, err := contract.CastVote(ctx, "election-001", testVote(10), "nullifier1",
+		testEligibilityHash, testValidityHash, testVoterRoot)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not allow write-ins")
+
+	// Nor may a tally count the write-in channel
+	storeCompletedTally(t, ctx, stub, createMockElection(), `{"1": 1}`)
+	assert.NoError(t, contract.ReopenTally(ctx, "election-001", "recount"))
+	err = contract.StoreTallyResult(ctx, "election-001", `{"1": 1, "__write_in__": 1}`, "hash", "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not allow write-ins")
+}
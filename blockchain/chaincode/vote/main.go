@@ -6,6 +6,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/voting/chaincode/vote/contracts"
@@ -14,6 +15,15 @@ import (
 func main() {
 	voteContract := new(contracts.VoteContract)
 
+	// Comma separated MSP IDs allowed to run administrative functions
+	if adminMSPs := os.Getenv("VOTE_ADMIN_MSP_IDS"); adminMSPs != "" {
+		mspIDs, err := contracts.ParseAdminMSPIDs(adminMSPs)
+		if err != nil {
+			log.Panicf("Error reading VOTE_ADMIN_MSP_IDS: %v", err)
+		}
+		voteContract.AdminMSPIDs = mspIDs
+	}
+
 	chaincode, err := contractapi.NewChaincode(voteContract)
 	if err != nil {
 		log.Panicf("Error creating vote chaincode: %v", err)